@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/processor"
@@ -119,6 +120,7 @@ func NewCapture(
 		ID:            id,
 		AdvertiseAddr: conf.AdvertiseAddr,
 		Version:       version.ReleaseVersion,
+		Labels:        conf.Labels,
 	}
 	processorManager := processor.NewManager()
 	log.Info("creating capture", zap.String("capture-id", id), util.ZapFieldCapture(stdCtx))
@@ -257,6 +259,16 @@ func (c *Capture) Cleanup() {
 	}
 }
 
+// schemaStorage returns the schema storage of the changefeed running in the capture
+func (c *Capture) schemaStorage(changefeedID string) entry.SchemaStorage {
+	c.procLock.Lock()
+	defer c.procLock.Unlock()
+	if p, ok := c.processors[changefeedID]; ok {
+		return p.schemaStorage
+	}
+	return nil
+}
+
 // Close closes the capture by unregistering it from etcd
 func (c *Capture) Close(ctx context.Context) error {
 	if config.NewReplicaImpl {
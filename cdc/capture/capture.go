@@ -24,6 +24,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/owner"
@@ -45,6 +46,8 @@ import (
 type Capture struct {
 	captureMu sync.Mutex
 	info      *model.CaptureInfo
+	// draining is kept across the resets of the capture, so that a drained capture is not scheduled again
+	draining bool
 
 	ownerMu          sync.Mutex
 	owner            *owner.Owner
@@ -85,6 +88,8 @@ func (c *Capture) reset() error {
 		ID:            uuid.New().String(),
 		AdvertiseAddr: conf.AdvertiseAddr,
 		Version:       version.ReleaseVersion,
+		Labels:        conf.Labels,
+		Draining:      c.draining,
 	}
 	c.processorManager = c.newProcessorManager()
 	if c.session != nil {
@@ -323,6 +328,80 @@ func (c *Capture) register(ctx cdcContext.Context) error {
 	return nil
 }
 
+// Drain marks the capture as draining, then the owner stops dispatching tables to the capture
+// and moves the tables of the capture to the other captures one by one.
+func (c *Capture) Drain(ctx context.Context) error {
+	c.captureMu.Lock()
+	defer c.captureMu.Unlock()
+	if c.info == nil || c.session == nil {
+		return cerror.ErrCaptureNotRunning.GenWithStackByArgs()
+	}
+	c.draining = true
+	if c.info.Draining {
+		return nil
+	}
+	// the old info may be in use by the processors, so it's copied instead of modified
+	info := *c.info
+	info.Draining = true
+	if err := c.etcdClient.PutCaptureInfo(ctx, &info, c.session.Lease()); err != nil {
+		return errors.Trace(err)
+	}
+	c.info = &info
+	log.Info("capture is draining", zap.String("capture-id", info.ID))
+	return nil
+}
+
+// DrainStatus returns the progress of draining the capture
+func (c *Capture) DrainStatus(ctx context.Context) (*model.DrainStatus, error) {
+	info, err := c.runningInfo()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	status := &model.DrainStatus{CaptureID: info.ID, Draining: info.Draining}
+	if !status.Draining {
+		return status, nil
+	}
+	_, changefeeds, err := c.etcdClient.GetChangeFeeds(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for changefeedID := range changefeeds {
+		statuses, err := c.etcdClient.GetAllTaskStatus(ctx, changefeedID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		status.RemainingTables += model.CountDrainingTables(info.ID, statuses)
+	}
+	return status, nil
+}
+
+// CanBeDrained returns whether any other capture is alive and schedulable, which the tables can be moved to
+func (c *Capture) CanBeDrained(ctx context.Context) (bool, error) {
+	info, err := c.runningInfo()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	_, captures, err := c.etcdClient.GetCaptures(ctx)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	for _, capture := range captures {
+		if capture.ID != info.ID && capture.IsSchedulable() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c *Capture) runningInfo() (model.CaptureInfo, error) {
+	c.captureMu.Lock()
+	defer c.captureMu.Unlock()
+	if c.info == nil {
+		return model.CaptureInfo{}, cerror.ErrCaptureNotRunning.GenWithStackByArgs()
+	}
+	return *c.info, nil
+}
+
 // AsyncClose closes the capture by unregistering it from etcd
 func (c *Capture) AsyncClose() {
 	defer c.cancel()
@@ -352,6 +431,17 @@ func (c *Capture) WriteDebugInfo(w io.Writer) {
 	}
 }
 
+// SchemaStorage returns the schema storage of the changefeed running in the capture,
+// nil is returned if the changefeed is not running in the capture.
+func (c *Capture) SchemaStorage(changefeedID model.ChangeFeedID) entry.SchemaStorage {
+	c.captureMu.Lock()
+	defer c.captureMu.Unlock()
+	if c.processorManager == nil {
+		return nil
+	}
+	return c.processorManager.SchemaStorage(changefeedID)
+}
+
 // IsOwner returns whether the capture is an owner
 func (c *Capture) IsOwner() bool {
 	return c.OperateOwnerUnderLock(func(o *owner.Owner) error {
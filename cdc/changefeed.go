@@ -496,8 +496,11 @@ func (c *changeFeed) rebalanceTables(ctx context.Context, captures map[model.Cap
 	}
 	c.scheduler.AlignCapture(captureIDs)
 
-	_, moveTableJobs := c.scheduler.CalRebalanceOperates(0)
-	log.Info("rebalance operations", zap.Reflect("moveTableJobs", moveTableJobs))
+	skewness := c.scheduler.Skewness()
+	changefeedWorkloadSkewnessGauge.WithLabelValues(c.id).Set(skewness)
+	expectedSkewness, moveTableJobs := c.scheduler.CalRebalanceOperates(0)
+	log.Info("rebalance operations", zap.Reflect("moveTableJobs", moveTableJobs),
+		zap.Float64("skewness", skewness), zap.Float64("expectedSkewness", expectedSkewness))
 	c.moveTableJobs = moveTableJobs
 	return nil
 }
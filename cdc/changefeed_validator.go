@@ -0,0 +1,133 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/cyclic"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/util"
+	tidbkv "github.com/pingcap/tidb/kv"
+	pd "github.com/tikv/pd/client"
+)
+
+// ValidateChangefeed checks whether the changefeed can be created or updated without any side effects,
+// all the problems found are reported in the result rather than returning the first one.
+// The tables are selected in the snapshot at checkpointTs, which is the start-ts for a new changefeed.
+func ValidateChangefeed(
+	ctx context.Context, pdClient pd.Client, kvStorage tidbkv.Storage,
+	id model.ChangeFeedID, info *model.ChangeFeedInfo, checkpointTs uint64,
+) *model.ChangefeedValidation {
+	result := &model.ChangefeedValidation{
+		ChangefeedID: id,
+		StartTs:      checkpointTs,
+	}
+	if err := model.ValidateChangefeedID(id); err != nil {
+		result.AddError(model.ValidationChangefeedID, err)
+	}
+	if info.TargetTs > 0 && info.TargetTs <= checkpointTs {
+		result.AddError(model.ValidationTs, cerror.ErrTargetTsBeforeStartTs.GenWithStackByArgs(info.TargetTs, checkpointTs))
+	}
+	if info.Config.CheckGCSafePoint {
+		if err := util.CheckStartTsBeforeGC(ctx, pdClient, id, checkpointTs); err != nil {
+			result.AddError(model.ValidationGCSafePoint, err)
+		}
+	}
+
+	cfg := info.Config
+	if cfg.Notification != nil {
+		if err := cfg.Notification.Validate(); err != nil {
+			result.AddError(model.ValidationReplicaConfig, err)
+		}
+	}
+	if cfg.Scheduler != nil {
+		if err := cfg.Scheduler.Validate(); err != nil {
+			result.AddError(model.ValidationReplicaConfig, err)
+		}
+		if len(cfg.Scheduler.SplitRules) != 0 && cfg.Cyclic.IsEnabled() {
+			result.AddError(model.ValidationReplicaConfig, cerror.ErrInvalidSchedulerConfig.GenWithStack(
+				"tables can't be split if the cyclic replication is enabled"))
+		}
+		if len(cfg.Scheduler.SplitRules) != 0 && !cfg.EnableOldValue {
+			result.AddError(model.ValidationReplicaConfig, cerror.ErrInvalidSchedulerConfig.GenWithStack(
+				"tables can't be split if the old value is disabled"))
+		}
+	}
+	if cfg.Resource != nil {
+		if err := cfg.Resource.Validate(); err != nil {
+			result.AddError(model.ValidationReplicaConfig, err)
+		}
+	}
+	if cfg.Sink != nil {
+		if err := cfg.Sink.Validate(); err != nil {
+			result.AddError(model.ValidationReplicaConfig, err)
+		}
+		if !cfg.EnableOldValue && len(cfg.Sink.PartialUpdateTables) != 0 {
+			result.AddError(model.ValidationReplicaConfig, cerror.ErrOldValueNotEnabled.GenWithStack(
+				"if use partial update, old value feature must be enabled"))
+		}
+	}
+	if !cfg.EnableOldValue && cfg.ForceReplicate {
+		result.AddError(model.ValidationReplicaConfig, cerror.ErrOldValueNotEnabled.GenWithStack(
+			"if use force replicate, old value feature must be enabled"))
+	}
+	switch info.Engine {
+	case model.SortUnified, model.SortInMemory, model.SortInFile, model.SortInLevelDB:
+	default:
+		result.AddError(model.ValidationReplicaConfig, cerror.ErrUnknownSortEngine.GenWithStackByArgs(info.Engine))
+	}
+	// the tables and the sink can't be checked without a filter
+	f, err := filter.NewFilter(cfg)
+	if err != nil {
+		result.AddError(model.ValidationReplicaConfig, err)
+		return result
+	}
+
+	ineligibleTables, eligibleTables, err := entry.VerifyTables(f, kvStorage, checkpointTs)
+	if err != nil {
+		result.AddError(model.ValidationTables, err)
+	} else {
+		result.IneligibleTables = ineligibleTables
+		result.EligibleTables = eligibleTables
+		if len(ineligibleTables) > 0 {
+			if cfg.ForceReplicate {
+				result.AddWarning(model.ValidationTables, fmt.Sprintf(
+					"%d tables without a primary key or a not null unique key are replicated forcibly, "+
+						"their rows may be duplicated in the downstream", len(ineligibleTables)))
+			} else {
+				result.AddWarning(model.ValidationTables, fmt.Sprintf(
+					"%d tables without a primary key or a not null unique key are not replicated", len(ineligibleTables)))
+			}
+		}
+		if len(ineligibleTables) == 0 && len(eligibleTables) == 0 {
+			result.AddWarning(model.ValidationTables, "no table is selected by the filter")
+		}
+		if cfg.Cyclic.IsEnabled() && !cyclic.IsTablesPaired(eligibleTables) {
+			result.AddError(model.ValidationTables, cerror.ErrCyclicMarkTablesNotPaired.GenWithStackByArgs())
+		}
+	}
+
+	if info.SinkURI == "" {
+		result.AddError(model.ValidationSink, cerror.ErrSinkURIInvalid.GenWithStack("empty sink uri"))
+	} else if err := sink.Validate(ctx, id, info.SinkURI, f, cfg, info.Opts); err != nil {
+		result.AddError(model.ValidationSink, err)
+	}
+	return result
+}
@@ -436,6 +436,7 @@ func datum2Column(tableInfo *model.TableInfo, datums map[int64]types.Datum, fill
 			Type:  colInfo.Tp,
 			Value: colValue,
 			Flag:  tableInfo.ColumnsFlag[colInfo.ID],
+			Meta:  tableInfo.ColumnsMeta[colInfo.ID],
 		}
 	}
 	return cols, nil
@@ -535,6 +536,7 @@ func (m *mounterImpl) mountIndexKVEntry(tableInfo *model.TableInfo, idx *indexKV
 			Type:  colInfo.Tp,
 			Value: value,
 			Flag:  tableInfo.ColumnsFlag[colInfo.ID],
+			Meta:  tableInfo.ColumnsMeta[colInfo.ID],
 		}
 	}
 	var intRowID int64
@@ -275,6 +275,12 @@ func testMounterDisableOldValue(c *check.C, tc struct {
 			c.Assert(row.Table.Table, check.Equals, tc.tableName)
 			c.Assert(row.Table.Schema, check.Equals, "test")
 			// TODO: test column flag, column type and index columns
+			for _, col := range append(row.Columns, row.PreColumns...) {
+				if col != nil {
+					// the column meta is shared by the rows of the same table version
+					c.Assert(col.Meta, check.NotNil)
+				}
+			}
 			if len(row.Columns) != 0 {
 				checkSQL, params := prepareCheckSQL(c, tc.tableName, row.Columns)
 				result := tk.MustQuery(checkSQL, params...)
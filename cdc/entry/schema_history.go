@@ -0,0 +1,284 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	tidbkv "github.com/pingcap/tidb/kv"
+	timeta "github.com/pingcap/tidb/meta"
+)
+
+// TableSchemaHistory is the definition of a table used by the mounter at a ts,
+// and the DDL jobs of the table applied to the schema storage before the ts
+type TableSchemaHistory struct {
+	Table   *TableSchema     `json:"table"`
+	DDLJobs []*DDLJobSummary `json:"ddl-jobs"`
+}
+
+// TableSchema is the definition of a table in a schema snapshot
+type TableSchema struct {
+	Schema           string `json:"schema"`
+	Table            string `json:"table"`
+	TableID          int64  `json:"table-id"`
+	TableInfoVersion uint64 `json:"table-info-version"`
+	// HandleIndexID is the ID of the index used as the handle key, -1 means the pk is the handle,
+	// and -2 means the table is not eligible
+	HandleIndexID int64           `json:"handle-index-id"`
+	HandleKey     []string        `json:"handle-key"`
+	Columns       []*ColumnSchema `json:"columns"`
+	Indices       []*IndexSchema  `json:"indices"`
+}
+
+// ColumnSchema is the definition of a column
+type ColumnSchema struct {
+	ID    int64                `json:"id"`
+	Name  string               `json:"name"`
+	Type  string               `json:"type"`
+	State string               `json:"state"`
+	Flag  model.ColumnFlagType `json:"flag"`
+	// Flags are the names of the bits set in the flag
+	Flags []string `json:"flags"`
+}
+
+// IndexSchema is the definition of an index
+type IndexSchema struct {
+	ID      int64    `json:"id"`
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Primary bool     `json:"primary"`
+	Unique  bool     `json:"unique"`
+	State   string   `json:"state"`
+}
+
+// DDLJobSummary describes a DDL job applied to the schema storage
+type DDLJobSummary struct {
+	ID         int64  `json:"id"`
+	Type       string `json:"type"`
+	Schema     string `json:"schema"`
+	Query      string `json:"query"`
+	FinishedTs uint64 `json:"finished-ts"`
+}
+
+var columnFlagNames = []struct {
+	name string
+	is   func(*model.ColumnFlagType) bool
+}{
+	{"binary", (*model.ColumnFlagType).IsBinary},
+	{"handle-key", (*model.ColumnFlagType).IsHandleKey},
+	{"generated-column", (*model.ColumnFlagType).IsGeneratedColumn},
+	{"primary-key", (*model.ColumnFlagType).IsPrimaryKey},
+	{"unique-key", (*model.ColumnFlagType).IsUniqueKey},
+	{"multiple-key", (*model.ColumnFlagType).IsMultipleKey},
+	{"nullable", (*model.ColumnFlagType).IsNullable},
+	{"unsigned", (*model.ColumnFlagType).IsUnsigned},
+}
+
+func newTableSchema(tableInfo *model.TableInfo) *TableSchema {
+	schema := &TableSchema{
+		Schema:           tableInfo.TableName.Schema,
+		Table:            tableInfo.TableName.Table,
+		TableID:          tableInfo.ID,
+		TableInfoVersion: tableInfo.TableInfoVersion,
+		HandleIndexID:    tableInfo.HandleIndexID,
+		HandleKey:        []string{},
+		Columns:          make([]*ColumnSchema, 0, len(tableInfo.Columns)),
+		Indices:          make([]*IndexSchema, 0, len(tableInfo.Indices)),
+	}
+	for _, col := range tableInfo.Columns {
+		flag := tableInfo.ColumnsFlag[col.ID]
+		column := &ColumnSchema{
+			ID:    col.ID,
+			Name:  col.Name.O,
+			Type:  col.GetTypeDesc(),
+			State: col.State.String(),
+			Flag:  flag,
+			Flags: []string{},
+		}
+		for _, f := range columnFlagNames {
+			if f.is(&flag) {
+				column.Flags = append(column.Flags, f.name)
+			}
+		}
+		if flag.IsHandleKey() {
+			schema.HandleKey = append(schema.HandleKey, col.Name.O)
+		}
+		schema.Columns = append(schema.Columns, column)
+	}
+	for _, idx := range tableInfo.Indices {
+		index := &IndexSchema{
+			ID:      idx.ID,
+			Name:    idx.Name.O,
+			Columns: make([]string, 0, len(idx.Columns)),
+			Primary: idx.Primary,
+			Unique:  idx.Unique,
+			State:   idx.State.String(),
+		}
+		for _, col := range idx.Columns {
+			index.Columns = append(index.Columns, col.Name.O)
+		}
+		schema.Indices = append(schema.Indices, index)
+	}
+	return schema
+}
+
+// jobTableIDs returns the IDs of the tables changed by the job, the truncated table has a new ID
+func jobTableIDs(job *timodel.Job) []int64 {
+	ids := []int64{job.TableID}
+	if job.BinlogInfo != nil && job.BinlogInfo.TableInfo != nil {
+		ids = append(ids, job.BinlogInfo.TableInfo.ID)
+	}
+	return ids
+}
+
+// QueryTableSchemaHistory returns the table in the schema snapshot at the ts and the DDL jobs of the table finished
+// in (startTs, ts]. The snapshot is taken from the schema storage of the changefeed if it's running in the capture
+// and the ts is in the range of the storage, otherwise the DDL jobs are replayed to a schema storage starting at the
+// start ts, as the processor does, and the snapshot at the start ts is loaded from the schema snapshot cache if it's
+// enabled. The running storage can be nil, the filter and the force replicate option should be the ones of the changefeed.
+// Note the table info version of the tables unchanged after the start ts of a schema storage is its start ts.
+func QueryTableSchemaHistory(
+	ctx context.Context, storage tidbkv.Storage, running SchemaStorage, f *filter.Filter, forceReplicate bool,
+	table model.TableName, startTs, ts uint64,
+) (*TableSchemaHistory, error) {
+	if startTs > ts {
+		return nil, errors.Errorf("start ts %d is larger than ts %d", startTs, ts)
+	}
+	startMeta := timeta.NewSnapshotMeta(storage.GetSnapshot(tidbkv.NewVersion(startTs)))
+	pendingJobID, err := minPendingJobID(startMeta)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	meta := timeta.NewSnapshotMeta(storage.GetSnapshot(tidbkv.NewVersion(ts)))
+	jobs, err := loadDDLJobs(meta, startTs, ts, pendingJobID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	snap, err := getRunningSchemaSnapshot(ctx, running, ts)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if snap == nil {
+		snap, err = replaySchemaSnapshot(ctx, storage, startMeta, f, forceReplicate, startTs, ts, jobs)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	// the skipped jobs are not applied to the schema storage
+	applied := make([]*timodel.Job, 0, len(jobs))
+	skipper := &schemaStorageImpl{filter: f}
+	for _, job := range jobs {
+		if !skipper.skipJob(job) {
+			applied = append(applied, job)
+		}
+	}
+
+	tableInfo, ok := snap.GetTableByName(table.Schema, table.Table)
+	if !ok {
+		return nil, cerror.ErrSnapshotTableNotFound.GenWithStack("table %s not found in schema snapshot at %d", table, ts)
+	}
+
+	history := &TableSchemaHistory{
+		Table:   newTableSchema(tableInfo),
+		DDLJobs: []*DDLJobSummary{},
+	}
+	// the jobs are iterated from the newest one, because the table ID is changed by the truncate table jobs
+	tableIDs := map[int64]struct{}{tableInfo.ID: {}}
+	for i := len(applied) - 1; i >= 0; i-- {
+		job := applied[i]
+		matched := false
+		for _, id := range jobTableIDs(job) {
+			if _, ok := tableIDs[id]; ok {
+				matched = true
+			}
+		}
+		if !matched {
+			continue
+		}
+		for _, id := range jobTableIDs(job) {
+			tableIDs[id] = struct{}{}
+		}
+		history.DDLJobs = append(history.DDLJobs, &DDLJobSummary{
+			ID:         job.ID,
+			Type:       job.Type.String(),
+			Schema:     job.SchemaName,
+			Query:      job.Query,
+			FinishedTs: job.BinlogInfo.FinishedTS,
+		})
+	}
+	// the jobs are returned in the order they are applied
+	for i, j := 0, len(history.DDLJobs)-1; i < j; i, j = i+1, j-1 {
+		history.DDLJobs[i], history.DDLJobs[j] = history.DDLJobs[j], history.DDLJobs[i]
+	}
+	return history, nil
+}
+
+// getRunningSchemaSnapshot returns the snapshot at the ts in the running schema storage,
+// nil is returned if the storage is nil or the ts is out of the range of the storage.
+func getRunningSchemaSnapshot(ctx context.Context, running SchemaStorage, ts uint64) (*schemaSnapshot, error) {
+	if running == nil || ts > running.ResolvedTs() {
+		return nil, nil
+	}
+	snap, err := running.GetSnapshot(ctx, ts)
+	if err != nil {
+		if cerror.ErrSchemaStorageGCed.Equal(err) || cerror.ErrSchemaSnapshotNotFound.Equal(err) {
+			return nil, nil
+		}
+		return nil, errors.Trace(err)
+	}
+	return snap, nil
+}
+
+// replaySchemaSnapshot applies the DDL jobs to a schema storage starting at the start ts and returns
+// the snapshot at the ts, the start meta must be a snapshot meta at the start ts.
+func replaySchemaSnapshot(
+	ctx context.Context, storage tidbkv.Storage, startMeta *timeta.Meta, f *filter.Filter, forceReplicate bool,
+	startTs, ts uint64, jobs []*timodel.Job,
+) (*schemaSnapshot, error) {
+	var snap *schemaSnapshot
+	var err error
+	if cache := getSchemaSnapshotCache(storage.UUID()); cache != nil {
+		snap, err = cache.getSnapshot(startMeta, startTs, forceReplicate)
+	} else {
+		snap, err = buildSchemaSnapshotFromMeta(startMeta, startTs, forceReplicate)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	schemaStorage := &schemaStorageImpl{
+		snaps:          []*schemaSnapshot{snap},
+		resolvedTs:     startTs,
+		filter:         f,
+		explicitTables: forceReplicate,
+	}
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := schemaStorage.HandleDDLJob(job); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	schemaStorage.AdvanceResolvedTs(ts)
+	return schemaStorage.GetSnapshot(ctx, ts)
+}
@@ -0,0 +1,192 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type schemaHistorySuite struct{}
+
+var _ = check.Suite(&schemaHistorySuite{})
+
+func (s *schemaHistorySuite) TestQueryTableSchemaHistory(c *check.C) {
+	defer testleak.AfterTest(c)()
+	helper := NewSchemaTestHelper(c)
+	defer helper.Close()
+	ctx := context.Background()
+	f, err := filter.NewFilter(config.GetDefaultReplicaConfig())
+	c.Assert(err, check.IsNil)
+	helper.DDL2Job("create database test1")
+	helper.DDL2Job("create table test1.t1(id int primary key, a varchar(10) not null, b int, unique key uk(a))")
+	_, startTs := currentMeta(c, helper)
+
+	helper.DDL2Job("alter table test1.t1 add column c bigint unsigned")
+	helper.DDL2Job("create table test1.t2(id int primary key)")
+	helper.DDL2Job("truncate table test1.t1")
+	helper.DDL2Job("alter table test1.t1 drop column b")
+	_, ts := currentMeta(c, helper)
+
+	name := model.TableName{Schema: "test1", Table: "t1"}
+	history, err := QueryTableSchemaHistory(ctx, helper.Storage(), nil, f, false, name, startTs, ts)
+	c.Assert(err, check.IsNil)
+	table := history.Table
+	c.Assert(table.Schema, check.Equals, "test1")
+	c.Assert(table.Table, check.Equals, "t1")
+	c.Assert(table.HandleKey, check.DeepEquals, []string{"id"})
+	c.Assert(table.Columns, check.HasLen, 3)
+	c.Assert(table.Columns[0].Name, check.Equals, "id")
+	c.Assert(table.Columns[0].Flags, check.DeepEquals, []string{"binary", "handle-key", "primary-key", "unique-key"})
+	c.Assert(table.Columns[1].Name, check.Equals, "a")
+	c.Assert(table.Columns[1].Type, check.Equals, "varchar(10)")
+	c.Assert(table.Columns[1].Flags, check.DeepEquals, []string{"unique-key"})
+	c.Assert(table.Columns[2].Name, check.Equals, "c")
+	c.Assert(table.Columns[2].Flags, check.DeepEquals, []string{"binary", "nullable", "unsigned"})
+	c.Assert(table.Indices, check.HasLen, 2)
+	c.Assert(table.Indices[0].Name, check.Equals, "uk")
+	c.Assert(table.Indices[0].Columns, check.DeepEquals, []string{"a"})
+	c.Assert(table.Indices[0].Unique, check.IsTrue)
+	c.Assert(table.Indices[1].Primary, check.IsTrue)
+	c.Assert(table.Indices[1].ID, check.Equals, table.HandleIndexID)
+
+	// the jobs before the truncate table job are found by the old table ID
+	c.Assert(history.DDLJobs, check.HasLen, 3)
+	types := make([]string, 0, len(history.DDLJobs))
+	for _, job := range history.DDLJobs {
+		types = append(types, job.Type)
+		c.Assert(job.FinishedTs, check.Greater, startTs)
+		c.Assert(job.FinishedTs <= ts, check.IsTrue)
+	}
+	c.Assert(types, check.DeepEquals, []string{
+		timodel.ActionAddColumn.String(), timodel.ActionTruncateTable.String(), timodel.ActionDropColumn.String(),
+	})
+
+	// the table is not created at the start ts
+	history, err = QueryTableSchemaHistory(ctx, helper.Storage(), nil, f, false,
+		model.TableName{Schema: "test1", Table: "t2"}, startTs, startTs)
+	c.Assert(cerror.ErrSnapshotTableNotFound.Equal(err), check.IsTrue)
+	c.Assert(history, check.IsNil)
+	_, err = QueryTableSchemaHistory(ctx, helper.Storage(), nil, f, false, name, ts, startTs)
+	c.Assert(err, check.ErrorMatches, ".*larger than.*")
+}
+
+type countingSchemaStorage struct {
+	SchemaStorage
+	getSnapshotCount int
+}
+
+func (s *countingSchemaStorage) GetSnapshot(ctx context.Context, ts uint64) (*schemaSnapshot, error) {
+	s.getSnapshotCount++
+	return s.SchemaStorage.GetSnapshot(ctx, ts)
+}
+
+func (s *schemaHistorySuite) TestQueryRunningSchemaStorage(c *check.C) {
+	defer testleak.AfterTest(c)()
+	helper := NewSchemaTestHelper(c)
+	defer helper.Close()
+	ctx := context.Background()
+	f, err := filter.NewFilter(config.GetDefaultReplicaConfig())
+	c.Assert(err, check.IsNil)
+	helper.DDL2Job("create database test1")
+	helper.DDL2Job("create table test1.t1(id int primary key, a int)")
+	startMeta, startTs := currentMeta(c, helper)
+	helper.DDL2Job("alter table test1.t1 add column b int")
+	helper.DDL2Job("truncate table test1.t1")
+	meta, ts := currentMeta(c, helper)
+
+	storage, err := NewSchemaStorage(startMeta, startTs, f, false)
+	c.Assert(err, check.IsNil)
+	pendingJobID, err := minPendingJobID(startMeta)
+	c.Assert(err, check.IsNil)
+	jobs, err := loadDDLJobs(meta, startTs, ts, pendingJobID)
+	c.Assert(err, check.IsNil)
+	for _, job := range jobs {
+		c.Assert(storage.HandleDDLJob(job), check.IsNil)
+	}
+	storage.AdvanceResolvedTs(ts)
+	running := &countingSchemaStorage{SchemaStorage: storage}
+
+	name := model.TableName{Schema: "test1", Table: "t1"}
+	expected, err := QueryTableSchemaHistory(ctx, helper.Storage(), nil, f, false, name, startTs, ts)
+	c.Assert(err, check.IsNil)
+	history, err := QueryTableSchemaHistory(ctx, helper.Storage(), running, f, false, name, startTs, ts)
+	c.Assert(err, check.IsNil)
+	c.Assert(running.getSnapshotCount, check.Equals, 1)
+	c.Assert(history, check.DeepEquals, expected)
+
+	// the ts not resolved by the running storage is replayed
+	helper.DDL2Job("alter table test1.t1 drop column b")
+	_, ts2 := currentMeta(c, helper)
+	history, err = QueryTableSchemaHistory(ctx, helper.Storage(), running, f, false, name, startTs, ts2)
+	c.Assert(err, check.IsNil)
+	c.Assert(running.getSnapshotCount, check.Equals, 1)
+	c.Assert(history.Table.Columns, check.HasLen, 2)
+	c.Assert(history.DDLJobs, check.HasLen, 3)
+
+	// the ts GCed by the running storage is replayed
+	storage.DoGC(ts)
+	history, err = QueryTableSchemaHistory(ctx, helper.Storage(), running, f, false, name, startTs, startTs)
+	c.Assert(err, check.IsNil)
+	c.Assert(running.getSnapshotCount, check.Equals, 2)
+	c.Assert(history.Table.Columns, check.HasLen, 2)
+	c.Assert(history.DDLJobs, check.HasLen, 0)
+
+	// the query is stopped if the context is canceled
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = QueryTableSchemaHistory(canceledCtx, helper.Storage(), nil, f, false, name, startTs, ts)
+	c.Assert(errors.Cause(err), check.Equals, context.Canceled)
+}
+
+func (s *schemaHistorySuite) TestQuerySchemaSnapshotCache(c *check.C) {
+	defer testleak.AfterTest(c)()
+	helper := NewSchemaTestHelper(c)
+	defer helper.Close()
+	conf := config.GetDefaultServerConfig()
+	conf.DataDir = c.MkDir()
+	config.StoreGlobalServerConfig(conf)
+	defer config.StoreGlobalServerConfig(config.GetDefaultServerConfig())
+	ctx := context.Background()
+	f, err := filter.NewFilter(config.GetDefaultReplicaConfig())
+	c.Assert(err, check.IsNil)
+	helper.DDL2Job("create database test1")
+	helper.DDL2Job("create table test1.t1(id int primary key, a int)")
+	_, startTs := currentMeta(c, helper)
+	helper.DDL2Job("alter table test1.t1 add column b int")
+	_, ts := currentMeta(c, helper)
+
+	name := model.TableName{Schema: "test1", Table: "t1"}
+	expected, err := QueryTableSchemaHistory(ctx, helper.Storage(), nil, f, false, name, startTs, ts)
+	c.Assert(err, check.IsNil)
+	// the snapshot at the start ts is cached for the upstream cluster
+	cache := getSchemaSnapshotCache(helper.Storage().UUID())
+	files, err := cache.list(false)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.DeepEquals, []snapshotFile{{name: snapshotFileName(startTs, false), ts: startTs}})
+
+	history, err := QueryTableSchemaHistory(ctx, helper.Storage(), nil, f, false, name, startTs, ts)
+	c.Assert(err, check.IsNil)
+	c.Assert(history, check.DeepEquals, expected)
+	c.Assert(history.Table.Columns, check.HasLen, 3)
+	c.Assert(history.DDLJobs, check.HasLen, 1)
+}
@@ -0,0 +1,539 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	timeta "github.com/pingcap/tidb/meta"
+	"github.com/tikv/client-go/v2/oracle"
+	"go.uber.org/zap"
+)
+
+// A snapshot file starts with the magic and the format version, followed by the snapshot encoded
+// by snapshotEncoder and compressed by snappy, and ends with the crc32 of the compressed snapshot.
+// The snapshots of each upstream cluster are cached in a separate dir, and the encoded snapshot
+// starts with the ID of the upstream cluster, which is checked when it's loaded.
+const (
+	schemaSnapshotCacheDir = "schema-snapshot"
+	snapshotFileMagic      = "TiCDCSSC"
+	snapshotFileVersion    = uint32(2)
+	snapshotFileSuffix     = ".snap"
+
+	historyJobsBatchSize = 128
+)
+
+// schemaSnapshotCacheMu serializes the writes of the cache files of all the changefeeds in the capture
+var schemaSnapshotCacheMu sync.Mutex
+
+// schemaSnapshotUpstreamID is the ID of the upstream cluster of the capture, the cache is disabled until it's set
+var schemaSnapshotUpstreamID atomic.Value
+
+// SetSchemaSnapshotCacheUpstream sets the ID of the upstream cluster of the cached schema snapshots,
+// which should be the UUID of the kv storage, it contains the cluster ID of the upstream PD.
+func SetSchemaSnapshotCacheUpstream(upstreamID string) {
+	schemaSnapshotUpstreamID.Store(upstreamID)
+}
+
+func getSchemaSnapshotCacheUpstream() string {
+	upstreamID, _ := schemaSnapshotUpstreamID.Load().(string)
+	return upstreamID
+}
+
+// schemaSnapshotCache persists the schema snapshots to the data dir. Listing all the tables from the meta
+// takes minutes on a cluster with lots of tables, so the newest cached snapshot at or below the required
+// ts is loaded instead, and only the DDL jobs finished after the cached snapshot are replayed.
+type schemaSnapshotCache struct {
+	upstreamID   string
+	dir          string
+	maxFiles     int
+	saveInterval time.Duration
+}
+
+// getSchemaSnapshotCache returns the cache of the upstream cluster in the data dir, nil is returned
+// if the cache is disabled or the upstream cluster is unknown.
+func getSchemaSnapshotCache(upstreamID string) *schemaSnapshotCache {
+	cfg := config.GetGlobalServerConfig()
+	if upstreamID == "" || cfg.DataDir == "" || cfg.SchemaSnapshotCache == nil || !cfg.SchemaSnapshotCache.Enable {
+		return nil
+	}
+	return &schemaSnapshotCache{
+		upstreamID:   upstreamID,
+		dir:          filepath.Join(cfg.DataDir, schemaSnapshotCacheDir, url.PathEscape(upstreamID)),
+		maxFiles:     cfg.SchemaSnapshotCache.MaxFiles,
+		saveInterval: time.Duration(cfg.SchemaSnapshotCache.SaveInterval),
+	}
+}
+
+// getSnapshot returns the snapshot at the ts, which is loaded from the cache if possible,
+// the meta must be a snapshot meta at the ts.
+func (c *schemaSnapshotCache) getSnapshot(meta *timeta.Meta, ts uint64, explicitTables bool) (*schemaSnapshot, error) {
+	startTime := time.Now()
+	snap, cachedTs, err := c.load(meta, ts, explicitTables)
+	if err != nil {
+		log.Warn("failed to load the cached schema snapshot, list the tables from the meta",
+			zap.Uint64("ts", ts), zap.Error(err))
+	} else if snap != nil {
+		log.Info("schema snapshot is loaded from the cache", zap.Uint64("ts", ts),
+			zap.Uint64("cachedTs", cachedTs), zap.Duration("duration", time.Since(startTime)))
+		if oracle.GetTimeFromTS(ts).Sub(oracle.GetTimeFromTS(cachedTs)) >= c.saveInterval {
+			c.save(meta, snap)
+		}
+		return snap, nil
+	}
+	snap, err = buildSchemaSnapshotFromMeta(meta, ts, explicitTables)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c.save(meta, snap)
+	return snap, nil
+}
+
+type snapshotFile struct {
+	name string
+	ts   uint64
+}
+
+func snapshotFileName(ts uint64, explicitTables bool) string {
+	flag := 0
+	if explicitTables {
+		flag = 1
+	}
+	return fmt.Sprintf("%020d-%d%s", ts, flag, snapshotFileSuffix)
+}
+
+// list returns the cached snapshots sorted by their ts
+func (c *schemaSnapshotCache) list(explicitTables bool) ([]snapshotFile, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Trace(err)
+	}
+	var files []snapshotFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, snapshotFileSuffix) {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(name, snapshotFileSuffix), "-")
+		if len(parts) != 2 {
+			continue
+		}
+		ts, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil || name != snapshotFileName(ts, explicitTables) {
+			continue
+		}
+		files = append(files, snapshotFile{name: name, ts: ts})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ts < files[j].ts })
+	return files, nil
+}
+
+// load loads the newest cached snapshot at or below the ts and replays the DDL jobs after it,
+// nil is returned if there is no such snapshot.
+func (c *schemaSnapshotCache) load(meta *timeta.Meta, ts uint64, explicitTables bool) (*schemaSnapshot, uint64, error) {
+	files, err := c.list(explicitTables)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	i := sort.Search(len(files), func(i int) bool { return files[i].ts > ts }) - 1
+	if i < 0 {
+		return nil, 0, nil
+	}
+	path := filepath.Join(c.dir, files[i].name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	snap, pendingJobID, err := decodeSchemaSnapshot(data, c.upstreamID)
+	if err != nil {
+		// the broken file is removed so that it's replaced by a new snapshot
+		if err := os.Remove(path); err != nil {
+			log.Warn("failed to remove the broken schema snapshot", zap.String("path", path), zap.Error(err))
+		}
+		return nil, 0, cerror.ErrInvalidSnapshotFile.Wrap(err).GenWithStackByArgs(path)
+	}
+	jobs, err := loadDDLJobs(meta, snap.currentTs, ts, pendingJobID)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	for _, job := range jobs {
+		if err := snap.handleDDL(job); err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+	}
+	snap.currentTs = ts
+	return snap, files[i].ts, nil
+}
+
+// save writes the snapshot to the cache and removes the oldest snapshots exceeding the max files,
+// the snapshot is only used to speed up the startup, so the failure is ignored.
+func (c *schemaSnapshotCache) save(meta *timeta.Meta, snap *schemaSnapshot) {
+	startTime := time.Now()
+	pendingJobID, err := minPendingJobID(meta)
+	if err == nil {
+		err = c.write(snap, pendingJobID)
+	}
+	if err != nil {
+		log.Warn("failed to cache the schema snapshot", zap.Uint64("ts", snap.currentTs), zap.Error(err))
+		return
+	}
+	log.Info("schema snapshot is cached", zap.Uint64("ts", snap.currentTs),
+		zap.Int("tables", len(snap.tables)), zap.Duration("duration", time.Since(startTime)))
+}
+
+func (c *schemaSnapshotCache) write(snap *schemaSnapshot, pendingJobID int64) error {
+	data, err := encodeSchemaSnapshot(snap, c.upstreamID, pendingJobID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	schemaSnapshotCacheMu.Lock()
+	defer schemaSnapshotCacheMu.Unlock()
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return errors.Trace(err)
+	}
+	// the file is renamed after it's written, so a partially written file is never loaded
+	tmp, err := os.CreateTemp(c.dir, "*.tmp")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.dir, snapshotFileName(snap.currentTs, snap.explicitTables)))
+	}
+	if err != nil {
+		os.Remove(tmp.Name()) //nolint:errcheck
+		return errors.Trace(err)
+	}
+
+	files, err := c.list(snap.explicitTables)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for i := 0; i < len(files)-c.maxFiles; i++ {
+		if err := os.Remove(filepath.Join(c.dir, files[i].name)); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// minPendingJobID returns the minimum ID of the DDL jobs in the queues of the meta,
+// math.MaxInt64 is returned if there is no pending job.
+func minPendingJobID(meta *timeta.Meta) (int64, error) {
+	jobs, err := meta.GetAllDDLJobsInQueue(timeta.DefaultJobListKey, timeta.AddIndexJobListKey)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	minID := int64(math.MaxInt64)
+	for _, job := range jobs {
+		if job.ID < minID {
+			minID = job.ID
+		}
+	}
+	return minID, nil
+}
+
+// loadDDLJobs returns the DDL jobs finished in (startTs, endTs] sorted by their finished ts, the meta must be
+// a snapshot meta at the end ts. The jobs are allocated increasing IDs when they are queued, and the history
+// jobs are iterated by their IDs in descending order. A job finished at or below the start ts with the ID less
+// than the pending job ID is created before the start ts, so are the jobs of less IDs, which are not pending
+// at the start ts, so they are finished at or below the start ts, and the iteration stops at the job.
+func loadDDLJobs(meta *timeta.Meta, startTs, endTs uint64, pendingJobID int64) ([]*timodel.Job, error) {
+	var jobs []*timodel.Job
+	appendJob := func(job *timodel.Job) {
+		if job.BinlogInfo == nil || (!job.IsSynced() && !job.IsDone()) {
+			return
+		}
+		if job.BinlogInfo.FinishedTS > startTs && job.BinlogInfo.FinishedTS <= endTs {
+			jobs = append(jobs, job)
+		}
+	}
+	// the job is moved to the history after it's done, so the done jobs may still be in the queues
+	queued, err := meta.GetAllDDLJobsInQueue(timeta.DefaultJobListKey, timeta.AddIndexJobListKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, job := range queued {
+		appendJob(job)
+	}
+
+	iter, err := meta.GetLastHistoryDDLJobsIterator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var batch []*timodel.Job
+	for done := false; !done; {
+		batch, err = iter.GetLastJobs(historyJobsBatchSize, batch)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, job := range batch {
+			if job.ID < pendingJobID && job.BinlogInfo != nil && job.BinlogInfo.FinishedTS <= startTs {
+				done = true
+				break
+			}
+			appendJob(job)
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].BinlogInfo.FinishedTS < jobs[j].BinlogInfo.FinishedTS
+	})
+	return jobs, nil
+}
+
+type snapshotEncoder struct {
+	buf    bytes.Buffer
+	varBuf [binary.MaxVarintLen64]byte
+}
+
+func (e *snapshotEncoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.varBuf[:], v)
+	e.buf.Write(e.varBuf[:n])
+}
+
+func (e *snapshotEncoder) varint(v int64) {
+	n := binary.PutVarint(e.varBuf[:], v)
+	e.buf.Write(e.varBuf[:n])
+}
+
+func (e *snapshotEncoder) bytes(v []byte) {
+	e.uvarint(uint64(len(v)))
+	e.buf.Write(v)
+}
+
+func (e *snapshotEncoder) json(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+	e.bytes(data)
+	return nil
+}
+
+func (e *snapshotEncoder) ids(ids map[int64]struct{}) {
+	e.uvarint(uint64(len(ids)))
+	for id := range ids {
+		e.varint(id)
+	}
+}
+
+// encodeSchemaSnapshot encodes the snapshot to the content of a snapshot file, the name maps and the
+// tables in the schemas are not encoded, because they are rebuilt from the schemas and the tables.
+func encodeSchemaSnapshot(snap *schemaSnapshot, upstreamID string, pendingJobID int64) ([]byte, error) {
+	e := new(snapshotEncoder)
+	e.bytes([]byte(upstreamID))
+	e.uvarint(snap.currentTs)
+	if snap.explicitTables {
+		e.uvarint(1)
+	} else {
+		e.uvarint(0)
+	}
+	e.varint(pendingJobID)
+
+	e.uvarint(uint64(len(snap.schemas)))
+	for _, dbInfo := range snap.schemas {
+		if err := e.json(dbInfo); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	e.uvarint(uint64(len(snap.tables)))
+	for _, tableInfo := range snap.tables {
+		e.varint(tableInfo.SchemaID)
+		e.bytes([]byte(tableInfo.TableName.Schema))
+		e.uvarint(tableInfo.TableInfoVersion)
+		if err := e.json(tableInfo.TableInfo); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	e.uvarint(uint64(len(snap.partitionTable)))
+	for partitionID, tableInfo := range snap.partitionTable {
+		e.varint(partitionID)
+		e.varint(tableInfo.ID)
+	}
+	e.ids(snap.truncateTableID)
+	e.ids(snap.ineligibleTableID)
+
+	compressed := snappy.Encode(nil, e.buf.Bytes())
+	data := make([]byte, 0, len(snapshotFileMagic)+8+len(compressed))
+	data = append(data, snapshotFileMagic...)
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(snapshotFileMagic):], snapshotFileVersion)
+	data = append(data, compressed...)
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], crc32.ChecksumIEEE(compressed))
+	return data, nil
+}
+
+type snapshotDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *snapshotDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = errors.New("invalid uvarint")
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *snapshotDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err = errors.New("invalid varint")
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *snapshotDecoder) bytes() []byte {
+	size := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.data)) < size {
+		d.err = errors.New("unexpected end of data")
+		return nil
+	}
+	v := d.data[:size]
+	d.data = d.data[size:]
+	return v
+}
+
+func (d *snapshotDecoder) json(v interface{}) {
+	data := d.bytes()
+	if d.err != nil {
+		return
+	}
+	d.err = json.Unmarshal(data, v)
+}
+
+func (d *snapshotDecoder) ids() map[int64]struct{} {
+	count := d.uvarint()
+	ids := make(map[int64]struct{})
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		ids[d.varint()] = struct{}{}
+	}
+	return ids
+}
+
+// decodeSchemaSnapshot decodes the content of a snapshot file, the snapshot of another upstream cluster is rejected
+func decodeSchemaSnapshot(data []byte, upstreamID string) (*schemaSnapshot, int64, error) {
+	prefixLen := len(snapshotFileMagic) + 4
+	if len(data) < prefixLen+4 || string(data[:len(snapshotFileMagic)]) != snapshotFileMagic {
+		return nil, 0, errors.New("invalid magic")
+	}
+	if version := binary.BigEndian.Uint32(data[len(snapshotFileMagic):]); version != snapshotFileVersion {
+		return nil, 0, errors.Errorf("unsupported version %d", version)
+	}
+	compressed := data[prefixLen : len(data)-4]
+	if crc32.ChecksumIEEE(compressed) != binary.BigEndian.Uint32(data[len(data)-4:]) {
+		return nil, 0, errors.New("checksum mismatch")
+	}
+	body, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+
+	d := &snapshotDecoder{data: body}
+	if id := string(d.bytes()); d.err == nil && id != upstreamID {
+		return nil, 0, errors.Errorf("upstream mismatch, expected %s, got %s", upstreamID, id)
+	}
+	currentTs := d.uvarint()
+	snap := newEmptySchemaSnapshot(d.uvarint() == 1)
+	snap.currentTs = currentTs
+	pendingJobID := d.varint()
+
+	schemaCount := d.uvarint()
+	for i := uint64(0); i < schemaCount && d.err == nil; i++ {
+		dbInfo := new(timodel.DBInfo)
+		d.json(dbInfo)
+		snap.schemas[dbInfo.ID] = dbInfo
+		snap.schemaNameToID[dbInfo.Name.O] = dbInfo.ID
+		snap.tableInSchema[dbInfo.ID] = []int64{}
+	}
+	tableCount := d.uvarint()
+	for i := uint64(0); i < tableCount && d.err == nil; i++ {
+		schemaID := d.varint()
+		schemaName := string(d.bytes())
+		version := d.uvarint()
+		info := new(timodel.TableInfo)
+		d.json(info)
+		if d.err != nil {
+			break
+		}
+		tableInfo := model.WrapTableInfo(schemaID, schemaName, version, info)
+		snap.tables[tableInfo.ID] = tableInfo
+		snap.tableNameToID[tableInfo.TableName] = tableInfo.ID
+		snap.tableInSchema[schemaID] = append(snap.tableInSchema[schemaID], tableInfo.ID)
+	}
+	partitionCount := d.uvarint()
+	for i := uint64(0); i < partitionCount && d.err == nil; i++ {
+		partitionID, tableID := d.varint(), d.varint()
+		tableInfo, ok := snap.tables[tableID]
+		if !ok {
+			d.err = errors.Errorf("table %d of partition %d not found", tableID, partitionID)
+			break
+		}
+		snap.partitionTable[partitionID] = tableInfo
+	}
+	snap.truncateTableID = d.ids()
+	snap.ineligibleTableID = d.ids()
+	if d.err != nil {
+		return nil, 0, errors.Trace(d.err)
+	}
+	return snap, pendingJobID, nil
+}
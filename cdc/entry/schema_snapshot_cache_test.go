@@ -0,0 +1,204 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+	tidbkv "github.com/pingcap/tidb/kv"
+	timeta "github.com/pingcap/tidb/meta"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+type schemaSnapshotCacheSuite struct{}
+
+var _ = check.Suite(&schemaSnapshotCacheSuite{})
+
+func currentMeta(c *check.C, helper *SchemaTestHelper) (*timeta.Meta, uint64) {
+	ver, err := helper.Storage().CurrentVersion(oracle.GlobalTxnScope)
+	c.Assert(err, check.IsNil)
+	return timeta.NewSnapshotMeta(helper.Storage().GetSnapshot(tidbkv.NewVersion(ver.Ver))), ver.Ver
+}
+
+func checkSnapshotEqual(c *check.C, obtained, expected *schemaSnapshot) {
+	c.Assert(obtained.currentTs, check.Equals, expected.currentTs)
+	c.Assert(obtained.explicitTables, check.Equals, expected.explicitTables)
+	c.Assert(obtained.schemaNameToID, check.DeepEquals, expected.schemaNameToID)
+	c.Assert(obtained.tableNameToID, check.DeepEquals, expected.tableNameToID)
+	c.Assert(obtained.ineligibleTableID, check.DeepEquals, expected.ineligibleTableID)
+	c.Assert(obtained.schemas, check.HasLen, len(expected.schemas))
+	for id, dbInfo := range expected.schemas {
+		c.Assert(obtained.schemas[id].Name, check.Equals, dbInfo.Name)
+	}
+	c.Assert(obtained.tables, check.HasLen, len(expected.tables))
+	for id, tableInfo := range expected.tables {
+		c.Assert(obtained.tables[id].TableName, check.Equals, tableInfo.TableName)
+		c.Assert(obtained.tables[id].SchemaID, check.Equals, tableInfo.SchemaID)
+		c.Assert(obtained.tables[id].Columns, check.HasLen, len(tableInfo.Columns))
+	}
+	c.Assert(obtained.partitionTable, check.HasLen, len(expected.partitionTable))
+	for id, tableInfo := range expected.partitionTable {
+		c.Assert(obtained.partitionTable[id].ID, check.Equals, tableInfo.ID)
+	}
+	c.Assert(obtained.tableInSchema, check.HasLen, len(expected.tableInSchema))
+	for id, tableIDs := range expected.tableInSchema {
+		obtainedIDs := append([]int64{}, obtained.tableInSchema[id]...)
+		expectedIDs := append([]int64{}, tableIDs...)
+		sort.Slice(obtainedIDs, func(i, j int) bool { return obtainedIDs[i] < obtainedIDs[j] })
+		sort.Slice(expectedIDs, func(i, j int) bool { return expectedIDs[i] < expectedIDs[j] })
+		c.Assert(obtainedIDs, check.DeepEquals, expectedIDs)
+	}
+}
+
+func (s *schemaSnapshotCacheSuite) TestEncodeAndDecode(c *check.C) {
+	defer testleak.AfterTest(c)()
+	helper := NewSchemaTestHelper(c)
+	defer helper.Close()
+	helper.DDL2Job("create database test1")
+	helper.DDL2Job("create table test1.t1(id int primary key, a int)")
+	helper.DDL2Job("create table test1.t2(a int)")
+	helper.DDL2Job("create table test1.p(id int primary key) partition by range(id) " +
+		"(partition p0 values less than (10), partition p1 values less than (20))")
+	meta, ts := currentMeta(c, helper)
+	snap, err := buildSchemaSnapshotFromMeta(meta, ts, false)
+	c.Assert(err, check.IsNil)
+	snap.truncateTableID[100] = struct{}{}
+	t2, ok := snap.GetTableByName("test1", "t2")
+	c.Assert(ok, check.IsTrue)
+	c.Assert(snap.IsIneligibleTableID(t2.ID), check.IsTrue)
+
+	data, err := encodeSchemaSnapshot(snap, "tikv-1", 42)
+	c.Assert(err, check.IsNil)
+	decoded, pendingJobID, err := decodeSchemaSnapshot(data, "tikv-1")
+	c.Assert(err, check.IsNil)
+	c.Assert(pendingJobID, check.Equals, int64(42))
+	checkSnapshotEqual(c, decoded, snap)
+	c.Assert(decoded.truncateTableID, check.DeepEquals, snap.truncateTableID)
+
+	// the snapshot of another upstream cluster is rejected
+	_, _, err = decodeSchemaSnapshot(data, "tikv-2")
+	c.Assert(err, check.ErrorMatches, ".*upstream mismatch.*")
+
+	// the corrupted file is detected by the checksum
+	data[len(data)/2] ^= 0xff
+	_, _, err = decodeSchemaSnapshot(data, "tikv-1")
+	c.Assert(err, check.ErrorMatches, ".*checksum mismatch.*")
+	_, _, err = decodeSchemaSnapshot([]byte("not a snapshot file"), "tikv-1")
+	c.Assert(err, check.ErrorMatches, ".*invalid magic.*")
+}
+
+func (s *schemaSnapshotCacheSuite) TestLoadAndReplay(c *check.C) {
+	defer testleak.AfterTest(c)()
+	helper := NewSchemaTestHelper(c)
+	defer helper.Close()
+	cache := &schemaSnapshotCache{upstreamID: "tikv-1", dir: c.MkDir(), maxFiles: 2}
+	helper.DDL2Job("create database test1")
+	helper.DDL2Job("create table test1.t1(id int primary key, a int)")
+	helper.DDL2Job("create table test1.t2(a int)")
+	helper.DDL2Job("create table test1.t3(id int primary key)")
+	helper.DDL2Job("create table test1.p(id int primary key) partition by range(id) " +
+		"(partition p0 values less than (10), partition p1 values less than (20))")
+	meta1, ts1 := currentMeta(c, helper)
+	snap, err := cache.getSnapshot(meta1, ts1, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.currentTs, check.Equals, ts1)
+	files, err := cache.list(false)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.DeepEquals, []snapshotFile{{name: snapshotFileName(ts1, false), ts: ts1}})
+
+	helper.DDL2Job("alter table test1.t1 add column b int")
+	helper.DDL2Job("rename table test1.t2 to test1.t4")
+	helper.DDL2Job("drop table test1.t3")
+	helper.DDL2Job("truncate table test1.t1")
+	helper.DDL2Job("alter table test1.p add partition (partition p2 values less than (30))")
+	helper.DDL2Job("create database test2")
+	helper.DDL2Job("create table test2.t1(id int primary key)")
+	meta2, ts2 := currentMeta(c, helper)
+	snap, err = cache.getSnapshot(meta2, ts2, false)
+	c.Assert(err, check.IsNil)
+	expected, err := buildSchemaSnapshotFromMeta(meta2, ts2, false)
+	c.Assert(err, check.IsNil)
+	checkSnapshotEqual(c, snap, expected)
+	// the truncated table is only known by replaying the DDL jobs
+	c.Assert(snap.truncateTableID, check.HasLen, 1)
+
+	// the snapshots of the other flag are not loaded
+	files, err = cache.list(true)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 0)
+	snap, err = cache.getSnapshot(meta2, ts2, true)
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.explicitTables, check.IsTrue)
+
+	// the oldest snapshot is removed
+	files, err = cache.list(false)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 2)
+	helper.DDL2Job("create table test2.t2(id int primary key)")
+	meta3, ts3 := currentMeta(c, helper)
+	_, err = cache.getSnapshot(meta3, ts3, false)
+	c.Assert(err, check.IsNil)
+	files, err = cache.list(false)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.DeepEquals, []snapshotFile{
+		{name: snapshotFileName(ts2, false), ts: ts2},
+		{name: snapshotFileName(ts3, false), ts: ts3},
+	})
+
+	// the broken snapshot is replaced by the snapshot listed from the meta
+	path := filepath.Join(cache.dir, snapshotFileName(ts3, false))
+	c.Assert(os.WriteFile(path, []byte("broken"), 0o644), check.IsNil)
+	snap, err = cache.getSnapshot(meta3, ts3, false)
+	c.Assert(err, check.IsNil)
+	expected, err = buildSchemaSnapshotFromMeta(meta3, ts3, false)
+	c.Assert(err, check.IsNil)
+	checkSnapshotEqual(c, snap, expected)
+	data, err := os.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	_, _, err = decodeSchemaSnapshot(data, "tikv-1")
+	c.Assert(err, check.IsNil)
+
+	// the snapshot of another upstream cluster in the same dir is rejected and replaced
+	other := &schemaSnapshotCache{upstreamID: "tikv-2", dir: cache.dir, maxFiles: 2}
+	_, _, err = other.load(meta3, ts3, false)
+	c.Assert(err, check.ErrorMatches, ".*ErrInvalidSnapshotFile.*")
+	snap, err = other.getSnapshot(meta3, ts3, false)
+	c.Assert(err, check.IsNil)
+	checkSnapshotEqual(c, snap, expected)
+	data, err = os.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	_, _, err = decodeSchemaSnapshot(data, "tikv-2")
+	c.Assert(err, check.IsNil)
+}
+
+func (s *schemaSnapshotCacheSuite) TestCacheDirOfUpstream(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := config.GetDefaultServerConfig()
+	conf.DataDir = c.MkDir()
+	config.StoreGlobalServerConfig(conf)
+	defer config.StoreGlobalServerConfig(config.GetDefaultServerConfig())
+
+	// the cache is disabled if the upstream cluster is unknown
+	c.Assert(getSchemaSnapshotCache(""), check.IsNil)
+	cache1 := getSchemaSnapshotCache("tikv-1")
+	cache2 := getSchemaSnapshotCache("tikv-2")
+	c.Assert(cache1.upstreamID, check.Equals, "tikv-1")
+	c.Assert(cache1.dir, check.Equals, filepath.Join(conf.DataDir, schemaSnapshotCacheDir, "tikv-1"))
+	c.Assert(cache2.dir, check.Not(check.Equals), cache1.dir)
+}
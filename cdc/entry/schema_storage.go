@@ -28,6 +28,7 @@ import (
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/filter"
 	"github.com/pingcap/ticdc/pkg/retry"
+	tidbkv "github.com/pingcap/tidb/kv"
 	timeta "github.com/pingcap/tidb/meta"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -109,6 +110,42 @@ func NewSingleSchemaSnapshotFromMeta(meta *timeta.Meta, currentTs uint64, explic
 	return newSchemaSnapshotFromMeta(meta, currentTs, explicitTables)
 }
 
+// VerifyTables returns the tables selected by the filter in the snapshot at startTs,
+// the tables are split by whether they are eligible to replicate and sorted by name.
+func VerifyTables(f *filter.Filter, storage tidbkv.Storage, startTs uint64) (ineligibleTables, eligibleTables []model.TableName, err error) {
+	meta := timeta.NewSnapshotMeta(storage.GetSnapshot(tidbkv.NewVersion(startTs)))
+	snap, err := NewSingleSchemaSnapshotFromMeta(meta, startTs, false /* explicitTables */)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	for tID, tableName := range snap.CloneTables() {
+		tableInfo, exist := snap.TableByID(tID)
+		if !exist {
+			return nil, nil, cerror.ErrSchemaStorageTableMiss.GenWithStackByArgs(tID)
+		}
+		if f.ShouldIgnoreTable(tableName.Schema, tableName.Table) {
+			continue
+		}
+		if !tableInfo.IsEligible(false /* forceReplicate */) {
+			ineligibleTables = append(ineligibleTables, tableName)
+		} else {
+			eligibleTables = append(eligibleTables, tableName)
+		}
+	}
+	sortTableNames(ineligibleTables)
+	sortTableNames(eligibleTables)
+	return
+}
+
+func sortTableNames(tables []model.TableName) {
+	sort.Slice(tables, func(i, j int) bool {
+		if tables[i].Schema != tables[j].Schema {
+			return tables[i].Schema < tables[j].Schema
+		}
+		return tables[i].Table < tables[j].Table
+	})
+}
+
 func newEmptySchemaSnapshot(explicitTables bool) *schemaSnapshot {
 	return &schemaSnapshot{
 		tableNameToID:  make(map[model.TableName]int64),
@@ -127,6 +164,14 @@ func newEmptySchemaSnapshot(explicitTables bool) *schemaSnapshot {
 }
 
 func newSchemaSnapshotFromMeta(meta *timeta.Meta, currentTs uint64, explicitTables bool) (*schemaSnapshot, error) {
+	if cache := getSchemaSnapshotCache(getSchemaSnapshotCacheUpstream()); cache != nil {
+		return cache.getSnapshot(meta, currentTs, explicitTables)
+	}
+	return buildSchemaSnapshotFromMeta(meta, currentTs, explicitTables)
+}
+
+// buildSchemaSnapshotFromMeta lists all the schemas and the tables from the meta
+func buildSchemaSnapshotFromMeta(meta *timeta.Meta, currentTs uint64, explicitTables bool) (*schemaSnapshot, error) {
 	snap := newEmptySchemaSnapshot(explicitTables)
 	dbinfos, err := meta.ListDatabases()
 	if err != nil {
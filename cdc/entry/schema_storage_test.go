@@ -25,6 +25,8 @@ import (
 	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
 	"github.com/pingcap/ticdc/pkg/util/testleak"
 	ticonfig "github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/domain"
@@ -741,6 +743,41 @@ func (t *schemaSuite) TestSnapshotClone(c *check.C) {
 	c.Assert(len(snap.tables), check.Equals, tableCount)
 }
 
+func (t *schemaSuite) TestVerifyTables(c *check.C) {
+	defer testleak.AfterTest(c)()
+	store, err := mockstore.NewMockStore()
+	c.Assert(err, check.IsNil)
+	defer store.Close() //nolint:errcheck
+
+	session.SetSchemaLease(0)
+	session.DisableStats4Test()
+	domain, err := session.BootstrapSession(store)
+	c.Assert(err, check.IsNil)
+	defer domain.Close()
+	domain.SetStatsUpdating(true)
+	tk := testkit.NewTestKit(c, store)
+	tk.MustExec("create database test2")
+	tk.MustExec("create table test.simple_test2 (id bigint primary key)")
+	tk.MustExec("create table test.simple_test1 (id bigint primary key)")
+	tk.MustExec("create table test.simple_test3 (id bigint, unique key uk(id))")
+	tk.MustExec("create table test2.simple_test4 (id bigint primary key)")
+	ver, err := store.CurrentVersion(oracle.GlobalTxnScope)
+	c.Assert(err, check.IsNil)
+	tk.MustExec("create table test.simple_test5 (id bigint primary key)")
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.Rules = []string{"test.*"}
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	ineligibleTables, eligibleTables, err := VerifyTables(f, store, ver.Ver)
+	c.Assert(err, check.IsNil)
+	c.Assert(ineligibleTables, check.DeepEquals, []model.TableName{{Schema: "test", Table: "simple_test3"}})
+	c.Assert(eligibleTables, check.DeepEquals, []model.TableName{
+		{Schema: "test", Table: "simple_test1"},
+		{Schema: "test", Table: "simple_test2"},
+	})
+}
+
 func (t *schemaSuite) TestExplicitTables(c *check.C) {
 	defer testleak.AfterTest(c)()
 	store, err := mockstore.NewMockStore()
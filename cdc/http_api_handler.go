@@ -14,16 +14,24 @@
 package cdc
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
 	"github.com/pingcap/ticdc/pkg/httputil"
+	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/tikv/client-go/v2/oracle"
 	"go.uber.org/zap"
 )
@@ -31,6 +39,11 @@ import (
 const (
 	// apiOpVarChangefeeds is the key of list option in HTTP API
 	apiOpVarChangefeeds = "state"
+
+	// changefeedValidateTimeout is the timeout of validating a changefeed, which connects to the downstream
+	changefeedValidateTimeout = 30 * time.Second
+	// changefeedSchemaTimeout is the timeout of querying the schema history of a table
+	changefeedSchemaTimeout = 30 * time.Second
 )
 
 // JSONTime used to wrap time into json format
@@ -126,6 +139,174 @@ func (s *Server) handleChangefeedsList(w http.ResponseWriter, req *http.Request)
 	writeData(w, resps)
 }
 
+// handleChangefeedValidate validates the changefeed info in the request body without creating or updating the changefeed.
+// The tables of an existing changefeed are selected at its checkpoint, otherwise at the start-ts in the changefeed info.
+func (s *Server) handleChangefeedValidate(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeErrorJSON(w, http.StatusBadRequest, *cerror.ErrSupportPostOnly)
+		return
+	}
+	if s.kvStorage == nil {
+		writeInternalServerErrorJSON(w, errors.New("the capture is not ready"))
+		return
+	}
+	changefeedID := req.URL.Query().Get(APIOpVarChangefeedID)
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		writeInternalServerErrorJSON(w, err)
+		return
+	}
+	info := new(model.ChangeFeedInfo)
+	if err := json.Unmarshal(data, info); err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, *cerror.ErrAPIInvalidParam.Wrap(err))
+		return
+	}
+	if info.Opts == nil {
+		info.Opts = make(map[string]string)
+	}
+	if info.Config == nil {
+		info.Config = config.GetDefaultReplicaConfig()
+	}
+	if err := info.VerifyAndFix(); err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, *cerror.ErrAPIInvalidParam.Wrap(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), changefeedValidateTimeout)
+	defer cancel()
+	tz, err := util.GetTimezone(config.GetGlobalServerConfig().TZ)
+	if err != nil {
+		writeInternalServerErrorJSON(w, err)
+		return
+	}
+	ctx = util.PutTimezoneInCtx(ctx, tz)
+
+	checkpointTs := info.StartTs
+	status, _, err := s.etcdClient.GetChangeFeedStatus(ctx, changefeedID)
+	if err != nil && cerror.ErrChangeFeedNotExists.NotEqual(err) {
+		writeInternalServerErrorJSON(w, err)
+		return
+	}
+	if status != nil {
+		checkpointTs = status.CheckpointTs
+	}
+	if checkpointTs == 0 {
+		physical, logical, err := s.pdClient.GetTS(ctx)
+		if err != nil {
+			writeInternalServerErrorJSON(w, err)
+			return
+		}
+		checkpointTs = oracle.ComposeTS(physical, logical)
+	}
+	writeData(w, ValidateChangefeed(ctx, s.pdClient, s.kvStorage, changefeedID, info, checkpointTs))
+}
+
+// handleChangefeedSchema returns the definition of a table in the schema snapshot of a changefeed at a ts,
+// and the DDL jobs of the table applied to the schema storage after the start ts.
+// The ts is the checkpoint of the changefeed by default, and the start ts is the ts by default.
+func (s *Server) handleChangefeedSchema(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeErrorJSON(w, http.StatusBadRequest, *cerror.ErrSupportGetOnly)
+		return
+	}
+	if s.kvStorage == nil {
+		writeInternalServerErrorJSON(w, errors.New("the capture is not ready"))
+		return
+	}
+	query := req.URL.Query()
+	changefeedID := query.Get(APIOpVarChangefeedID)
+	if err := model.ValidateChangefeedID(changefeedID); err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, *cerror.ErrAPIInvalidParam.Wrap(err))
+		return
+	}
+	names := strings.SplitN(query.Get(APIOpVarTable), ".", 2)
+	if len(names) != 2 || names[0] == "" || names[1] == "" {
+		writeErrorJSON(w, http.StatusBadRequest,
+			*cerror.ErrAPIInvalidParam.Wrap(errors.Errorf("invalid table %s, the format is schema.table", query.Get(APIOpVarTable))))
+		return
+	}
+	table := model.TableName{Schema: names[0], Table: names[1]}
+
+	ctx, cancel := context.WithTimeout(req.Context(), changefeedSchemaTimeout)
+	defer cancel()
+	info, err := s.etcdClient.GetChangeFeedInfo(ctx, changefeedID)
+	if err != nil {
+		if cerror.ErrChangeFeedNotExists.Equal(err) {
+			writeErrorJSON(w, http.StatusBadRequest, *cerror.ErrAPIInvalidParam.Wrap(err))
+			return
+		}
+		writeInternalServerErrorJSON(w, err)
+		return
+	}
+	replicaConfig := info.Config
+	if replicaConfig == nil {
+		replicaConfig = config.GetDefaultReplicaConfig()
+	}
+	f, err := filter.NewFilter(replicaConfig)
+	if err != nil {
+		writeInternalServerErrorJSON(w, err)
+		return
+	}
+
+	var ts uint64
+	if tsStr := query.Get(APIOpVarTs); tsStr != "" {
+		ts, err = strconv.ParseUint(tsStr, 10, 64)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, *cerror.ErrAPIInvalidParam.Wrap(err))
+			return
+		}
+	} else {
+		status, _, err := s.etcdClient.GetChangeFeedStatus(ctx, changefeedID)
+		if err != nil && cerror.ErrChangeFeedNotExists.NotEqual(err) {
+			writeInternalServerErrorJSON(w, err)
+			return
+		}
+		ts = info.StartTs
+		if status != nil {
+			ts = status.CheckpointTs
+		}
+	}
+	startTs := ts
+	if startTsStr := query.Get(APIOpVarStartTs); startTsStr != "" {
+		startTs, err = strconv.ParseUint(startTsStr, 10, 64)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, *cerror.ErrAPIInvalidParam.Wrap(err))
+			return
+		}
+	}
+	if startTs > ts {
+		writeErrorJSON(w, http.StatusBadRequest,
+			*cerror.ErrAPIInvalidParam.Wrap(errors.Errorf("start ts %d is larger than ts %d", startTs, ts)))
+		return
+	}
+
+	history, err := entry.QueryTableSchemaHistory(ctx, s.kvStorage, s.runningSchemaStorage(changefeedID),
+		f, replicaConfig.ForceReplicate, table, startTs, ts)
+	if err != nil {
+		if cerror.ErrSnapshotTableNotFound.Equal(err) {
+			writeErrorJSON(w, http.StatusBadRequest, *cerror.ErrAPIInvalidParam.Wrap(err))
+			return
+		}
+		writeInternalServerErrorJSON(w, err)
+		return
+	}
+	writeData(w, history)
+}
+
+// runningSchemaStorage returns the schema storage of the changefeed if it's running in the capture
+func (s *Server) runningSchemaStorage(changefeedID model.ChangeFeedID) entry.SchemaStorage {
+	if config.NewReplicaImpl {
+		if s.captureV2 == nil {
+			return nil
+		}
+		return s.captureV2.SchemaStorage(changefeedID)
+	}
+	if s.capture == nil {
+		return nil
+	}
+	return s.capture.schemaStorage(changefeedID)
+}
+
 func writeInternalServerErrorJSON(w http.ResponseWriter, err error) {
 	writeErrorJSON(w, http.StatusInternalServerError, *cerror.ErrInternalServerError.Wrap(err))
 }
@@ -0,0 +1,130 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/security"
+	"go.uber.org/zap"
+)
+
+const bearerAuthPrefix = "Bearer "
+
+// authRoleLevel defines the privilege order of the roles, a role is allowed
+// to access the handlers which require a role with lower or equal level.
+var authRoleLevel = map[string]int{
+	config.AuthRoleReadOnly: 1,
+	config.AuthRoleAdmin:    2,
+}
+
+// httpAuthenticator authenticates the requests of the HTTP API and
+// authorizes them by the role required by each handler.
+type httpAuthenticator struct {
+	enable      bool
+	certCNRoles map[string]string
+	tokens      []*config.AuthToken
+	users       map[string]*config.AuthUser
+}
+
+func newHTTPAuthenticator(conf *config.AuthConfig) *httpAuthenticator {
+	a := &httpAuthenticator{}
+	if conf == nil || !conf.Enable {
+		return a
+	}
+	a.enable = true
+	a.certCNRoles = make(map[string]string, len(conf.ReadOnlyCertCN)+len(conf.AdminCertCN))
+	for _, cn := range conf.ReadOnlyCertCN {
+		a.certCNRoles[cn] = config.AuthRoleReadOnly
+	}
+	// admin overrides read-only if a common name is listed in both
+	for _, cn := range conf.AdminCertCN {
+		a.certCNRoles[cn] = config.AuthRoleAdmin
+	}
+	a.tokens = conf.Tokens
+	a.users = make(map[string]*config.AuthUser, len(conf.Users))
+	for _, u := range conf.Users {
+		a.users[u.Name] = u
+	}
+	return a
+}
+
+// authenticate returns the role of the request. If the request carries an
+// Authorization header, only the header is used, otherwise the common name
+// of the verified client certificate is used.
+func (a *httpAuthenticator) authenticate(req *http.Request) (role string, ok bool) {
+	if authorization := req.Header.Get("Authorization"); authorization != "" {
+		if strings.HasPrefix(authorization, bearerAuthPrefix) {
+			token := []byte(strings.TrimPrefix(authorization, bearerAuthPrefix))
+			for _, t := range a.tokens {
+				if subtle.ConstantTimeCompare(token, []byte(t.Token)) == 1 {
+					return t.Role, true
+				}
+			}
+			return "", false
+		}
+		name, password, ok := req.BasicAuth()
+		if !ok {
+			return "", false
+		}
+		user, exist := a.users[name]
+		if !exist || !security.VerifyHtpasswd(user.Password, password) {
+			return "", false
+		}
+		return user.Role, true
+	}
+	if req.TLS != nil {
+		for _, chain := range req.TLS.VerifiedChains {
+			if len(chain) == 0 {
+				continue
+			}
+			if role, exist := a.certCNRoles[chain[0].Subject.CommonName]; exist {
+				return role, true
+			}
+		}
+	}
+	return "", false
+}
+
+// wrap returns a handler which only calls the handler h if the request is
+// authenticated with a role not lower than the required role.
+func (a *httpAuthenticator) wrap(requiredRole string, h http.Handler) http.Handler {
+	if !a.enable {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		role, ok := a.authenticate(req)
+		if !ok {
+			log.Warn("unauthorized http request",
+				zap.String("path", req.URL.Path), zap.String("remote", req.RemoteAddr))
+			if len(a.users) != 0 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="TiCDC"`)
+			}
+			writeError(w, http.StatusUnauthorized, cerror.ErrAPIUnauthorized.GenWithStackByArgs())
+			return
+		}
+		if authRoleLevel[role] < authRoleLevel[requiredRole] {
+			log.Warn("forbidden http request", zap.String("role", role),
+				zap.String("path", req.URL.Path), zap.String("remote", req.RemoteAddr))
+			writeError(w, http.StatusForbidden, cerror.ErrAPIForbidden.GenWithStackByArgs(role, req.URL.Path))
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
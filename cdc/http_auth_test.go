@@ -0,0 +1,135 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type httpAuthSuite struct{}
+
+var _ = check.Suite(&httpAuthSuite{})
+
+func newTestAuthenticator(c *check.C) *httpAuthenticator {
+	hash, err := bcrypt.GenerateFromPassword([]byte("admin-password"), bcrypt.MinCost)
+	c.Assert(err, check.IsNil)
+	conf := &config.AuthConfig{
+		Enable:         true,
+		AdminCertCN:    []string{"admin-cn"},
+		ReadOnlyCertCN: []string{"monitor-cn"},
+		Tokens: []*config.AuthToken{
+			{Token: "admin-token", Role: config.AuthRoleAdmin},
+			{Token: "read-only-token", Role: config.AuthRoleReadOnly},
+		},
+		Users: []*config.AuthUser{
+			{Name: "admin", Password: string(hash), Role: config.AuthRoleAdmin},
+			// SHA1 of "password"
+			{Name: "monitor", Password: "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", Role: config.AuthRoleReadOnly},
+		},
+	}
+	c.Assert(conf.ValidateAndAdjust(true), check.IsNil)
+	return newHTTPAuthenticator(conf)
+}
+
+func serveWithAuth(auth *httpAuthenticator, role string, req *http.Request) int {
+	handler := auth.wrap(role, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w.Code
+}
+
+func (s *httpAuthSuite) TestAuthDisabled(c *check.C) {
+	defer testleak.AfterTest(c)()
+	auth := newHTTPAuthenticator(&config.AuthConfig{Enable: false})
+	req := httptest.NewRequest(http.MethodPost, "/capture/owner/resign", nil)
+	c.Assert(serveWithAuth(auth, config.AuthRoleAdmin, req), check.Equals, http.StatusOK)
+}
+
+func (s *httpAuthSuite) TestAuthToken(c *check.C) {
+	defer testleak.AfterTest(c)()
+	auth := newTestAuthenticator(c)
+	testCases := []struct {
+		token    string
+		role     string
+		expected int
+	}{
+		{"", config.AuthRoleReadOnly, http.StatusUnauthorized},
+		{"wrong-token", config.AuthRoleReadOnly, http.StatusUnauthorized},
+		{"read-only-token", config.AuthRoleReadOnly, http.StatusOK},
+		{"read-only-token", config.AuthRoleAdmin, http.StatusForbidden},
+		{"admin-token", config.AuthRoleReadOnly, http.StatusOK},
+		{"admin-token", config.AuthRoleAdmin, http.StatusOK},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		c.Assert(serveWithAuth(auth, tc.role, req), check.Equals, tc.expected, check.Commentf("%v", tc))
+	}
+}
+
+func (s *httpAuthSuite) TestAuthBasic(c *check.C) {
+	defer testleak.AfterTest(c)()
+	auth := newTestAuthenticator(c)
+	testCases := []struct {
+		user     string
+		password string
+		role     string
+		expected int
+	}{
+		{"admin", "wrong-password", config.AuthRoleReadOnly, http.StatusUnauthorized},
+		{"nobody", "password", config.AuthRoleReadOnly, http.StatusUnauthorized},
+		{"admin", "admin-password", config.AuthRoleAdmin, http.StatusOK},
+		{"monitor", "password", config.AuthRoleReadOnly, http.StatusOK},
+		{"monitor", "password", config.AuthRoleAdmin, http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.SetBasicAuth(tc.user, tc.password)
+		c.Assert(serveWithAuth(auth, tc.role, req), check.Equals, tc.expected, check.Commentf("%v", tc))
+	}
+}
+
+func (s *httpAuthSuite) TestAuthCertCN(c *check.C) {
+	defer testleak.AfterTest(c)()
+	auth := newTestAuthenticator(c)
+	newRequest := func(cn string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}},
+		}
+		return req
+	}
+	c.Assert(serveWithAuth(auth, config.AuthRoleAdmin, newRequest("admin-cn")), check.Equals, http.StatusOK)
+	c.Assert(serveWithAuth(auth, config.AuthRoleReadOnly, newRequest("monitor-cn")), check.Equals, http.StatusOK)
+	c.Assert(serveWithAuth(auth, config.AuthRoleAdmin, newRequest("monitor-cn")), check.Equals, http.StatusForbidden)
+	c.Assert(serveWithAuth(auth, config.AuthRoleReadOnly, newRequest("unknown-cn")), check.Equals, http.StatusUnauthorized)
+
+	// an invalid Authorization header is not rescued by the client certificate
+	req := newRequest("admin-cn")
+	req.Header.Set("Authorization", "Bearer wrong-token")
+	c.Assert(serveWithAuth(auth, config.AuthRoleReadOnly, req), check.Equals, http.StatusUnauthorized)
+}
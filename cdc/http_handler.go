@@ -44,6 +44,14 @@ const (
 	APIOpVarTableID = "table-id"
 	// APIOpForceRemoveChangefeed is used when remove a changefeed
 	APIOpForceRemoveChangefeed = "force-remove"
+	// APIOpVarSlowestTables is the key of the number of the slowest tables in HTTP API
+	APIOpVarSlowestTables = "slowest-tables"
+	// APIOpVarTable is the key of the table name in the schema.table format in HTTP API
+	APIOpVarTable = "table"
+	// APIOpVarTs is the key of the ts in HTTP API
+	APIOpVarTs = "ts"
+	// APIOpVarStartTs is the key of the start ts in HTTP API
+	APIOpVarStartTs = "start-ts"
 )
 
 type commonResp struct {
@@ -57,6 +65,8 @@ type ChangefeedResp struct {
 	TSO          uint64              `json:"tso"`
 	Checkpoint   string              `json:"checkpoint"`
 	RunningError *model.RunningError `json:"error"`
+	// Tables are the slowest tables of the changefeed, only returned if they are requested
+	Tables []*model.CaptureTableStatus `json:"tables,omitempty"`
 }
 
 func handleOwnerResp(w http.ResponseWriter, err error) {
@@ -268,6 +278,46 @@ func (s *Server) handleMoveTable(w http.ResponseWriter, req *http.Request) {
 	handleOwnerResp(w, err)
 }
 
+// handleDrainCapture drains the capture which serves the request. A POST request marks the capture
+// as draining, and a GET request queries the progress of draining.
+func (s *Server) handleDrainCapture(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost && req.Method != http.MethodGet {
+		writeError(w, http.StatusBadRequest, cerror.ErrAPIInvalidParam.GenWithStack("this api supports GET and POST methods only"))
+		return
+	}
+	if !config.NewReplicaImpl {
+		writeError(w, http.StatusBadRequest, cerror.ErrDrainCaptureNotSupported.GenWithStackByArgs())
+		return
+	}
+	if s.captureV2 == nil {
+		writeError(w, http.StatusServiceUnavailable, cerror.ErrCaptureNotRunning.GenWithStackByArgs())
+		return
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), 5*time.Second)
+	defer cancel()
+	if req.Method == http.MethodPost {
+		canBeDrained, err := s.captureV2.CanBeDrained(ctx)
+		if err != nil {
+			writeInternalServerError(w, err)
+			return
+		}
+		if !canBeDrained {
+			writeError(w, http.StatusBadRequest, cerror.ErrNoSchedulableCapture.GenWithStackByArgs())
+			return
+		}
+		if err := s.captureV2.Drain(ctx); err != nil {
+			writeInternalServerError(w, err)
+			return
+		}
+	}
+	status, err := s.captureV2.DrainStatus(ctx)
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	writeData(w, status)
+}
+
 func (s *Server) handleChangefeedQuery(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		writeError(w, http.StatusBadRequest, cerror.ErrSupportPostOnly.GenWithStackByArgs())
@@ -299,6 +349,15 @@ func (s *Server) handleChangefeedQuery(w http.ResponseWriter, req *http.Request)
 			cerror.ErrAPIInvalidParam.GenWithStack("invalid changefeed id: %s", changefeedID))
 		return
 	}
+	slowestTables := 0
+	if v := req.Form.Get(APIOpVarSlowestTables); v != "" {
+		slowestTables, err = strconv.Atoi(v)
+		if err != nil || slowestTables < 0 {
+			writeError(w, http.StatusBadRequest,
+				cerror.ErrAPIInvalidParam.GenWithStack("invalid number of slowest tables: %s", v))
+			return
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	cfInfo, err := s.etcdClient.GetChangeFeedInfo(ctx, changefeedID)
@@ -323,6 +382,14 @@ func (s *Server) handleChangefeedQuery(w http.ResponseWriter, req *http.Request)
 		tm := oracle.GetTimeFromTS(cfStatus.CheckpointTs)
 		resp.Checkpoint = tm.Format("2006-01-02 15:04:05.000")
 	}
+	if slowestTables > 0 {
+		positions, err := s.etcdClient.GetAllTaskPositions(ctx, changefeedID)
+		if err != nil {
+			writeInternalServerError(w, err)
+			return
+		}
+		resp.Tables = model.SlowestTables(positions, slowestTables)
+	}
 	writeData(w, resp)
 }
 
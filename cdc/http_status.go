@@ -31,6 +31,7 @@ import (
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/pkg/config"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/memquota"
 	"github.com/pingcap/ticdc/pkg/version"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -39,33 +40,46 @@ import (
 )
 
 func (s *Server) startStatusHTTP() error {
+	conf := config.GetGlobalServerConfig()
+	auth := newHTTPAuthenticator(conf.Auth)
 	serverMux := http.NewServeMux()
+	handle := func(pattern, role string, handler http.Handler) {
+		serverMux.Handle(pattern, auth.wrap(role, handler))
+	}
+	handleFunc := func(pattern, role string, handler http.HandlerFunc) {
+		handle(pattern, role, handler)
+	}
 
-	serverMux.HandleFunc("/debug/pprof/", pprof.Index)
-	serverMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	serverMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	serverMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	serverMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
-
-	serverMux.HandleFunc("/status", s.handleStatus)
-	serverMux.HandleFunc("/debug/info", s.handleDebugInfo)
-	serverMux.HandleFunc("/capture/owner/resign", s.handleResignOwner)
-	serverMux.HandleFunc("/capture/owner/admin", s.handleChangefeedAdmin)
-	serverMux.HandleFunc("/capture/owner/rebalance_trigger", s.handleRebalanceTrigger)
-	serverMux.HandleFunc("/capture/owner/move_table", s.handleMoveTable)
-	serverMux.HandleFunc("/capture/owner/changefeed/query", s.handleChangefeedQuery)
-	serverMux.HandleFunc("/admin/log", handleAdminLogLevel)
-	serverMux.HandleFunc("/api/v1/changefeeds", s.handleChangefeeds)
-	serverMux.HandleFunc("/api/v1/health", s.handleHealth)
+	handleFunc("/debug/pprof/", config.AuthRoleAdmin, pprof.Index)
+	handleFunc("/debug/pprof/cmdline", config.AuthRoleAdmin, pprof.Cmdline)
+	handleFunc("/debug/pprof/profile", config.AuthRoleAdmin, pprof.Profile)
+	handleFunc("/debug/pprof/symbol", config.AuthRoleAdmin, pprof.Symbol)
+	handleFunc("/debug/pprof/trace", config.AuthRoleAdmin, pprof.Trace)
+
+	handleFunc("/status", config.AuthRoleReadOnly, s.handleStatus)
+	handleFunc("/debug/stalled_regions", config.AuthRoleReadOnly, handleStalledRegions)
+	// debug info contains the raw etcd data which may include the credentials in sink URIs
+	handleFunc("/debug/info", config.AuthRoleAdmin, s.handleDebugInfo)
+	handleFunc("/capture/owner/resign", config.AuthRoleAdmin, s.handleResignOwner)
+	handleFunc("/capture/owner/admin", config.AuthRoleAdmin, s.handleChangefeedAdmin)
+	handleFunc("/capture/owner/rebalance_trigger", config.AuthRoleAdmin, s.handleRebalanceTrigger)
+	handleFunc("/capture/owner/move_table", config.AuthRoleAdmin, s.handleMoveTable)
+	handleFunc("/capture/owner/changefeed/query", config.AuthRoleReadOnly, s.handleChangefeedQuery)
+	handleFunc("/capture/drain", config.AuthRoleAdmin, s.handleDrainCapture)
+	handleFunc("/admin/log", config.AuthRoleAdmin, handleAdminLogLevel)
+	handleFunc("/api/v1/changefeeds", config.AuthRoleReadOnly, s.handleChangefeeds)
+	// validating a changefeed connects to the downstream with the credentials in the request
+	handleFunc("/api/v1/changefeeds/validate", config.AuthRoleAdmin, s.handleChangefeedValidate)
+	handleFunc("/api/v1/changefeeds/schema", config.AuthRoleReadOnly, s.handleChangefeedSchema)
+	handleFunc("/api/v1/health", config.AuthRoleReadOnly, s.handleHealth)
 
 	if util.FailpointBuild {
 		// `http.StripPrefix` is needed because `failpoint.HttpHandler` assumes that it handles the prefix `/`.
-		serverMux.Handle("/debug/fail/", http.StripPrefix("/debug/fail", &failpoint.HttpHandler{}))
+		handle("/debug/fail/", config.AuthRoleAdmin, http.StripPrefix("/debug/fail", &failpoint.HttpHandler{}))
 	}
 
 	prometheus.DefaultGatherer = registry
-	serverMux.Handle("/metrics", promhttp.Handler())
-	conf := config.GetGlobalServerConfig()
+	handle("/metrics", config.AuthRoleReadOnly, promhttp.Handler())
 	tlsConfig, err := conf.Security.ToTLSConfigWithVerify()
 	if err != nil {
 		log.Error("status server get tls config failed", zap.Error(err))
@@ -115,6 +129,8 @@ func (s *Server) writeEtcdInfo(ctx context.Context, cli *kv.CDCEtcdClient, w io.
 func (s *Server) handleDebugInfo(w http.ResponseWriter, req *http.Request) {
 	if config.NewReplicaImpl {
 		s.captureV2.WriteDebugInfo(w)
+		fmt.Fprintf(w, "\n\n*** memory info ***:\n\n")
+		memquota.GetGlobalArbitrator().WriteDebugInfo(w)
 		fmt.Fprintf(w, "\n\n*** etcd info ***:\n\n")
 		s.writeEtcdInfo(req.Context(), s.etcdClient, w)
 		return
@@ -136,6 +152,9 @@ func (s *Server) handleDebugInfo(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	fmt.Fprintf(w, "\n\n*** memory info ***:\n\n")
+	memquota.GetGlobalArbitrator().WriteDebugInfo(w)
+
 	fmt.Fprintf(w, "\n\n*** etcd info ***:\n\n")
 	s.writeEtcdInfo(req.Context(), &s.capture.etcdClient, w)
 }
@@ -163,6 +182,11 @@ func (s *Server) handleStatus(w http.ResponseWriter, req *http.Request) {
 	writeData(w, st)
 }
 
+// handleStalledRegions lists the regions whose resolved ts doesn't advance in the kv clients of the capture
+func handleStalledRegions(w http.ResponseWriter, req *http.Request) {
+	writeData(w, kv.StalledRegions())
+}
+
 func writeInternalServerError(w http.ResponseWriter, err error) {
 	writeError(w, http.StatusInternalServerError, err)
 }
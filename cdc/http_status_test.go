@@ -74,6 +74,7 @@ func (s *httpStatusSuite) TestHTTPStatus(c *check.C) {
 	testHandleRebalance(c)
 	testHandleMoveTable(c)
 	testHandleChangefeedQuery(c)
+	testHandleDrainCapture(c)
 	testHandleFailpoint(c)
 }
 
@@ -116,6 +117,26 @@ func testHandleChangefeedQuery(c *check.C) {
 	testRequestNonOwnerFailed(c, uri)
 }
 
+func testHandleDrainCapture(c *check.C) {
+	uri := fmt.Sprintf("http://%s/capture/drain", advertiseAddr4Test)
+	req, err := http.NewRequest(http.MethodDelete, uri, nil)
+	c.Assert(err, check.IsNil)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusBadRequest)
+
+	// the capture is not running in the test
+	resp, err = http.Get(uri)
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusServiceUnavailable)
+	resp, err = http.PostForm(uri, url.Values{})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusServiceUnavailable)
+}
+
 func testHTTPPostOnly(c *check.C, uri string) {
 	resp, err := http.Get(uri)
 	c.Assert(err, check.IsNil)
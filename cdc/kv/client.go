@@ -196,6 +196,21 @@ func (s *regionFeedState) getRegionSpan() regionspan.ComparableSpan {
 	return s.sri.span
 }
 
+func (s *regionFeedState) getStartFeedTime() time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.startFeedTime
+}
+
+func (s *regionFeedState) getStoreID() uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.sri.rpcCtx == nil {
+		return 0
+	}
+	return s.sri.rpcCtx.Peer.GetStoreId()
+}
+
 type syncRegionFeedStateMap struct {
 	mu            *sync.Mutex
 	regionInfoMap map[uint64]*regionFeedState
@@ -534,6 +549,11 @@ type eventFeedSession struct {
 	// The channel to schedule scanning and requesting regions in a specified range.
 	requestRangeCh chan rangeRequestTask
 
+	// The scheduler limits the incremental scans of all the kv clients in the capture
+	scanScheduler *storeScanScheduler
+	// scanLimits are the scan priority and rate of the changefeed, which are registered when the feed starts
+	scanLimits *changefeedLimits
+
 	rangeLock        *regionspan.RegionRangeLock
 	enableOldValue   bool
 	enableKVClientV2 bool
@@ -568,13 +588,19 @@ func newEventFeedSession(
 ) *eventFeedSession {
 	id := strconv.FormatUint(allocID(), 10)
 	kvClientCfg := config.GetGlobalServerConfig().KVClient
+	regionScanLimit := kvClientCfg.RegionScanLimit
+	if limit := util.RegionScanLimitFromCtx(ctx); limit > 0 {
+		// the changefeed limits its incremental scans by itself
+		regionScanLimit = limit
+	}
 	return &eventFeedSession{
 		client:            client,
 		regionCache:       regionCache,
 		kvStorage:         kvStorage,
 		totalSpan:         totalSpan,
 		eventCh:           eventCh,
-		regionRouter:      NewSizedRegionRouter(ctx, kvClientCfg.RegionScanLimit),
+		regionRouter:      NewSizedRegionRouter(ctx, regionScanLimit),
+		scanScheduler:     getStoreScanScheduler(),
 		regionCh:          make(chan singleRegionInfo, defaultRegionChanSize),
 		errCh:             make(chan regionErrorInfo, defaultRegionChanSize),
 		requestRangeCh:    make(chan rangeRequestTask, defaultRegionChanSize),
@@ -598,6 +624,11 @@ func (s *eventFeedSession) eventFeed(ctx context.Context, ts uint64) error {
 
 	log.Debug("event feed started", zap.Stringer("span", s.totalSpan), zap.Uint64("ts", ts))
 
+	changefeedID := util.ChangefeedIDFromCtx(ctx)
+	s.scanLimits = s.scanScheduler.register(
+		changefeedID, util.ScanPriorityFromCtx(ctx), int64(util.ScanBytesPerSecondFromCtx(ctx)))
+	defer s.scanScheduler.unregister(changefeedID)
+
 	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
@@ -838,6 +869,7 @@ func (s *eventFeedSession) requestRegionToStore(
 				}
 				bo := tikv.NewBackoffer(ctx, tikvRequestMaxBackoff)
 				s.client.regionCache.OnSendFail(bo, rpcCtx, regionScheduleReload, err)
+				s.regionRouter.Revoke(rpcCtx.Addr)
 				err = s.onRegionFail(ctx, regionErrorInfo{
 					singleRegionInfo: sri,
 					err:              &connectToStoreErr{},
@@ -903,6 +935,7 @@ func (s *eventFeedSession) requestRegionToStore(
 
 			// Wait for a while and retry sending the request
 			time.Sleep(time.Millisecond * time.Duration(rand.Intn(100)))
+			s.regionRouter.Revoke(rpcCtx.Addr)
 			err = s.onRegionFail(ctx, regionErrorInfo{
 				singleRegionInfo: sri,
 				err:              &sendRequestToStoreErr{},
@@ -1497,10 +1530,15 @@ func (s *eventFeedSession) singleEventFeed(
 		return nil
 	}
 
-	resolveLockInterval := 20 * time.Second
+	cfg := config.GetGlobalServerConfig().KVClient
+	resolveLockInterval := time.Duration(cfg.ResolveLockInterval)
 	failpoint.Inject("kvClientResolveLockInterval", func(val failpoint.Value) {
 		resolveLockInterval = time.Duration(val.(int)) * time.Second
 	})
+	regionReconnectInterval := time.Duration(cfg.RegionReconnectInterval)
+	failpoint.Inject("kvClientRegionReconnectInterval", func(val failpoint.Value) {
+		regionReconnectInterval = time.Duration(val.(int)) * time.Second
+	})
 
 	for {
 		var event *regionEvent
@@ -1534,6 +1572,12 @@ func (s *eventFeedSession) singleEventFeed(
 			}
 			currentTimeFromPD := oracle.GetTimeFromTS(version.Ver)
 			sinceLastResolvedTs := currentTimeFromPD.Sub(oracle.GetTimeFromTS(lastResolvedTs))
+			if sinceLastResolvedTs > regionReconnectInterval && time.Since(startFeedTime) > regionReconnectInterval && initialized {
+				log.Warn("kv client reconnect triggered by the stalled region",
+					zap.Uint64("regionID", regionID), zap.Stringer("span", span),
+					zap.Duration("duration", sinceLastResolvedTs), zap.Uint64("resolvedTs", lastResolvedTs))
+				return lastResolvedTs, initialized, errReconnect
+			}
 			if sinceLastResolvedTs > resolveLockInterval && initialized {
 				log.Warn("region not receiving resolved event from tikv or resolved ts is not pushing for too long time, try to resolve lock",
 					zap.Uint64("regionID", regionID), zap.Stringer("span", span),
@@ -1572,6 +1616,13 @@ func (s *eventFeedSession) singleEventFeed(
 					if !regionspan.KeyInSpan(comparableKey, span) && entry.Type != cdcpb.Event_INITIALIZED {
 						continue
 					}
+					if !initialized && entry.Type != cdcpb.Event_INITIALIZED {
+						// the events before the region is initialized are mostly from the incremental scan
+						err = s.scanScheduler.waitBytes(ctx, storeAddr, s.scanLimits, scanEntrySize(entry))
+						if err != nil {
+							return
+						}
+					}
 					switch entry.Type {
 					case cdcpb.Event_INITIALIZED:
 						if time.Since(startFeedTime) > 20*time.Second {
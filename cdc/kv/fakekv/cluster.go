@@ -0,0 +1,347 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakekv
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/cdcpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/pkg/version"
+	"github.com/tikv/client-go/v2/mockstore/mocktikv"
+	"github.com/tikv/client-go/v2/tikv"
+	pd "github.com/tikv/pd/client"
+	"go.uber.org/zap"
+)
+
+// pdClient reports the stores with a version supported by TiCDC
+type pdClient struct {
+	pd.Client
+}
+
+// GetStore implements the pd.Client interface
+func (c *pdClient) GetStore(ctx context.Context, storeID uint64) (*metapb.Store, error) {
+	store, err := c.Client.GetStore(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+	store.Version = version.MinTiKVVersion.String()
+	return store, nil
+}
+
+// subscription is a region subscribed by a request of the client
+type subscription struct {
+	regionID  uint64
+	requestID uint64
+	stream    *stream
+}
+
+// Cluster is an in-process fake TiKV cluster. The regions and the stores are kept in a mocktikv cluster
+// which also serves the fake PD, and every store serves the ChangeData gRPC service. The tests script the
+// events sent to the regions subscribed by the client, and change the regions by splits, merges and
+// leader transfers, which are reported to the client by the region errors as TiKV does.
+type Cluster struct {
+	cluster   *mocktikv.Cluster
+	pdClient  pd.Client
+	kvStorage tikv.Storage
+	stores    []*Store
+
+	mu             sync.Mutex
+	streamID       uint64
+	streams        map[uint64]*stream
+	subscriptions  map[uint64]*subscription
+	autoInitialize bool
+	// changedCh is closed and recreated whenever the subscriptions change
+	changedCh chan struct{}
+}
+
+// NewCluster creates a fake cluster with the stores, all the stores have a peer of the only region
+// which covers the whole key space, and the leader is on the first store.
+func NewCluster(storeCount int) (*Cluster, error) {
+	if storeCount < 1 {
+		return nil, errors.New("a fake cluster needs at least one store")
+	}
+	rpcClient, cluster, mockPDClient, err := mocktikv.NewTiKVAndPDClient("", nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c := &Cluster{
+		cluster:        cluster,
+		pdClient:       &pdClient{Client: mockPDClient},
+		streams:        make(map[uint64]*stream),
+		subscriptions:  make(map[uint64]*subscription),
+		autoInitialize: true,
+		changedCh:      make(chan struct{}),
+	}
+	c.kvStorage, err = tikv.NewTestTiKVStore(rpcClient, c.pdClient, nil, nil, 0)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	storeIDs := cluster.AllocIDs(storeCount)
+	for _, id := range storeIDs {
+		store, err := newStore(c, id)
+		if err != nil {
+			c.Close()
+			return nil, errors.Trace(err)
+		}
+		c.stores = append(c.stores, store)
+		cluster.AddStore(id, store.Addr())
+	}
+	peerIDs := cluster.AllocIDs(storeCount)
+	cluster.Bootstrap(cluster.AllocID(), storeIDs, peerIDs, peerIDs[0])
+	return c, nil
+}
+
+// PDClient returns the fake PD client
+func (c *Cluster) PDClient() pd.Client {
+	return c.pdClient
+}
+
+// KVStorage returns the storage of the fake cluster
+func (c *Cluster) KVStorage() tikv.Storage {
+	return c.kvStorage
+}
+
+// Stores returns the stores in the cluster
+func (c *Cluster) Stores() []*Store {
+	return c.stores
+}
+
+// Close stops all the stores and closes the storage
+func (c *Cluster) Close() {
+	for _, store := range c.stores {
+		store.stop()
+	}
+	if c.kvStorage != nil {
+		if err := c.kvStorage.Close(); err != nil {
+			log.Warn("failed to close the storage of the fake cluster", zap.Error(err))
+		}
+	}
+}
+
+// SetAutoInitialize sets whether the INITIALIZED events are sent to the regions right after they're
+// subscribed. If it's disabled, the tests send the INITIALIZED events by EmitRows with InitializedRow.
+func (c *Cluster) SetAutoInitialize(autoInitialize bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.autoInitialize = autoInitialize
+}
+
+// RegionIDByKey returns the ID of the region containing the key
+func (c *Cluster) RegionIDByKey(key []byte) uint64 {
+	region, _ := c.cluster.GetRegionByKey(key)
+	return region.GetId()
+}
+
+// WaitSubscribed blocks until the region is subscribed by the client
+func (c *Cluster) WaitSubscribed(ctx context.Context, regionID uint64) error {
+	for {
+		c.mu.Lock()
+		_, ok := c.subscriptions[regionID]
+		changedCh := c.changedCh
+		c.mu.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-changedCh:
+		}
+	}
+}
+
+// Split splits the region at the key, and returns the ID of the new region on the right side
+func (c *Cluster) Split(regionID uint64, key []byte) (uint64, error) {
+	region, leaderPeerID := c.cluster.GetRegion(regionID)
+	if region == nil {
+		return 0, errors.Errorf("region %d not found", regionID)
+	}
+	newRegionID := c.cluster.AllocID()
+	peerIDs := c.cluster.AllocIDs(len(region.Peers))
+	newLeaderPeerID := peerIDs[0]
+	for i, peer := range region.Peers {
+		if peer.Id == leaderPeerID {
+			newLeaderPeerID = peerIDs[i]
+		}
+	}
+	c.cluster.SplitRaw(regionID, newRegionID, key, peerIDs, newLeaderPeerID)
+	current, _ := c.cluster.GetRegion(regionID)
+	err := c.EmitError(regionID, EpochNotMatchError(current))
+	return newRegionID, ignoreNotSubscribed(err)
+}
+
+// Merge merges the second region into the first one, their key ranges should be adjacent
+func (c *Cluster) Merge(regionID1, regionID2 uint64) error {
+	c.cluster.Merge(regionID1, regionID2)
+	current, _ := c.cluster.GetRegion(regionID1)
+	if err := c.EmitError(regionID1, EpochNotMatchError(current)); ignoreNotSubscribed(err) != nil {
+		return err
+	}
+	return ignoreNotSubscribed(c.EmitError(regionID2, RegionNotFoundError(regionID2)))
+}
+
+// ChangeLeader transfers the leader of the region to the store
+func (c *Cluster) ChangeLeader(regionID, storeID uint64) error {
+	region, _ := c.cluster.GetRegion(regionID)
+	if region == nil {
+		return errors.Errorf("region %d not found", regionID)
+	}
+	for _, peer := range region.Peers {
+		if peer.StoreId == storeID {
+			c.cluster.ChangeLeader(regionID, peer.Id)
+			return ignoreNotSubscribed(c.EmitError(regionID, NotLeaderError(regionID, peer)))
+		}
+	}
+	return errors.Errorf("region %d has no peer on store %d", regionID, storeID)
+}
+
+// DisconnectStore breaks all the streams established to the store
+func (c *Cluster) DisconnectStore(storeID uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, st := range c.streams {
+		if st.storeID == storeID {
+			st.close()
+		}
+	}
+}
+
+// EmitRows sends the rows to the region
+func (c *Cluster) EmitRows(regionID uint64, rows ...*cdcpb.Event_Row) error {
+	return c.emit(regionID, false, func(requestID uint64) *cdcpb.Event {
+		return &cdcpb.Event{
+			RegionId:  regionID,
+			RequestId: requestID,
+			Event:     &cdcpb.Event_Entries_{Entries: &cdcpb.Event_Entries{Entries: rows}},
+		}
+	})
+}
+
+// EmitResolvedTs sends the resolved ts to the region
+func (c *Cluster) EmitResolvedTs(regionID uint64, resolvedTs uint64) error {
+	return c.emit(regionID, false, func(requestID uint64) *cdcpb.Event {
+		return &cdcpb.Event{
+			RegionId:  regionID,
+			RequestId: requestID,
+			Event:     &cdcpb.Event_ResolvedTs{ResolvedTs: resolvedTs},
+		}
+	})
+}
+
+// EmitError sends the region error to the region, the region is unsubscribed as TiKV does
+func (c *Cluster) EmitError(regionID uint64, regionErr *cdcpb.Error) error {
+	return c.emit(regionID, true, func(requestID uint64) *cdcpb.Event {
+		return regionErrorEvent(regionID, requestID, regionErr)
+	})
+}
+
+func (c *Cluster) emit(regionID uint64, unsubscribe bool, makeEvent func(requestID uint64) *cdcpb.Event) error {
+	c.mu.Lock()
+	sub, ok := c.subscriptions[regionID]
+	if ok && unsubscribe {
+		c.removeSubscriptionLocked(regionID)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return errors.Annotatef(errNotSubscribed, "region %d", regionID)
+	}
+	event := makeEvent(sub.requestID)
+	return sub.stream.send(&cdcpb.ChangeDataEvent{Events: []*cdcpb.Event{event}})
+}
+
+func (c *Cluster) newStream(storeID uint64, server cdcpb.ChangeData_EventFeedServer) *stream {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.streamID++
+	st := &stream{
+		id:      c.streamID,
+		storeID: storeID,
+		server:  server,
+		closeCh: make(chan struct{}),
+	}
+	c.streams[st.id] = st
+	return st
+}
+
+func (c *Cluster) removeStream(st *stream) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.streams, st.id)
+	for regionID, sub := range c.subscriptions {
+		if sub.stream == st {
+			c.removeSubscriptionLocked(regionID)
+		}
+	}
+}
+
+func (c *Cluster) removeSubscriptionLocked(regionID uint64) {
+	delete(c.subscriptions, regionID)
+	close(c.changedCh)
+	c.changedCh = make(chan struct{})
+}
+
+// handleRequest registers the region subscribed by the request, or responds a region error
+// if the region doesn't match the request.
+func (c *Cluster) handleRequest(st *stream, req *cdcpb.ChangeDataRequest) error {
+	if _, ok := req.Request.(*cdcpb.ChangeDataRequest_NotifyTxnStatus_); ok {
+		return nil
+	}
+	var regionErr *cdcpb.Error
+	region, leaderPeerID := c.cluster.GetRegion(req.RegionId)
+	if region == nil {
+		regionErr = RegionNotFoundError(req.RegionId)
+	} else {
+		for _, peer := range region.Peers {
+			if peer.Id == leaderPeerID && peer.StoreId != st.storeID {
+				regionErr = NotLeaderError(req.RegionId, peer)
+			}
+		}
+		if regionErr == nil && (req.RegionEpoch.GetVersion() != region.RegionEpoch.GetVersion() ||
+			req.RegionEpoch.GetConfVer() != region.RegionEpoch.GetConfVer()) {
+			regionErr = EpochNotMatchError(region)
+		}
+	}
+	if regionErr != nil {
+		log.Debug("fake tikv rejects the request", zap.Reflect("request", req), zap.Reflect("error", regionErr))
+		event := regionErrorEvent(req.RegionId, req.RequestId, regionErr)
+		return st.send(&cdcpb.ChangeDataEvent{Events: []*cdcpb.Event{event}})
+	}
+
+	c.mu.Lock()
+	c.subscriptions[req.RegionId] = &subscription{
+		regionID:  req.RegionId,
+		requestID: req.RequestId,
+		stream:    st,
+	}
+	close(c.changedCh)
+	c.changedCh = make(chan struct{})
+	autoInitialize := c.autoInitialize
+	c.mu.Unlock()
+	log.Debug("fake tikv registers the region", zap.Uint64("regionID", req.RegionId),
+		zap.Uint64("requestID", req.RequestId), zap.Uint64("storeID", st.storeID))
+
+	if autoInitialize {
+		return st.send(&cdcpb.ChangeDataEvent{Events: []*cdcpb.Event{{
+			RegionId:  req.RegionId,
+			RequestId: req.RequestId,
+			Event:     &cdcpb.Event_Entries_{Entries: &cdcpb.Event_Entries{Entries: []*cdcpb.Event_Row{InitializedRow()}}},
+		}}})
+	}
+	return nil
+}
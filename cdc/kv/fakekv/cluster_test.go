@@ -0,0 +1,213 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakekv_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/kv/fakekv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/regionspan"
+	"github.com/pingcap/ticdc/pkg/security"
+	"github.com/pingcap/ticdc/pkg/txnutil"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func Test(t *testing.T) {
+	conf := config.GetDefaultServerConfig()
+	config.StoreGlobalServerConfig(conf)
+	kv.InitWorkerPool()
+	go func() {
+		kv.RunWorkerPool(context.Background()) //nolint:errcheck
+	}()
+	check.TestingT(t)
+}
+
+type clusterSuite struct{}
+
+var _ = check.Suite(&clusterSuite{})
+
+type pullerInit struct{}
+
+func (*pullerInit) IsInitialized() bool {
+	return true
+}
+
+// eventFeed runs the kv client against the fake cluster over the span [a, z)
+type eventFeed struct {
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	client  kv.CDCKVClient
+	eventCh chan *model.RegionFeedEvent
+}
+
+func newEventFeed(c *check.C, cluster *fakekv.Cluster, startTs uint64) *eventFeed {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &eventFeed{
+		cancel:  cancel,
+		client:  kv.NewCDCClient(ctx, cluster.PDClient(), cluster.KVStorage(), &security.Credential{}),
+		eventCh: make(chan *model.RegionFeedEvent, 128),
+	}
+	span := regionspan.ComparableSpan{Start: []byte("a"), End: []byte("z")}
+	lockResolver := txnutil.NewLockerResolver(cluster.KVStorage())
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		err := f.client.EventFeed(ctx, span, startTs, false, lockResolver, &pullerInit{}, f.eventCh)
+		c.Assert(errors.Cause(err), check.Equals, context.Canceled)
+	}()
+	return f
+}
+
+func (f *eventFeed) close() {
+	f.cancel()
+	f.wg.Wait()
+	f.client.Close() //nolint:errcheck
+}
+
+// nextValue skips the resolved events and returns the next value event
+func (f *eventFeed) nextValue(c *check.C) *model.RawKVEntry {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event := <-f.eventCh:
+			if event.Val != nil {
+				return event.Val
+			}
+		case <-timeout:
+			c.Fatal("timeout to receive the value event")
+		}
+	}
+}
+
+// waitResolved waits until a resolved event of the key reaches the ts
+func (f *eventFeed) waitResolved(c *check.C, key string, ts uint64) {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event := <-f.eventCh:
+			if event.Resolved != nil && event.Resolved.ResolvedTs >= ts &&
+				regionspan.KeyInSpan([]byte(key), event.Resolved.Span) {
+				return
+			}
+		case <-timeout:
+			c.Fatalf("timeout to receive the resolved event of %s", key)
+		}
+	}
+}
+
+func (s *clusterSuite) TestEventFeed(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cluster, err := fakekv.NewCluster(2)
+	c.Assert(err, check.IsNil)
+	defer cluster.Close()
+	f := newEventFeed(c, cluster, 100)
+	defer f.close()
+
+	region := cluster.RegionIDByKey([]byte("a"))
+	c.Assert(cluster.WaitSubscribed(ctx, region), check.IsNil)
+	c.Assert(cluster.EmitRows(region, fakekv.PrewriteRow([]byte("a"), []byte("v1"), 110)), check.IsNil)
+	c.Assert(cluster.EmitRows(region, fakekv.CommitRow([]byte("a"), 110, 120)), check.IsNil)
+	val := f.nextValue(c)
+	c.Assert(val.OpType, check.Equals, model.OpTypePut)
+	c.Assert(string(val.Key), check.Equals, "a")
+	c.Assert(string(val.Value), check.Equals, "v1")
+	c.Assert(val.CRTs, check.Equals, uint64(120))
+
+	// the rolled back transactions are not sent to the client
+	c.Assert(cluster.EmitRows(region, fakekv.PrewriteRow([]byte("b"), nil, 125)), check.IsNil)
+	c.Assert(cluster.EmitRows(region, fakekv.RollbackRow([]byte("b"), 125)), check.IsNil)
+	c.Assert(cluster.EmitRows(region, fakekv.CommittedRow([]byte("c"), nil, 126, 127)), check.IsNil)
+	val = f.nextValue(c)
+	c.Assert(val.OpType, check.Equals, model.OpTypeDelete)
+	c.Assert(string(val.Key), check.Equals, "c")
+	c.Assert(cluster.EmitResolvedTs(region, 130), check.IsNil)
+	f.waitResolved(c, "a", 130)
+
+	// the client subscribes the regions again after the split
+	newRegion, err := cluster.Split(region, []byte("m"))
+	c.Assert(err, check.IsNil)
+	c.Assert(cluster.WaitSubscribed(ctx, region), check.IsNil)
+	c.Assert(cluster.WaitSubscribed(ctx, newRegion), check.IsNil)
+	c.Assert(cluster.EmitRows(newRegion, fakekv.CommittedRow([]byte("n"), []byte("v2"), 135, 140)), check.IsNil)
+	val = f.nextValue(c)
+	c.Assert(string(val.Key), check.Equals, "n")
+	c.Assert(val.CRTs, check.Equals, uint64(140))
+
+	// and after the leader is transferred
+	c.Assert(cluster.ChangeLeader(newRegion, cluster.Stores()[1].ID()), check.IsNil)
+	c.Assert(cluster.WaitSubscribed(ctx, newRegion), check.IsNil)
+	c.Assert(cluster.EmitResolvedTs(newRegion, 150), check.IsNil)
+	f.waitResolved(c, "n", 150)
+
+	// and after the stream is disconnected
+	cluster.DisconnectStore(cluster.Stores()[0].ID())
+	c.Assert(cluster.WaitSubscribed(ctx, region), check.IsNil)
+	c.Assert(cluster.EmitResolvedTs(region, 160), check.IsNil)
+	f.waitResolved(c, "a", 160)
+
+	// and after the regions are merged
+	c.Assert(cluster.ChangeLeader(newRegion, cluster.Stores()[0].ID()), check.IsNil)
+	c.Assert(cluster.WaitSubscribed(ctx, newRegion), check.IsNil)
+	c.Assert(cluster.Merge(region, newRegion), check.IsNil)
+	c.Assert(cluster.WaitSubscribed(ctx, region), check.IsNil)
+	c.Assert(cluster.EmitResolvedTs(region, 170), check.IsNil)
+	f.waitResolved(c, "n", 170)
+
+	err = cluster.EmitResolvedTs(newRegion, 180)
+	c.Assert(fakekv.IsNotSubscribed(err), check.IsTrue)
+}
+
+func (s *clusterSuite) TestRunScript(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cluster, err := fakekv.NewCluster(1)
+	c.Assert(err, check.IsNil)
+	defer cluster.Close()
+	f := newEventFeed(c, cluster, 100)
+	defer f.close()
+
+	script := `
+# a transaction on two regions
+split m
+prewrite a v1 110
+prewrite n v2 110
+commit a 110 120
+commit n 110 120
+resolved a 130
+resolved n 130
+`
+	c.Assert(fakekv.RunScript(ctx, cluster, strings.NewReader(script)), check.IsNil)
+	keys := []string{string(f.nextValue(c).Key), string(f.nextValue(c).Key)}
+	c.Assert(keys, check.DeepEquals, []string{"a", "n"})
+	f.waitResolved(c, "n", 130)
+
+	err = fakekv.RunScript(ctx, cluster, strings.NewReader("resolved a"))
+	c.Assert(err, check.ErrorMatches, ".*line 1: resolved a: command resolved expects 2 arguments, got 1")
+	err = fakekv.RunScript(ctx, cluster, strings.NewReader("\nunknown a"))
+	c.Assert(err, check.ErrorMatches, ".*line 2: unknown a: unknown command unknown")
+}
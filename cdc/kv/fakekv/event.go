@@ -0,0 +1,119 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakekv
+
+import (
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/cdcpb"
+	"github.com/pingcap/kvproto/pkg/errorpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// errNotSubscribed is returned if the events are sent to a region not subscribed by the client
+var errNotSubscribed = errors.New("region is not subscribed")
+
+// IsNotSubscribed returns true if the error is caused by sending the events to a region not subscribed
+func IsNotSubscribed(err error) bool {
+	return errors.Cause(err) == errNotSubscribed
+}
+
+func ignoreNotSubscribed(err error) error {
+	if IsNotSubscribed(err) {
+		return nil
+	}
+	return err
+}
+
+func opType(value []byte) cdcpb.Event_Row_OpType {
+	if value == nil {
+		return cdcpb.Event_Row_DELETE
+	}
+	return cdcpb.Event_Row_PUT
+}
+
+// PrewriteRow returns a prewrite row, the key is deleted if the value is nil
+func PrewriteRow(key, value []byte, startTs uint64) *cdcpb.Event_Row {
+	return &cdcpb.Event_Row{
+		StartTs: startTs,
+		Type:    cdcpb.Event_PREWRITE,
+		OpType:  opType(value),
+		Key:     key,
+		Value:   value,
+	}
+}
+
+// CommitRow returns a commit row of the key prewritten at the start ts
+func CommitRow(key []byte, startTs, commitTs uint64) *cdcpb.Event_Row {
+	return &cdcpb.Event_Row{
+		StartTs:  startTs,
+		CommitTs: commitTs,
+		Type:     cdcpb.Event_COMMIT,
+		OpType:   cdcpb.Event_Row_PUT,
+		Key:      key,
+	}
+}
+
+// CommittedRow returns a committed row, which is sent by the incremental scan or the one phase commit.
+// The key is deleted if the value is nil.
+func CommittedRow(key, value []byte, startTs, commitTs uint64) *cdcpb.Event_Row {
+	return &cdcpb.Event_Row{
+		StartTs:  startTs,
+		CommitTs: commitTs,
+		Type:     cdcpb.Event_COMMITTED,
+		OpType:   opType(value),
+		Key:      key,
+		Value:    value,
+	}
+}
+
+// RollbackRow returns a rollback row of the key prewritten at the start ts
+func RollbackRow(key []byte, startTs uint64) *cdcpb.Event_Row {
+	return &cdcpb.Event_Row{
+		StartTs: startTs,
+		Type:    cdcpb.Event_ROLLBACK,
+		Key:     key,
+	}
+}
+
+// InitializedRow returns the row sent after the incremental scan of a region finishes
+func InitializedRow() *cdcpb.Event_Row {
+	return &cdcpb.Event_Row{Type: cdcpb.Event_INITIALIZED}
+}
+
+// EpochNotMatchError returns an epoch-not-match error with the current region
+func EpochNotMatchError(current *metapb.Region) *cdcpb.Error {
+	err := &errorpb.EpochNotMatch{}
+	if current != nil {
+		err.CurrentRegions = []*metapb.Region{current}
+	}
+	return &cdcpb.Error{EpochNotMatch: err}
+}
+
+// NotLeaderError returns a not-leader error with the new leader of the region
+func NotLeaderError(regionID uint64, leader *metapb.Peer) *cdcpb.Error {
+	return &cdcpb.Error{NotLeader: &errorpb.NotLeader{RegionId: regionID, Leader: leader}}
+}
+
+// RegionNotFoundError returns a region-not-found error
+func RegionNotFoundError(regionID uint64) *cdcpb.Error {
+	return &cdcpb.Error{RegionNotFound: &errorpb.RegionNotFound{RegionId: regionID}}
+}
+
+func regionErrorEvent(regionID, requestID uint64, regionErr *cdcpb.Error) *cdcpb.Event {
+	return &cdcpb.Event{
+		RegionId:  regionID,
+		RequestId: requestID,
+		Event:     &cdcpb.Event_Error{Error: regionErr},
+	}
+}
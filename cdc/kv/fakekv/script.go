@@ -0,0 +1,197 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakekv
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/cdcpb"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// RunScript runs the commands read from the reader against the cluster, one command per line.
+// The regions are referred by a key they contain, and the events are sent after the regions are
+// subscribed. A value of "-" means the key is deleted. Empty lines and lines starting with "#"
+// are ignored. The supported commands are:
+//
+//	prewrite <key> <value> <start-ts>
+//	commit <key> <start-ts> <commit-ts>
+//	committed <key> <value> <start-ts> <commit-ts>
+//	rollback <key> <start-ts>
+//	initialized <key>
+//	resolved <key> <resolved-ts>
+//	split <key>                 split the region containing the key at the key
+//	merge <key1> <key2>         merge the region containing key2 into the one containing key1
+//	leader <key> <store-id>     transfer the leader of the region to the store
+//	disconnect <store-id>       break the streams established to the store
+//	wait <key>                  wait until the region is subscribed
+//	sleep <duration>
+func RunScript(ctx context.Context, cluster *Cluster, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := runCommand(ctx, cluster, strings.Fields(line)); err != nil {
+			return errors.Annotatef(err, "line %d: %s", lineNo, line)
+		}
+	}
+	return errors.Trace(scanner.Err())
+}
+
+var commandArgs = map[string]int{
+	"prewrite":    3,
+	"commit":      3,
+	"committed":   4,
+	"rollback":    2,
+	"initialized": 1,
+	"resolved":    2,
+	"split":       1,
+	"merge":       2,
+	"leader":      2,
+	"disconnect":  1,
+	"wait":        1,
+	"sleep":       1,
+}
+
+func runCommand(ctx context.Context, cluster *Cluster, fields []string) error {
+	cmd, args := fields[0], fields[1:]
+	n, ok := commandArgs[cmd]
+	if !ok {
+		return errors.Errorf("unknown command %s", cmd)
+	}
+	if len(args) != n {
+		return errors.Errorf("command %s expects %d arguments, got %d", cmd, n, len(args))
+	}
+	log.Debug("fake tikv runs the command", zap.Strings("command", fields))
+
+	switch cmd {
+	case "prewrite":
+		startTs, err := parseTs(args[2])
+		if err != nil {
+			return err
+		}
+		return emitRow(ctx, cluster, PrewriteRow([]byte(args[0]), parseValue(args[1]), startTs))
+	case "commit":
+		startTs, err := parseTs(args[1])
+		if err != nil {
+			return err
+		}
+		commitTs, err := parseTs(args[2])
+		if err != nil {
+			return err
+		}
+		return emitRow(ctx, cluster, CommitRow([]byte(args[0]), startTs, commitTs))
+	case "committed":
+		startTs, err := parseTs(args[2])
+		if err != nil {
+			return err
+		}
+		commitTs, err := parseTs(args[3])
+		if err != nil {
+			return err
+		}
+		return emitRow(ctx, cluster, CommittedRow([]byte(args[0]), parseValue(args[1]), startTs, commitTs))
+	case "rollback":
+		startTs, err := parseTs(args[1])
+		if err != nil {
+			return err
+		}
+		return emitRow(ctx, cluster, RollbackRow([]byte(args[0]), startTs))
+	case "initialized":
+		regionID, err := waitRegion(ctx, cluster, args[0])
+		if err != nil {
+			return err
+		}
+		return cluster.EmitRows(regionID, InitializedRow())
+	case "resolved":
+		resolvedTs, err := parseTs(args[1])
+		if err != nil {
+			return err
+		}
+		regionID, err := waitRegion(ctx, cluster, args[0])
+		if err != nil {
+			return err
+		}
+		return cluster.EmitResolvedTs(regionID, resolvedTs)
+	case "split":
+		_, err := cluster.Split(cluster.RegionIDByKey([]byte(args[0])), []byte(args[0]))
+		return err
+	case "merge":
+		return cluster.Merge(cluster.RegionIDByKey([]byte(args[0])), cluster.RegionIDByKey([]byte(args[1])))
+	case "leader":
+		storeID, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		return cluster.ChangeLeader(cluster.RegionIDByKey([]byte(args[0])), storeID)
+	case "disconnect":
+		storeID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		cluster.DisconnectStore(storeID)
+		return nil
+	case "wait":
+		_, err := waitRegion(ctx, cluster, args[0])
+		return err
+	case "sleep":
+		d, err := time.ParseDuration(args[0])
+		if err != nil {
+			return errors.Trace(err)
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-time.After(d):
+		}
+	}
+	return nil
+}
+
+func parseTs(s string) (uint64, error) {
+	ts, err := strconv.ParseUint(s, 10, 64)
+	return ts, errors.Trace(err)
+}
+
+func parseValue(s string) []byte {
+	if s == "-" {
+		return nil
+	}
+	return []byte(s)
+}
+
+// waitRegion waits until the region containing the key is subscribed, and returns its ID
+func waitRegion(ctx context.Context, cluster *Cluster, key string) (uint64, error) {
+	regionID := cluster.RegionIDByKey([]byte(key))
+	return regionID, cluster.WaitSubscribed(ctx, regionID)
+}
+
+func emitRow(ctx context.Context, cluster *Cluster, row *cdcpb.Event_Row) error {
+	regionID, err := waitRegion(ctx, cluster, string(row.Key))
+	if err != nil {
+		return err
+	}
+	return cluster.EmitRows(regionID, row)
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakekv
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/cdcpb"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// stream is an EventFeed stream established by the client
+type stream struct {
+	id      uint64
+	storeID uint64
+	server  cdcpb.ChangeData_EventFeedServer
+	// sendMu serializes the events sent to the stream
+	sendMu sync.Mutex
+	// closeCh is closed to disconnect the stream from the server side
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *stream) send(event *cdcpb.ChangeDataEvent) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	return errors.Trace(s.server.Send(event))
+}
+
+func (s *stream) close() {
+	s.closeOnce.Do(func() {
+		close(s.closeCh)
+	})
+}
+
+// Store is a fake TiKV store serving the ChangeData gRPC service
+type Store struct {
+	id      uint64
+	addr    string
+	cluster *Cluster
+	server  *grpc.Server
+	wg      sync.WaitGroup
+}
+
+var _ cdcpb.ChangeDataServer = &Store{}
+
+func newStore(cluster *Cluster, id uint64) (*Store, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s := &Store{
+		id:      id,
+		addr:    lis.Addr().String(),
+		cluster: cluster,
+		server:  grpc.NewServer(),
+	}
+	cdcpb.RegisterChangeDataServer(s.server, s)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(lis); err != nil {
+			log.Warn("fake tikv store exits", zap.Uint64("storeID", id), zap.Error(err))
+		}
+	}()
+	return s, nil
+}
+
+// ID returns the ID of the store
+func (s *Store) ID() uint64 {
+	return s.id
+}
+
+// Addr returns the address of the gRPC server of the store
+func (s *Store) Addr() string {
+	return s.addr
+}
+
+func (s *Store) stop() {
+	s.server.Stop()
+	s.wg.Wait()
+}
+
+// EventFeed implements the cdcpb.ChangeDataServer interface
+func (s *Store) EventFeed(server cdcpb.ChangeData_EventFeedServer) error {
+	st := s.cluster.newStream(s.id, server)
+	defer s.cluster.removeStream(st)
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			req, err := server.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := s.cluster.handleRequest(st, req); err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		log.Debug("fake tikv event feed stream exits", zap.Uint64("storeID", s.id), zap.Error(err))
+		return nil
+	case <-st.closeCh:
+		log.Debug("fake tikv event feed stream is disconnected", zap.Uint64("storeID", s.id))
+		return errors.New("stream is disconnected by the fake tikv")
+	case <-server.Context().Done():
+		return nil
+	}
+}
@@ -74,6 +74,20 @@ var (
 			Name:      "region_token",
 			Help:      "size of region token in kv client",
 		}, []string{"store", "table", "changefeed"})
+	storeScanRunningGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "kvclient",
+			Name:      "store_scan_running_region_count",
+			Help:      "The number of regions admitted to scan on the store",
+		}, []string{"store", "changefeed"})
+	storeScanQueuedGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "kvclient",
+			Name:      "store_scan_queued_region_count",
+			Help:      "The number of regions waiting to scan on the store",
+		}, []string{"store", "changefeed"})
 	batchResolvedEventSize = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: "ticdc",
@@ -82,6 +96,27 @@ var (
 			Help:      "The number of region in one batch resolved ts event",
 			Buckets:   prometheus.ExponentialBuckets(2, 2, 16),
 		}, []string{"capture", "changefeed"})
+	stalledRegionGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "kvclient",
+			Name:      "stalled_region_count",
+			Help:      "The number of regions whose resolved ts doesn't advance for longer than the resolve lock interval",
+		}, []string{"capture", "changefeed"})
+	stalledRegionMaxAgeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "kvclient",
+			Name:      "stalled_region_max_resolved_ts_age",
+			Help:      "The max seconds between the resolved ts of the stalled regions and the current ts",
+		}, []string{"capture", "changefeed"})
+	stalledRegionActionCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "kvclient",
+			Name:      "stalled_region_action_count",
+			Help:      "The number of the lock resolutions and the reconnections triggered by the stalled regions",
+		}, []string{"type", "capture", "changefeed"})
 	etcdRequestCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "ticdc",
@@ -101,6 +136,11 @@ func InitMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(sendEventCounter)
 	registry.MustRegister(clientChannelSize)
 	registry.MustRegister(clientRegionTokenSize)
+	registry.MustRegister(storeScanRunningGauge)
+	registry.MustRegister(storeScanQueuedGauge)
 	registry.MustRegister(batchResolvedEventSize)
+	registry.MustRegister(stalledRegionGauge)
+	registry.MustRegister(stalledRegionMaxAgeGauge)
+	registry.MustRegister(stalledRegionActionCounter)
 	registry.MustRegister(etcdRequestCounter)
 }
@@ -249,13 +249,26 @@ func (w *regionWorker) handleSingleRegionError(ctx context.Context, err error, s
 }
 
 func (w *regionWorker) resolveLock(ctx context.Context) error {
-	resolveLockInterval := 20 * time.Second
+	cfg := config.GetGlobalServerConfig().KVClient
+	resolveLockInterval := time.Duration(cfg.ResolveLockInterval)
 	failpoint.Inject("kvClientResolveLockInterval", func(val failpoint.Value) {
 		resolveLockInterval = time.Duration(val.(int)) * time.Second
 	})
+	regionReconnectInterval := time.Duration(cfg.RegionReconnectInterval)
+	failpoint.Inject("kvClientRegionReconnectInterval", func(val failpoint.Value) {
+		regionReconnectInterval = time.Duration(val.(int)) * time.Second
+	})
 	advanceCheckTicker := time.NewTicker(time.Second * 5)
 	defer advanceCheckTicker.Stop()
 
+	captureAddr := util.CaptureAddrFromCtx(ctx)
+	changefeedID := util.ChangefeedIDFromCtx(ctx)
+	metricResolveLock := stalledRegionActionCounter.WithLabelValues("resolve-lock", captureAddr, changefeedID)
+	metricReconnect := stalledRegionActionCounter.WithLabelValues("reconnect", captureAddr, changefeedID)
+	// stalled is only accessed in this goroutine, and is published to the stalledRegions
+	stalled := make(map[uint64]*StalledRegion)
+	defer stalledRegions.removeWorker(w)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -269,6 +282,7 @@ func (w *regionWorker) resolveLock(ctx context.Context) error {
 				continue
 			}
 			currentTimeFromPD := oracle.GetTimeFromTS(version.Ver)
+			w.removeRecoveredRegions(stalled, currentTimeFromPD, resolveLockInterval)
 			expired := make([]*regionTsInfo, 0)
 			for w.rtsManager.Len() > 0 {
 				item := w.rtsManager.Pop()
@@ -304,6 +318,35 @@ func (w *regionWorker) resolveLock(ctx context.Context) error {
 							zap.Duration("duration", sinceLastResolvedTs), zap.Duration("since last event", sinceLastResolvedTs))
 						return errReconnect
 					}
+					region, ok := stalled[rts.regionID]
+					if !ok {
+						region = &StalledRegion{
+							Capture:      captureAddr,
+							ChangefeedID: changefeedID,
+							RegionID:     rts.regionID,
+							StoreID:      state.getStoreID(),
+							StoreAddr:    w.storeAddr,
+							Span:         state.getRegionSpan().String(),
+						}
+						stalled[rts.regionID] = region
+					}
+					region.ResolvedTs = lastResolvedTs
+					region.ResolvedTsAge = sinceLastResolvedTs.Seconds()
+					stalledRegions.upsert(w, *region)
+					// The resolved ts is still stuck after resolving the locks, the region may be stuck in TiKV,
+					// e.g. the new leader doesn't push the resolved ts. TiKV rejects registering a region twice
+					// in a stream, so the stream is rebuilt to subscribe the region again.
+					if sinceLastResolvedTs > regionReconnectInterval && time.Since(state.getStartFeedTime()) > regionReconnectInterval {
+						log.Warn("kv client reconnect triggered by the stalled region",
+							zap.Uint64("regionID", rts.regionID),
+							zap.Uint64("storeID", region.StoreID),
+							zap.String("addr", w.storeAddr),
+							zap.Stringer("span", state.getRegionSpan()),
+							zap.Duration("duration", sinceLastResolvedTs),
+							zap.Uint64("resolvedTs", lastResolvedTs))
+						metricReconnect.Inc()
+						return errReconnect
+					}
 					log.Warn("region not receiving resolved event from tikv or resolved ts is not pushing for too long time, try to resolve lock",
 						zap.Uint64("regionID", rts.regionID),
 						zap.Stringer("span", state.getRegionSpan()),
@@ -316,6 +359,9 @@ func (w *regionWorker) resolveLock(ctx context.Context) error {
 						log.Warn("failed to resolve lock", zap.Uint64("regionID", rts.regionID), zap.Error(err))
 						continue
 					}
+					region.ResolveLockCount++
+					metricResolveLock.Inc()
+					stalledRegions.upsert(w, *region)
 				}
 				rts.ts.resolvedTs = lastResolvedTs
 				w.rtsManager.Upsert(rts)
@@ -324,6 +370,28 @@ func (w *regionWorker) resolveLock(ctx context.Context) error {
 	}
 }
 
+// removeRecoveredRegions removes the regions whose resolved ts advances again, or which are not
+// subscribed by the worker anymore.
+func (w *regionWorker) removeRecoveredRegions(
+	stalled map[uint64]*StalledRegion, currentTimeFromPD time.Time, resolveLockInterval time.Duration,
+) {
+	for regionID, region := range stalled {
+		state, ok := w.getRegionState(regionID)
+		if ok && !state.isStopped() {
+			lastResolvedTs := state.getLastResolvedTs()
+			if currentTimeFromPD.Sub(oracle.GetTimeFromTS(lastResolvedTs)) >= resolveLockInterval {
+				continue
+			}
+			log.Info("the resolved ts of the stalled region advances",
+				zap.Uint64("regionID", regionID),
+				zap.Uint64("resolvedTs", lastResolvedTs),
+				zap.Int("resolveLockCount", region.ResolveLockCount))
+		}
+		delete(stalled, regionID)
+		stalledRegions.remove(w, regionID)
+	}
+}
+
 func (w *regionWorker) processEvent(ctx context.Context, event *regionStatefulEvent) error {
 	if event.finishedCounter != nil {
 		atomic.AddInt32(event.finishedCounter, -1)
@@ -577,6 +645,13 @@ func (w *regionWorker) handleEventEntry(
 		if !regionspan.KeyInSpan(comparableKey, state.sri.span) && entry.Type != cdcpb.Event_INITIALIZED {
 			continue
 		}
+		if !state.initialized && entry.Type != cdcpb.Event_INITIALIZED {
+			// the events before the region is initialized are mostly from the incremental scan
+			err := w.session.scanScheduler.waitBytes(ctx, state.sri.rpcCtx.Addr, w.session.scanLimits, scanEntrySize(entry))
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
 		switch entry.Type {
 		case cdcpb.Event_INITIALIZED:
 			if time.Since(state.startFeedTime) > 20*time.Second {
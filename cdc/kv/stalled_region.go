@@ -0,0 +1,120 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"sort"
+	"sync"
+)
+
+// StalledRegion is a region whose resolved ts doesn't advance for longer than the resolve lock interval
+type StalledRegion struct {
+	Capture      string `json:"capture"`
+	ChangefeedID string `json:"changefeed"`
+	RegionID     uint64 `json:"region_id"`
+	StoreID      uint64 `json:"store_id"`
+	StoreAddr    string `json:"store_addr"`
+	Span         string `json:"span"`
+	ResolvedTs   uint64 `json:"resolved_ts"`
+	// ResolvedTsAge is the seconds between the resolved ts and the current ts from PD
+	ResolvedTsAge float64 `json:"resolved_ts_age"`
+	// ResolveLockCount is how many times the locks in the region have been resolved since it's stalled
+	ResolveLockCount int `json:"resolve_lock_count"`
+}
+
+type stalledRegionKey struct {
+	worker   *regionWorker
+	regionID uint64
+}
+
+type metricLabels struct {
+	capture    string
+	changefeed string
+}
+
+// stalledRegionTracker collects the stalled regions of all the region workers in the capture
+type stalledRegionTracker struct {
+	mu      sync.Mutex
+	regions map[stalledRegionKey]StalledRegion
+}
+
+var stalledRegions = &stalledRegionTracker{
+	regions: make(map[stalledRegionKey]StalledRegion),
+}
+
+func (t *stalledRegionTracker) upsert(w *regionWorker, region StalledRegion) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.regions[stalledRegionKey{worker: w, regionID: region.RegionID}] = region
+	t.updateMetricsLocked(metricLabels{capture: region.Capture, changefeed: region.ChangefeedID})
+}
+
+func (t *stalledRegionTracker) remove(w *regionWorker, regionID uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := stalledRegionKey{worker: w, regionID: regionID}
+	region, ok := t.regions[key]
+	if !ok {
+		return
+	}
+	delete(t.regions, key)
+	t.updateMetricsLocked(metricLabels{capture: region.Capture, changefeed: region.ChangefeedID})
+}
+
+// removeWorker removes all the stalled regions of the region worker
+func (t *stalledRegionTracker) removeWorker(w *regionWorker) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	labels := make(map[metricLabels]struct{})
+	for key, region := range t.regions {
+		if key.worker == w {
+			delete(t.regions, key)
+			labels[metricLabels{capture: region.Capture, changefeed: region.ChangefeedID}] = struct{}{}
+		}
+	}
+	for l := range labels {
+		t.updateMetricsLocked(l)
+	}
+}
+
+func (t *stalledRegionTracker) updateMetricsLocked(l metricLabels) {
+	count := 0
+	maxAge := 0.0
+	for _, region := range t.regions {
+		if region.Capture != l.capture || region.ChangefeedID != l.changefeed {
+			continue
+		}
+		count++
+		if region.ResolvedTsAge > maxAge {
+			maxAge = region.ResolvedTsAge
+		}
+	}
+	stalledRegionGauge.WithLabelValues(l.capture, l.changefeed).Set(float64(count))
+	stalledRegionMaxAgeGauge.WithLabelValues(l.capture, l.changefeed).Set(maxAge)
+}
+
+// StalledRegions returns the stalled regions of all the kv clients in the capture,
+// the regions stalled for the longest time come first.
+func StalledRegions() []StalledRegion {
+	stalledRegions.mu.Lock()
+	regions := make([]StalledRegion, 0, len(stalledRegions.regions))
+	for _, region := range stalledRegions.regions {
+		regions = append(regions, region)
+	}
+	stalledRegions.mu.Unlock()
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].ResolvedTsAge > regions[j].ResolvedTsAge
+	})
+	return regions
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+type stalledRegionSuite struct{}
+
+var _ = check.Suite(&stalledRegionSuite{})
+
+func (s *stalledRegionSuite) TestStalledRegions(c *check.C) {
+	defer testleak.AfterTest(c)()
+	w1, w2 := &regionWorker{}, &regionWorker{}
+	defer stalledRegions.removeWorker(w1)
+	defer stalledRegions.removeWorker(w2)
+
+	stalledRegions.upsert(w1, StalledRegion{RegionID: 1, ResolvedTsAge: 30})
+	stalledRegions.upsert(w1, StalledRegion{RegionID: 2, ResolvedTsAge: 60})
+	// the same region subscribed by another kv client
+	stalledRegions.upsert(w2, StalledRegion{RegionID: 1, ResolvedTsAge: 45})
+	regions := StalledRegions()
+	c.Assert(regions, check.HasLen, 3)
+	c.Assert(regions[0].RegionID, check.Equals, uint64(2))
+	c.Assert(regions[1].ResolvedTsAge, check.Equals, float64(45))
+	c.Assert(regions[2].ResolvedTsAge, check.Equals, float64(30))
+
+	stalledRegions.upsert(w1, StalledRegion{RegionID: 1, ResolvedTsAge: 90, ResolveLockCount: 1})
+	regions = StalledRegions()
+	c.Assert(regions, check.HasLen, 3)
+	c.Assert(regions[0].ResolveLockCount, check.Equals, 1)
+
+	stalledRegions.remove(w1, 1)
+	c.Assert(StalledRegions(), check.HasLen, 2)
+	stalledRegions.removeWorker(w2)
+	regions = StalledRegions()
+	c.Assert(regions, check.HasLen, 1)
+	c.Assert(regions[0].RegionID, check.Equals, uint64(2))
+}
+
+func (s *stalledRegionSuite) TestRemoveRecoveredRegions(c *check.C) {
+	defer testleak.AfterTest(c)()
+	w := &regionWorker{statesManager: newRegionStateManager(4)}
+	defer stalledRegions.removeWorker(w)
+
+	now := time.Now()
+	newState := func(resolvedTime time.Time) *regionFeedState {
+		ts := oracle.ComposeTS(oracle.GetPhysical(resolvedTime), 0)
+		state := newRegionFeedState(singleRegionInfo{ts: ts}, 1)
+		state.start()
+		return state
+	}
+	// region 1 is still stalled, region 2 advances, region 3 is stopped and region 4 is removed
+	w.setRegionState(1, newState(now.Add(-time.Minute)))
+	w.setRegionState(2, newState(now))
+	stopped := newState(now.Add(-time.Minute))
+	stopped.markStopped()
+	w.setRegionState(3, stopped)
+	stalled := make(map[uint64]*StalledRegion)
+	for id := uint64(1); id <= 4; id++ {
+		stalled[id] = &StalledRegion{RegionID: id}
+		stalledRegions.upsert(w, *stalled[id])
+	}
+
+	w.removeRecoveredRegions(stalled, now, 20*time.Second)
+	c.Assert(stalled, check.HasLen, 1)
+	c.Assert(stalled[1], check.NotNil)
+	regions := StalledRegions()
+	c.Assert(regions, check.HasLen, 1)
+	c.Assert(regions[0].RegionID, check.Equals, uint64(1))
+}
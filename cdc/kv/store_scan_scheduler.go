@@ -0,0 +1,253 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/cdcpb"
+	"github.com/pingcap/ticdc/pkg/config"
+	"golang.org/x/time/rate"
+)
+
+// storeScanScheduler limits the incremental scans of all the kv clients in the capture on each store.
+// A region is admitted if the running scans on its store are fewer than the concurrency, and no other
+// changefeed queuing regions on the store is running fewer scans, so the changefeeds share the scans fairly.
+// Note the limits apply to each capture, the scans on a store are limited by limit * number of captures.
+type storeScanScheduler struct {
+	concurrency    int
+	bytesPerSecond int64
+
+	mu     sync.Mutex
+	stores map[string]*storeScans
+	// changefeeds are the changefeeds with running event feeds in the capture
+	changefeeds map[string]*changefeedLimits
+}
+
+// changefeedLimits are the priority and the scan rate of a changefeed shared by its event feeds
+type changefeedLimits struct {
+	refs     int
+	priority int
+	// limiter limits the bytes scanned by the changefeed in all the stores, it's nil if unlimited
+	limiter *rate.Limiter
+}
+
+type storeScans struct {
+	running     int
+	changefeeds map[string]*changefeedScans
+	limiter     *rate.Limiter
+}
+
+type changefeedScans struct {
+	running int
+	// queued is the number of the regions waiting for the admission of the scheduler, the regions held back
+	// by the per-table limits of the changefeed are not counted, so a throttled changefeed doesn't block the others
+	queued int
+}
+
+var (
+	globalStoreScanScheduler     *storeScanScheduler
+	globalStoreScanSchedulerOnce sync.Once
+)
+
+// getStoreScanScheduler returns the scheduler of the capture, the limits are read from the server config
+func getStoreScanScheduler() *storeScanScheduler {
+	globalStoreScanSchedulerOnce.Do(func() {
+		cfg := config.GetGlobalServerConfig().KVClient
+		globalStoreScanScheduler = newStoreScanScheduler(cfg.StoreScanConcurrency, int64(cfg.StoreScanBytesPerSecond))
+	})
+	return globalStoreScanScheduler
+}
+
+// newStoreScanScheduler creates a scheduler, 0 means unlimited
+func newStoreScanScheduler(concurrency int, bytesPerSecond int64) *storeScanScheduler {
+	return &storeScanScheduler{
+		concurrency:    concurrency,
+		bytesPerSecond: bytesPerSecond,
+		stores:         make(map[string]*storeScans),
+		changefeeds:    make(map[string]*changefeedLimits),
+	}
+}
+
+// register registers an event feed of the changefeed, the scans of the changefeed are admitted by the priority
+// and limited by the scan rate, 0 means unlimited. The limits are updated by the latest event feed.
+func (s *storeScanScheduler) register(changefeed string, priority int, bytesPerSecond int64) *changefeedLimits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limits, ok := s.changefeeds[changefeed]
+	if !ok {
+		limits = &changefeedLimits{}
+		s.changefeeds[changefeed] = limits
+	}
+	limits.refs++
+	limits.priority = priority
+	switch {
+	case bytesPerSecond <= 0:
+		limits.limiter = nil
+	case limits.limiter == nil:
+		limits.limiter = rate.NewLimiter(rate.Limit(bytesPerSecond), int(bytesPerSecond))
+	case limits.limiter.Limit() != rate.Limit(bytesPerSecond):
+		limits.limiter.SetLimit(rate.Limit(bytesPerSecond))
+		limits.limiter.SetBurst(int(bytesPerSecond))
+	}
+	return limits
+}
+
+// unregister removes an event feed of the changefeed
+func (s *storeScanScheduler) unregister(changefeed string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limits, ok := s.changefeeds[changefeed]
+	if !ok {
+		return
+	}
+	limits.refs--
+	if limits.refs <= 0 {
+		delete(s.changefeeds, changefeed)
+	}
+}
+
+// priorityLocked returns the priority of the changefeed, the changefeeds without any registered event feed
+// have the normal priority
+func (s *storeScanScheduler) priorityLocked(changefeed string) int {
+	if limits, ok := s.changefeeds[changefeed]; ok {
+		return limits.priority
+	}
+	return (*config.ResourceConfig)(nil).PriorityWeight()
+}
+
+func (s *storeScanScheduler) getStoreLocked(store string) *storeScans {
+	scans, ok := s.stores[store]
+	if !ok {
+		scans = &storeScans{changefeeds: make(map[string]*changefeedScans)}
+		if s.bytesPerSecond > 0 {
+			scans.limiter = rate.NewLimiter(rate.Limit(s.bytesPerSecond), int(s.bytesPerSecond))
+		}
+		s.stores[store] = scans
+	}
+	return scans
+}
+
+func (s *storeScanScheduler) getChangefeedLocked(store, changefeed string) *changefeedScans {
+	scans := s.getStoreLocked(store)
+	cf, ok := scans.changefeeds[changefeed]
+	if !ok {
+		cf = &changefeedScans{}
+		scans.changefeeds[changefeed] = cf
+	}
+	return cf
+}
+
+// gcLocked removes the changefeed without any running or queued region
+func (s *storeScanScheduler) gcLocked(store, changefeed string) {
+	scans := s.stores[store]
+	if cf := scans.changefeeds[changefeed]; cf.running == 0 && cf.queued == 0 {
+		delete(scans.changefeeds, changefeed)
+	}
+}
+
+func (s *storeScanScheduler) updateMetricsLocked(store, changefeed string, cf *changefeedScans) {
+	storeScanRunningGauge.WithLabelValues(store, changefeed).Set(float64(cf.running))
+	storeScanQueuedGauge.WithLabelValues(store, changefeed).Set(float64(cf.queued))
+}
+
+// enqueue changes the number of the regions of the changefeed waiting for the admission on the store
+func (s *storeScanScheduler) enqueue(store, changefeed string, delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cf := s.getChangefeedLocked(store, changefeed)
+	cf.queued += delta
+	s.updateMetricsLocked(store, changefeed, cf)
+	s.gcLocked(store, changefeed)
+}
+
+// tryAcquire admits a region of the changefeed to scan on the store
+func (s *storeScanScheduler) tryAcquire(store, changefeed string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scans := s.getStoreLocked(store)
+	cf := s.getChangefeedLocked(store, changefeed)
+	if s.concurrency > 0 {
+		admitted := scans.running < s.concurrency
+		priority := s.priorityLocked(changefeed)
+		for id, other := range scans.changefeeds {
+			if id == changefeed || other.queued == 0 {
+				continue
+			}
+			// the changefeeds with higher priorities are admitted first, and the ones running
+			// fewer scans are admitted first among the changefeeds with the same priority
+			otherPriority := s.priorityLocked(id)
+			if otherPriority > priority || (otherPriority == priority && other.running < cf.running) {
+				admitted = false
+			}
+		}
+		if !admitted {
+			s.gcLocked(store, changefeed)
+			return false
+		}
+	}
+	scans.running++
+	cf.running++
+	s.updateMetricsLocked(store, changefeed, cf)
+	return true
+}
+
+// release gives back the admissions of the changefeed on the store
+func (s *storeScanScheduler) release(store, changefeed string, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scans := s.getStoreLocked(store)
+	cf := s.getChangefeedLocked(store, changefeed)
+	scans.running -= count
+	cf.running -= count
+	s.updateMetricsLocked(store, changefeed, cf)
+	s.gcLocked(store, changefeed)
+}
+
+// waitBytes blocks until the scanned bytes are allowed by the bytes limit of the store and the scan rate
+// of the changefeed, limits is nil if the changefeed isn't limited.
+func (s *storeScanScheduler) waitBytes(ctx context.Context, store string, limits *changefeedLimits, size int) error {
+	if s.bytesPerSecond > 0 {
+		s.mu.Lock()
+		limiter := s.getStoreLocked(store).limiter
+		s.mu.Unlock()
+		if err := waitLimiter(ctx, limiter, size); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if limits != nil {
+		s.mu.Lock()
+		limiter := limits.limiter
+		s.mu.Unlock()
+		if limiter != nil {
+			return waitLimiter(ctx, limiter, size)
+		}
+	}
+	return nil
+}
+
+// waitLimiter waits for the size, the size larger than the burst of the limiter is allowed after the burst
+func waitLimiter(ctx context.Context, limiter *rate.Limiter, size int) error {
+	if size > limiter.Burst() {
+		size = limiter.Burst()
+	}
+	return errors.Trace(limiter.WaitN(ctx, size))
+}
+
+// scanEntrySize returns the bytes of an event scanned by TiKV
+func scanEntrySize(entry *cdcpb.Event_Row) int {
+	return len(entry.Key) + len(entry.Value) + len(entry.OldValue)
+}
@@ -0,0 +1,260 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"github.com/tikv/client-go/v2/tikv"
+)
+
+type storeScanSchedulerSuite struct{}
+
+var _ = check.Suite(&storeScanSchedulerSuite{})
+
+func (s *storeScanSchedulerSuite) TestConcurrency(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler := newStoreScanScheduler(2, 0)
+	c.Assert(scheduler.tryAcquire("s1", "cf1"), check.IsTrue)
+	c.Assert(scheduler.tryAcquire("s1", "cf1"), check.IsTrue)
+	c.Assert(scheduler.tryAcquire("s1", "cf1"), check.IsFalse)
+	// the stores are limited separately
+	c.Assert(scheduler.tryAcquire("s2", "cf1"), check.IsTrue)
+	scheduler.release("s1", "cf1", 1)
+	c.Assert(scheduler.tryAcquire("s1", "cf1"), check.IsTrue)
+	scheduler.release("s1", "cf1", 2)
+	scheduler.release("s2", "cf1", 1)
+	c.Assert(scheduler.stores["s1"].running, check.Equals, 0)
+	c.Assert(scheduler.stores["s1"].changefeeds, check.HasLen, 0)
+
+	// unlimited
+	scheduler = newStoreScanScheduler(0, 0)
+	for i := 0; i < 100; i++ {
+		c.Assert(scheduler.tryAcquire("s1", "cf1"), check.IsTrue)
+	}
+}
+
+func (s *storeScanSchedulerSuite) TestFairness(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler := newStoreScanScheduler(4, 0)
+	for i := 0; i < 3; i++ {
+		c.Assert(scheduler.tryAcquire("s1", "cf1"), check.IsTrue)
+	}
+	scheduler.enqueue("s1", "cf1", 10)
+	scheduler.enqueue("s1", "cf2", 10)
+	// cf2 is running fewer scans than cf1, so it's admitted first
+	c.Assert(scheduler.tryAcquire("s1", "cf1"), check.IsFalse)
+	c.Assert(scheduler.tryAcquire("s1", "cf2"), check.IsTrue)
+	scheduler.enqueue("s1", "cf2", -1)
+	scheduler.release("s1", "cf1", 2)
+	c.Assert(scheduler.tryAcquire("s1", "cf1"), check.IsTrue)
+	scheduler.enqueue("s1", "cf1", -1)
+	// cf1 and cf2 are both running 2 scans
+	c.Assert(scheduler.tryAcquire("s1", "cf2"), check.IsTrue)
+	c.Assert(scheduler.tryAcquire("s1", "cf1"), check.IsFalse)
+
+	// a changefeed without queued regions doesn't block the others
+	scheduler.enqueue("s1", "cf2", -9)
+	scheduler.release("s1", "cf2", 2)
+	c.Assert(scheduler.tryAcquire("s1", "cf1"), check.IsTrue)
+	c.Assert(scheduler.stores["s1"].changefeeds, check.HasLen, 1)
+}
+
+func (s *storeScanSchedulerSuite) TestWaitBytes(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scheduler := newStoreScanScheduler(0, 1024)
+	start := time.Now()
+	// the burst is exhausted by the first wait, the size larger than the burst is allowed
+	c.Assert(scheduler.waitBytes(ctx, "s1", nil, 4096), check.IsNil)
+	c.Assert(scheduler.waitBytes(ctx, "s1", nil, 256), check.IsNil)
+	c.Assert(time.Since(start), check.GreaterEqual, 200*time.Millisecond)
+	// the other stores are not throttled
+	start = time.Now()
+	c.Assert(scheduler.waitBytes(ctx, "s2", nil, 1024), check.IsNil)
+	c.Assert(time.Since(start), check.Less, 200*time.Millisecond)
+
+	cancel()
+	c.Assert(scheduler.waitBytes(ctx, "s1", nil, 1024), check.NotNil)
+}
+
+func (s *storeScanSchedulerSuite) TestChangefeedScanRate(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scheduler := newStoreScanScheduler(0, 0)
+	// the scan rate of the changefeed is shared by all the stores
+	limits := scheduler.register("cf1", 1, 1024)
+	c.Assert(scheduler.register("cf1", 1, 1024), check.Equals, limits)
+	start := time.Now()
+	c.Assert(scheduler.waitBytes(ctx, "s1", limits, 1024), check.IsNil)
+	c.Assert(scheduler.waitBytes(ctx, "s2", limits, 256), check.IsNil)
+	c.Assert(time.Since(start), check.GreaterEqual, 200*time.Millisecond)
+	// the other changefeeds are not throttled
+	start = time.Now()
+	unlimited := scheduler.register("cf2", 1, 0)
+	c.Assert(scheduler.waitBytes(ctx, "s1", unlimited, 4096), check.IsNil)
+	c.Assert(time.Since(start), check.Less, 200*time.Millisecond)
+
+	scheduler.unregister("cf1")
+	c.Assert(scheduler.changefeeds, check.HasLen, 2)
+	scheduler.unregister("cf1")
+	scheduler.unregister("cf2")
+	c.Assert(scheduler.changefeeds, check.HasLen, 0)
+}
+
+func (s *storeScanSchedulerSuite) TestPriority(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler := newStoreScanScheduler(2, 0)
+	scheduler.register("low", 1, 0)
+	scheduler.register("high", 4, 0)
+	c.Assert(scheduler.tryAcquire("s1", "low"), check.IsTrue)
+	c.Assert(scheduler.tryAcquire("s1", "low"), check.IsTrue)
+	scheduler.enqueue("s1", "low", 10)
+	scheduler.enqueue("s1", "high", 10)
+	// the changefeed with the higher priority is admitted first, even if it's running more scans
+	scheduler.release("s1", "low", 1)
+	c.Assert(scheduler.tryAcquire("s1", "low"), check.IsFalse)
+	c.Assert(scheduler.tryAcquire("s1", "high"), check.IsTrue)
+	scheduler.release("s1", "low", 1)
+	c.Assert(scheduler.tryAcquire("s1", "low"), check.IsFalse)
+	c.Assert(scheduler.tryAcquire("s1", "high"), check.IsTrue)
+	// the lower priority one is admitted once the higher priority one has no queued region
+	scheduler.enqueue("s1", "high", -10)
+	scheduler.release("s1", "high", 1)
+	c.Assert(scheduler.tryAcquire("s1", "low"), check.IsTrue)
+}
+
+func (s *storeScanSchedulerSuite) TestRouterAdmission(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler := newStoreScanScheduler(2, 0)
+	store := "store-1"
+	r1 := newSizedRegionRouter(util.PutChangefeedIDInCtx(ctx, "cf1"), 10, scheduler)
+	r2 := newSizedRegionRouter(util.PutChangefeedIDInCtx(ctx, "cf2"), 10, scheduler)
+	errCh := make(chan error, 2)
+	go func() { errCh <- r1.Run(ctx) }()
+	go func() { errCh <- r2.Run(ctx) }()
+
+	for i := 0; i < 4; i++ {
+		r1.AddRegion(singleRegionInfo{ts: uint64(i), rpcCtx: &tikv.RPCContext{Addr: store}})
+	}
+	// the regions exceeding the concurrency of the store are queued
+	for i := 0; i < 2; i++ {
+		sri := <-r1.Chan()
+		c.Assert(sri.ts, check.Equals, uint64(i))
+		r1.Acquire(store)
+	}
+	for i := 0; i < 2; i++ {
+		r2.AddRegion(singleRegionInfo{ts: uint64(i), rpcCtx: &tikv.RPCContext{Addr: store}})
+	}
+	scheduler.mu.Lock()
+	c.Assert(scheduler.stores[store].changefeeds["cf1"].queued, check.Equals, 2)
+	c.Assert(scheduler.stores[store].changefeeds["cf2"].queued, check.Equals, 2)
+	scheduler.mu.Unlock()
+
+	// the released token is given to cf2 which is running fewer scans
+	r1.Release(store)
+	select {
+	case sri := <-r2.Chan():
+		c.Assert(sri.ts, check.Equals, uint64(0))
+	case <-time.After(time.Second):
+		c.Fatal("region of cf2 is not admitted")
+	}
+	select {
+	case <-r1.Chan():
+		c.Fatal("region of cf1 is admitted unexpectedly")
+	case <-time.After(3 * sizedRegionCheckInterval):
+	}
+	// the admission of a region failing before sending the request is revoked
+	r2.Revoke(store)
+	select {
+	case sri := <-r2.Chan():
+		c.Assert(sri.ts, check.Equals, uint64(1))
+	case <-time.After(time.Second):
+		c.Fatal("region of cf2 is not admitted")
+	}
+
+	// the admissions and the queued regions are given back after the routers exit
+	cancel()
+	<-errCh
+	<-errCh
+	c.Assert(scheduler.stores[store].running, check.Equals, 0)
+	c.Assert(scheduler.stores[store].changefeeds, check.HasLen, 0)
+}
+
+func (s *storeScanSchedulerSuite) TestThrottledChangefeed(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler := newStoreScanScheduler(4, 0)
+	store := "store-1"
+	// cf1 is throttled by its per-table limit, only one region is scanned at a time
+	r1 := newSizedRegionRouter(util.PutChangefeedIDInCtx(ctx, "cf1"), 1, scheduler)
+	r2 := newSizedRegionRouter(util.PutChangefeedIDInCtx(ctx, "cf2"), 10, scheduler)
+	errCh := make(chan error, 2)
+	go func() { errCh <- r1.Run(ctx) }()
+	go func() { errCh <- r2.Run(ctx) }()
+
+	r1.AddRegion(singleRegionInfo{ts: 0, rpcCtx: &tikv.RPCContext{Addr: store}})
+	sri := <-r1.Chan()
+	c.Assert(sri.ts, check.Equals, uint64(0))
+	r1.Acquire(store)
+	for i := 1; i < 5; i++ {
+		r1.AddRegion(singleRegionInfo{ts: uint64(i), rpcCtx: &tikv.RPCContext{Addr: store}})
+	}
+	// the regions held back by the per-table limit are not queued in the scheduler
+	scheduler.mu.Lock()
+	c.Assert(scheduler.stores[store].changefeeds["cf1"].queued, check.Equals, 0)
+	scheduler.mu.Unlock()
+
+	// cf2 isn't capped at the running scans of cf1, it uses the rest of the store concurrency
+	for i := 0; i < 3; i++ {
+		r2.AddRegion(singleRegionInfo{ts: uint64(i), rpcCtx: &tikv.RPCContext{Addr: store}})
+		select {
+		case sri := <-r2.Chan():
+			c.Assert(sri.ts, check.Equals, uint64(i))
+			r2.Acquire(store)
+		case <-time.After(time.Second):
+			c.Fatal("region of cf2 is not admitted")
+		}
+	}
+	scheduler.mu.Lock()
+	c.Assert(scheduler.stores[store].running, check.Equals, 4)
+	c.Assert(scheduler.stores[store].changefeeds["cf2"].running, check.Equals, 3)
+	scheduler.mu.Unlock()
+
+	// the next region of cf1 waits for the store concurrency after its own token is released
+	r1.Release(store)
+	scheduler.mu.Lock()
+	c.Assert(scheduler.stores[store].changefeeds["cf1"].queued, check.Equals, 1)
+	scheduler.mu.Unlock()
+	r2.Release(store)
+	select {
+	case sri := <-r1.Chan():
+		c.Assert(sri.ts, check.Equals, uint64(1))
+	case <-time.After(time.Second):
+		c.Fatal("region of cf1 is not admitted")
+	}
+
+	cancel()
+	<-errCh
+	<-errCh
+	c.Assert(scheduler.stores[store].running, check.Equals, 0)
+	c.Assert(scheduler.stores[store].changefeeds, check.HasLen, 0)
+}
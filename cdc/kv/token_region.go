@@ -41,6 +41,9 @@ type LimitRegionRouter interface {
 	Acquire(id string)
 	// Release gives back one token, this function is thread-safe
 	Release(id string)
+	// Revoke gives back the admission of a region whose request is not sent,
+	// this function is thread-safe
+	Revoke(id string)
 	// Run runs in background and does some logic work
 	Run(ctx context.Context) error
 }
@@ -68,16 +71,30 @@ type sizedRegionRouter struct {
 	metrics   *srrMetrics
 	tokens    map[string]int
 	sizeLimit int
+	// the scheduler admits the regions to scan with the other kv clients in the capture
+	scheduler *storeScanScheduler
+	admitted  map[string]int
+	// waiting is the number of the buffered regions reported to the scheduler as queued, which are
+	// only waiting for the admission of the scheduler rather than the tokens of the router
+	waiting map[string]int
+	closed  bool
 }
 
 // NewSizedRegionRouter creates a new sizedRegionRouter
 func NewSizedRegionRouter(ctx context.Context, sizeLimit int) *sizedRegionRouter {
+	return newSizedRegionRouter(ctx, sizeLimit, getStoreScanScheduler())
+}
+
+func newSizedRegionRouter(ctx context.Context, sizeLimit int, scheduler *storeScanScheduler) *sizedRegionRouter {
 	return &sizedRegionRouter{
 		buffer:    make(map[string][]singleRegionInfo),
 		output:    make(chan singleRegionInfo, regionRouterChanSize),
 		sizeLimit: sizeLimit,
 		tokens:    make(map[string]int),
 		metrics:   newSrrMetrics(ctx),
+		scheduler: scheduler,
+		admitted:  make(map[string]int),
+		waiting:   make(map[string]int),
 	}
 }
 
@@ -87,17 +104,38 @@ func (r *sizedRegionRouter) Chan() <-chan singleRegionInfo {
 
 func (r *sizedRegionRouter) AddRegion(sri singleRegionInfo) {
 	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return
+	}
 	var id string
 	// if rpcCtx is not provided, use the default "" bucket
 	if sri.rpcCtx != nil {
 		id = sri.rpcCtx.Addr
 	}
-	if r.sizeLimit > r.tokens[id] && len(r.output) < regionRouterChanSize {
+	if r.sizeLimit > r.tokens[id] && len(r.output) < regionRouterChanSize &&
+		len(r.buffer[id]) == 0 && r.scheduler.tryAcquire(id, r.metrics.changefeed) {
+		r.admitted[id]++
 		r.output <- sri
 	} else {
 		r.buffer[id] = append(r.buffer[id], sri)
+		r.updateWaitingLocked(id)
+	}
+}
+
+// updateWaitingLocked reports the number of the buffered regions which could be sent if the scheduler admitted them
+func (r *sizedRegionRouter) updateWaitingLocked(id string) {
+	waiting := len(r.buffer[id])
+	if available := r.sizeLimit - r.tokens[id]; available < waiting {
+		waiting = available
+	}
+	if waiting < 0 {
+		waiting = 0
+	}
+	if delta := waiting - r.waiting[id]; delta != 0 {
+		r.scheduler.enqueue(id, r.metrics.changefeed, delta)
+		r.waiting[id] = waiting
 	}
-	r.lock.Unlock()
 }
 
 func (r *sizedRegionRouter) Acquire(id string) {
@@ -108,6 +146,7 @@ func (r *sizedRegionRouter) Acquire(id string) {
 		r.metrics.tokens[id] = clientRegionTokenSize.WithLabelValues(id, r.metrics.table, r.metrics.changefeed)
 	}
 	r.metrics.tokens[id].Inc()
+	r.updateWaitingLocked(id)
 }
 
 func (r *sizedRegionRouter) Release(id string) {
@@ -118,9 +157,45 @@ func (r *sizedRegionRouter) Release(id string) {
 		r.metrics.tokens[id] = clientRegionTokenSize.WithLabelValues(id, r.metrics.table, r.metrics.changefeed)
 	}
 	r.metrics.tokens[id].Dec()
+	r.revokeLocked(id)
+	r.updateWaitingLocked(id)
+}
+
+func (r *sizedRegionRouter) Revoke(id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.revokeLocked(id)
+}
+
+func (r *sizedRegionRouter) revokeLocked(id string) {
+	if r.admitted[id] > 0 {
+		r.admitted[id]--
+		r.scheduler.release(id, r.metrics.changefeed, 1)
+	}
+}
+
+// close gives back all the admissions and the queued regions to the scheduler
+func (r *sizedRegionRouter) close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.closed = true
+	for id, count := range r.admitted {
+		if count > 0 {
+			r.scheduler.release(id, r.metrics.changefeed, count)
+		}
+	}
+	for id, waiting := range r.waiting {
+		if waiting > 0 {
+			r.scheduler.enqueue(id, r.metrics.changefeed, -waiting)
+		}
+	}
+	r.admitted = make(map[string]int)
+	r.waiting = make(map[string]int)
+	r.buffer = make(map[string][]singleRegionInfo)
 }
 
 func (r *sizedRegionRouter) Run(ctx context.Context) error {
+	defer r.close()
 	ticker := time.NewTicker(sizedRegionCheckInterval)
 	defer ticker.Stop()
 	for {
@@ -147,15 +222,23 @@ func (r *sizedRegionRouter) Run(ctx context.Context) error {
 				if available == 0 {
 					continue
 				}
-				for i := 0; i < available; i++ {
+				sent := 0
+				for ; sent < available; sent++ {
+					if !r.scheduler.tryAcquire(id, r.metrics.changefeed) {
+						break
+					}
+					r.admitted[id]++
 					select {
 					case <-ctx.Done():
 						r.lock.Unlock()
 						return errors.Trace(ctx.Err())
-					case r.output <- buf[i]:
+					case r.output <- buf[sent]:
 					}
 				}
-				r.buffer[id] = r.buffer[id][available:]
+				if sent > 0 {
+					r.buffer[id] = r.buffer[id][sent:]
+					r.updateWaitingLocked(id)
+				}
 			}
 			r.lock.Unlock()
 		}
@@ -25,6 +25,16 @@ type CaptureInfo struct {
 	ID            CaptureID `json:"id"`
 	AdvertiseAddr string    `json:"address"`
 	Version       string    `json:"version"`
+	// Labels are specified in the server config, they are used by the affinity rules of changefeeds
+	Labels map[string]string `json:"labels,omitempty"`
+	// Draining means the capture is going to exit, no table is dispatched to it
+	// and its tables are moved to the other captures one by one
+	Draining bool `json:"draining,omitempty"`
+}
+
+// IsSchedulable returns whether tables can be dispatched to the capture
+func (c *CaptureInfo) IsSchedulable() bool {
+	return !c.Draining
 }
 
 // Marshal using json.Marshal.
@@ -43,3 +53,43 @@ func (c *CaptureInfo) Unmarshal(data []byte) error {
 	return errors.Annotatef(cerror.WrapError(cerror.ErrUnmarshalFailed, err),
 		"unmarshal data: %v", data)
 }
+
+// DrainStatus represents the progress of draining a capture
+type DrainStatus struct {
+	CaptureID CaptureID `json:"capture-id"`
+	Draining  bool      `json:"draining"`
+	// RemainingTables is the number of tables which are replicated by the capture,
+	// or are moved away from the capture but not replicating yet
+	RemainingTables int `json:"remaining-tables"`
+}
+
+// Drained returns whether all the tables have been moved away from the capture
+func (s *DrainStatus) Drained() bool {
+	return s.Draining && s.RemainingTables == 0
+}
+
+// CountDrainingTables returns the number of tables which are replicated by the capture,
+// or are being added to the other captures, in the task statuses of a changefeed.
+func CountDrainingTables(captureID CaptureID, statuses ProcessorsInfos) int {
+	count := 0
+	for id, status := range statuses {
+		if status == nil {
+			continue
+		}
+		if id == captureID {
+			count += len(status.Tables)
+			for tableID, operation := range status.Operation {
+				if _, exist := status.Tables[tableID]; !exist && operation.Status != OperFinished {
+					count++
+				}
+			}
+			continue
+		}
+		for _, operation := range status.Operation {
+			if !operation.Delete && operation.Status != OperFinished {
+				count++
+			}
+		}
+	}
+	return count
+}
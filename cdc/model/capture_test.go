@@ -38,3 +38,34 @@ func (s *captureSuite) TestMarshalUnmarshal(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(decodedInfo, check.DeepEquals, info)
 }
+
+func (s *captureSuite) TestCountDrainingTables(c *check.C) {
+	defer testleak.AfterTest(c)()
+	statuses := ProcessorsInfos{
+		"capture-1": {
+			Tables: map[TableID]*TableReplicaInfo{1: {}, 2: {}},
+			Operation: map[TableID]*TableOperation{
+				2: {Status: OperFinished},
+				3: {Delete: true, Status: OperProcessed},
+			},
+		},
+		"capture-2": {
+			Tables: map[TableID]*TableReplicaInfo{4: {}, 5: {}},
+			Operation: map[TableID]*TableOperation{
+				4: {Status: OperProcessed},
+				5: {Status: OperFinished},
+			},
+		},
+	}
+	// table 1 and 2 are replicated by capture 1, table 3 is being removed and table 4 is being added
+	c.Assert(CountDrainingTables("capture-1", statuses), check.Equals, 4)
+
+	statuses["capture-1"] = &TaskStatus{}
+	statuses["capture-2"].Operation[4].Status = OperFinished
+	c.Assert(CountDrainingTables("capture-1", statuses), check.Equals, 0)
+
+	status := &DrainStatus{CaptureID: "capture-1"}
+	c.Assert(status.Drained(), check.IsFalse)
+	status.Draining = true
+	c.Assert(status.Drained(), check.IsTrue)
+}
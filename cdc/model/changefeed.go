@@ -37,6 +37,9 @@ const (
 	SortInMemory SortEngine = "memory"
 	SortInFile   SortEngine = "file"
 	SortUnified  SortEngine = "unified"
+	// SortInLevelDB persists the events in an embedded LevelDB, so the un-flushed events
+	// don't need to be pulled and sorted again after the capture restarts
+	SortInLevelDB SortEngine = "leveldb"
 )
 
 // FeedState represents the running state of a changefeed
@@ -88,6 +91,13 @@ type ChangeFeedInfo struct {
 	SyncPointEnabled  bool          `json:"sync-point-enabled"`
 	SyncPointInterval time.Duration `json:"sync-point-interval"`
 	CreatorVersion    string        `json:"creator-version"`
+
+	// CheckpointLagNotified is true if the checkpoint lag notification has been sent,
+	// it is persisted to avoid sending the notification again after the owner changes.
+	CheckpointLagNotified bool `json:"checkpoint-lag-notified,omitempty"`
+	// PendingNotifications are the notifications not delivered to the webhooks yet, they are persisted together
+	// with the state changes generating them, so the delivery is resumed after the owner changes.
+	PendingNotifications []*ChangefeedNotification `json:"pending-notifications,omitempty"`
 }
 
 const changeFeedIDMaxLen = 128
@@ -209,6 +219,12 @@ func (info *ChangeFeedInfo) VerifyAndFix() error {
 	if info.Config.Scheduler == nil {
 		info.Config.Scheduler = defaultConfig.Scheduler
 	}
+	if info.Config.Notification == nil {
+		info.Config.Notification = defaultConfig.Notification
+	}
+	if info.Config.Resource == nil {
+		info.Config.Resource = defaultConfig.Resource
+	}
 	return nil
 }
 
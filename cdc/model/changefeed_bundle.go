@@ -0,0 +1,125 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pingcap/ticdc/pkg/cyclic/mark"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// ChangefeedBundleVersion is the version of the changefeed bundle format,
+// it should be increased once the format is changed incompatibly.
+const ChangefeedBundleVersion = 1
+
+// RedactedSecret replaces the secrets in the exported sink URIs
+const RedactedSecret = "******"
+
+// sinkURISecretParams are the query parameters of the sink URIs which contain secrets
+var sinkURISecretParams = []string{"sasl-password", "access-key", "secret-access-key", "session-token"}
+
+// ChangefeedBundle is a set of exported changefeeds which can be imported into another cluster
+type ChangefeedBundle struct {
+	Version     int                   `json:"version"`
+	ExportTime  time.Time             `json:"export-time"`
+	Changefeeds []*ExportedChangefeed `json:"changefeeds"`
+}
+
+// ExportedChangefeed is the definition of an exported changefeed
+type ExportedChangefeed struct {
+	ID   ChangeFeedID    `json:"id"`
+	Info *ChangeFeedInfo `json:"info"`
+	// CheckpointTs is the checkpoint of the changefeed when it's exported, 0 if the checkpoint is not exported
+	CheckpointTs uint64 `json:"checkpoint-ts,omitempty"`
+}
+
+// NewExportedChangefeed exports the definition of a changefeed, the runtime states in the changefeed info are dropped.
+func NewExportedChangefeed(id ChangeFeedID, info *ChangeFeedInfo, checkpointTs uint64, redact bool) (*ExportedChangefeed, error) {
+	exported, err := info.Clone()
+	if err != nil {
+		return nil, err
+	}
+	exported.AdminJobType = AdminNone
+	exported.State = ""
+	exported.ErrorHis = nil
+	exported.Error = nil
+	exported.CheckpointLagNotified = false
+	// the cyclic option is generated from the replica config when the changefeed info is loaded
+	delete(exported.Opts, mark.OptCyclicConfig)
+	if redact {
+		exported.SinkURI, err = RedactSinkURI(exported.SinkURI)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &ExportedChangefeed{
+		ID:           id,
+		Info:         exported,
+		CheckpointTs: checkpointTs,
+	}, nil
+}
+
+// IsRedacted returns true if some secrets in the sink URI are redacted
+func (c *ExportedChangefeed) IsRedacted() bool {
+	return strings.Contains(c.Info.SinkURI, RedactedSecret) ||
+		strings.Contains(c.Info.SinkURI, url.QueryEscape(RedactedSecret))
+}
+
+// Validate checks whether the bundle can be imported
+func (b *ChangefeedBundle) Validate() error {
+	if b.Version <= 0 || b.Version > ChangefeedBundleVersion {
+		return cerror.ErrInvalidChangefeedBundle.GenWithStack(
+			"unsupported bundle version %d, the latest supported version is %d", b.Version, ChangefeedBundleVersion)
+	}
+	ids := make(map[ChangeFeedID]struct{}, len(b.Changefeeds))
+	for _, cf := range b.Changefeeds {
+		if err := ValidateChangefeedID(cf.ID); err != nil {
+			return cerror.WrapError(cerror.ErrInvalidChangefeedBundle, err)
+		}
+		if _, ok := ids[cf.ID]; ok {
+			return cerror.ErrInvalidChangefeedBundle.GenWithStack("duplicated changefeed %s", cf.ID)
+		}
+		ids[cf.ID] = struct{}{}
+		if cf.Info == nil || cf.Info.SinkURI == "" {
+			return cerror.ErrInvalidChangefeedBundle.GenWithStack("the sink uri of changefeed %s is missing", cf.ID)
+		}
+	}
+	return nil
+}
+
+// RedactSinkURI replaces the password and the secret query parameters in the sink URI with RedactedSecret
+func RedactSinkURI(sinkURI string) (string, error) {
+	u, err := url.Parse(sinkURI)
+	if err != nil {
+		return "", cerror.WrapError(cerror.ErrSinkURIInvalid, err)
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), RedactedSecret)
+	}
+	query := u.Query()
+	redacted := false
+	for _, param := range sinkURISecretParams {
+		if query.Get(param) != "" {
+			query.Set(param, RedactedSecret)
+			redacted = true
+		}
+	}
+	if redacted {
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
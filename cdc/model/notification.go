@@ -0,0 +1,59 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationType is the type of a changefeed notification
+type NotificationType string
+
+// All NotificationTypes
+const (
+	NotificationStateChanged           NotificationType = "state-changed"
+	NotificationError                  NotificationType = "error"
+	NotificationCheckpointLag          NotificationType = "checkpoint-lag"
+	NotificationCheckpointLagRecovered NotificationType = "checkpoint-lag-recovered"
+)
+
+// ChangefeedNotification is the event posted to the webhooks when the state of a changefeed changes
+type ChangefeedNotification struct {
+	// ID is unique for every event and kept unchanged in retries, the receivers can de-duplicate the events by it
+	ID           string           `json:"id"`
+	Type         NotificationType `json:"type"`
+	ChangefeedID ChangeFeedID     `json:"changefeed-id"`
+	OldState     FeedState        `json:"old-state,omitempty"`
+	NewState     FeedState        `json:"new-state,omitempty"`
+	// ErrorCode is the RFC code of the error, which is listed in errors.toml
+	ErrorCode    string `json:"error-code,omitempty"`
+	ErrorMessage string `json:"error-message,omitempty"`
+	CheckpointTs uint64 `json:"checkpoint-ts"`
+	// CheckpointLag is the checkpoint lag in milliseconds, only set for the checkpoint lag events
+	CheckpointLag int64     `json:"checkpoint-lag,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// NewChangefeedNotification creates a notification with a new event ID
+func NewChangefeedNotification(tp NotificationType, changefeedID ChangeFeedID, checkpointTs uint64) *ChangefeedNotification {
+	return &ChangefeedNotification{
+		ID:           uuid.New().String(),
+		Type:         tp,
+		ChangefeedID: changefeedID,
+		CheckpointTs: checkpointTs,
+		Time:         time.Now(),
+	}
+}
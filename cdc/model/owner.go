@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
 
 	"github.com/pingcap/errors"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
@@ -83,6 +84,11 @@ type TaskPosition struct {
 	Count uint64 `json:"count"`
 	// Error code when error happens
 	Error *RunningError `json:"error"`
+	// The replication status of each table, it is reported periodically by corresponding processor.
+	Tables map[TableID]*TableReplicationStatus `json:"tables,omitempty"`
+	// BlockedTables are the checkpoint ts of the tables blocked by the table barriers,
+	// the owner executes the DDL of a table once all of its pipelines reach the table barrier.
+	BlockedTables map[TableID]Ts `json:"blocked-tables,omitempty"`
 }
 
 // Marshal returns the json marshal format of a TaskStatus
@@ -104,6 +110,58 @@ func (tp *TaskPosition) String() string {
 	return data
 }
 
+// TableReplicationStatus records the replication status of a table in a processor
+type TableReplicationStatus struct {
+	// Name is the quoted schema and table name
+	Name         string `json:"name"`
+	ResolvedTs   Ts     `json:"resolved-ts"`
+	CheckpointTs Ts     `json:"checkpoint-ts"`
+	// SorterBacklog is the number of events sent to the sorter but not yet output by it
+	SorterBacklog uint64 `json:"sorter-backlog"`
+	// SinkBufferSize is the number of events buffered in the sink node
+	SinkBufferSize uint64 `json:"sink-buffer-size"`
+	// MemoryConsumption is the memory in bytes occupied by the events between the sorter and the sink
+	MemoryConsumption uint64 `json:"memory-consumption"`
+}
+
+// CaptureTableStatus records the replication status of a table and the capture which replicates it
+type CaptureTableStatus struct {
+	CaptureID CaptureID `json:"capture-id"`
+	TableID   TableID   `json:"table-id"`
+	*TableReplicationStatus
+}
+
+// SlowestTables returns the tables whose checkpoint ts are the smallest among all positions,
+// at most limit tables are returned, and all tables are returned if limit is not positive.
+func SlowestTables(positions map[CaptureID]*TaskPosition, limit int) []*CaptureTableStatus {
+	tables := make([]*CaptureTableStatus, 0)
+	for captureID, position := range positions {
+		if position == nil {
+			continue
+		}
+		for tableID, status := range position.Tables {
+			tables = append(tables, &CaptureTableStatus{
+				CaptureID:              captureID,
+				TableID:                tableID,
+				TableReplicationStatus: status,
+			})
+		}
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		if tables[i].CheckpointTs != tables[j].CheckpointTs {
+			return tables[i].CheckpointTs < tables[j].CheckpointTs
+		}
+		if tables[i].ResolvedTs != tables[j].ResolvedTs {
+			return tables[i].ResolvedTs < tables[j].ResolvedTs
+		}
+		return tables[i].TableID < tables[j].TableID
+	})
+	if limit > 0 && len(tables) > limit {
+		tables = tables[:limit]
+	}
+	return tables
+}
+
 // MoveTableStatus represents for the status of a MoveTableJob
 type MoveTableStatus int
 
@@ -178,6 +236,23 @@ type TaskWorkload map[TableID]WorkloadInfo
 // WorkloadInfo records the workload info of a table
 type WorkloadInfo struct {
 	Workload uint64 `json:"workload"`
+	// RowsPerSecond and BytesPerSecond are the measured throughput of the table
+	RowsPerSecond  uint64 `json:"rows-per-second,omitempty"`
+	BytesPerSecond uint64 `json:"bytes-per-second,omitempty"`
+}
+
+// workloadBytesUnit is the number of bytes counted as one unit of workload
+const workloadBytesUnit = 1024
+
+// NewWorkloadInfo creates a WorkloadInfo by the throughput of a table,
+// each row and each KiB per second is counted as one unit of workload.
+// An idle table still has one unit of workload, so that the number of tables is also balanced.
+func NewWorkloadInfo(rowsPerSecond, bytesPerSecond uint64) WorkloadInfo {
+	return WorkloadInfo{
+		Workload:       1 + rowsPerSecond + bytesPerSecond/workloadBytesUnit,
+		RowsPerSecond:  rowsPerSecond,
+		BytesPerSecond: bytesPerSecond,
+	}
 }
 
 // Unmarshal unmarshals into *TaskWorkload from json marshal byte slice
@@ -200,6 +275,34 @@ func (w *TaskWorkload) Marshal() (string, error) {
 type TableReplicaInfo struct {
 	StartTs     Ts      `json:"start-ts"`
 	MarkTableID TableID `json:"mark-table-id"`
+	// StartKey and EndKey are the key range of a span if the table is split into spans,
+	// a nil StartKey or EndKey means the start or the end of the table.
+	StartKey []byte `json:"start-key,omitempty"`
+	EndKey   []byte `json:"end-key,omitempty"`
+}
+
+// A table split into spans is scheduled as several tables, whose IDs are the span table IDs.
+// A span table ID is a positive int64 which sets the spanTableIDFlag bit and encodes the
+// index of the span in the bits above spanIndexShift and the ID of the table in the lower bits.
+const (
+	spanTableIDFlag = int64(1) << 62
+	spanIndexShift  = 48
+	spanIndexMask   = int64(1)<<14 - 1
+	spanTableIDMask = int64(1)<<spanIndexShift - 1
+)
+
+// SpanTableID returns the ID of the span with the given index of the table
+func SpanTableID(tableID TableID, index int) TableID {
+	return spanTableIDFlag | (int64(index)&spanIndexMask)<<spanIndexShift | tableID&spanTableIDMask
+}
+
+// DecodeSpanTableID returns the ID of the table and the index of the span if the ID is a span table ID,
+// otherwise it returns the ID itself and isSpan is false.
+func DecodeSpanTableID(id TableID) (tableID TableID, index int, isSpan bool) {
+	if id&spanTableIDFlag == 0 {
+		return id, 0, false
+	}
+	return id & spanTableIDMask, int(id >> spanIndexShift & spanIndexMask), true
 }
 
 // Clone clones a TableReplicaInfo
@@ -310,6 +413,8 @@ func (ts *TaskStatus) Snapshot(cfID ChangeFeedID, captureID CaptureID, checkpoin
 		snap.Tables[tableID] = &TableReplicaInfo{
 			StartTs:     ts,
 			MarkTableID: table.MarkTableID,
+			StartKey:    table.StartKey,
+			EndKey:      table.EndKey,
 		}
 	}
 	return snap
@@ -410,6 +515,13 @@ type ChangeFeedStatus struct {
 	ResolvedTs   uint64       `json:"resolved-ts"`
 	CheckpointTs uint64       `json:"checkpoint-ts"`
 	AdminJobType AdminJobType `json:"admin-job-type"`
+	// SplitKeys are the keys by which the tables are split into spans, they are recorded
+	// so that the tables are split in the same way after the owner changes.
+	SplitKeys map[TableID][][]byte `json:"split-keys,omitempty"`
+	// TableBarriers are the commit ts of the pending DDLs which only affect one table, indexed by the
+	// physical table ID. A table doesn't replicate the events after its barrier until the DDL is executed,
+	// while the other tables are not blocked.
+	TableBarriers map[TableID]Ts `json:"table-barriers,omitempty"`
 }
 
 // Marshal returns json encoded string of ChangeFeedStatus, only contains necessary fields stored in storage
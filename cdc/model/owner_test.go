@@ -14,6 +14,7 @@
 package model
 
 import (
+	"encoding/json"
 	"math"
 	"testing"
 
@@ -86,6 +87,35 @@ func (s *ownerCommonSuite) TestTaskPositionMarshal(c *check.C) {
 	c.Assert(newPos, check.DeepEquals, pos)
 }
 
+func (s *ownerCommonSuite) TestSlowestTables(c *check.C) {
+	defer testleak.AfterTest(c)()
+	positions := map[CaptureID]*TaskPosition{
+		"capture-1": {
+			Tables: map[TableID]*TableReplicationStatus{
+				1: {Name: "`test`.`t1`", ResolvedTs: 30, CheckpointTs: 20},
+				2: {Name: "`test`.`t2`", ResolvedTs: 30, CheckpointTs: 10},
+			},
+		},
+		"capture-2": {
+			Tables: map[TableID]*TableReplicationStatus{
+				3: {Name: "`test`.`t3`", ResolvedTs: 25, CheckpointTs: 20},
+			},
+		},
+		"capture-3": nil,
+	}
+	tables := SlowestTables(positions, 2)
+	c.Assert(tables, check.HasLen, 2)
+	c.Assert(tables[0].CaptureID, check.Equals, "capture-1")
+	c.Assert(tables[0].TableID, check.Equals, TableID(2))
+	c.Assert(tables[1].CaptureID, check.Equals, "capture-2")
+	c.Assert(tables[1].TableID, check.Equals, TableID(3))
+	c.Assert(SlowestTables(positions, 0), check.HasLen, 3)
+
+	data, err := json.Marshal(tables[0])
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, `{"capture-id":"capture-1","table-id":2,"name":"`+"`test`.`t2`"+`","resolved-ts":30,"checkpoint-ts":10,"sorter-backlog":0,"sink-buffer-size":0,"memory-consumption":0}`)
+}
+
 func (s *ownerCommonSuite) TestChangeFeedStatusMarshal(c *check.C) {
 	defer testleak.AfterTest(c)()
 	status := &ChangeFeedStatus{
@@ -104,6 +134,25 @@ func (s *ownerCommonSuite) TestChangeFeedStatusMarshal(c *check.C) {
 	c.Assert(newStatus, check.DeepEquals, status)
 }
 
+func (s *ownerCommonSuite) TestSpanTableID(c *check.C) {
+	defer testleak.AfterTest(c)()
+	tableID, index, isSpan := DecodeSpanTableID(53)
+	c.Assert(tableID, check.Equals, TableID(53))
+	c.Assert(index, check.Equals, 0)
+	c.Assert(isSpan, check.IsFalse)
+
+	for _, index := range []int{0, 1, 1023} {
+		id := SpanTableID(53, index)
+		c.Assert(id, check.Greater, TableID(0))
+		c.Assert(id, check.Not(check.Equals), TableID(53))
+		tableID, decodedIndex, isSpan := DecodeSpanTableID(id)
+		c.Assert(tableID, check.Equals, TableID(53))
+		c.Assert(decodedIndex, check.Equals, index)
+		c.Assert(isSpan, check.IsTrue)
+	}
+	c.Assert(SpanTableID(53, 1), check.Not(check.Equals), SpanTableID(54, 1))
+}
+
 func (s *ownerCommonSuite) TestTableOperationState(c *check.C) {
 	defer testleak.AfterTest(c)()
 	processedMap := map[uint64]bool{
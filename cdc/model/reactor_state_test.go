@@ -88,6 +88,8 @@ func (s *stateSuite) TestChangefeedStateUpdate(c *check.C) {
 						Sink:             &config.SinkConfig{Protocol: "default"},
 						Cyclic:           &config.CyclicConfig{},
 						Scheduler:        &config.SchedulerConfig{Tp: "table-number", PollingTime: -1},
+						Notification:     &config.NotificationConfig{},
+						Resource:         &config.ResourceConfig{Priority: config.PriorityNormal},
 					},
 				},
 				Status: &ChangeFeedStatus{CheckpointTs: 421980719742451713, ResolvedTs: 421980720003809281},
@@ -148,6 +150,8 @@ func (s *stateSuite) TestChangefeedStateUpdate(c *check.C) {
 						Sink:             &config.SinkConfig{Protocol: "default"},
 						Cyclic:           &config.CyclicConfig{},
 						Scheduler:        &config.SchedulerConfig{Tp: "table-number", PollingTime: -1},
+						Notification:     &config.NotificationConfig{},
+						Resource:         &config.ResourceConfig{Priority: config.PriorityNormal},
 					},
 				},
 				Status: &ChangeFeedStatus{CheckpointTs: 421980719742451713, ResolvedTs: 421980720003809281},
@@ -215,6 +219,8 @@ func (s *stateSuite) TestChangefeedStateUpdate(c *check.C) {
 						Sink:             &config.SinkConfig{Protocol: "default"},
 						Cyclic:           &config.CyclicConfig{},
 						Scheduler:        &config.SchedulerConfig{Tp: "table-number", PollingTime: -1},
+						Notification:     &config.NotificationConfig{},
+						Resource:         &config.ResourceConfig{Priority: config.PriorityNormal},
 					},
 				},
 				Status: &ChangeFeedStatus{CheckpointTs: 421980719742451713, ResolvedTs: 421980720003809281},
@@ -337,11 +343,13 @@ func (s *stateSuite) TestPatchInfo(c *check.C) {
 		SinkURI: "123",
 		Engine:  SortUnified,
 		Config: &config.ReplicaConfig{
-			Filter:    defaultConfig.Filter,
-			Mounter:   defaultConfig.Mounter,
-			Sink:      defaultConfig.Sink,
-			Cyclic:    defaultConfig.Cyclic,
-			Scheduler: defaultConfig.Scheduler,
+			Filter:       defaultConfig.Filter,
+			Mounter:      defaultConfig.Mounter,
+			Sink:         defaultConfig.Sink,
+			Cyclic:       defaultConfig.Cyclic,
+			Scheduler:    defaultConfig.Scheduler,
+			Notification: defaultConfig.Notification,
+			Resource:     defaultConfig.Resource,
 		},
 	})
 	state.PatchInfo(func(info *ChangeFeedInfo) (*ChangeFeedInfo, bool, error) {
@@ -354,11 +362,13 @@ func (s *stateSuite) TestPatchInfo(c *check.C) {
 		StartTs: 6,
 		Engine:  SortUnified,
 		Config: &config.ReplicaConfig{
-			Filter:    defaultConfig.Filter,
-			Mounter:   defaultConfig.Mounter,
-			Sink:      defaultConfig.Sink,
-			Cyclic:    defaultConfig.Cyclic,
-			Scheduler: defaultConfig.Scheduler,
+			Filter:       defaultConfig.Filter,
+			Mounter:      defaultConfig.Mounter,
+			Sink:         defaultConfig.Sink,
+			Cyclic:       defaultConfig.Cyclic,
+			Scheduler:    defaultConfig.Scheduler,
+			Notification: defaultConfig.Notification,
+			Resource:     defaultConfig.Resource,
 		},
 	})
 	state.PatchInfo(func(info *ChangeFeedInfo) (*ChangeFeedInfo, bool, error) {
@@ -48,6 +48,7 @@ type TableInfo struct {
 	RowColumnsOffset map[int64]int
 
 	ColumnsFlag map[int64]ColumnFlagType
+	ColumnsMeta map[int64]*ColumnMeta
 
 	// only for new row format decoder
 	handleColID []int64
@@ -75,6 +76,7 @@ func WrapTableInfo(schemaID int64, schemaName string, version uint64, info *mode
 		uniqueColumns:    make(map[int64]struct{}),
 		RowColumnsOffset: make(map[int64]int, len(info.Columns)),
 		ColumnsFlag:      make(map[int64]ColumnFlagType, len(info.Columns)),
+		ColumnsMeta:      make(map[int64]*ColumnMeta, len(info.Columns)),
 		handleColID:      []int64{-1},
 		HandleIndexID:    HandleIndexTableIneligible,
 		rowColInfos:      make([]rowcodec.ColInfo, len(info.Columns)),
@@ -114,6 +116,7 @@ func WrapTableInfo(schemaID int64, schemaName string, version uint64, info *mode
 			VirtualGenCol: col.IsGenerated(),
 		}
 		ti.rowColFieldTps[col.ID] = ti.rowColInfos[i].Ft
+		ti.ColumnsMeta[col.ID] = newColumnMeta(col)
 	}
 
 	for i, idx := range ti.Indices {
@@ -230,6 +233,24 @@ func (ti *TableInfo) initColumnsFlag() {
 	}
 }
 
+func newColumnMeta(col *model.ColumnInfo) *ColumnMeta {
+	meta := &ColumnMeta{
+		SQLType:         col.GetTypeDesc(),
+		Length:          col.Flen,
+		Decimal:         col.Decimal,
+		Charset:         col.Charset,
+		Collation:       col.Collate,
+		Elems:           col.Elems,
+		GeneratedExpr:   col.GeneratedExprString,
+		GeneratedStored: col.GeneratedStored,
+	}
+	if v := col.GetDefaultValue(); v != nil {
+		defaultValue := ColumnValueString(v)
+		meta.DefaultValue = &defaultValue
+	}
+	return meta
+}
+
 // GetColumnInfo returns the column info by ID
 func (ti *TableInfo) GetColumnInfo(colID int64) (info *model.ColumnInfo, exist bool) {
 	colOffset, exist := ti.columnsOffset[colID]
@@ -440,3 +440,70 @@ func (s *schemaStorageSuite) TestTableInfoClone(c *check.C) {
 	cloned.SchemaID = 100
 	c.Assert(info.SchemaID, check.Equals, int64(10))
 }
+
+func (s *schemaStorageSuite) TestColumnsMeta(c *check.C) {
+	defer testleak.AfterTest(c)()
+	t := timodel.TableInfo{
+		ID:   1071,
+		Name: timodel.CIStr{O: "t1"},
+		Columns: []*timodel.ColumnInfo{
+			{
+				ID:   1,
+				Name: timodel.CIStr{O: "price"},
+				FieldType: parser_types.FieldType{
+					Tp:      mysql.TypeNewDecimal,
+					Flag:    mysql.NotNullFlag,
+					Flen:    10,
+					Decimal: 2,
+					Charset: "binary",
+					Collate: "binary",
+				},
+				DefaultValue: "0.00",
+				State:        timodel.StatePublic,
+			},
+			{
+				ID:   2,
+				Name: timodel.CIStr{O: "color"},
+				FieldType: parser_types.FieldType{
+					Tp:      mysql.TypeEnum,
+					Flen:    -1,
+					Decimal: -1,
+					Charset: "utf8mb4",
+					Collate: "utf8mb4_bin",
+					Elems:   []string{"red", "blue"},
+				},
+				State: timodel.StatePublic,
+			},
+			{
+				ID:   3,
+				Name: timodel.CIStr{O: "total"},
+				FieldType: parser_types.FieldType{
+					Tp:      mysql.TypeNewDecimal,
+					Flen:    12,
+					Decimal: 2,
+					Charset: "binary",
+					Collate: "binary",
+				},
+				GeneratedExprString: "`price` * 2",
+				GeneratedStored:     true,
+				State:               timodel.StatePublic,
+			},
+		},
+	}
+	info := WrapTableInfo(10, "test", 0, &t)
+	defaultValue := "0.00"
+	c.Assert(info.ColumnsMeta, check.DeepEquals, map[int64]*ColumnMeta{
+		1: {
+			SQLType: "decimal(10,2)", Length: 10, Decimal: 2, Charset: "binary", Collation: "binary",
+			DefaultValue: &defaultValue,
+		},
+		2: {
+			SQLType: "enum('red','blue')", Length: -1, Decimal: -1, Charset: "utf8mb4", Collation: "utf8mb4_bin",
+			Elems: []string{"red", "blue"},
+		},
+		3: {
+			SQLType: "decimal(12,2)", Length: 12, Decimal: 2, Charset: "binary", Collation: "binary",
+			GeneratedExpr: "`price` * 2", GeneratedStored: true,
+		},
+	})
+}
@@ -14,12 +14,15 @@
 package model
 
 import (
+	"bytes"
 	"fmt"
+	"reflect"
 	"strconv"
 	"sync"
 
 	"github.com/pingcap/log"
 	"github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/pkg/quotes"
 	"github.com/pingcap/ticdc/pkg/util"
 	"go.uber.org/zap"
@@ -229,6 +232,9 @@ type RowChangedEvent struct {
 	Columns      []*Column `json:"columns"`
 	PreColumns   []*Column `json:"pre-columns"`
 	IndexColumns [][]int   `json:"-"`
+	// PartialUpdate is true if the update event only carries the handle key columns and the changed columns,
+	// the other columns are nil in both the Columns and the PreColumns
+	PartialUpdate bool `json:"partial-update,omitempty"`
 
 	// approximate size of this event, calculate by tikv proto bytes size
 	ApproximateSize int64 `json:"-"`
@@ -285,12 +291,89 @@ func (r *RowChangedEvent) HandleKeyColumns() []*Column {
 	return pkeyCols
 }
 
+// IsUpdate returns true if the row is an update event carrying the old values
+func (r *RowChangedEvent) IsUpdate() bool {
+	return len(r.PreColumns) != 0 && len(r.Columns) != 0
+}
+
+// TrimUnchangedColumns returns a copy of the row marked as a partial update, in which the columns whose values
+// are the same in the pre and post images are set to nil, except the handle key columns and the columns of
+// the unique indexes, which are kept for the dispatchers and the conflict detection of the sinks.
+// The row itself is never modified, and it's returned as is if it isn't an update or doesn't have a handle key.
+func (r *RowChangedEvent) TrimUnchangedColumns() *RowChangedEvent {
+	if !r.IsUpdate() || len(r.PreColumns) != len(r.Columns) {
+		return r
+	}
+	hasHandleKey := false
+	for _, col := range r.Columns {
+		if col != nil && col.Flag.IsHandleKey() {
+			hasHandleKey = true
+			break
+		}
+	}
+	if !hasHandleKey {
+		return r
+	}
+	keep := make([]bool, len(r.Columns))
+	for _, idxCols := range r.IndexColumns {
+		for _, i := range idxCols {
+			if i >= 0 && i < len(keep) {
+				keep[i] = true
+			}
+		}
+	}
+	trimmed := *r
+	trimmed.Columns = make([]*Column, len(r.Columns))
+	trimmed.PreColumns = make([]*Column, len(r.PreColumns))
+	for i, col := range r.Columns {
+		preCol := r.PreColumns[i]
+		if col != nil && preCol != nil && !keep[i] && !col.Flag.IsHandleKey() &&
+			col.Name == preCol.Name && columnValueEqual(col.Value, preCol.Value) {
+			continue
+		}
+		trimmed.Columns[i] = col
+		trimmed.PreColumns[i] = preCol
+	}
+	trimmed.PartialUpdate = true
+	return &trimmed
+}
+
+func columnValueEqual(a, b interface{}) bool {
+	if va, ok := a.([]byte); ok {
+		vb, ok := b.([]byte)
+		return ok && bytes.Equal(va, vb)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 // Column represents a column value in row changed event
 type Column struct {
 	Name  string         `json:"name"`
 	Type  byte           `json:"type"`
 	Flag  ColumnFlagType `json:"flag"`
 	Value interface{}    `json:"value"`
+	// Meta is shared by the columns of the same table version, it must not be modified
+	Meta *ColumnMeta `json:"meta,omitempty"`
+}
+
+// ColumnMeta is the metadata of a column, the downstream can create a matching column from it
+type ColumnMeta struct {
+	// SQLType is the full definition of the type, such as `decimal(10,2) unsigned`
+	SQLType string `json:"sql-type"`
+	// Length is the display width of the numeric types or the max length of the string types, -1 means unspecified
+	Length int `json:"length"`
+	// Decimal is the scale of the decimal types or the fractional seconds precision of the time types,
+	// -1 means unspecified
+	Decimal   int    `json:"decimal"`
+	Charset   string `json:"charset,omitempty"`
+	Collation string `json:"collation,omitempty"`
+	// Elems are the members of an enum or a set
+	Elems []string `json:"elems,omitempty"`
+	// DefaultValue is nil if the column has no default value or the default value is null
+	DefaultValue *string `json:"default-value,omitempty"`
+	// GeneratedExpr is the expression of a generated column
+	GeneratedExpr   string `json:"generated-expr,omitempty"`
+	GeneratedStored bool   `json:"generated-stored,omitempty"`
 }
 
 // ColumnValueString returns the string representation of the column value
@@ -339,8 +422,10 @@ func ColumnValueString(c interface{}) string {
 
 // ColumnInfo represents the name and type information passed to the sink
 type ColumnInfo struct {
-	Name string
-	Type byte
+	Name string         `json:"name"`
+	Type byte           `json:"type"`
+	Flag ColumnFlagType `json:"flag"`
+	Meta *ColumnMeta    `json:"meta,omitempty"`
 }
 
 // FromTiColumnInfo populates cdc's ColumnInfo from TiDB's model.ColumnInfo
@@ -349,22 +434,81 @@ func (c *ColumnInfo) FromTiColumnInfo(tiColumnInfo *model.ColumnInfo) {
 	c.Name = tiColumnInfo.Name.O
 }
 
+// KeyInfo represents the primary key or an index of a table passed to the sink
+type KeyInfo struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Primary bool     `json:"primary,omitempty"`
+	Unique  bool     `json:"unique,omitempty"`
+}
+
 // SimpleTableInfo is the simplified table info passed to the sink
 type SimpleTableInfo struct {
 	// db name
-	Schema string
+	Schema string `json:"schema"`
 	// table name
-	Table string
+	Table string `json:"table"`
 	// table ID
-	TableID    int64
-	ColumnInfo []*ColumnInfo
+	TableID    int64         `json:"table-id"`
+	ColumnInfo []*ColumnInfo `json:"columns"`
+	// Keys are the primary key and the indices of the table, only filled in the DDL events
+	Keys []*KeyInfo `json:"keys,omitempty"`
+}
+
+// newSimpleTableInfo builds the SimpleTableInfo with the whole schema of a table,
+// including the flags and the metas of the columns and the keys of the table
+func newSimpleTableInfo(tableInfo *TableInfo) *SimpleTableInfo {
+	info := &SimpleTableInfo{
+		Schema:     tableInfo.TableName.Schema,
+		Table:      tableInfo.TableName.Table,
+		TableID:    tableInfo.ID,
+		ColumnInfo: make([]*ColumnInfo, len(tableInfo.Columns)),
+	}
+	for i, colInfo := range tableInfo.Columns {
+		info.ColumnInfo[i] = new(ColumnInfo)
+		info.ColumnInfo[i].FromTiColumnInfo(colInfo)
+		// the maps are nil if the table info is not wrapped by WrapTableInfo
+		info.ColumnInfo[i].Flag = tableInfo.ColumnsFlag[colInfo.ID]
+		info.ColumnInfo[i].Meta = tableInfo.ColumnsMeta[colInfo.ID]
+	}
+	if tableInfo.PKIsHandle {
+		for _, colInfo := range tableInfo.Columns {
+			if mysql.HasPriKeyFlag(colInfo.Flag) {
+				info.Keys = append(info.Keys, &KeyInfo{
+					Name:    "PRIMARY",
+					Columns: []string{colInfo.Name.O},
+					Primary: true,
+					Unique:  true,
+				})
+				break
+			}
+		}
+	}
+	for _, idx := range tableInfo.Indices {
+		if idx.State != model.StatePublic {
+			continue
+		}
+		key := &KeyInfo{
+			Name:    idx.Name.O,
+			Columns: make([]string, len(idx.Columns)),
+			Primary: idx.Primary,
+			Unique:  idx.Unique,
+		}
+		for i, col := range idx.Columns {
+			key.Columns[i] = col.Name.O
+		}
+		info.Keys = append(info.Keys, key)
+	}
+	return info
 }
 
 // DDLEvent represents a DDL event
 type DDLEvent struct {
-	StartTs      uint64
-	CommitTs     uint64
-	TableInfo    *SimpleTableInfo
+	StartTs  uint64
+	CommitTs uint64
+	// TableInfo is the whole schema of the table after the DDL
+	TableInfo *SimpleTableInfo
+	// PreTableInfo is the whole schema of the table before the DDL, nil if the table didn't exist
 	PreTableInfo *SimpleTableInfo
 	Query        string
 	Type         model.ActionType
@@ -380,16 +524,8 @@ func (d *DDLEvent) FromJob(job *model.Job, preTableInfo *TableInfo) {
 	d.Type = job.Type
 
 	if job.BinlogInfo.TableInfo != nil {
-		tableName := job.BinlogInfo.TableInfo.Name.O
-		tableInfo := job.BinlogInfo.TableInfo
-		d.TableInfo.ColumnInfo = make([]*ColumnInfo, len(tableInfo.Columns))
-
-		for i, colInfo := range tableInfo.Columns {
-			d.TableInfo.ColumnInfo[i] = new(ColumnInfo)
-			d.TableInfo.ColumnInfo[i].FromTiColumnInfo(colInfo)
-		}
-
-		d.TableInfo.Table = tableName
+		tableInfo := WrapTableInfo(job.SchemaID, job.SchemaName, job.BinlogInfo.FinishedTS, job.BinlogInfo.TableInfo)
+		d.TableInfo = newSimpleTableInfo(tableInfo)
 		d.TableInfo.TableID = job.TableID
 	}
 	d.fillPreTableInfo(preTableInfo)
@@ -399,16 +535,7 @@ func (d *DDLEvent) fillPreTableInfo(preTableInfo *TableInfo) {
 	if preTableInfo == nil {
 		return
 	}
-	d.PreTableInfo = new(SimpleTableInfo)
-	d.PreTableInfo.Schema = preTableInfo.TableName.Schema
-	d.PreTableInfo.Table = preTableInfo.TableName.Table
-	d.PreTableInfo.TableID = preTableInfo.ID
-
-	d.PreTableInfo.ColumnInfo = make([]*ColumnInfo, len(preTableInfo.Columns))
-	for i, colInfo := range preTableInfo.Columns {
-		d.PreTableInfo.ColumnInfo[i] = new(ColumnInfo)
-		d.PreTableInfo.ColumnInfo[i].FromTiColumnInfo(colInfo)
-	}
+	d.PreTableInfo = newSimpleTableInfo(preTableInfo)
 }
 
 // SingleTableTxn represents a transaction which includes many row events in a single table
@@ -233,3 +233,111 @@ func (s *commonDataStructureSuite) TestDDLEventFromJob(c *check.C) {
 	event.FromJob(job, nil)
 	c.Assert(event.PreTableInfo, check.IsNil)
 }
+
+func (s *commonDataStructureSuite) TestDDLEventTableSchema(c *check.C) {
+	defer testleak.AfterTest(c)()
+	tableInfo := &timodel.TableInfo{
+		ID:         49,
+		Name:       timodel.CIStr{O: "t1"},
+		PKIsHandle: true,
+		Columns: []*timodel.ColumnInfo{
+			{ID: 1, Name: timodel.CIStr{O: "id"}, FieldType: types.FieldType{Tp: mysql.TypeLong, Flag: mysql.PriKeyFlag | mysql.NotNullFlag}, State: timodel.StatePublic},
+			{ID: 2, Name: timodel.CIStr{O: "a"}, FieldType: types.FieldType{Tp: mysql.TypeVarchar, Flen: 10}, Offset: 1, State: timodel.StatePublic},
+		},
+		Indices: []*timodel.IndexInfo{
+			{
+				ID:      1,
+				Name:    timodel.CIStr{O: "idx_a"},
+				Columns: []*timodel.IndexColumn{{Name: timodel.CIStr{O: "a"}, Offset: 1}},
+				State:   timodel.StatePublic,
+			},
+			{
+				ID:      2,
+				Name:    timodel.CIStr{O: "idx_b"},
+				Columns: []*timodel.IndexColumn{{Name: timodel.CIStr{O: "a"}, Offset: 1}},
+				State:   timodel.StateWriteOnly,
+			},
+		},
+	}
+	job := &timodel.Job{
+		ID:         1071,
+		TableID:    49,
+		SchemaID:   2,
+		SchemaName: "test",
+		Type:       timodel.ActionAddIndex,
+		Query:      "alter table t1 add index idx_a(a)",
+		BinlogInfo: &timodel.HistoryInfo{TableInfo: tableInfo, FinishedTS: 420536581196873729},
+	}
+	event := &DDLEvent{}
+	event.FromJob(job, WrapTableInfo(2, "test", 420536581131337731, tableInfo))
+	for _, info := range []*SimpleTableInfo{event.TableInfo, event.PreTableInfo} {
+		c.Assert(info.Schema, check.Equals, "test")
+		c.Assert(info.Table, check.Equals, "t1")
+		c.Assert(info.ColumnInfo, check.HasLen, 2)
+		c.Assert(info.ColumnInfo[0].Flag.IsHandleKey(), check.IsTrue)
+		c.Assert(info.ColumnInfo[0].Flag.IsNullable(), check.IsFalse)
+		c.Assert(info.ColumnInfo[1].Flag.IsMultipleKey(), check.IsFalse)
+		c.Assert(info.ColumnInfo[1].Flag.IsNullable(), check.IsTrue)
+		c.Assert(info.ColumnInfo[1].Meta.SQLType, check.Equals, "varchar(10)")
+		// the index not in public state is skipped
+		c.Assert(info.Keys, check.DeepEquals, []*KeyInfo{
+			{Name: "PRIMARY", Columns: []string{"id"}, Primary: true, Unique: true},
+			{Name: "idx_a", Columns: []string{"a"}},
+		})
+	}
+}
+
+func (s *commonDataStructureSuite) TestTrimUnchangedColumns(c *check.C) {
+	defer testleak.AfterTest(c)()
+	newRow := func() *RowChangedEvent {
+		return &RowChangedEvent{
+			Table: &TableName{Schema: "a", Table: "b"},
+			PreColumns: []*Column{
+				{Name: "id", Value: int64(1), Flag: HandleKeyFlag | PrimaryKeyFlag},
+				{Name: "a", Value: []byte("a0")},
+				{Name: "b", Value: int64(2)},
+				{Name: "c", Value: nil},
+				{Name: "u", Value: int64(5), Flag: UniqueKeyFlag},
+			},
+			Columns: []*Column{
+				{Name: "id", Value: int64(1), Flag: HandleKeyFlag | PrimaryKeyFlag},
+				{Name: "a", Value: []byte("a0")},
+				{Name: "b", Value: int64(3)},
+				{Name: "c", Value: nil},
+				{Name: "u", Value: int64(5), Flag: UniqueKeyFlag},
+			},
+			IndexColumns: [][]int{{0}, {4}},
+		}
+	}
+
+	row := newRow()
+	trimmed := row.TrimUnchangedColumns()
+	c.Assert(trimmed.PartialUpdate, check.IsTrue)
+	// the handle key columns and the columns of the unique indexes are kept
+	c.Assert(trimmed.PreColumns, check.DeepEquals, []*Column{
+		{Name: "id", Value: int64(1), Flag: HandleKeyFlag | PrimaryKeyFlag}, nil,
+		{Name: "b", Value: int64(2)}, nil,
+		{Name: "u", Value: int64(5), Flag: UniqueKeyFlag},
+	})
+	c.Assert(trimmed.Columns, check.DeepEquals, []*Column{
+		{Name: "id", Value: int64(1), Flag: HandleKeyFlag | PrimaryKeyFlag}, nil,
+		{Name: "b", Value: int64(3)}, nil,
+		{Name: "u", Value: int64(5), Flag: UniqueKeyFlag},
+	})
+	c.Assert(trimmed.IndexColumns, check.DeepEquals, row.IndexColumns)
+	// the row itself is kept intact
+	c.Assert(row, check.DeepEquals, newRow())
+
+	// the rows without a handle key are returned as they are
+	row = newRow()
+	row.PreColumns[0].Flag = 0
+	row.Columns[0].Flag = 0
+	c.Assert(row.TrimUnchangedColumns(), check.Equals, row)
+	c.Assert(row.PartialUpdate, check.IsFalse)
+
+	// the inserts and the deletes are returned as they are
+	row = newRow()
+	row.PreColumns = nil
+	c.Assert(row.TrimUnchangedColumns(), check.Equals, row)
+	c.Assert(row.PartialUpdate, check.IsFalse)
+}
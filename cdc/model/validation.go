@@ -0,0 +1,89 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"github.com/pingcap/errors"
+)
+
+// ValidationItem is the item checked by the changefeed validation
+type ValidationItem string
+
+// All ValidationItems
+const (
+	ValidationChangefeedID  ValidationItem = "changefeed-id"
+	ValidationTs            ValidationItem = "ts"
+	ValidationGCSafePoint   ValidationItem = "gc-safepoint"
+	ValidationReplicaConfig ValidationItem = "replica-config"
+	ValidationTables        ValidationItem = "tables"
+	ValidationSink          ValidationItem = "sink"
+)
+
+// ValidationProblem is a problem found by the changefeed validation
+type ValidationProblem struct {
+	Item ValidationItem `json:"item"`
+	// Warning is true if the problem doesn't prevent the changefeed from running
+	Warning bool `json:"warning,omitempty"`
+	// ErrorCode is the RFC code of the error, which is listed in errors.toml
+	ErrorCode string `json:"error-code,omitempty"`
+	Message   string `json:"message"`
+}
+
+// ChangefeedValidation is the result of validating a changefeed without creating or updating it
+type ChangefeedValidation struct {
+	ChangefeedID ChangeFeedID `json:"changefeed-id"`
+	// StartTs is the ts at which the tables are selected
+	StartTs uint64 `json:"start-ts"`
+	// EligibleTables are the tables selected by the filter which can be replicated
+	EligibleTables []TableName `json:"eligible-tables"`
+	// IneligibleTables are the tables selected by the filter which have neither a primary key nor a not null unique key
+	IneligibleTables []TableName          `json:"ineligible-tables"`
+	Problems         []*ValidationProblem `json:"problems"`
+}
+
+// AddError records the error found when checking the item
+func (v *ChangefeedValidation) AddError(item ValidationItem, err error) {
+	problem := &ValidationProblem{
+		Item:    item,
+		Message: err.Error(),
+	}
+	// the outermost RFC error is used, since the cause of a wrapped RFC error is usually not an RFC error
+	rfcErr := errors.Find(err, func(e error) bool {
+		_, ok := e.(*errors.Error)
+		return ok
+	})
+	if rfcErr != nil {
+		problem.ErrorCode = string(rfcErr.(*errors.Error).RFCCode())
+	}
+	v.Problems = append(v.Problems, problem)
+}
+
+// AddWarning records a problem which doesn't prevent the changefeed from running
+func (v *ChangefeedValidation) AddWarning(item ValidationItem, message string) {
+	v.Problems = append(v.Problems, &ValidationProblem{
+		Item:    item,
+		Warning: true,
+		Message: message,
+	})
+}
+
+// Passed returns true if no error is found
+func (v *ChangefeedValidation) Passed() bool {
+	for _, problem := range v.Problems {
+		if !problem.Warning {
+			return false
+		}
+	}
+	return true
+}
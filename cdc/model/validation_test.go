@@ -0,0 +1,46 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"errors"
+
+	"github.com/pingcap/check"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type validationSuite struct{}
+
+var _ = check.Suite(&validationSuite{})
+
+func (s *validationSuite) TestChangefeedValidation(c *check.C) {
+	defer testleak.AfterTest(c)()
+	result := &ChangefeedValidation{ChangefeedID: "test"}
+	c.Assert(result.Passed(), check.IsTrue)
+
+	result.AddWarning(ValidationTables, "no table is selected by the filter")
+	c.Assert(result.Passed(), check.IsTrue)
+
+	result.AddError(ValidationGCSafePoint, cerror.ErrStartTsBeforeGC.GenWithStackByArgs(10, 20))
+	result.AddError(ValidationSink, cerror.WrapError(cerror.ErrMySQLConnectionError, errors.New("connection refused")))
+	result.AddError(ValidationTs, errors.New("unknown error"))
+	c.Assert(result.Passed(), check.IsFalse)
+	c.Assert(result.Problems, check.HasLen, 4)
+	c.Assert(result.Problems[0].Warning, check.IsTrue)
+	c.Assert(result.Problems[1].ErrorCode, check.Equals, "CDC:ErrStartTsBeforeGC")
+	c.Assert(result.Problems[1].Message, check.Matches, ".*start-ts 10 is earlier than GC safepoint at 20.*")
+	c.Assert(result.Problems[2].ErrorCode, check.Equals, "CDC:ErrMySQLConnectionError")
+	c.Assert(result.Problems[3].ErrorCode, check.Equals, "")
+}
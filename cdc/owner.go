@@ -1311,6 +1311,7 @@ loop:
 		cf.Close()
 		changefeedCheckpointTsGauge.DeleteLabelValues(cf.id)
 		changefeedCheckpointTsLagGauge.DeleteLabelValues(cf.id)
+		changefeedWorkloadSkewnessGauge.DeleteLabelValues(cf.id)
 	}
 	if o.stepDown != nil {
 		if err := o.stepDown(ctx); err != nil {
@@ -0,0 +1,186 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package owner
+
+import (
+	"sort"
+
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+)
+
+type affinityRule struct {
+	filter     filter.Filter
+	labels     map[string]string
+	antiLabels map[string]string
+	required   bool
+}
+
+// matchCapture returns true if the capture has all the labels and none of the anti-labels of the rule
+func (r *affinityRule) matchCapture(capture *model.CaptureInfo) bool {
+	for key, value := range r.labels {
+		if capture.Labels[key] != value {
+			return false
+		}
+	}
+	for key, value := range r.antiLabels {
+		if v, exist := capture.Labels[key]; exist && v == value {
+			return false
+		}
+	}
+	return true
+}
+
+// affinity decides which captures a table can be dispatched to, according to the affinity rules
+// and the labels of the captures. All the methods can be called on a nil affinity, which means
+// every table can be dispatched to every capture.
+type affinity struct {
+	rules       []*affinityRule
+	spreadLabel string
+	tableName   func(tableID model.TableID) (model.TableName, bool)
+}
+
+// newAffinity creates an affinity by the scheduler config of a changefeed,
+// it returns nil if neither affinity rules nor the spread label is specified.
+func newAffinity(
+	cfg *config.SchedulerConfig, caseSensitive bool,
+	tableName func(tableID model.TableID) (model.TableName, bool),
+) (*affinity, error) {
+	if cfg == nil || (len(cfg.AffinityRules) == 0 && cfg.SpreadLabel == "") {
+		return nil, nil
+	}
+	a := &affinity{
+		rules:       make([]*affinityRule, 0, len(cfg.AffinityRules)),
+		spreadLabel: cfg.SpreadLabel,
+		tableName:   tableName,
+	}
+	for _, rule := range cfg.AffinityRules {
+		f, err := filter.Parse(rule.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrInvalidSchedulerConfig, err)
+		}
+		if !caseSensitive {
+			f = filter.CaseInsensitive(f)
+		}
+		a.rules = append(a.rules, &affinityRule{
+			filter:     f,
+			labels:     rule.Labels,
+			antiLabels: rule.AntiLabels,
+			required:   rule.Required,
+		})
+	}
+	return a, nil
+}
+
+// ruleOf returns the first rule matching the table, or nil if no rule matches the table
+func (a *affinity) ruleOf(tableID model.TableID) *affinityRule {
+	if a == nil || len(a.rules) == 0 {
+		return nil
+	}
+	tableName, ok := a.tableName(tableID)
+	if !ok {
+		return nil
+	}
+	for _, rule := range a.rules {
+		if rule.filter.MatchTable(tableName.Schema, tableName.Table) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// isPreferred returns true if the table is expected to be replicated by the capture
+func (a *affinity) isPreferred(tableID model.TableID, capture *model.CaptureInfo) bool {
+	rule := a.ruleOf(tableID)
+	return rule == nil || rule.matchCapture(capture)
+}
+
+// preferredCaptures returns the alive captures matching the rule of the table.
+// All the captures are returned if no rule matches the table.
+func (a *affinity) preferredCaptures(
+	tableID model.TableID, captures map[model.CaptureID]*model.CaptureInfo,
+) map[model.CaptureID]*model.CaptureInfo {
+	rule := a.ruleOf(tableID)
+	if rule == nil {
+		return captures
+	}
+	preferred := make(map[model.CaptureID]*model.CaptureInfo)
+	for captureID, capture := range captures {
+		if rule.matchCapture(capture) {
+			preferred[captureID] = capture
+		}
+	}
+	return preferred
+}
+
+// candidates returns the captures which the table can be dispatched to. If no capture matching the
+// rule of the table is alive, it returns nothing for a required rule, so that the table waits for
+// a matching capture, and returns all the captures otherwise.
+func (a *affinity) candidates(
+	tableID model.TableID, captures map[model.CaptureID]*model.CaptureInfo,
+) map[model.CaptureID]*model.CaptureInfo {
+	rule := a.ruleOf(tableID)
+	if rule == nil {
+		return captures
+	}
+	preferred := a.preferredCaptures(tableID, captures)
+	if len(preferred) == 0 && !rule.required {
+		return captures
+	}
+	return preferred
+}
+
+// selectCapture selects the capture with the minimum workload from the candidates.
+// If the spread label is specified, it selects the label value with the minimum workload first.
+func (a *affinity) selectCapture(
+	candidates map[model.CaptureID]*model.CaptureInfo, workloads map[model.CaptureID]uint64,
+) model.CaptureID {
+	captureIDs := make([]model.CaptureID, 0, len(candidates))
+	for captureID := range candidates {
+		captureIDs = append(captureIDs, captureID)
+	}
+	// sort the captures to make the result stable
+	sort.Strings(captureIDs)
+
+	if a != nil && a.spreadLabel != "" {
+		groupWorkloads := make(map[string]uint64)
+		for _, captureID := range captureIDs {
+			groupWorkloads[candidates[captureID].Labels[a.spreadLabel]] += workloads[captureID]
+		}
+		minGroup, found := "", false
+		for _, captureID := range captureIDs {
+			group := candidates[captureID].Labels[a.spreadLabel]
+			if !found || groupWorkloads[group] < groupWorkloads[minGroup] {
+				minGroup, found = group, true
+			}
+		}
+		inGroup := captureIDs[:0]
+		for _, captureID := range captureIDs {
+			if candidates[captureID].Labels[a.spreadLabel] == minGroup {
+				inGroup = append(inGroup, captureID)
+			}
+		}
+		captureIDs = inGroup
+	}
+
+	var minCapture model.CaptureID
+	for _, captureID := range captureIDs {
+		if minCapture == "" || workloads[captureID] < workloads[minCapture] {
+			minCapture = captureID
+		}
+	}
+	return minCapture
+}
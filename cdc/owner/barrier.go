@@ -17,6 +17,7 @@ import (
 	"math"
 
 	"github.com/pingcap/log"
+	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/ticdc/cdc/model"
 )
 
@@ -31,6 +32,36 @@ const (
 	finishBarrier
 )
 
+// tableDDLTypes are the types of the DDLs which only change the definition of one table without changing
+// the physical table IDs, such a DDL blocks the table rather than the whole changefeed until it's executed.
+var tableDDLTypes = map[timodel.ActionType]struct{}{
+	timodel.ActionAddColumn:                    {},
+	timodel.ActionAddColumns:                   {},
+	timodel.ActionDropColumn:                   {},
+	timodel.ActionDropColumns:                  {},
+	timodel.ActionModifyColumn:                 {},
+	timodel.ActionSetDefaultValue:              {},
+	timodel.ActionAddIndex:                     {},
+	timodel.ActionDropIndex:                    {},
+	timodel.ActionDropIndexes:                  {},
+	timodel.ActionRenameIndex:                  {},
+	timodel.ActionAlterIndexVisibility:         {},
+	timodel.ActionAddPrimaryKey:                {},
+	timodel.ActionDropPrimaryKey:               {},
+	timodel.ActionRebaseAutoID:                 {},
+	timodel.ActionRebaseAutoRandomBase:         {},
+	timodel.ActionModifyTableAutoIdCache:       {},
+	timodel.ActionShardRowID:                   {},
+	timodel.ActionModifyTableComment:           {},
+	timodel.ActionModifyTableCharsetAndCollate: {},
+}
+
+// isTableDDL returns true if the DDL job only affects the table of the job
+func isTableDDL(job *timodel.Job) bool {
+	_, ok := tableDDLTypes[job.Type]
+	return ok && job.TableID != 0
+}
+
 // barriers stores some barrierType and barrierTs, and can calculate the min barrierTs
 // barriers is NOT-THREAD-SAFE
 type barriers struct {
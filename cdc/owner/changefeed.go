@@ -15,6 +15,7 @@ package owner
 
 import (
 	"context"
+	"reflect"
 	"sync"
 	"time"
 
@@ -23,8 +24,10 @@ import (
 	"github.com/pingcap/log"
 	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
 	cdcContext "github.com/pingcap/ticdc/pkg/context"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/regionspan"
 	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/tidb/sessionctx/binloginfo"
 	"github.com/prometheus/client_golang/prometheus"
@@ -37,6 +40,7 @@ type changefeed struct {
 	state *model.ChangefeedReactorState
 
 	scheduler        *scheduler
+	splitter         *tableSplitter
 	barriers         *barriers
 	feedStateManager *feedStateManager
 	gcManager        *gcManager
@@ -45,6 +49,10 @@ type changefeed struct {
 	sink        AsyncSink
 	ddlPuller   DDLPuller
 	initialized bool
+	// currentTables are the tables and the spans which should be replicated
+	currentTables []model.TableID
+	// tableBarrierEnabled is true if the DDLs only affecting one table block the table rather than the changefeed
+	tableBarrierEnabled bool
 
 	// only used for asyncExecDDL function
 	// ddlEventCache is not nil when the changefeed is executing a DDL event asynchronously
@@ -151,7 +159,11 @@ func (c *changefeed) tick(ctx cdcContext.Context, state *model.ChangefeedReactor
 	if err != nil {
 		return errors.Trace(err)
 	}
-	shouldUpdateState, err := c.scheduler.Tick(c.state, c.schema.AllPhysicalTables(), captures)
+	c.currentTables, err = c.splitter.Expand(ctx, c.state, c.schema.AllPhysicalTables())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	shouldUpdateState, err := c.scheduler.Tick(c.state, c.currentTables, captures)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -204,6 +216,22 @@ LOOP:
 	if err != nil {
 		return errors.Trace(err)
 	}
+	c.scheduler.affinity, err = newAffinity(c.state.Info.Config.Scheduler, c.state.Info.Config.CaseSensitive, c.schema.TableName)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	pdClient := ctx.GlobalVars().PDClient
+	c.splitter, err = newTableSplitter(c.state.Info.Config, c.state.Info.SinkURI, c.schema.TableName,
+		func(ctx context.Context, span regionspan.Span) ([][]byte, error) {
+			return scanRegionStartKeys(ctx, pdClient, span)
+		})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.scheduler.splitter = c.splitter
+	// the DDL events are sent to all the partitions of a message queue in order with the resolved events,
+	// so the DDLs block the whole changefeed if the sink is a message queue
+	c.tableBarrierEnabled = !sink.IsMQSink(c.state.Info.SinkURI) && !c.state.Info.Config.Cyclic.IsEnabled()
 	cancelCtx, cancel := cdcContext.WithCancel(ctx)
 	c.cancel = cancel
 	c.sink, err = c.newSink(cancelCtx)
@@ -241,12 +269,14 @@ func (c *changefeed) releaseResources() {
 	c.cancel = func() {}
 	c.ddlPuller.Close()
 	c.schema = nil
+	c.currentTables = nil
 	if err := c.sink.Close(); err != nil {
 		log.Warn("Closing sink failed in Owner", zap.String("changefeedID", c.state.ID), zap.Error(err))
 	}
 	c.wg.Wait()
 	changefeedCheckpointTsGauge.DeleteLabelValues(c.id)
 	changefeedCheckpointTsLagGauge.DeleteLabelValues(c.id)
+	changefeedWorkloadSkewnessGauge.DeleteLabelValues(c.id)
 	c.metricsChangefeedCheckpointTsGauge = nil
 	c.metricsChangefeedCheckpointTsLagGauge = nil
 	c.initialized = false
@@ -315,11 +345,18 @@ func (c *changefeed) preflightCheck(captures map[model.CaptureID]*model.CaptureI
 }
 
 func (c *changefeed) handleBarrier(ctx cdcContext.Context) (uint64, error) {
+	if err := c.handleTableBarriers(ctx); err != nil {
+		return 0, errors.Trace(err)
+	}
 	barrierTp, barrierTs := c.barriers.Min()
 	blocked := (barrierTs == c.state.Status.CheckpointTs) && (barrierTs == c.state.Status.ResolvedTs)
 	switch barrierTp {
 	case ddlJobBarrier:
 		ddlResolvedTs, ddlJob := c.ddlPuller.FrontDDL()
+		if ddlJob != nil && c.tableBarrierEnabled && isTableDDL(ddlJob) {
+			// the DDL is waiting for its table to reach the table barrier
+			return barrierTs, nil
+		}
 		if ddlJob == nil || ddlResolvedTs != barrierTs {
 			c.barriers.Update(ddlJobBarrier, ddlResolvedTs)
 			return barrierTs, nil
@@ -359,6 +396,111 @@ func (c *changefeed) handleBarrier(ctx cdcContext.Context) (uint64, error) {
 	return barrierTs, nil
 }
 
+// handleTableBarriers executes the DDLs at the front of the DDL queue which only affect one table, once the table
+// reaches the commit ts of the DDL. Then it sets a table barrier for every table affected by the pending DDLs before
+// the first DDL which affects more than one table, and sets the ddlJobBarrier to the commit ts of the first such DDL,
+// so that only the tables with pending DDLs are blocked.
+func (c *changefeed) handleTableBarriers(ctx cdcContext.Context) error {
+	if !c.tableBarrierEnabled {
+		return nil
+	}
+	for {
+		ddlResolvedTs, ddlJob := c.ddlPuller.FrontDDL()
+		if ddlJob == nil || !isTableDDL(ddlJob) || !c.tableReachedBarrier(ddlJob.TableID, ddlResolvedTs) {
+			break
+		}
+		done, err := c.asyncExecDDL(ctx, ddlJob)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !done {
+			break
+		}
+		c.ddlPuller.PopFrontDDL()
+	}
+
+	ddlBarrierTs, ddlJobs := c.ddlPuller.PendingDDLs()
+	tableBarriers := make(map[model.TableID]model.Ts)
+	for _, job := range ddlJobs {
+		if !isTableDDL(job) {
+			ddlBarrierTs = job.BinlogInfo.FinishedTS
+			break
+		}
+		// the DDLs are pulled in order, so no DDL is missed before the commit ts of the pulled DDLs
+		if job.BinlogInfo.FinishedTS > ddlBarrierTs {
+			ddlBarrierTs = job.BinlogInfo.FinishedTS
+		}
+		for _, tableID := range c.schema.PhysicalTableIDs(job.TableID) {
+			if _, exist := tableBarriers[tableID]; !exist {
+				tableBarriers[tableID] = job.BinlogInfo.FinishedTS
+			}
+		}
+	}
+	if len(tableBarriers) != 0 {
+		c.barriers.Update(ddlJobBarrier, ddlBarrierTs)
+	}
+	if !reflect.DeepEqual(tableBarriers, c.state.Status.TableBarriers) &&
+		(len(tableBarriers) != 0 || len(c.state.Status.TableBarriers) != 0) {
+		c.state.PatchStatus(func(status *model.ChangeFeedStatus) (*model.ChangeFeedStatus, bool, error) {
+			if status == nil {
+				return nil, false, nil
+			}
+			if len(tableBarriers) == 0 {
+				status.TableBarriers = nil
+			} else {
+				status.TableBarriers = tableBarriers
+			}
+			return status, true, nil
+		})
+	}
+	return nil
+}
+
+// tableReachedBarrier returns true if all the pipelines of the table have replicated the events before the barrierTs
+func (c *changefeed) tableReachedBarrier(tableID model.TableID, barrierTs model.Ts) bool {
+	if c.currentTables == nil {
+		// the tables haven't been dispatched to the captures
+		return false
+	}
+	physicalTables := make(map[model.TableID]struct{})
+	for _, tableID := range c.schema.PhysicalTableIDs(tableID) {
+		physicalTables[tableID] = struct{}{}
+	}
+	affected := func(id model.TableID) bool {
+		physicalTableID, _, _ := model.DecodeSpanTableID(id)
+		_, exist := physicalTables[physicalTableID]
+		return exist
+	}
+	reached := make(map[model.TableID]struct{})
+	for captureID, status := range c.state.TaskStatuses {
+		for id, operation := range status.Operation {
+			if affected(id) && operation.Status != model.OperFinished {
+				return false
+			}
+		}
+		position := c.state.TaskPositions[captureID]
+		for id := range status.Tables {
+			if !affected(id) {
+				continue
+			}
+			if position == nil {
+				return false
+			}
+			if checkpointTs, exist := position.BlockedTables[id]; !exist || checkpointTs < barrierTs {
+				return false
+			}
+			reached[id] = struct{}{}
+		}
+	}
+	for _, id := range c.currentTables {
+		if _, exist := reached[id]; affected(id) && !exist {
+			// the table is being dispatched
+			return false
+		}
+	}
+	return true
+}
+
 func (c *changefeed) asyncExecDDL(ctx cdcContext.Context, job *timodel.Job) (done bool, err error) {
 	if job.BinlogInfo == nil {
 		log.Warn("ignore the invalid DDL job", zap.Reflect("job", job))
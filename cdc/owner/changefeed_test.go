@@ -51,6 +51,10 @@ func (m *mockDDLPuller) PopFrontDDL() (uint64, *timodel.Job) {
 	return m.resolvedTs, nil
 }
 
+func (m *mockDDLPuller) PendingDDLs() (uint64, []*timodel.Job) {
+	return m.resolvedTs, m.ddlQueue
+}
+
 func (m *mockDDLPuller) Close() {}
 
 func (m *mockDDLPuller) Run(ctx cdcContext.Context) error {
@@ -247,6 +251,85 @@ func (s *changefeedSuite) TestExecDDL(c *check.C) {
 	c.Assert(state.TaskStatuses[ctx.GlobalVars().CaptureInfo.ID].Tables, check.HasKey, job.TableID)
 }
 
+func (s *changefeedSuite) TestExecTableDDL(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := cdcContext.NewBackendContext4Test(true)
+	cf, state, captures, tester := createChangefeed4Test(ctx, c)
+	defer cf.Close()
+	helper := entry.NewSchemaTestHelper(c)
+	defer helper.Close()
+	tickThreeTime := func() {
+		cf.Tick(ctx, state, captures)
+		tester.MustApplyPatches()
+		cf.Tick(ctx, state, captures)
+		tester.MustApplyPatches()
+		cf.Tick(ctx, state, captures)
+		tester.MustApplyPatches()
+	}
+	// pre check and initialize
+	tickThreeTime()
+	c.Assert(cf.tableBarrierEnabled, check.IsTrue)
+
+	mockDDLPuller := cf.ddlPuller.(*mockDDLPuller)
+	mockAsyncSink := cf.sink.(*mockAsyncSink)
+	var tableID model.TableID
+	for _, query := range []string{"create database test1", "create table test1.test1(id int primary key)"} {
+		job := helper.DDL2Job(query)
+		tableID = job.TableID
+		mockDDLPuller.resolvedTs += 1000
+		job.BinlogInfo.FinishedTS = mockDDLPuller.resolvedTs
+		mockDDLPuller.ddlQueue = append(mockDDLPuller.ddlQueue, job)
+		tickThreeTime()
+		mockAsyncSink.ddlDone = true
+		tickThreeTime()
+	}
+	captureID := ctx.GlobalVars().CaptureInfo.ID
+	c.Assert(state.TaskStatuses[captureID].Tables, check.HasKey, tableID)
+	updatePosition := func(position *model.TaskPosition) {
+		state.PatchTaskPosition(captureID, func(*model.TaskPosition) (*model.TaskPosition, bool, error) {
+			return position, true, nil
+		})
+		tester.MustApplyPatches()
+	}
+	// the processor finishes adding the table
+	state.PatchTaskStatus(captureID, func(status *model.TaskStatus) (*model.TaskStatus, bool, error) {
+		status.Operation[tableID].Status = model.OperFinished
+		return status, true, nil
+	})
+	updatePosition(&model.TaskPosition{CheckPointTs: mockDDLPuller.resolvedTs, ResolvedTs: mockDDLPuller.resolvedTs})
+
+	// the alter table DDL only blocks the altered table
+	job := helper.DDL2Job("alter table test1.test1 add column a int")
+	mockDDLPuller.resolvedTs += 1000
+	ddlTs := mockDDLPuller.resolvedTs
+	job.BinlogInfo.FinishedTS = ddlTs
+	mockDDLPuller.ddlQueue = append(mockDDLPuller.ddlQueue, job)
+	mockDDLPuller.resolvedTs += 1000
+	tickThreeTime()
+	c.Assert(state.Status.TableBarriers, check.DeepEquals, map[model.TableID]model.Ts{tableID: ddlTs})
+	updatePosition(&model.TaskPosition{
+		CheckPointTs:  ddlTs - 1,
+		ResolvedTs:    mockDDLPuller.resolvedTs,
+		BlockedTables: map[model.TableID]model.Ts{tableID: ddlTs - 1},
+	})
+	tickThreeTime()
+	c.Assert(state.Status.ResolvedTs, check.Equals, mockDDLPuller.resolvedTs)
+	c.Assert(state.Status.CheckpointTs, check.Equals, ddlTs-1)
+	c.Assert(mockAsyncSink.ddlExecuting.Query, check.Equals, "create table test1.test1(id int primary key)")
+
+	// the DDL is executed once the table reaches the table barrier
+	mockAsyncSink.ddlDone = true
+	updatePosition(&model.TaskPosition{
+		CheckPointTs:  ddlTs,
+		ResolvedTs:    mockDDLPuller.resolvedTs,
+		BlockedTables: map[model.TableID]model.Ts{tableID: ddlTs},
+	})
+	tickThreeTime()
+	c.Assert(mockAsyncSink.ddlExecuting.Query, check.Equals, "alter table test1.test1 add column a int")
+	c.Assert(mockDDLPuller.ddlQueue, check.HasLen, 0)
+	c.Assert(state.Status.TableBarriers, check.IsNil)
+}
+
 func (s *changefeedSuite) TestSyncPoint(c *check.C) {
 	defer testleak.AfterTest(c)()
 	ctx := cdcContext.NewBackendContext4Test(true)
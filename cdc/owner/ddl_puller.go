@@ -41,6 +41,8 @@ type DDLPuller interface {
 	FrontDDL() (uint64, *timodel.Job)
 	// PopFrontDDL returns and pops the first DDL job in the internal queue
 	PopFrontDDL() (uint64, *timodel.Job)
+	// PendingDDLs returns the resolved ts and all the DDL jobs in the internal queue
+	PendingDDLs() (uint64, []*timodel.Job)
 	// Close closes the DDLPuller
 	Close()
 }
@@ -161,6 +163,14 @@ func (h *ddlPullerImpl) PopFrontDDL() (uint64, *timodel.Job) {
 	return job.BinlogInfo.FinishedTS, job
 }
 
+func (h *ddlPullerImpl) PendingDDLs() (uint64, []*timodel.Job) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	jobs := make([]*timodel.Job, len(h.pendingDDLJobs))
+	copy(jobs, h.pendingDDLJobs)
+	return h.resolvedTS, jobs
+}
+
 func (h *ddlPullerImpl) Close() {
 	h.cancel()
 }
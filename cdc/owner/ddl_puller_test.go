@@ -162,6 +162,9 @@ func (s *ddlPullerSuite) TestPuller(c *check.C) {
 	resolvedTs, ddl = p.PopFrontDDL()
 	c.Assert(resolvedTs, check.Equals, uint64(16))
 	c.Assert(ddl.ID, check.Equals, int64(1))
+	_, ddls := p.PendingDDLs()
+	c.Assert(ddls, check.HasLen, 1)
+	c.Assert(ddls[0].ID, check.Equals, int64(2))
 	resolvedTs, ddl = p.PopFrontDDL()
 	c.Assert(resolvedTs, check.Equals, uint64(18))
 	c.Assert(ddl.ID, check.Equals, int64(2))
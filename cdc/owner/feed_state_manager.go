@@ -20,6 +20,7 @@ import (
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/model"
 	cerrors "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/tikv/client-go/v2/oracle"
 	"go.uber.org/zap"
 )
 
@@ -30,11 +31,16 @@ type feedStateManager struct {
 	shouldBeRunning bool
 
 	adminJobQueue []*model.AdminJob
+	// notifications are generated in the current tick, they are persisted in the changefeed info after the tick
+	notifications []*model.ChangefeedNotification
+	// removed is true if the changefeed is removed in the current tick, its notifications can't be persisted
+	removed bool
 }
 
 func (m *feedStateManager) Tick(state *model.ChangefeedReactorState) {
 	m.state = state
 	m.shouldBeRunning = true
+	m.removed = false
 	defer func() {
 		if m.shouldBeRunning {
 			m.patchState(model.StateNormal)
@@ -54,6 +60,7 @@ func (m *feedStateManager) Tick(state *model.ChangefeedReactorState) {
 	}
 	errs := m.errorsReportedByProcessors()
 	m.HandleError(errs...)
+	m.checkCheckpointLag()
 }
 
 func (m *feedStateManager) ShouldRunning() bool {
@@ -111,6 +118,7 @@ func (m *feedStateManager) handleAdminJob() (jobsPending bool) {
 		}
 		m.shouldBeRunning = false
 		jobsPending = true
+		m.removed = true
 		m.patchState(model.StateRemoved)
 		// remove changefeed info and state
 		m.state.PatchInfo(func(info *model.ChangeFeedInfo) (*model.ChangeFeedInfo, bool, error) {
@@ -173,6 +181,13 @@ func (m *feedStateManager) pushAdminJob(job *model.AdminJob) {
 }
 
 func (m *feedStateManager) patchState(feedState model.FeedState) {
+	// the state of a new created changefeed may be empty, it is not a state change
+	if oldState := m.state.Info.State; oldState != "" && oldState != feedState {
+		notification := m.newNotification(model.NotificationStateChanged)
+		notification.OldState = oldState
+		notification.NewState = feedState
+		m.notifications = append(m.notifications, notification)
+	}
 	var adminJobType model.AdminJobType
 	switch feedState {
 	case model.StateNormal:
@@ -257,6 +272,12 @@ func (m *feedStateManager) errorsReportedByProcessors() []*model.RunningError {
 }
 
 func (m *feedStateManager) HandleError(errs ...*model.RunningError) {
+	for _, err := range errs {
+		notification := m.newNotification(model.NotificationError)
+		notification.ErrorCode = err.Code
+		notification.ErrorMessage = err.Message
+		m.notifications = append(m.notifications, notification)
+	}
 	m.state.PatchInfo(func(info *model.ChangeFeedInfo) (*model.ChangeFeedInfo, bool, error) {
 		for _, err := range errs {
 			info.Error = err
@@ -283,3 +304,70 @@ func (m *feedStateManager) HandleError(errs ...*model.RunningError) {
 		return
 	}
 }
+
+// checkCheckpointLag generates a notification when the checkpoint lag exceeds the threshold,
+// and another one when the checkpoint lag falls back below the threshold.
+func (m *feedStateManager) checkCheckpointLag() {
+	threshold := checkpointLagThreshold(m.state.Info)
+	notified := m.state.Info.CheckpointLagNotified
+	if threshold <= 0 && !notified {
+		return
+	}
+	checkpointTs := m.state.Info.GetCheckpointTs(m.state.Status)
+	lag := time.Since(oracle.GetTimeFromTS(checkpointTs))
+	exceeded := threshold > 0 && lag > threshold
+	if exceeded == notified {
+		return
+	}
+	tp := model.NotificationCheckpointLagRecovered
+	if exceeded {
+		tp = model.NotificationCheckpointLag
+		log.Warn("the checkpoint lag exceeds the threshold", zap.String("changefeedID", m.state.ID),
+			zap.Duration("lag", lag), zap.Duration("threshold", threshold))
+	}
+	notification := m.newNotification(tp)
+	notification.CheckpointLag = lag.Milliseconds()
+	m.notifications = append(m.notifications, notification)
+	m.state.PatchInfo(func(info *model.ChangeFeedInfo) (*model.ChangeFeedInfo, bool, error) {
+		if info == nil {
+			return nil, false, nil
+		}
+		info.CheckpointLagNotified = exceeded
+		return info, true, nil
+	})
+}
+
+func (m *feedStateManager) newNotification(tp model.NotificationType) *model.ChangefeedNotification {
+	checkpointTs := m.state.Info.GetCheckpointTs(m.state.Status)
+	return model.NewChangefeedNotification(tp, m.state.ID, checkpointTs)
+}
+
+// persistNotifications appends the notifications generated since the last call to the pending notifications
+// in the changefeed info, so they are persisted together with the state changes generating them.
+// The notifications of a removed changefeed can't be persisted, they are returned to be sent by the owner.
+func (m *feedStateManager) persistNotifications() []*model.ChangefeedNotification {
+	notifications := m.notifications
+	m.notifications = nil
+	if len(notifications) == 0 || m.removed {
+		return notifications
+	}
+	if len(notificationWebhooks(m.state.Info)) == 0 {
+		return nil
+	}
+	changefeedID := m.state.ID
+	m.state.PatchInfo(func(info *model.ChangeFeedInfo) (*model.ChangeFeedInfo, bool, error) {
+		if info == nil {
+			return nil, false, nil
+		}
+		info.PendingNotifications = append(info.PendingNotifications, notifications...)
+		// the webhooks may be unavailable for a long time, the oldest notifications are dropped
+		// to keep the changefeed info small
+		if overflow := len(info.PendingNotifications) - maxPendingNotifications; overflow > 0 {
+			log.Warn("too many notifications are pending, drop the oldest ones",
+				zap.String("changefeedID", changefeedID), zap.Int("count", overflow))
+			info.PendingNotifications = info.PendingNotifications[overflow:]
+		}
+		return info, true, nil
+	})
+	return nil
+}
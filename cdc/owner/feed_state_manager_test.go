@@ -14,12 +14,16 @@
 package owner
 
 import (
+	"time"
+
 	"github.com/pingcap/check"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/config"
 	cdcContext "github.com/pingcap/ticdc/pkg/context"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/orchestrator"
 	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"github.com/tikv/client-go/v2/oracle"
 )
 
 var _ = check.Suite(&feedStateManagerSuite{})
@@ -248,3 +252,96 @@ func (s *feedStateManagerSuite) TestChangefeedStatusNotExist(c *check.C) {
 	c.Assert(state.Info, check.IsNil)
 	c.Assert(state.Exist(), check.IsFalse)
 }
+
+func (s *feedStateManagerSuite) TestNotifications(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := cdcContext.NewBackendContext4Test(true)
+	manager := new(feedStateManager)
+	state := model.NewChangefeedReactorState(ctx.ChangefeedVars().ID)
+	tester := orchestrator.NewReactorStateTester(c, state, nil)
+	checkpointTs := oracle.GoTimeToTS(time.Now().Add(-time.Hour))
+	state.PatchInfo(func(info *model.ChangeFeedInfo) (*model.ChangeFeedInfo, bool, error) {
+		c.Assert(info, check.IsNil)
+		return &model.ChangeFeedInfo{
+			SinkURI: "123",
+			State:   model.StateNormal,
+			Config: &config.ReplicaConfig{Notification: &config.NotificationConfig{
+				WebhookURLs:            []string{"http://127.0.0.1:8080"},
+				CheckpointLagThreshold: config.TomlDuration(time.Minute),
+			}},
+		}, true, nil
+	})
+	state.PatchStatus(func(status *model.ChangeFeedStatus) (*model.ChangeFeedStatus, bool, error) {
+		c.Assert(status, check.IsNil)
+		return &model.ChangeFeedStatus{CheckpointTs: checkpointTs}, true, nil
+	})
+	tester.MustApplyPatches()
+	// takeNotifications returns the notifications persisted in the changefeed info and clears them
+	takeNotifications := func() []*model.ChangefeedNotification {
+		c.Assert(manager.persistNotifications(), check.HasLen, 0)
+		tester.MustApplyPatches()
+		notifications := state.Info.PendingNotifications
+		state.PatchInfo(func(info *model.ChangeFeedInfo) (*model.ChangeFeedInfo, bool, error) {
+			info.PendingNotifications = nil
+			return info, true, nil
+		})
+		tester.MustApplyPatches()
+		return notifications
+	}
+
+	// the checkpoint lag exceeds the threshold
+	manager.Tick(state)
+	tester.MustApplyPatches()
+	notifications := takeNotifications()
+	c.Assert(notifications, check.HasLen, 1)
+	c.Assert(notifications[0].Type, check.Equals, model.NotificationCheckpointLag)
+	c.Assert(notifications[0].ChangefeedID, check.Equals, ctx.ChangefeedVars().ID)
+	c.Assert(notifications[0].CheckpointTs, check.Equals, checkpointTs)
+	c.Assert(notifications[0].CheckpointLag >= time.Hour.Milliseconds(), check.IsTrue)
+	c.Assert(state.Info.CheckpointLagNotified, check.IsTrue)
+
+	// the lag notification is sent only once
+	manager.Tick(state)
+	tester.MustApplyPatches()
+	c.Assert(takeNotifications(), check.HasLen, 0)
+
+	// the checkpoint catches up
+	state.PatchStatus(func(status *model.ChangeFeedStatus) (*model.ChangeFeedStatus, bool, error) {
+		status.CheckpointTs = oracle.GoTimeToTS(time.Now())
+		return status, true, nil
+	})
+	tester.MustApplyPatches()
+	manager.Tick(state)
+	tester.MustApplyPatches()
+	notifications = takeNotifications()
+	c.Assert(notifications, check.HasLen, 1)
+	c.Assert(notifications[0].Type, check.Equals, model.NotificationCheckpointLagRecovered)
+	c.Assert(state.Info.CheckpointLagNotified, check.IsFalse)
+
+	// a fast fail error fails the changefeed
+	manager.HandleError(&model.RunningError{
+		Addr:    ctx.GlobalVars().CaptureInfo.AdvertiseAddr,
+		Code:    string(cerror.ErrSnapshotLostByGC.RFCCode()),
+		Message: "fake error for test",
+	})
+	tester.MustApplyPatches()
+	c.Assert(state.Info.State, check.Equals, model.StateFailed)
+	notifications = takeNotifications()
+	c.Assert(notifications, check.HasLen, 2)
+	c.Assert(notifications[0].Type, check.Equals, model.NotificationError)
+	c.Assert(notifications[0].ErrorCode, check.Equals, "CDC:ErrSnapshotLostByGC")
+	c.Assert(notifications[1].Type, check.Equals, model.NotificationStateChanged)
+	c.Assert(notifications[1].OldState, check.Equals, model.StateNormal)
+	c.Assert(notifications[1].NewState, check.Equals, model.StateFailed)
+	c.Assert(notifications[0].ID, check.Not(check.Equals), notifications[1].ID)
+
+	// the notifications of the removed changefeed can't be persisted
+	manager.PushAdminJob(&model.AdminJob{CfID: ctx.ChangefeedVars().ID, Type: model.AdminRemove})
+	manager.Tick(state)
+	notifications = manager.persistNotifications()
+	tester.MustApplyPatches()
+	c.Assert(state.Info, check.IsNil)
+	c.Assert(notifications, check.HasLen, 1)
+	c.Assert(notifications[0].OldState, check.Equals, model.StateFailed)
+	c.Assert(notifications[0].NewState, check.Equals, model.StateRemoved)
+}
@@ -44,6 +44,13 @@ var (
 			Name:      "maintain_table_num",
 			Help:      "number of replicated tables maintained in owner",
 		}, []string{"changefeed", "capture", "type"})
+	changefeedWorkloadSkewnessGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "owner",
+			Name:      "workload_skewness",
+			Help:      "skewness of the workloads of captures in changefeeds, which is calculated when rebalancing",
+		}, []string{"changefeed"})
 )
 
 const (
@@ -59,4 +66,5 @@ func InitMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(changefeedCheckpointTsLagGauge)
 	registry.MustRegister(ownershipCounter)
 	registry.MustRegister(ownerMaintainTableNumGauge)
+	registry.MustRegister(changefeedWorkloadSkewnessGauge)
 }
@@ -0,0 +1,294 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package owner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/retry"
+	"go.uber.org/zap"
+)
+
+const (
+	notificationChSize     = 1024
+	webhookRequestTimeout  = 10 * time.Second
+	webhookMaxTries        = 8
+	webhookBackoffBaseInMs = 500
+	webhookBackoffMaxInMs  = 30 * 1000
+	// maxPendingNotifications is the max number of the notifications persisted in a changefeed info
+	maxPendingNotifications = 64
+)
+
+// notificationTask is a notification and the webhooks it should be sent to
+type notificationTask struct {
+	webhookURLs  []string
+	notification *model.ChangefeedNotification
+	// persisted is true if the notification is pending in the changefeed info
+	persisted bool
+}
+
+// notifier sends the notifications of changefeeds to the webhooks in background.
+//
+// A notification is generated in a tick and persisted in the changefeed info together with the patches
+// which change the changefeed state. The pending notifications in the changefeed info are sent after the
+// patches have been applied to etcd, and removed from the changefeed info after they are delivered.
+// So the delivery is resumed by the new owner if the owner changes, and a notification may be sent
+// more than once, the receivers can de-duplicate the notifications by the ID.
+type notifier struct {
+	// pending are the notifications of the removed changefeeds, which can't be persisted
+	pending []*notificationTask
+
+	client  *http.Client
+	taskCh  chan *notificationTask
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu sync.Mutex
+	// persisted tracks the queued notifications pending in the changefeed infos,
+	// the value is true if the notification has been delivered
+	persisted map[model.ChangeFeedID]map[string]bool
+}
+
+func newNotifier() *notifier {
+	return &notifier{
+		client:    &http.Client{Timeout: webhookRequestTimeout},
+		taskCh:    make(chan *notificationTask, notificationChSize),
+		cancel:    func() {},
+		persisted: make(map[model.ChangeFeedID]map[string]bool),
+	}
+}
+
+// notificationWebhooks returns the webhooks of the changefeed and the webhooks configured for the server
+func notificationWebhooks(info *model.ChangeFeedInfo) []string {
+	var webhookURLs []string
+	if serverConf := config.GetGlobalServerConfig().Notification; serverConf != nil {
+		webhookURLs = append(webhookURLs, serverConf.WebhookURLs...)
+	}
+	if info != nil && info.Config != nil && info.Config.Notification != nil {
+		webhookURLs = append(webhookURLs, info.Config.Notification.WebhookURLs...)
+	}
+	return webhookURLs
+}
+
+// checkpointLagThreshold returns the checkpoint lag threshold of the changefeed,
+// the threshold of the changefeed overrides the threshold configured for the server.
+func checkpointLagThreshold(info *model.ChangeFeedInfo) time.Duration {
+	if info != nil && info.Config != nil && info.Config.Notification != nil &&
+		info.Config.Notification.CheckpointLagThreshold > 0 {
+		return time.Duration(info.Config.Notification.CheckpointLagThreshold)
+	}
+	if serverConf := config.GetGlobalServerConfig().Notification; serverConf != nil {
+		return time.Duration(serverConf.CheckpointLagThreshold)
+	}
+	return 0
+}
+
+// Add buffers the notifications of the removed changefeed until the next Flush
+func (n *notifier) Add(info *model.ChangeFeedInfo, notifications []*model.ChangefeedNotification) {
+	if len(notifications) == 0 {
+		return
+	}
+	webhookURLs := notificationWebhooks(info)
+	if len(webhookURLs) == 0 {
+		return
+	}
+	for _, notification := range notifications {
+		n.pending = append(n.pending, &notificationTask{
+			webhookURLs:  webhookURLs,
+			notification: notification,
+		})
+	}
+}
+
+// Flush sends the buffered notifications asynchronously
+func (n *notifier) Flush(ctx context.Context) {
+	if len(n.pending) == 0 {
+		return
+	}
+	n.start(ctx)
+	for _, task := range n.pending {
+		if !n.enqueue(task) {
+			log.Warn("too many notifications are pending, drop the notification",
+				zap.Reflect("notification", task.notification))
+		}
+	}
+	n.pending = nil
+}
+
+// Deliver sends the pending notifications in the changefeed info asynchronously, the notifications
+// already queued are skipped. The IDs of the delivered notifications are returned, they should be
+// removed from the changefeed info.
+func (n *notifier) Deliver(ctx context.Context, changefeedID model.ChangeFeedID, info *model.ChangeFeedInfo) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(info.PendingNotifications) == 0 {
+		delete(n.persisted, changefeedID)
+		return nil
+	}
+	n.start(ctx)
+	webhookURLs := notificationWebhooks(info)
+	queued := n.persisted[changefeedID]
+	// the notifications removed from the changefeed info are no longer tracked
+	persisted := make(map[string]bool, len(info.PendingNotifications))
+	var delivered []string
+	for _, notification := range info.PendingNotifications {
+		done, ok := queued[notification.ID]
+		if !ok {
+			// the webhooks are removed from the config after the notification is generated
+			if len(webhookURLs) == 0 {
+				done = true
+			} else if !n.enqueue(&notificationTask{
+				webhookURLs:  webhookURLs,
+				notification: notification,
+				persisted:    true,
+			}) {
+				// retry in the next tick
+				continue
+			}
+		}
+		persisted[notification.ID] = done
+		if done {
+			delivered = append(delivered, notification.ID)
+		}
+	}
+	n.persisted[changefeedID] = persisted
+	return delivered
+}
+
+// removeChangefeed stops tracking the notifications of the changefeed
+func (n *notifier) removeChangefeed(changefeedID model.ChangeFeedID) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.persisted, changefeedID)
+}
+
+func (n *notifier) markDelivered(notification *model.ChangefeedNotification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if persisted, ok := n.persisted[notification.ChangefeedID]; ok {
+		if _, ok := persisted[notification.ID]; ok {
+			persisted[notification.ID] = true
+		}
+	}
+}
+
+func (n *notifier) start(ctx context.Context) {
+	if n.started {
+		return
+	}
+	n.started = true
+	ctx, cancel := context.WithCancel(ctx)
+	n.cancel = cancel
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.run(ctx)
+	}()
+}
+
+func (n *notifier) enqueue(task *notificationTask) bool {
+	select {
+	case n.taskCh <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops sending the notifications
+func (n *notifier) Close() {
+	n.cancel()
+	n.wg.Wait()
+}
+
+func (n *notifier) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case task := <-n.taskCh:
+			// the notification is not delivered if the owner exits, the next owner will send it again
+			if n.send(ctx, task) && task.persisted {
+				n.markDelivered(task.notification)
+			}
+		}
+	}
+}
+
+// send sends the notification to the webhooks, false is returned if it's interrupted by the context.
+// The notification is given up if a webhook still fails after the retries.
+func (n *notifier) send(ctx context.Context, task *notificationTask) bool {
+	data, err := json.Marshal(task.notification)
+	if err != nil {
+		log.Warn("failed to marshal the notification", zap.Reflect("notification", task.notification), zap.Error(err))
+		return true
+	}
+	for _, webhookURL := range task.webhookURLs {
+		retryable := true
+		err := retry.Do(ctx, func() error {
+			statusCode, err := n.post(ctx, webhookURL, data)
+			if err != nil {
+				// the client errors except for 429 Too Many Requests can not be recovered by retrying
+				retryable = statusCode < 400 || statusCode >= 500 || statusCode == http.StatusTooManyRequests
+			}
+			return err
+		}, retry.WithBackoffBaseDelay(webhookBackoffBaseInMs),
+			retry.WithBackoffMaxDelay(webhookBackoffMaxInMs),
+			retry.WithMaxTries(webhookMaxTries),
+			retry.WithIsRetryableErr(func(error) bool { return retryable }))
+		if ctx.Err() != nil {
+			return false
+		}
+		if err != nil {
+			log.Warn("failed to send the notification", zap.String("webhook", webhookURL),
+				zap.Reflect("notification", task.notification), zap.Error(err))
+			continue
+		}
+		log.Info("notification sent", zap.String("webhook", webhookURL),
+			zap.Reflect("notification", task.notification))
+	}
+	return true
+}
+
+func (n *notifier) post(ctx context.Context, webhookURL string, data []byte) (statusCode int, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(data))
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	// drain the body to reuse the connection
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, cerror.ErrWebhookRequestFailed.GenWithStackByArgs(webhookURL, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
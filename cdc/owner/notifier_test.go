@@ -0,0 +1,157 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package owner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+var _ = check.Suite(&notifierSuite{})
+
+type notifierSuite struct{}
+
+func (s *notifierSuite) TestNotify(c *check.C) {
+	defer testleak.AfterTest(c)()
+	var requestCount, rejectCount int32
+	received := make(chan *model.ChangefeedNotification, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// the first request fails and should be retried
+		if atomic.AddInt32(&requestCount, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		notification := new(model.ChangefeedNotification)
+		c.Assert(json.NewDecoder(req.Body).Decode(notification), check.IsNil)
+		received <- notification
+	}))
+	defer server.Close()
+	rejectServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&rejectCount, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejectServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := newNotifier()
+	defer n.Close()
+	info := &model.ChangeFeedInfo{Config: &config.ReplicaConfig{
+		Notification: &config.NotificationConfig{WebhookURLs: []string{rejectServer.URL, server.URL}},
+	}}
+	notification := model.NewChangefeedNotification(model.NotificationStateChanged, "test-changefeed", 10)
+	notification.OldState = model.StateNormal
+	notification.NewState = model.StateError
+	n.Add(info, []*model.ChangefeedNotification{notification})
+	// no webhook is configured
+	n.Add(&model.ChangeFeedInfo{Config: config.GetDefaultReplicaConfig()}, []*model.ChangefeedNotification{notification})
+	c.Assert(n.pending, check.HasLen, 1)
+	n.Flush(ctx)
+	c.Assert(n.pending, check.HasLen, 0)
+
+	select {
+	case r := <-received:
+		c.Assert(r.ID, check.Equals, notification.ID)
+		c.Assert(r.Type, check.Equals, model.NotificationStateChanged)
+		c.Assert(r.ChangefeedID, check.Equals, "test-changefeed")
+		c.Assert(r.OldState, check.Equals, model.StateNormal)
+		c.Assert(r.NewState, check.Equals, model.StateError)
+		c.Assert(r.CheckpointTs, check.Equals, uint64(10))
+	case <-time.After(10 * time.Second):
+		c.Fatal("notification is not received")
+	}
+	c.Assert(atomic.LoadInt32(&requestCount), check.Equals, int32(2))
+	// the client error is not retried
+	c.Assert(atomic.LoadInt32(&rejectCount), check.Equals, int32(1))
+}
+
+func (s *notifierSuite) TestDeliverPendingNotifications(c *check.C) {
+	defer testleak.AfterTest(c)()
+	received := make(chan *model.ChangefeedNotification, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		notification := new(model.ChangefeedNotification)
+		c.Assert(json.NewDecoder(req.Body).Decode(notification), check.IsNil)
+		received <- notification
+	}))
+	defer server.Close()
+	mustReceive := func(id string) {
+		select {
+		case r := <-received:
+			c.Assert(r.ID, check.Equals, id)
+		case <-time.After(10 * time.Second):
+			c.Fatal("notification is not received")
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notification := model.NewChangefeedNotification(model.NotificationError, "test-changefeed", 10)
+	info := &model.ChangeFeedInfo{
+		Config: &config.ReplicaConfig{
+			Notification: &config.NotificationConfig{WebhookURLs: []string{server.URL}},
+		},
+		PendingNotifications: []*model.ChangefeedNotification{notification},
+	}
+	n := newNotifier()
+	c.Assert(n.Deliver(ctx, "test-changefeed", info), check.HasLen, 0)
+	mustReceive(notification.ID)
+	var delivered []string
+	for i := 0; i < 100 && len(delivered) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		delivered = n.Deliver(ctx, "test-changefeed", info)
+	}
+	c.Assert(delivered, check.DeepEquals, []string{notification.ID})
+	n.Close()
+
+	// the new owner sends the notification again if it's not removed from the changefeed info
+	n = newNotifier()
+	defer n.Close()
+	c.Assert(n.Deliver(ctx, "test-changefeed", info), check.HasLen, 0)
+	mustReceive(notification.ID)
+	info.PendingNotifications = nil
+	c.Assert(n.Deliver(ctx, "test-changefeed", info), check.HasLen, 0)
+	c.Assert(n.persisted, check.HasLen, 0)
+	select {
+	case r := <-received:
+		c.Fatalf("unexpected notification %v", r)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func (s *notifierSuite) TestCheckpointLagThreshold(c *check.C) {
+	defer testleak.AfterTest(c)()
+	defer config.StoreGlobalServerConfig(config.GetDefaultServerConfig())
+	serverConfig := config.GetDefaultServerConfig()
+	serverConfig.Notification.WebhookURLs = []string{"http://127.0.0.1:8080"}
+	serverConfig.Notification.CheckpointLagThreshold = config.TomlDuration(time.Hour)
+	config.StoreGlobalServerConfig(serverConfig)
+
+	info := &model.ChangeFeedInfo{Config: config.GetDefaultReplicaConfig()}
+	c.Assert(checkpointLagThreshold(info), check.Equals, time.Hour)
+	c.Assert(notificationWebhooks(info), check.DeepEquals, []string{"http://127.0.0.1:8080"})
+
+	info.Config.Notification.CheckpointLagThreshold = config.TomlDuration(time.Minute)
+	info.Config.Notification.WebhookURLs = []string{"http://127.0.0.1:8081"}
+	c.Assert(checkpointLagThreshold(info), check.Equals, time.Minute)
+	c.Assert(notificationWebhooks(info), check.DeepEquals, []string{"http://127.0.0.1:8080", "http://127.0.0.1:8081"})
+}
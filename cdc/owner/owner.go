@@ -66,6 +66,7 @@ type Owner struct {
 	changefeeds map[model.ChangeFeedID]*changefeed
 
 	gcManager *gcManager
+	notifier  *notifier
 
 	ownerJobQueueMu sync.Mutex
 	ownerJobQueue   []*ownerJob
@@ -82,6 +83,7 @@ func NewOwner() *Owner {
 	return &Owner{
 		changefeeds:   make(map[model.ChangeFeedID]*changefeed),
 		gcManager:     newGCManager(),
+		notifier:      newNotifier(),
 		lastTickTime:  time.Now(),
 		newChangefeed: newChangefeed,
 	}
@@ -106,6 +108,9 @@ func (o *Owner) Tick(stdCtx context.Context, rawState orchestrator.ReactorState)
 	failpoint.Inject("sleep-in-owner-tick", nil)
 	ctx := stdCtx.(cdcContext.Context)
 	state := rawState.(*model.GlobalReactorState)
+	// Tick is called only if all the patches generated in the last tick have been applied,
+	// so it is safe to send the notifications generated in the last tick now.
+	o.notifier.Flush(ctx)
 	o.updateMetrics(state)
 	if !o.clusterVersionConsistent(state.Captures) {
 		// sleep one second to avoid printing too much log
@@ -119,9 +124,11 @@ func (o *Owner) Tick(stdCtx context.Context, rawState orchestrator.ReactorState)
 	o.handleJobs()
 	for changefeedID, changefeedState := range state.Changefeeds {
 		if changefeedState.Info == nil {
+			o.notifier.removeChangefeed(changefeedID)
 			o.cleanUpChangefeed(changefeedState)
 			continue
 		}
+		o.deliverNotifications(ctx, changefeedState)
 		ctx = cdcContext.WithChangefeedVars(ctx, &cdcContext.ChangefeedVars{
 			ID:   changefeedID,
 			Info: changefeedState.Info,
@@ -132,6 +139,7 @@ func (o *Owner) Tick(stdCtx context.Context, rawState orchestrator.ReactorState)
 			o.changefeeds[changefeedID] = cfReactor
 		}
 		cfReactor.Tick(ctx, changefeedState, state.Captures)
+		o.notifier.Add(changefeedState.Info, cfReactor.feedStateManager.persistNotifications())
 	}
 	if len(o.changefeeds) != len(state.Changefeeds) {
 		for changefeedID, cfReactor := range o.changefeeds {
@@ -140,17 +148,45 @@ func (o *Owner) Tick(stdCtx context.Context, rawState orchestrator.ReactorState)
 			}
 			cfReactor.Close()
 			delete(o.changefeeds, changefeedID)
+			o.notifier.removeChangefeed(changefeedID)
 		}
 	}
 	if atomic.LoadInt32(&o.closed) != 0 {
 		for _, cfReactor := range o.changefeeds {
 			cfReactor.Close()
 		}
+		o.notifier.Close()
 		return state, cerror.ErrReactorFinished.GenWithStackByArgs()
 	}
 	return state, nil
 }
 
+// deliverNotifications sends the pending notifications of the changefeed, and removes the delivered ones
+func (o *Owner) deliverNotifications(ctx context.Context, state *model.ChangefeedReactorState) {
+	delivered := o.notifier.Deliver(ctx, state.ID, state.Info)
+	if len(delivered) == 0 {
+		return
+	}
+	state.PatchInfo(func(info *model.ChangeFeedInfo) (*model.ChangeFeedInfo, bool, error) {
+		if info == nil {
+			return nil, false, nil
+		}
+		deliveredIDs := make(map[string]struct{}, len(delivered))
+		for _, id := range delivered {
+			deliveredIDs[id] = struct{}{}
+		}
+		var pending []*model.ChangefeedNotification
+		for _, notification := range info.PendingNotifications {
+			if _, ok := deliveredIDs[notification.ID]; !ok {
+				pending = append(pending, notification)
+			}
+		}
+		changed := len(pending) != len(info.PendingNotifications)
+		info.PendingNotifications = pending
+		return info, changed, nil
+	})
+}
+
 // EnqueueJob enqueues a admin job into a internal queue, and the Owner will handle the job in the next tick
 func (o *Owner) EnqueueJob(adminJob model.AdminJob) {
 	o.pushOwnerJob(&ownerJob{
@@ -15,15 +15,21 @@ package owner
 
 import (
 	"math"
+	"sort"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/model"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	pscheduler "github.com/pingcap/ticdc/pkg/scheduler"
 	"go.uber.org/zap"
 )
 
+// schedulerTypeWorkload is the scheduler type which rebalances tables by their measured workloads
+const schedulerTypeWorkload = "workload"
+
 type schedulerJobType string
 
 const (
@@ -38,6 +44,9 @@ type schedulerJob struct {
 	// if the operation is a add operation, boundaryTs is start ts
 	BoundaryTs    uint64
 	TargetCapture model.CaptureID
+	// Blocked is true if the table is split in another way and can't be added until
+	// the spans of the table in the previous way are removed, so that a row is never replicated by two spans
+	Blocked bool
 }
 
 type moveTableJob struct {
@@ -48,17 +57,31 @@ type moveTableJob struct {
 type scheduler struct {
 	state         *model.ChangefeedReactorState
 	currentTables []model.TableID
-	captures      map[model.CaptureID]*model.CaptureInfo
+	// captures are the alive captures which tables can be dispatched to
+	captures map[model.CaptureID]*model.CaptureInfo
+	// drainingCaptures are the alive captures whose tables are being moved away
+	drainingCaptures map[model.CaptureID]*model.CaptureInfo
 
 	moveTableTargets      map[model.TableID]model.CaptureID
 	moveTableJobQueue     []*moveTableJob
 	needRebalanceNextTick bool
 	lastTickCaptureCount  int
+	lastRebalanceTime     time.Time
+
+	// workloadScheduler calculates the tables to move if the changefeed is rebalanced by workloads
+	workloadScheduler pscheduler.Scheduler
+	// affinity restricts the captures which a table can be dispatched to, it's nil if no affinity is configured
+	affinity *affinity
+	// waitingTables are the tables waiting for a capture matching their required affinity rules
+	waitingTables map[model.TableID]struct{}
+	// splitter provides the key ranges of the spans if some tables are split, it's nil if no table is split
+	splitter *tableSplitter
 }
 
 func newScheduler() *scheduler {
 	return &scheduler{
 		moveTableTargets: make(map[model.TableID]model.CaptureID),
+		waitingTables:    make(map[model.TableID]struct{}),
 	}
 }
 
@@ -68,32 +91,123 @@ func newScheduler() *scheduler {
 func (s *scheduler) Tick(state *model.ChangefeedReactorState, currentTables []model.TableID, captures map[model.CaptureID]*model.CaptureInfo) (shouldUpdateState bool, err error) {
 	s.state = state
 	s.currentTables = currentTables
-	s.captures = captures
+	s.captures, s.drainingCaptures = splitDrainingCaptures(captures)
+	if len(s.captures) == 0 {
+		// the tables have nowhere to go if all captures are draining, so they are not moved
+		log.Warn("all captures are draining, dispatch tables to the draining captures", zap.String("changefeed", state.ID))
+		s.captures, s.drainingCaptures = captures, nil
+	}
 
 	s.cleanUpFinishedOperations()
 	pendingJob, err := s.syncTablesWithCurrentTables()
 	if err != nil {
 		return false, errors.Trace(err)
 	}
-	s.dispatchToTargetCaptures(pendingJob)
+	dispatchableJobs := s.dispatchToTargetCaptures(pendingJob)
 	if len(pendingJob) != 0 {
 		log.Debug("scheduler:generated pending job to be executed", zap.Any("pendingJob", pendingJob))
 	}
-	s.handleJobs(pendingJob)
+	s.handleJobs(dispatchableJobs)
 
 	// only if the pending job list is empty and no table is being rebalanced or moved,
-	// can the global resolved ts and checkpoint ts be updated
+	// can the global resolved ts and checkpoint ts be updated,
+	// so a table waiting for a capture matching its affinity rule blocks the changefeed
 	shouldUpdateState = len(pendingJob) == 0
+	if shouldUpdateState {
+		// the tables are moved away from the draining captures only if no table is being dispatched
+		s.drainCaptures()
+	}
 	shouldUpdateState = s.rebalance() && shouldUpdateState
 	shouldUpdateStateInMoveTable, err := s.handleMoveTableJob()
 	if err != nil {
 		return false, errors.Trace(err)
 	}
 	shouldUpdateState = shouldUpdateStateInMoveTable && shouldUpdateState
-	s.lastTickCaptureCount = len(captures)
+	s.lastTickCaptureCount = len(s.captures)
 	return shouldUpdateState, nil
 }
 
+func splitDrainingCaptures(captures map[model.CaptureID]*model.CaptureInfo) (
+	schedulable, draining map[model.CaptureID]*model.CaptureInfo,
+) {
+	schedulable = make(map[model.CaptureID]*model.CaptureInfo, len(captures))
+	for captureID, capture := range captures {
+		if capture.IsSchedulable() {
+			schedulable[captureID] = capture
+			continue
+		}
+		if draining == nil {
+			draining = make(map[model.CaptureID]*model.CaptureInfo)
+		}
+		draining[captureID] = capture
+	}
+	return
+}
+
+// drainCaptures moves the tables away from the draining captures by MoveTable.
+// The tables are moved one by one, the next table is moved only if the previous one is replicating,
+// so that the draining doesn't bring a lag spike.
+func (s *scheduler) drainCaptures() {
+	if len(s.drainingCaptures) == 0 || len(s.moveTableJobQueue) != 0 {
+		return
+	}
+	for _, status := range s.state.TaskStatuses {
+		for _, operation := range status.Operation {
+			if operation.Status != model.OperFinished {
+				// wait for the table being moved to be replicating
+				return
+			}
+		}
+	}
+	for tableID := range s.moveTableTargets {
+		replicated := false
+		for _, status := range s.state.TaskStatuses {
+			if _, exist := status.Tables[tableID]; exist {
+				replicated = true
+				break
+			}
+		}
+		if !replicated {
+			// the table has been removed from the source capture, but not added to the target capture yet
+			return
+		}
+	}
+	drainingTables := make([]model.TableID, 0)
+	tableCaptures := make(map[model.TableID]model.CaptureID)
+	for captureID := range s.drainingCaptures {
+		status, exist := s.state.TaskStatuses[captureID]
+		if !exist {
+			continue
+		}
+		for tableID := range status.Tables {
+			drainingTables = append(drainingTables, tableID)
+			tableCaptures[tableID] = captureID
+		}
+	}
+	// move the tables in order to make the result stable
+	sort.Slice(drainingTables, func(i, j int) bool { return drainingTables[i] < drainingTables[j] })
+	workloads := make(map[model.CaptureID]uint64, len(s.captures))
+	for captureID := range s.captures {
+		if status, exist := s.state.TaskStatuses[captureID]; exist {
+			workloads[captureID] = uint64(len(status.Tables))
+		}
+	}
+	for _, tableID := range drainingTables {
+		candidates := s.affinity.candidates(tableID, s.captures)
+		if len(candidates) == 0 {
+			continue
+		}
+		target := s.affinity.selectCapture(candidates, workloads)
+		log.Info("Drain: move table away from the draining capture",
+			zap.String("changefeed", s.state.ID),
+			zap.Int64("table-id", tableID),
+			zap.String("source", tableCaptures[tableID]),
+			zap.String("target", target))
+		s.MoveTable(tableID, target)
+		return
+	}
+}
+
 func (s *scheduler) MoveTable(tableID model.TableID, target model.CaptureID) {
 	s.moveTableJobQueue = append(s.moveTableJobQueue, &moveTableJob{
 		tableID: tableID,
@@ -162,8 +276,10 @@ func (s *scheduler) table2CaptureIndex() (map[model.TableID]model.CaptureID, err
 }
 
 // dispatchToTargetCaptures sets the the TargetCapture of scheduler jobs
-// If the TargetCapture of a job is not set, it chooses a capture with the minimum workload and sets the TargetCapture to the capture.
-func (s *scheduler) dispatchToTargetCaptures(pendingJobs []*schedulerJob) {
+// If the TargetCapture of a job is not set, it chooses a capture with the minimum workload from the captures
+// allowed by the affinity rules and sets the TargetCapture to the capture.
+// It returns the jobs whose TargetCapture is set, the other jobs wait for a capture matching their affinity rules.
+func (s *scheduler) dispatchToTargetCaptures(pendingJobs []*schedulerJob) []*schedulerJob {
 	workloads := make(map[model.CaptureID]uint64)
 
 	for captureID := range s.captures {
@@ -178,6 +294,9 @@ func (s *scheduler) dispatchToTargetCaptures(pendingJobs []*schedulerJob) {
 	}
 
 	for _, pendingJob := range pendingJobs {
+		if pendingJob.Blocked {
+			continue
+		}
 		if pendingJob.TargetCapture == "" {
 			target, exist := s.moveTableTargets[pendingJob.TableID]
 			if !exist {
@@ -198,30 +317,35 @@ func (s *scheduler) dispatchToTargetCaptures(pendingJobs []*schedulerJob) {
 		}
 	}
 
-	getMinWorkloadCapture := func() model.CaptureID {
-		minCapture := ""
-		minWorkLoad := uint64(math.MaxUint64)
-		for captureID, workload := range workloads {
-			if workload < minWorkLoad {
-				minCapture = captureID
-				minWorkLoad = workload
-			}
-		}
-
-		if minCapture == "" {
-			log.Panic("Unreachable, no capture is found")
-		}
-		return minCapture
+	if len(s.captures) == 0 {
+		log.Panic("Unreachable, no capture is found")
 	}
 
+	dispatchableJobs := make([]*schedulerJob, 0, len(pendingJobs))
 	for _, pendingJob := range pendingJobs {
+		if pendingJob.Blocked {
+			continue
+		}
 		if pendingJob.TargetCapture != "" {
+			dispatchableJobs = append(dispatchableJobs, pendingJob)
 			continue
 		}
-		minCapture := getMinWorkloadCapture()
+		candidates := s.affinity.candidates(pendingJob.TableID, s.captures)
+		if len(candidates) == 0 {
+			if _, waiting := s.waitingTables[pendingJob.TableID]; !waiting {
+				log.Warn("no capture matches the required affinity rule of the table, the table waits for a matching capture",
+					zap.String("changefeed", s.state.ID), zap.Int64("table-id", pendingJob.TableID))
+				s.waitingTables[pendingJob.TableID] = struct{}{}
+			}
+			continue
+		}
+		delete(s.waitingTables, pendingJob.TableID)
+		minCapture := s.affinity.selectCapture(candidates, workloads)
 		pendingJob.TargetCapture = minCapture
 		workloads[minCapture] += 1
+		dispatchableJobs = append(dispatchableJobs, pendingJob)
 	}
+	return dispatchableJobs
 }
 
 // syncTablesWithCurrentTables iterates all current tables and check whether all the table has been listened.
@@ -248,6 +372,18 @@ func (s *scheduler) syncTablesWithCurrentTables() ([]*schedulerJob, error) {
 	}
 	// The remaining tables are the tables which should be not listened
 	tablesThatShouldNotBeListened := allTableListeningNow
+	// the spans of a table are added only after the table or its spans in the previous way are removed
+	replacedTables := make(map[model.TableID]struct{})
+	for tableID := range tablesThatShouldNotBeListened {
+		tableID, _, _ = model.DecodeSpanTableID(tableID)
+		replacedTables[tableID] = struct{}{}
+	}
+	for _, job := range pendingJob {
+		tableID, _, _ := model.DecodeSpanTableID(job.TableID)
+		if _, exist := replacedTables[tableID]; exist {
+			job.Blocked = true
+		}
+	}
 	for tableID, captureID := range tablesThatShouldNotBeListened {
 		opts := s.state.TaskStatuses[captureID].Operation
 		if opts != nil && opts[tableID] != nil && opts[tableID].Delete {
@@ -275,9 +411,12 @@ func (s *scheduler) handleJobs(jobs []*schedulerJob) {
 					log.Warn("task status of the capture is not found, may be the capture is already down. specify a new capture and redo the job", zap.Any("job", job))
 					return status, false, nil
 				}
+				startKey, endKey := s.splitter.KeyRange(job.TableID)
 				status.AddTable(job.TableID, &model.TableReplicaInfo{
 					StartTs:     job.BoundaryTs,
 					MarkTableID: 0, // mark table ID will be set in processors
+					StartKey:    startKey,
+					EndKey:      endKey,
 				}, job.BoundaryTs)
 			case schedulerJobTypeRemoveTable:
 				failpoint.Inject("OwnerRemoveTableError", func() {
@@ -318,7 +457,15 @@ func (s *scheduler) rebalance() (shouldUpdateState bool) {
 		// if no table is rebalanced, we can update the resolved ts and checkpoint ts
 		return true
 	}
-	// we only support rebalance by table number for now
+	s.lastRebalanceTime = time.Now()
+	if s.rebalanceByAffinity() {
+		// balance the tables after they are moved to the captures matching their affinity rules
+		s.needRebalanceNextTick = true
+		return true
+	}
+	if s.state.Info != nil && s.state.Info.Config.Scheduler.Tp == schedulerTypeWorkload {
+		return s.rebalanceByWorkload()
+	}
 	return s.rebalanceByTableNum()
 }
 
@@ -332,10 +479,100 @@ func (s *scheduler) shouldRebalance() bool {
 		// or some captures offline
 		return true
 	}
-	// TODO periodic trigger rebalance
+	if s.state.Info != nil {
+		pollingTime := time.Duration(s.state.Info.Config.Scheduler.PollingTime) * time.Minute
+		if pollingTime > 0 && time.Since(s.lastRebalanceTime) > pollingTime {
+			return true
+		}
+	}
 	return false
 }
 
+// rebalanceByAffinity moves the tables which are replicated by the captures not matching their affinity rules
+// to the matching captures, if any of the matching captures is alive. It happens when a table was dispatched
+// to another capture because no matching capture was alive. It returns true if any table is moved.
+func (s *scheduler) rebalanceByAffinity() (moved bool) {
+	if s.affinity == nil {
+		return false
+	}
+	workloads := make(map[model.CaptureID]uint64, len(s.captures))
+	for captureID := range s.captures {
+		if status, exist := s.state.TaskStatuses[captureID]; exist {
+			workloads[captureID] = uint64(len(status.Tables))
+		}
+	}
+	for captureID, status := range s.state.TaskStatuses {
+		capture, alive := s.captures[captureID]
+		if !alive {
+			continue
+		}
+		for tableID := range status.Tables {
+			if _, exist := status.Operation[tableID]; exist || s.affinity.isPreferred(tableID, capture) {
+				continue
+			}
+			preferred := s.affinity.preferredCaptures(tableID, s.captures)
+			if len(preferred) == 0 {
+				continue
+			}
+			target := s.affinity.selectCapture(preferred, workloads)
+			workloads[target]++
+			log.Info("Rebalance: move table to the capture matching its affinity rule",
+				zap.String("changefeed", s.state.ID),
+				zap.Int64("table-id", tableID),
+				zap.String("source", captureID),
+				zap.String("target", target))
+			s.MoveTable(tableID, target)
+			moved = true
+		}
+	}
+	return
+}
+
+// rebalanceByWorkload moves tables from the busy captures to the idle captures according to the measured workloads,
+// the moved tables will be dispatched to the target captures by handleMoveTableJob.
+func (s *scheduler) rebalanceByWorkload() (shouldUpdateState bool) {
+	if s.workloadScheduler == nil {
+		s.workloadScheduler = pscheduler.NewScheduler(schedulerTypeWorkload)
+	}
+	captureIDs := make(map[model.CaptureID]struct{}, len(s.captures))
+	for captureID := range s.captures {
+		captureIDs[captureID] = struct{}{}
+		workloads := make(model.TaskWorkload)
+		if status, exist := s.state.TaskStatuses[captureID]; exist {
+			if len(status.Operation) != 0 {
+				// the workloads are not stable while tables are being added or removed, try again in the next tick
+				s.needRebalanceNextTick = true
+				return true
+			}
+			for tableID := range status.Tables {
+				workload, exist := s.state.Workloads[captureID][tableID]
+				if !exist {
+					workload = model.WorkloadInfo{Workload: 1}
+				}
+				workloads[tableID] = workload
+			}
+		}
+		s.workloadScheduler.ResetWorkloads(captureID, workloads)
+	}
+	s.workloadScheduler.AlignCapture(captureIDs)
+
+	skewness := s.workloadScheduler.Skewness()
+	changefeedWorkloadSkewnessGauge.WithLabelValues(s.state.ID).Set(skewness)
+	expectedSkewness, moveTableJobs := s.workloadScheduler.CalRebalanceOperates(0)
+	log.Info("Start rebalancing by workload",
+		zap.String("changefeed", s.state.ID),
+		zap.Float64("skewness", skewness),
+		zap.Float64("expected-skewness", expectedSkewness),
+		zap.Any("move-table-jobs", moveTableJobs))
+	for tableID, job := range moveTableJobs {
+		if _, allowed := s.affinity.candidates(tableID, s.captures)[job.To]; !allowed {
+			continue
+		}
+		s.MoveTable(tableID, job.To)
+	}
+	return true
+}
+
 // rebalanceByTableNum removes tables from captures replicating an above-average number of tables.
 // the removed table will be dispatched again by syncTablesWithCurrentTables function
 func (s *scheduler) rebalanceByTableNum() (shouldUpdateState bool) {
@@ -351,6 +588,10 @@ func (s *scheduler) rebalanceByTableNum() (shouldUpdateState bool) {
 		zap.Int("target-limit", upperLimitPerCapture))
 
 	for captureID, taskStatus := range s.state.TaskStatuses {
+		if _, draining := s.drainingCaptures[captureID]; draining {
+			// the tables of the draining captures are moved by drainCaptures one by one
+			continue
+		}
 		tableNum2Remove := len(taskStatus.Tables) - upperLimitPerCapture
 		if tableNum2Remove <= 0 {
 			continue
@@ -363,6 +604,12 @@ func (s *scheduler) rebalanceByTableNum() (shouldUpdateState bool) {
 			if tableNum2Remove <= 0 {
 				break
 			}
+			if candidates := s.affinity.candidates(tableID, s.captures); len(candidates) == 1 {
+				if _, exist := candidates[captureID]; exist {
+					// the table would be dispatched to the same capture again
+					continue
+				}
+			}
 			shouldUpdateState = false
 			s.state.PatchTaskStatus(captureID, func(status *model.TaskStatus) (*model.TaskStatus, bool, error) {
 				if status == nil {
@@ -19,6 +19,7 @@ import (
 
 	"github.com/pingcap/check"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
 	"github.com/pingcap/ticdc/pkg/orchestrator"
 	"github.com/pingcap/ticdc/pkg/util/testleak"
 )
@@ -340,3 +341,288 @@ func (s *schedulerSuite) TestScheduleRebalance(c *check.C) {
 	}
 	c.Assert(tableIDs, check.DeepEquals, map[model.TableID]struct{}{1: {}, 2: {}, 3: {}, 4: {}, 5: {}, 6: {}})
 }
+
+func (s *schedulerSuite) TestScheduleRebalanceByWorkload(c *check.C) {
+	defer testleak.AfterTest(c)()
+	s.reset(c)
+	captureID1 := "test-capture-1"
+	captureID2 := "test-capture-2"
+	s.addCapture(captureID1)
+	s.addCapture(captureID2)
+	s.state.PatchInfo(func(info *model.ChangeFeedInfo) (*model.ChangeFeedInfo, bool, error) {
+		replicaConfig := config.GetDefaultReplicaConfig()
+		replicaConfig.Scheduler.Tp = schedulerTypeWorkload
+		return &model.ChangeFeedInfo{Config: replicaConfig}, true, nil
+	})
+	setTables := func(captureID model.CaptureID, workloads model.TaskWorkload) {
+		s.state.PatchTaskStatus(captureID, func(status *model.TaskStatus) (*model.TaskStatus, bool, error) {
+			status.Tables = make(map[model.TableID]*model.TableReplicaInfo)
+			for tableID := range workloads {
+				status.Tables[tableID] = &model.TableReplicaInfo{StartTs: 1}
+			}
+			return status, true, nil
+		})
+		s.state.PatchTaskWorkload(captureID, func(workload model.TaskWorkload) (model.TaskWorkload, bool, error) {
+			return workloads, true, nil
+		})
+	}
+	// capture 1 replicates two hot tables, and capture 2 replicates an idle table
+	setTables(captureID1, model.TaskWorkload{1: {Workload: 50}, 2: {Workload: 50}, 3: {Workload: 1}})
+	setTables(captureID2, model.TaskWorkload{4: {Workload: 1}})
+	s.tester.MustApplyPatches()
+
+	// one hot table is moved, though the table numbers are not balanced
+	shouldUpdateState, err := s.scheduler.Tick(s.state, []model.TableID{1, 2, 3, 4}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	c.Assert(s.state.TaskStatuses[captureID1].Tables, check.HasLen, 2)
+	c.Assert(s.state.TaskStatuses[captureID1].Tables[3], check.NotNil)
+	c.Assert(s.state.TaskStatuses[captureID1].Operation, check.HasLen, 1)
+	var movedTableID model.TableID
+	for tableID, operation := range s.state.TaskStatuses[captureID1].Operation {
+		c.Assert(operation.Delete, check.IsTrue)
+		movedTableID = tableID
+	}
+	c.Assert(movedTableID == 1 || movedTableID == 2, check.IsTrue)
+	s.finishTableOperation(captureID1, movedTableID)
+
+	// clean finished operation
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 2, 3, 4}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsTrue)
+	s.tester.MustApplyPatches()
+
+	// the moved table is added to capture 2
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 2, 3, 4}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	c.Assert(s.state.TaskStatuses[captureID2].Tables, check.HasLen, 2)
+	c.Assert(s.state.TaskStatuses[captureID2].Tables[movedTableID], check.NotNil)
+}
+
+func (s *schedulerSuite) TestScheduleAffinity(c *check.C) {
+	defer testleak.AfterTest(c)()
+	s.reset(c)
+	addLabeledCapture := func(captureID model.CaptureID, zone string) {
+		s.addCapture(captureID)
+		s.captures[captureID].Labels = map[string]string{"zone": zone}
+	}
+	addLabeledCapture("capture-z1", "z1")
+	addLabeledCapture("capture-z2", "z2")
+	tableNames := map[model.TableID]model.TableName{
+		1: {Schema: "test", Table: "pinned1"},
+		2: {Schema: "test", Table: "pinned2"},
+		3: {Schema: "test", Table: "soft"},
+	}
+	var err error
+	s.scheduler.affinity, err = newAffinity(&config.SchedulerConfig{
+		AffinityRules: []*config.AffinityRule{
+			{Matcher: []string{"test.pinned*"}, Labels: map[string]string{"zone": "z1"}, Required: true},
+			{Matcher: []string{"test.soft"}, Labels: map[string]string{"zone": "z3"}},
+		},
+	}, true, func(tableID model.TableID) (model.TableName, bool) {
+		name, ok := tableNames[tableID]
+		return name, ok
+	})
+	c.Assert(err, check.IsNil)
+
+	// table 1 is pinned to zone z1, and table 3 falls back to the other captures since no capture is in zone z3
+	shouldUpdateState, err := s.scheduler.Tick(s.state, []model.TableID{1, 3}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	c.Assert(s.state.TaskStatuses["capture-z1"].Tables, check.DeepEquals, map[model.TableID]*model.TableReplicaInfo{1: {StartTs: 0}})
+	c.Assert(s.state.TaskStatuses["capture-z2"].Tables, check.DeepEquals, map[model.TableID]*model.TableReplicaInfo{3: {StartTs: 0}})
+	s.finishTableOperation("capture-z1", 1)
+	s.finishTableOperation("capture-z2", 3)
+
+	// clean finished operation
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 3}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsTrue)
+	s.tester.MustApplyPatches()
+
+	// table 3 is moved back once a capture in zone z3 is alive
+	addLabeledCapture("capture-z3", "z3")
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 3}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	c.Assert(s.state.TaskStatuses["capture-z2"].Tables, check.HasLen, 0)
+	c.Assert(s.state.TaskStatuses["capture-z2"].Operation[3].Delete, check.IsTrue)
+	s.finishTableOperation("capture-z2", 3)
+
+	// clean finished operation
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 3}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsTrue)
+	s.tester.MustApplyPatches()
+
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 3}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	c.Assert(s.state.TaskStatuses["capture-z3"].Tables, check.DeepEquals, map[model.TableID]*model.TableReplicaInfo{3: {StartTs: 0}})
+	s.finishTableOperation("capture-z3", 3)
+
+	// the required tables wait for a capture in zone z1, which blocks the changefeed
+	delete(s.captures, "capture-z1")
+	s.state.PatchTaskStatus("capture-z1", func(status *model.TaskStatus) (*model.TaskStatus, bool, error) {
+		return nil, true, nil
+	})
+	s.tester.MustApplyPatches()
+	for i := 0; i < 2; i++ {
+		shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 2, 3}, s.captures)
+		c.Assert(err, check.IsNil)
+		c.Assert(shouldUpdateState, check.IsFalse)
+		s.tester.MustApplyPatches()
+		c.Assert(s.state.TaskStatuses["capture-z2"].Tables, check.HasLen, 0)
+		c.Assert(s.state.TaskStatuses["capture-z3"].Tables, check.HasLen, 1)
+	}
+
+	addLabeledCapture("capture-z1", "z1")
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 2, 3}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	c.Assert(s.state.TaskStatuses["capture-z1"].Tables, check.DeepEquals, map[model.TableID]*model.TableReplicaInfo{
+		1: {StartTs: 0}, 2: {StartTs: 0},
+	})
+}
+
+func (s *schedulerSuite) TestScheduleSpreadLabel(c *check.C) {
+	defer testleak.AfterTest(c)()
+	s.reset(c)
+	for captureID, zone := range map[model.CaptureID]string{"capture-a1": "z1", "capture-a2": "z1", "capture-b1": "z2"} {
+		s.addCapture(captureID)
+		s.captures[captureID].Labels = map[string]string{"zone": zone}
+	}
+	var err error
+	s.scheduler.affinity, err = newAffinity(&config.SchedulerConfig{SpreadLabel: "zone"}, true, nil)
+	c.Assert(err, check.IsNil)
+
+	// the tables are spread across the zones evenly, though the zones have different numbers of captures
+	shouldUpdateState, err := s.scheduler.Tick(s.state, []model.TableID{1, 2, 3, 4}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	c.Assert(s.state.TaskStatuses["capture-a1"].Tables, check.HasLen, 1)
+	c.Assert(s.state.TaskStatuses["capture-a2"].Tables, check.HasLen, 1)
+	c.Assert(s.state.TaskStatuses["capture-b1"].Tables, check.HasLen, 2)
+}
+
+func (s *schedulerSuite) TestScheduleDrainCapture(c *check.C) {
+	defer testleak.AfterTest(c)()
+	s.reset(c)
+	captureID1 := "test-capture-1"
+	captureID2 := "test-capture-2"
+	s.addCapture(captureID1)
+	shouldUpdateState, err := s.scheduler.Tick(s.state, []model.TableID{1, 2}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	s.finishTableOperation(captureID1, 1, 2)
+	// clean finished operation
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 2}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsTrue)
+	s.tester.MustApplyPatches()
+
+	// capture 1 is draining, its tables are moved to capture 2 one by one
+	s.addCapture(captureID2)
+	s.captures[captureID1].Draining = true
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 2}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	for _, tableID := range []model.TableID{1, 2} {
+		c.Assert(s.state.TaskStatuses[captureID1].Operation, check.HasLen, 1)
+		c.Assert(s.state.TaskStatuses[captureID1].Operation[tableID].Delete, check.IsTrue)
+		s.finishTableOperation(captureID1, tableID)
+
+		// clean finished operation
+		shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1, 2}, s.captures)
+		c.Assert(err, check.IsNil)
+		c.Assert(shouldUpdateState, check.IsTrue)
+		s.tester.MustApplyPatches()
+
+		// the table is added to capture 2, and the next table is not moved until the table is replicating
+		for i := 0; i < 2; i++ {
+			_, err = s.scheduler.Tick(s.state, []model.TableID{1, 2}, s.captures)
+			c.Assert(err, check.IsNil)
+			s.tester.MustApplyPatches()
+			c.Assert(s.state.TaskStatuses[captureID2].Tables[tableID], check.NotNil)
+			c.Assert(s.state.TaskStatuses[captureID1].Operation, check.HasLen, 0)
+		}
+		s.finishTableOperation(captureID2, tableID)
+
+		// clean finished operation, and move the next table
+		_, err = s.scheduler.Tick(s.state, []model.TableID{1, 2}, s.captures)
+		c.Assert(err, check.IsNil)
+		s.tester.MustApplyPatches()
+	}
+	c.Assert(s.state.TaskStatuses[captureID1].Tables, check.HasLen, 0)
+	c.Assert(s.state.TaskStatuses[captureID2].Tables, check.HasLen, 2)
+}
+
+func (s *schedulerSuite) TestScheduleSplitTable(c *check.C) {
+	defer testleak.AfterTest(c)()
+	s.reset(c)
+	s.addCapture("capture-1")
+	s.addCapture("capture-2")
+	splitKey := []byte("split-key")
+	span0, span1 := model.SpanTableID(1, 0), model.SpanTableID(1, 1)
+
+	// the whole table is replicated by capture-1
+	shouldUpdateState, err := s.scheduler.Tick(s.state, []model.TableID{1}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	captureID := "capture-1"
+	if len(s.state.TaskStatuses[captureID].Tables) == 0 {
+		captureID = "capture-2"
+	}
+	s.finishTableOperation(captureID, 1)
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{1}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsTrue)
+	s.tester.MustApplyPatches()
+
+	// the spans are not added until the whole table is removed, which blocks the changefeed
+	s.scheduler.splitter = &tableSplitter{splitKeys: map[model.TableID][][]byte{1: {splitKey}}}
+	for i := 0; i < 2; i++ {
+		shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{span0, span1}, s.captures)
+		c.Assert(err, check.IsNil)
+		c.Assert(shouldUpdateState, check.IsFalse)
+		s.tester.MustApplyPatches()
+		c.Assert(s.state.TaskStatuses["capture-1"].Tables, check.HasLen, 0)
+		c.Assert(s.state.TaskStatuses["capture-2"].Tables, check.HasLen, 0)
+		c.Assert(s.state.TaskStatuses[captureID].Operation[1].Delete, check.IsTrue)
+	}
+	s.finishTableOperation(captureID, 1)
+
+	// clean finished operation
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{span0, span1}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+
+	// the spans are dispatched to different captures with their key ranges
+	shouldUpdateState, err = s.scheduler.Tick(s.state, []model.TableID{span0, span1}, s.captures)
+	c.Assert(err, check.IsNil)
+	c.Assert(shouldUpdateState, check.IsFalse)
+	s.tester.MustApplyPatches()
+	tables := make(map[model.TableID]*model.TableReplicaInfo)
+	for _, status := range s.state.TaskStatuses {
+		c.Assert(status.Tables, check.HasLen, 1)
+		for tableID, replicaInfo := range status.Tables {
+			tables[tableID] = replicaInfo
+		}
+	}
+	c.Assert(tables, check.DeepEquals, map[model.TableID]*model.TableReplicaInfo{
+		span0: {StartTs: 0, EndKey: splitKey},
+		span1: {StartTs: 0, StartKey: splitKey},
+	})
+}
@@ -98,6 +98,30 @@ func (s *schemaWrap4Owner) HandleDDL(job *timodel.Job) error {
 	return nil
 }
 
+// TableName returns the name of the table or the partition table with the given ID,
+// the name of the table is returned for a span table ID.
+func (s *schemaWrap4Owner) TableName(tableID model.TableID) (model.TableName, bool) {
+	tableID, _, _ = model.DecodeSpanTableID(tableID)
+	return s.schemaSnapshot.GetTableNameByID(tableID)
+}
+
+// PhysicalTableIDs returns the IDs of the partitions of a partition table, or the ID itself for a normal table
+func (s *schemaWrap4Owner) PhysicalTableIDs(tableID model.TableID) []model.TableID {
+	tblInfo, ok := s.schemaSnapshot.TableByID(tableID)
+	if !ok {
+		return []model.TableID{tableID}
+	}
+	pi := tblInfo.GetPartitionInfo()
+	if pi == nil {
+		return []model.TableID{tableID}
+	}
+	tableIDs := make([]model.TableID, 0, len(pi.Definitions))
+	for _, partition := range pi.Definitions {
+		tableIDs = append(tableIDs, partition.ID)
+	}
+	return tableIDs
+}
+
 func (s *schemaWrap4Owner) IsIneligibleTableID(tableID model.TableID) bool {
 	return s.schemaSnapshot.IsIneligibleTableID(tableID)
 }
@@ -106,6 +106,13 @@ func (s *schemaSuite) TestBuildDDLEvent(c *check.C) {
 	c.Assert(err, check.IsNil)
 	schema, err := newSchemaWrap4Owner(helper.Storage(), ver.Ver, config.GetDefaultReplicaConfig())
 	c.Assert(err, check.IsNil)
+	idCol := &model.ColumnInfo{
+		Name: "id",
+		Type: mysql.TypeLong,
+		Flag: model.BinaryFlag | model.HandleKeyFlag | model.PrimaryKeyFlag | model.UniqueKeyFlag,
+		Meta: &model.ColumnMeta{SQLType: "int(11)", Length: 11, Charset: "binary", Collation: "binary"},
+	}
+	pk := &model.KeyInfo{Name: "PRIMARY", Columns: []string{"id"}, Primary: true, Unique: true}
 	// add normal table
 	job := helper.DDL2Job("create table test.t1(id int primary key)")
 	event, err := schema.BuildDDLEvent(job)
@@ -119,7 +126,8 @@ func (s *schemaSuite) TestBuildDDLEvent(c *check.C) {
 			Schema:     "test",
 			Table:      "t1",
 			TableID:    job.TableID,
-			ColumnInfo: []*model.ColumnInfo{{Name: "id", Type: mysql.TypeLong}},
+			ColumnInfo: []*model.ColumnInfo{idCol},
+			Keys:       []*model.KeyInfo{pk},
 		},
 		PreTableInfo: nil,
 	})
@@ -133,16 +141,22 @@ func (s *schemaSuite) TestBuildDDLEvent(c *check.C) {
 		Query:    "ALTER TABLE test.t1 ADD COLUMN c1 CHAR(16) NOT NULL",
 		Type:     timodel.ActionAddColumn,
 		TableInfo: &model.SimpleTableInfo{
-			Schema:     "test",
-			Table:      "t1",
-			TableID:    job.TableID,
-			ColumnInfo: []*model.ColumnInfo{{Name: "id", Type: mysql.TypeLong}, {Name: "c1", Type: mysql.TypeString}},
+			Schema:  "test",
+			Table:   "t1",
+			TableID: job.TableID,
+			ColumnInfo: []*model.ColumnInfo{idCol, {
+				Name: "c1",
+				Type: mysql.TypeString,
+				Meta: &model.ColumnMeta{SQLType: "char(16)", Length: 16, Charset: "utf8mb4", Collation: "utf8mb4_bin"},
+			}},
+			Keys: []*model.KeyInfo{pk},
 		},
 		PreTableInfo: &model.SimpleTableInfo{
 			Schema:     "test",
 			Table:      "t1",
 			TableID:    job.TableID,
-			ColumnInfo: []*model.ColumnInfo{{Name: "id", Type: mysql.TypeLong}},
+			ColumnInfo: []*model.ColumnInfo{idCol},
+			Keys:       []*model.KeyInfo{pk},
 		},
 	})
 }
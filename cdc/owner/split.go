@@ -0,0 +1,292 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package owner
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/cdc/sink/dispatcher"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/regionspan"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"github.com/pingcap/tidb/util/codec"
+	pd "github.com/tikv/pd/client"
+	"go.uber.org/zap"
+)
+
+const (
+	// regionScanLimit is the maximum number of regions scanned in a request to PD
+	regionScanLimit = 1024
+	// regionScanTimeout is the timeout of scanning the regions of a table
+	regionScanTimeout = 10 * time.Second
+)
+
+type splitRule struct {
+	filter filter.Filter
+	spans  int
+}
+
+// tableSplitter splits the hot tables into key range spans by their region distribution, every span is
+// scheduled as an independent table whose ID is the span table ID. The tables are split by the record keys,
+// so all the changes of a row are replicated by the same span in order.
+// All the methods can be called on a nil tableSplitter, which means no table is split.
+type tableSplitter struct {
+	rules     []*splitRule
+	tableName func(tableID model.TableID) (model.TableName, bool)
+	// canSplit returns false if the rows of the table can't be kept in order by the sink once the table is split
+	canSplit func(tableName model.TableName) bool
+	// scanRegions returns the start keys of the regions overlapping with the span
+	scanRegions func(ctx context.Context, span regionspan.Span) ([][]byte, error)
+
+	// splitKeys are the split keys of the tables, it's nil for the tables which are not split
+	splitKeys map[model.TableID][][]byte
+}
+
+// newTableSplitter creates a tableSplitter by the replica config of a changefeed,
+// it returns nil if no split rule is specified.
+func newTableSplitter(
+	cfg *config.ReplicaConfig, sinkURI string,
+	tableName func(tableID model.TableID) (model.TableName, bool),
+	scanRegions func(ctx context.Context, span regionspan.Span) ([][]byte, error),
+) (*tableSplitter, error) {
+	if cfg.Scheduler == nil || len(cfg.Scheduler.SplitRules) == 0 {
+		return nil, nil
+	}
+	if cfg.Cyclic.IsEnabled() {
+		return nil, cerror.ErrInvalidSchedulerConfig.GenWithStack("tables can't be split if the cyclic replication is enabled")
+	}
+	// Without the old value, the deletes of the tables whose handle isn't the primary key are mounted from the
+	// index keys, which are out of the record keys split into spans, so they race with the other changes of the rows.
+	if !cfg.EnableOldValue {
+		return nil, cerror.ErrInvalidSchedulerConfig.GenWithStack("tables can't be split if the old value is disabled")
+	}
+	s := &tableSplitter{
+		rules:       make([]*splitRule, 0, len(cfg.Scheduler.SplitRules)),
+		tableName:   tableName,
+		canSplit:    func(model.TableName) bool { return true },
+		scanRegions: scanRegions,
+		splitKeys:   make(map[model.TableID][][]byte),
+	}
+	for _, rule := range cfg.Scheduler.SplitRules {
+		f, err := filter.Parse(rule.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrInvalidSchedulerConfig, err)
+		}
+		if !cfg.CaseSensitive {
+			f = filter.CaseInsensitive(f)
+		}
+		s.rules = append(s.rules, &splitRule{filter: f, spans: rule.Spans})
+	}
+	if sink.IsMQSink(sinkURI) {
+		// the rows of a split table are sent to the partitions by several captures, which keeps
+		// the order of the changes of a row only if the rows are dispatched by their keys
+		s.canSplit = func(tableName model.TableName) bool {
+			keyBased, err := dispatcher.IsKeyBased(cfg, tableName.Schema, tableName.Table)
+			return err == nil && keyBased
+		}
+	}
+	return s, nil
+}
+
+// Expand replaces the split tables in the table list with their spans.
+// The split keys of a table are calculated when the table is found at the first time,
+// and recorded in the changefeed status so that the table is split in the same way after the owner changes.
+func (s *tableSplitter) Expand(
+	ctx context.Context, state *model.ChangefeedReactorState, tables []model.TableID,
+) ([]model.TableID, error) {
+	if s == nil {
+		return tables, nil
+	}
+	expanded := make([]model.TableID, 0, len(tables))
+	exists := make(map[model.TableID]struct{}, len(tables))
+	for _, tableID := range tables {
+		exists[tableID] = struct{}{}
+		splitKeys, err := s.splitKeysOf(ctx, state, tableID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(splitKeys) == 0 {
+			expanded = append(expanded, tableID)
+			continue
+		}
+		for i := 0; i <= len(splitKeys); i++ {
+			expanded = append(expanded, model.SpanTableID(tableID, i))
+		}
+	}
+	for tableID := range s.splitKeys {
+		if _, exist := exists[tableID]; !exist {
+			delete(s.splitKeys, tableID)
+		}
+	}
+	var staleTables []model.TableID
+	for tableID := range state.Status.SplitKeys {
+		if len(s.splitKeys[tableID]) == 0 {
+			staleTables = append(staleTables, tableID)
+		}
+	}
+	if len(staleTables) != 0 {
+		state.PatchStatus(func(status *model.ChangeFeedStatus) (*model.ChangeFeedStatus, bool, error) {
+			if status == nil {
+				return nil, false, nil
+			}
+			for _, tableID := range staleTables {
+				delete(status.SplitKeys, tableID)
+			}
+			return status, true, nil
+		})
+	}
+	return expanded, nil
+}
+
+func (s *tableSplitter) splitKeysOf(
+	ctx context.Context, state *model.ChangefeedReactorState, tableID model.TableID,
+) ([][]byte, error) {
+	if splitKeys, exist := s.splitKeys[tableID]; exist {
+		return splitKeys, nil
+	}
+	tableName, ok := s.tableName(tableID)
+	if !ok {
+		return nil, nil
+	}
+	var rule *splitRule
+	for _, r := range s.rules {
+		if r.filter.MatchTable(tableName.Schema, tableName.Table) {
+			rule = r
+			break
+		}
+	}
+	if rule == nil {
+		s.splitKeys[tableID] = nil
+		return nil, nil
+	}
+	if splitKeys, exist := state.Status.SplitKeys[tableID]; exist {
+		s.splitKeys[tableID] = splitKeys
+		return splitKeys, nil
+	}
+	if !s.canSplit(tableName) {
+		log.Warn("the table is not split because its rows are not dispatched by keys",
+			zap.String("changefeed", state.ID), zap.Stringer("table", tableName))
+		s.splitKeys[tableID] = nil
+		return nil, nil
+	}
+	splitKeys, err := s.calculateSplitKeys(ctx, tableID, rule.spans)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s.splitKeys[tableID] = splitKeys
+	log.Info("split the table into spans by the regions",
+		zap.String("changefeed", state.ID), zap.Stringer("table", tableName),
+		zap.Int64("table-id", tableID), zap.Int("spans", len(splitKeys)+1))
+	if len(splitKeys) != 0 {
+		state.PatchStatus(func(status *model.ChangeFeedStatus) (*model.ChangeFeedStatus, bool, error) {
+			if status == nil {
+				return nil, false, nil
+			}
+			if status.SplitKeys == nil {
+				status.SplitKeys = make(map[model.TableID][][]byte)
+			}
+			status.SplitKeys[tableID] = splitKeys
+			return status, true, nil
+		})
+	}
+	return splitKeys, nil
+}
+
+// calculateSplitKeys returns the keys which split the record keys of the table into the spans with
+// the same number of regions. The table is split into fewer spans if it has fewer regions.
+func (s *tableSplitter) calculateSplitKeys(ctx context.Context, tableID model.TableID, spans int) ([][]byte, error) {
+	recordSpan := regionspan.GetTableSpan(tableID, true)
+	ctx, cancel := context.WithTimeout(ctx, regionScanTimeout)
+	defer cancel()
+	startKeys, err := s.scanRegions(ctx, recordSpan)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	innerKeys := make([][]byte, 0, len(startKeys))
+	for _, key := range startKeys {
+		if bytes.Compare(key, recordSpan.Start) > 0 && bytes.Compare(key, recordSpan.End) < 0 {
+			innerKeys = append(innerKeys, key)
+		}
+	}
+	regions := len(innerKeys) + 1
+	if spans > regions {
+		spans = regions
+	}
+	if spans < 2 {
+		return nil, nil
+	}
+	splitKeys := make([][]byte, 0, spans-1)
+	for i := 1; i < spans; i++ {
+		splitKeys = append(splitKeys, innerKeys[i*regions/spans-1])
+	}
+	return splitKeys, nil
+}
+
+// KeyRange returns the key range of the span table,
+// nil keys mean the start or the end of the table, which are returned for the tables not split.
+func (s *tableSplitter) KeyRange(id model.TableID) (startKey, endKey []byte) {
+	tableID, index, isSpan := model.DecodeSpanTableID(id)
+	if s == nil || !isSpan {
+		return nil, nil
+	}
+	splitKeys := s.splitKeys[tableID]
+	if index > 0 && index <= len(splitKeys) {
+		startKey = splitKeys[index-1]
+	}
+	if index < len(splitKeys) {
+		endKey = splitKeys[index]
+	}
+	return
+}
+
+// scanRegionStartKeys returns the raw start keys of the regions overlapping with the span
+func scanRegionStartKeys(ctx context.Context, pdClient pd.Client, span regionspan.Span) ([][]byte, error) {
+	comparableSpan := regionspan.ToComparableSpan(span)
+	nextKey := comparableSpan.Start
+	startKeys := make([][]byte, 0)
+	for {
+		regions, err := pdClient.ScanRegions(ctx, nextKey, comparableSpan.End, regionScanLimit)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrPDBatchLoadRegions, err)
+		}
+		if len(regions) == 0 {
+			return startKeys, nil
+		}
+		for _, region := range regions {
+			if region.Meta == nil {
+				return nil, cerror.ErrMetaNotInRegion.GenWithStackByArgs()
+			}
+			// the start key of the first region is empty
+			if len(region.Meta.StartKey) != 0 {
+				_, startKey, err := codec.DecodeBytes(region.Meta.StartKey, nil)
+				if err != nil {
+					return nil, cerror.WrapError(cerror.ErrPDBatchLoadRegions, err)
+				}
+				startKeys = append(startKeys, startKey)
+			}
+		}
+		endKey := regions[len(regions)-1].Meta.EndKey
+		if len(endKey) == 0 || bytes.Compare(endKey, comparableSpan.End) >= 0 {
+			return startKeys, nil
+		}
+		nextKey = endKey
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package owner
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/orchestrator"
+	"github.com/pingcap/ticdc/pkg/regionspan"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"github.com/pingcap/tidb/tablecodec"
+)
+
+var _ = check.Suite(&splitSuite{})
+
+type splitSuite struct{}
+
+func recordKey(tableID model.TableID, suffix byte) []byte {
+	span := regionspan.GetTableSpan(tableID, true)
+	return append(append([]byte{}, span.Start...), suffix)
+}
+
+func (s *splitSuite) TestExpand(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	state := model.NewChangefeedReactorState("test-changefeed")
+	tester := orchestrator.NewReactorStateTester(c, state, nil)
+	state.PatchStatus(func(status *model.ChangeFeedStatus) (*model.ChangeFeedStatus, bool, error) {
+		return &model.ChangeFeedStatus{}, true, nil
+	})
+	tester.MustApplyPatches()
+
+	tableNames := map[model.TableID]model.TableName{
+		1: {Schema: "test", Table: "hot"},
+		2: {Schema: "test", Table: "cold"},
+	}
+	tableName := func(tableID model.TableID) (model.TableName, bool) {
+		name, ok := tableNames[tableID]
+		return name, ok
+	}
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Scheduler.SplitRules = []*config.SplitRule{{Matcher: []string{"test.hot"}, Spans: 3}}
+	scanned := 0
+	splitter, err := newTableSplitter(cfg, "blackhole://", tableName,
+		func(ctx context.Context, span regionspan.Span) ([][]byte, error) {
+			scanned++
+			// the first region starts before the record keys, and the table has 6 regions
+			return [][]byte{
+				regionspan.GetTableSpan(1, false).Start,
+				recordKey(1, 1), recordKey(1, 2), recordKey(1, 3), recordKey(1, 4), recordKey(1, 5),
+			}, nil
+		})
+	c.Assert(err, check.IsNil)
+
+	tables, err := splitter.Expand(ctx, state, []model.TableID{1, 2})
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.DeepEquals, []model.TableID{model.SpanTableID(1, 0), model.SpanTableID(1, 1), model.SpanTableID(1, 2), 2})
+	tester.MustApplyPatches()
+	c.Assert(state.Status.SplitKeys, check.DeepEquals, map[model.TableID][][]byte{1: {recordKey(1, 2), recordKey(1, 4)}})
+	startKey, endKey := splitter.KeyRange(model.SpanTableID(1, 0))
+	c.Assert(startKey, check.IsNil)
+	c.Assert(endKey, check.DeepEquals, recordKey(1, 2))
+	startKey, endKey = splitter.KeyRange(model.SpanTableID(1, 1))
+	c.Assert(startKey, check.DeepEquals, recordKey(1, 2))
+	c.Assert(endKey, check.DeepEquals, recordKey(1, 4))
+	startKey, endKey = splitter.KeyRange(model.SpanTableID(1, 2))
+	c.Assert(startKey, check.DeepEquals, recordKey(1, 4))
+	c.Assert(endKey, check.IsNil)
+	startKey, endKey = splitter.KeyRange(2)
+	c.Assert(startKey, check.IsNil)
+	c.Assert(endKey, check.IsNil)
+
+	// the regions are scanned only once
+	_, err = splitter.Expand(ctx, state, []model.TableID{1, 2})
+	c.Assert(err, check.IsNil)
+	c.Assert(scanned, check.Equals, 1)
+
+	// a new owner splits the table by the recorded split keys
+	splitter, err = newTableSplitter(cfg, "blackhole://", tableName,
+		func(ctx context.Context, span regionspan.Span) ([][]byte, error) {
+			return nil, errors.New("unexpected scan")
+		})
+	c.Assert(err, check.IsNil)
+	tables, err = splitter.Expand(ctx, state, []model.TableID{1, 2})
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.DeepEquals, []model.TableID{model.SpanTableID(1, 0), model.SpanTableID(1, 1), model.SpanTableID(1, 2), 2})
+	startKey, endKey = splitter.KeyRange(model.SpanTableID(1, 1))
+	c.Assert(startKey, check.DeepEquals, recordKey(1, 2))
+	c.Assert(endKey, check.DeepEquals, recordKey(1, 4))
+
+	// the split keys are removed once the table is dropped
+	tables, err = splitter.Expand(ctx, state, []model.TableID{2})
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.DeepEquals, []model.TableID{2})
+	tester.MustApplyPatches()
+	c.Assert(state.Status.SplitKeys, check.HasLen, 0)
+}
+
+func (s *splitSuite) TestSplitFewRegions(c *check.C) {
+	defer testleak.AfterTest(c)()
+	splitter := &tableSplitter{
+		scanRegions: func(ctx context.Context, span regionspan.Span) ([][]byte, error) {
+			return [][]byte{recordKey(1, 1)}, nil
+		},
+	}
+	// the table is split into 2 spans since it has only 2 regions
+	splitKeys, err := splitter.calculateSplitKeys(context.Background(), 1, 4)
+	c.Assert(err, check.IsNil)
+	c.Assert(splitKeys, check.DeepEquals, [][]byte{recordKey(1, 1)})
+
+	splitter.scanRegions = func(ctx context.Context, span regionspan.Span) ([][]byte, error) {
+		return nil, nil
+	}
+	splitKeys, err = splitter.calculateSplitKeys(context.Background(), 1, 4)
+	c.Assert(err, check.IsNil)
+	c.Assert(splitKeys, check.HasLen, 0)
+}
+
+func (s *splitSuite) TestNewTableSplitter(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	splitter, err := newTableSplitter(cfg, "kafka://127.0.0.1:9092/topic", nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(splitter, check.IsNil)
+
+	cfg.Scheduler.SplitRules = []*config.SplitRule{{Matcher: []string{"test.*"}, Spans: 2}}
+	cfg.Sink.DispatchRules = []*config.DispatchRule{{Matcher: []string{"test.hot"}, Dispatcher: "rowid"}}
+	splitter, err = newTableSplitter(cfg, "kafka://127.0.0.1:9092/topic", nil, nil)
+	c.Assert(err, check.IsNil)
+	// the rows of a table can be kept in order only if they are dispatched by keys
+	c.Assert(splitter.canSplit(model.TableName{Schema: "test", Table: "hot"}), check.IsTrue)
+	c.Assert(splitter.canSplit(model.TableName{Schema: "test", Table: "cold"}), check.IsFalse)
+	splitter, err = newTableSplitter(cfg, "mysql://127.0.0.1:3306/", nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(splitter.canSplit(model.TableName{Schema: "test", Table: "cold"}), check.IsTrue)
+
+	cfg.Cyclic.Enable = true
+	_, err = newTableSplitter(cfg, "mysql://127.0.0.1:3306/", nil, nil)
+	c.Assert(err, check.ErrorMatches, ".*cyclic replication is enabled.*")
+}
+
+func (s *splitSuite) TestSplitWithoutOldValue(c *check.C) {
+	defer testleak.AfterTest(c)()
+	// the handle of the table is the unique index, whose keys are out of the record keys split into spans
+	tableID := int64(1)
+	uniqueKey := tablecodec.EncodeTableIndexPrefix(tableID, 1)
+	inSpan := func(key []byte, span regionspan.Span) bool {
+		return bytes.Compare(key, span.Start) >= 0 && bytes.Compare(key, span.End) < 0
+	}
+	c.Assert(inSpan(uniqueKey, regionspan.GetTableSpan(tableID, true)), check.IsFalse)
+	c.Assert(inSpan(uniqueKey, regionspan.GetTableSpan(tableID, false)), check.IsTrue)
+
+	// the deletes are mounted from the index keys without the old value, so the table can't be split
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.EnableOldValue = false
+	cfg.Scheduler.SplitRules = []*config.SplitRule{{Matcher: []string{"test.*"}, Spans: 2}}
+	_, err := newTableSplitter(cfg, "mysql://127.0.0.1:3306/", nil, nil)
+	c.Assert(err, check.ErrorMatches, ".*old value is disabled.*")
+	cfg.EnableOldValue = true
+	splitter, err := newTableSplitter(cfg, "mysql://127.0.0.1:3306/", nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(splitter, check.NotNil)
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/cdc/puller/dbsorter"
 	psorter "github.com/pingcap/ticdc/cdc/puller/sorter"
 	"github.com/pingcap/ticdc/cdc/sink"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
@@ -39,6 +40,7 @@ import (
 	"github.com/pingcap/ticdc/pkg/notify"
 	"github.com/pingcap/ticdc/pkg/regionspan"
 	"github.com/pingcap/ticdc/pkg/retry"
+	"github.com/pingcap/ticdc/pkg/scheduler"
 	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	tidbkv "github.com/pingcap/tidb/kv"
@@ -115,7 +117,10 @@ type tableInfo struct {
 	markTableID   int64
 	mResolvedTs   uint64
 	mCheckpointTs uint64
-	workload      model.WorkloadInfo
+	// emittedRows and emittedBytes are the number and the approximate size of the rows emitted to the sink
+	emittedRows   uint64
+	emittedBytes  uint64
+	workloadMeter *scheduler.WorkloadMeter
 	cancel        context.CancelFunc
 }
 
@@ -200,7 +205,7 @@ func newProcessor(
 		session:       session,
 		sinkManager:   sinkManager,
 		ddlPuller:     ddlPuller,
-		mounter:       entry.NewMounter(schemaStorage, changefeed.Config.Mounter.WorkerNum, changefeed.Config.EnableOldValue),
+		mounter:       entry.NewMounter(schemaStorage, changefeed.Config.MounterWorkerNum(), changefeed.Config.EnableOldValue),
 		schemaStorage: schemaStorage,
 		errCh:         errCh,
 
@@ -472,7 +477,8 @@ func (p *oldProcessor) workloadWorker(ctx context.Context) error {
 		p.stateMu.Lock()
 		workload := make(model.TaskWorkload, len(p.tables))
 		for _, table := range p.tables {
-			workload[table.id] = table.workload
+			workload[table.id] = table.workloadMeter.Sample(time.Now(),
+				atomic.LoadUint64(&table.emittedRows), atomic.LoadUint64(&table.emittedBytes))
 		}
 		p.stateMu.Unlock()
 		err := p.etcdCli.PutTaskWorkload(ctx, p.changefeedID, p.captureInfo.ID, &workload)
@@ -801,13 +807,11 @@ func (p *oldProcessor) addTable(ctx context.Context, tableID int64, replicaInfo
 	ctx = util.PutTableInfoInCtx(ctx, tableID, tableName)
 	ctx, cancel := context.WithCancel(ctx)
 	table := &tableInfo{
-		id:         tableID,
-		name:       tableName,
-		resolvedTs: replicaInfo.StartTs,
+		id:            tableID,
+		name:          tableName,
+		resolvedTs:    replicaInfo.StartTs,
+		workloadMeter: scheduler.NewWorkloadMeter(),
 	}
-	// TODO(leoppro) calculate the workload of this table
-	// We temporarily set the value to constant 1
-	table.workload = model.WorkloadInfo{Workload: 1}
 
 	startPuller := func(tableID model.TableID, pResolvedTs *uint64, pCheckpointTs *uint64) sink.Sink {
 		// start table puller
@@ -859,6 +863,10 @@ func (p *oldProcessor) addTable(ctx context.Context, tableID int64, replicaInfo
 				p.sendError(errors.Trace(err))
 				return nil
 			}
+		case model.SortInLevelDB:
+			// the puller of the legacy processor always starts from the start ts of the table,
+			// the events pulled again simply overwrite the persisted ones.
+			sorter = dbsorter.NewSorter(p.changefeedID, tableID, replicaInfo.StartTs, util.CaptureAddrFromCtx(ctx))
 		default:
 			p.sendError(cerror.ErrUnknownSortEngine.GenWithStackByArgs(p.changefeed.Engine))
 			return nil
@@ -880,7 +888,7 @@ func (p *oldProcessor) addTable(ctx context.Context, tableID int64, replicaInfo
 
 		tableSink := p.sinkManager.CreateTableSink(tableID, replicaInfo.StartTs)
 		go func() {
-			p.sorterConsume(ctx, tableID, tableName, sorter, pResolvedTs, pCheckpointTs, replicaInfo, tableSink)
+			p.sorterConsume(ctx, tableID, tableName, sorter, pResolvedTs, pCheckpointTs, &table.emittedRows, &table.emittedBytes, replicaInfo, tableSink)
 		}()
 		return tableSink
 	}
@@ -930,6 +938,8 @@ func (p *oldProcessor) sorterConsume(
 	sorter puller.EventSorter,
 	pResolvedTs *uint64,
 	pCheckpointTs *uint64,
+	pEmittedRows *uint64,
+	pEmittedBytes *uint64,
 	replicaInfo *model.TableReplicaInfo,
 	sink sink.Sink,
 ) {
@@ -977,6 +987,7 @@ func (p *oldProcessor) sorterConsume(
 	rows := make([]*model.RowChangedEvent, 0, defaultSyncResolvedBatch)
 
 	flushRowChangedEvents := func() error {
+		var size int64
 		for _, ev := range events {
 			err := ev.WaitPrepare(ctx)
 			if err != nil {
@@ -986,6 +997,7 @@ func (p *oldProcessor) sorterConsume(
 				continue
 			}
 			rows = append(rows, ev.Row)
+			size += ev.RawKV.ApproximateSize()
 		}
 		failpoint.Inject("ProcessorSyncResolvedPreEmit", func() {
 			log.Info("Prepare to panic for ProcessorSyncResolvedPreEmit")
@@ -996,6 +1008,8 @@ func (p *oldProcessor) sorterConsume(
 		if err != nil {
 			return errors.Trace(err)
 		}
+		atomic.AddUint64(pEmittedRows, uint64(len(rows)))
+		atomic.AddUint64(pEmittedBytes, uint64(size))
 		events = events[:0]
 		rows = rows[:0]
 		return nil
@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/model"
 	tablepipeline "github.com/pingcap/ticdc/cdc/processor/pipeline"
 	cdcContext "github.com/pingcap/ticdc/pkg/context"
@@ -36,6 +37,7 @@ const (
 	commandTpUnknow commandTp = iota //nolint:varcheck,deadcode
 	commandTpClose
 	commandTpWriteDebugInfo
+	commandTpQuerySchemaStorage
 )
 
 type command struct {
@@ -44,6 +46,12 @@ type command struct {
 	done    chan struct{}
 }
 
+// schemaStorageQuery is the payload of the command querying the schema storage of a changefeed
+type schemaStorageQuery struct {
+	changefeedID model.ChangeFeedID
+	storage      entry.SchemaStorage
+}
+
 // Manager is a manager of processor, which maintains the state and behavior of processors
 type Manager struct {
 	processors map[model.ChangeFeedID]*processor
@@ -154,6 +162,21 @@ func (m *Manager) WriteDebugInfo(w io.Writer) {
 	}
 }
 
+// SchemaStorage returns the schema storage of the processor of the changefeed,
+// nil is returned if the changefeed is not running in the capture.
+func (m *Manager) SchemaStorage(changefeedID model.ChangeFeedID) entry.SchemaStorage {
+	timeout := time.Second * 3
+	query := &schemaStorageQuery{changefeedID: changefeedID}
+	done := m.sendCommand(commandTpQuerySchemaStorage, query)
+	select {
+	case <-done:
+		return query.storage
+	case <-time.After(timeout):
+		log.Warn("failed to query the schema storage of the processor", zap.String("changefeed", changefeedID))
+		return nil
+	}
+}
+
 func (m *Manager) sendCommand(tp commandTp, payload interface{}) chan struct{} {
 	timeout := time.Second * 3
 	cmd := &command{tp: tp, payload: payload, done: make(chan struct{})}
@@ -183,6 +206,11 @@ func (m *Manager) handleCommand() error {
 	case commandTpWriteDebugInfo:
 		w := cmd.payload.(io.Writer)
 		m.writeDebugInfo(w)
+	case commandTpQuerySchemaStorage:
+		query := cmd.payload.(*schemaStorageQuery)
+		if processor, exist := m.processors[query.changefeedID]; exist {
+			query.storage = processor.schemaStorage
+		}
 	default:
 		log.Warn("Unknown command in processor manager", zap.Any("command", cmd))
 	}
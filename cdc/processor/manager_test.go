@@ -21,6 +21,7 @@ import (
 
 	"github.com/pingcap/check"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/model"
 	tablepipeline "github.com/pingcap/ticdc/cdc/processor/pipeline"
 	"github.com/pingcap/ticdc/pkg/config"
@@ -167,6 +168,58 @@ func (s *managerSuite) TestDebugInfo(c *check.C) {
 	<-done
 }
 
+func (s *managerSuite) TestQuerySchemaStorage(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := cdcContext.NewBackendContext4Test(false)
+	s.resetSuit(ctx, c)
+	var err error
+
+	s.state.Changefeeds["test-changefeed"] = model.NewChangefeedReactorState("test-changefeed")
+	s.state.Changefeeds["test-changefeed"].PatchInfo(func(info *model.ChangeFeedInfo) (*model.ChangeFeedInfo, bool, error) {
+		return &model.ChangeFeedInfo{
+			SinkURI:    "blackhole://",
+			CreateTime: time.Now(),
+			StartTs:    0,
+			TargetTs:   math.MaxUint64,
+			Config:     config.GetDefaultReplicaConfig(),
+		}, true, nil
+	})
+	s.state.Changefeeds["test-changefeed"].PatchStatus(func(status *model.ChangeFeedStatus) (*model.ChangeFeedStatus, bool, error) {
+		return &model.ChangeFeedStatus{}, true, nil
+	})
+	s.state.Changefeeds["test-changefeed"].PatchTaskStatus(ctx.GlobalVars().CaptureInfo.ID, func(status *model.TaskStatus) (*model.TaskStatus, bool, error) {
+		return &model.TaskStatus{
+			Tables: map[int64]*model.TableReplicaInfo{1: {}},
+		}, true, nil
+	})
+	s.tester.MustApplyPatches()
+	_, err = s.manager.Tick(ctx, s.state)
+	c.Assert(err, check.IsNil)
+	s.tester.MustApplyPatches()
+	c.Assert(s.manager.processors, check.HasLen, 1)
+	schemaStorage, err := entry.NewSchemaStorage(nil, 0, nil, false)
+	c.Assert(err, check.IsNil)
+	s.manager.processors["test-changefeed"].schemaStorage = schemaStorage
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, err = s.manager.Tick(ctx, s.state)
+			if err != nil {
+				c.Assert(cerrors.ErrReactorFinished.Equal(errors.Cause(err)), check.IsTrue)
+				return
+			}
+			c.Assert(err, check.IsNil)
+			s.tester.MustApplyPatches()
+		}
+	}()
+	c.Assert(s.manager.SchemaStorage("test-changefeed"), check.Equals, schemaStorage)
+	c.Assert(s.manager.SchemaStorage("unknown-changefeed"), check.IsNil)
+	s.manager.AsyncClose()
+	<-done
+}
+
 func (s *managerSuite) TestClose(c *check.C) {
 	defer testleak.AfterTest(c)()
 	ctx := cdcContext.NewBackendContext4Test(false)
@@ -15,16 +15,23 @@ package pipeline
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/puller"
+	psorter "github.com/pingcap/ticdc/cdc/puller/sorter"
+	"github.com/pingcap/ticdc/cdc/recorder"
 	"github.com/pingcap/ticdc/pkg/config"
 	cdcContext "github.com/pingcap/ticdc/pkg/context"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/memquota"
 	"github.com/pingcap/ticdc/pkg/pipeline"
 	"github.com/pingcap/ticdc/pkg/regionspan"
 	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/tikv/client-go/v2/oracle"
+	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -37,24 +44,100 @@ type pullerNode struct {
 	replicaInfo *model.TableReplicaInfo
 	cancel      context.CancelFunc
 	wg          errgroup.Group
+	// barrierTs is the latest barrier ts received by the puller node
+	barrierTs model.Ts
+	// resumable is the sorter which persists the events, it's nil if the sort engine doesn't persist the events
+	resumable resumableSorter
+	// recordHeader describes the recorded table, it's nil if the events of the table are not recorded
+	recordHeader *recorder.Header
+	// usage is the memory of the events sent to the sorter node
+	usage *pullerUsage
 }
 
 func newPullerNode(
 	limitter *puller.BlurResourceLimitter,
-	tableID model.TableID, replicaInfo *model.TableReplicaInfo, tableName string) pipeline.Node {
+	tableID model.TableID, replicaInfo *model.TableReplicaInfo, tableName string, resumable resumableSorter,
+	recordHeader *recorder.Header, usage *pullerUsage) pipeline.Node {
 	return &pullerNode{
-		limitter:    limitter,
-		tableID:     tableID,
-		replicaInfo: replicaInfo,
-		tableName:   tableName,
+		limitter:     limitter,
+		tableID:      tableID,
+		replicaInfo:  replicaInfo,
+		tableName:    tableName,
+		resumable:    resumable,
+		recordHeader: recordHeader,
+		usage:        usage,
 	}
 }
 
+// pullerUsage is the memory of the events sent by the puller node but not received by the sorter node yet.
+// It's kept per table, because the messages in the pipeline are dropped when the pipeline is canceled, and
+// the usage of them is released after the pipeline stops.
+type pullerUsage struct {
+	size int64
+}
+
+func (u *pullerUsage) add(delta int64) {
+	atomic.AddInt64(&u.size, delta)
+	memquota.GetGlobalArbitrator().AddUsage(memquota.ComponentPuller, delta)
+}
+
+// releaseAll releases the usage of the events which are never received by the sorter node
+func (u *pullerUsage) releaseAll() {
+	if size := atomic.SwapInt64(&u.size, 0); size != 0 {
+		memquota.GetGlobalArbitrator().AddUsage(memquota.ComponentPuller, -size)
+	}
+}
+
+// newRecorder creates the writer recording the events pulled from the start ts, the table is not
+// recorded if the writer can't be created, because the recording is only used for debugging.
+func (n *pullerNode) newRecorder(startTs model.Ts) *recorder.Writer {
+	if n.recordHeader == nil {
+		return nil
+	}
+	cfg := config.GetGlobalServerConfig().Recorder
+	n.recordHeader.StartTs = startTs
+	w, err := recorder.NewWriter(cfg.Dir, n.recordHeader, cfg.MaxFileSize)
+	if err != nil {
+		log.Warn("failed to record the events of the table", zap.String("table", n.tableName), zap.Error(err))
+		return nil
+	}
+	log.Info("start to record the events of the table", zap.String("table", n.tableName), zap.String("path", w.Path()))
+	return w
+}
+
+// record writes the event to the record file, ErrRecordSchemaChanged is returned after the schema change
+// of the table is written, because the events after it can't be replayed with the recorded schema.
+func (n *pullerNode) record(rec *recorder.Writer, rawKV *model.RawKVEntry) error {
+	if err := rec.Write(rawKV); err != nil {
+		return errors.Trace(err)
+	}
+	if rawKV.OpType != model.OpTypeResolved || n.recordHeader.SchemaChangeTs == nil {
+		return nil
+	}
+	ts, changed := n.recordHeader.SchemaChangeTs()
+	if !changed {
+		return nil
+	}
+	if err := rec.WriteSchemaChange(ts); err != nil {
+		return errors.Trace(err)
+	}
+	return cerror.ErrRecordSchemaChanged.GenWithStackByArgs(rec.Path(), ts)
+}
+
 func (n *pullerNode) tableSpan(ctx cdcContext.Context) []regionspan.Span {
 	// start table puller
 	config := ctx.ChangefeedVars().Info.Config
 	spans := make([]regionspan.Span, 0, 4)
-	spans = append(spans, regionspan.GetTableSpan(n.tableID, config.EnableOldValue))
+	// a span of a split table only pulls the record keys in its key range
+	tableID, _, isSpan := model.DecodeSpanTableID(n.tableID)
+	span := regionspan.GetTableSpan(tableID, config.EnableOldValue || isSpan)
+	if n.replicaInfo.StartKey != nil {
+		span.Start = n.replicaInfo.StartKey
+	}
+	if n.replicaInfo.EndKey != nil {
+		span.End = n.replicaInfo.EndKey
+	}
+	spans = append(spans, span)
 
 	if config.Cyclic.IsEnabled() && n.replicaInfo.MarkTableID != 0 {
 		spans = append(spans, regionspan.GetTableSpan(n.replicaInfo.MarkTableID, config.EnableOldValue))
@@ -66,16 +149,48 @@ func (n *pullerNode) Init(ctx pipeline.NodeContext) error {
 	metricTableResolvedTsGauge := tableResolvedTsGauge.WithLabelValues(ctx.ChangefeedVars().ID, ctx.GlobalVars().CaptureInfo.AdvertiseAddr, n.tableName)
 	globalConfig := config.GetGlobalServerConfig()
 	config := ctx.ChangefeedVars().Info.Config
+	startTs := n.replicaInfo.StartTs
+	if n.resumable != nil {
+		// the events before the resume ts are output by the sorter
+		resumeTs, err := n.resumable.ResumeTs()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		startTs = resumeTs
+	}
 	ctxC, cancel := context.WithCancel(ctx)
 	ctxC = util.PutTableInfoInCtx(ctxC, n.tableID, n.tableName)
 	ctxC = util.PutChangefeedIDInCtx(ctxC, ctx.ChangefeedVars().ID)
-	plr := puller.NewPuller(ctxC, ctx.GlobalVars().PDClient, globalConfig.Security, ctx.GlobalVars().KVStorage,
-		n.replicaInfo.StartTs, n.tableSpan(ctx), n.limitter, config.EnableOldValue)
+	if config.Resource != nil {
+		ctxC = util.PutRegionScanLimitInCtx(ctxC, config.Resource.RegionScanLimit)
+		ctxC = util.PutScanBytesPerSecondInCtx(ctxC, config.Resource.ScanBytesPerSecond)
+	}
+	ctxC = util.PutScanPriorityInCtx(ctxC, config.Resource.PriorityWeight())
+	var plr puller.Puller
+	if globalConfig.SharedPuller {
+		plr = puller.NewSharedPuller(ctxC, ctx.GlobalVars().PDClient, globalConfig.Security, ctx.GlobalVars().KVStorage,
+			startTs, n.tableSpan(ctx), config.EnableOldValue)
+	} else {
+		plr = puller.NewPuller(ctxC, ctx.GlobalVars().PDClient, globalConfig.Security, ctx.GlobalVars().KVStorage,
+			startTs, n.tableSpan(ctx), n.limitter, config.EnableOldValue)
+	}
 	n.wg.Go(func() error {
 		ctx.Throw(errors.Trace(plr.Run(ctxC)))
 		return nil
 	})
+	rec := n.newRecorder(startTs)
 	n.wg.Go(func() error {
+		defer func() {
+			if rec != nil {
+				if err := rec.Close(); err != nil {
+					log.Warn("failed to close the record file", zap.String("path", rec.Path()), zap.Error(err))
+				}
+			}
+		}()
+		arbitrator := memquota.GetGlobalArbitrator()
+		priority := config.Resource.PriorityWeight()
+		useUnifiedSorter := ctx.ChangefeedVars().Info.Engine == model.SortUnified
+		resolvedTs := startTs
 		for {
 			select {
 			case <-ctxC.Done():
@@ -86,6 +201,30 @@ func (n *pullerNode) Init(ctx pipeline.NodeContext) error {
 				}
 				if rawKV.OpType == model.OpTypeResolved {
 					metricTableResolvedTsGauge.Set(float64(oracle.ExtractPhysical(rawKV.CRTs)))
+					resolvedTs = rawKV.CRTs
+				} else {
+					// Stop receiving the events from TiKV if the memory is exhausted, or yield the memory to the
+					// changefeeds with higher priorities. The tables not resolved beyond the barrier are never
+					// blocked, because the sinks release the memory only if the barrier advances.
+					if resolvedTs > atomic.LoadUint64(&n.barrierTs) {
+						if err := arbitrator.WaitAvailable(ctxC, priority); err != nil {
+							return nil
+						}
+						// stop receiving the events before the files written by the sorter exceed the disk quota
+						if useUnifiedSorter {
+							if err := psorter.WaitDiskAvailable(ctxC); err != nil {
+								return nil
+							}
+						}
+					}
+					n.usage.add(rawKV.ApproximateSize())
+				}
+				if rec != nil {
+					if err := n.record(rec, rawKV); err != nil {
+						log.Warn("stop recording the events of the table", zap.String("path", rec.Path()), zap.Error(err))
+						rec.Close() //nolint:errcheck
+						rec = nil
+					}
 				}
 				pEvent := model.NewPolymorphicEvent(rawKV)
 				ctx.SendToNextNode(pipeline.PolymorphicEventMessage(pEvent))
@@ -98,8 +237,12 @@ func (n *pullerNode) Init(ctx pipeline.NodeContext) error {
 
 // Receive receives the message from the previous node
 func (n *pullerNode) Receive(ctx pipeline.NodeContext) error {
+	msg := ctx.Message()
+	if msg.Tp == pipeline.MessageTypeBarrier {
+		atomic.StoreUint64(&n.barrierTs, msg.BarrierTs)
+	}
 	// just forward any messages to the next node
-	ctx.SendToNextNode(ctx.Message())
+	ctx.SendToNextNode(msg)
 	return nil
 }
 
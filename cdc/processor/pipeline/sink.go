@@ -74,6 +74,10 @@ type sinkNode struct {
 
 	eventBuffer []*model.PolymorphicEvent
 	rowBuffer   []*model.RowChangedEvent
+	// bufferSize is the length of eventBuffer, it can be read by other goroutines
+	bufferSize int64
+	// emittedRows is the number of rows emitted to the sink
+	emittedRows uint64
 
 	flowController tableFlowController
 }
@@ -94,6 +98,8 @@ func newSinkNode(sink sink.Sink, startTs model.Ts, targetTs model.Ts, flowContro
 func (n *sinkNode) ResolvedTs() model.Ts   { return atomic.LoadUint64(&n.resolvedTs) }
 func (n *sinkNode) CheckpointTs() model.Ts { return atomic.LoadUint64(&n.checkpointTs) }
 func (n *sinkNode) Status() TableStatus    { return n.status.Load() }
+func (n *sinkNode) BufferSize() uint64     { return uint64(atomic.LoadInt64(&n.bufferSize)) }
+func (n *sinkNode) EmittedRows() uint64    { return atomic.LoadUint64(&n.emittedRows) }
 
 func (n *sinkNode) Init(ctx pipeline.NodeContext) error {
 	// do nothing
@@ -143,6 +149,7 @@ func (n *sinkNode) flushSink(ctx pipeline.NodeContext, resolvedTs model.Ts) (err
 
 func (n *sinkNode) emitEvent(ctx pipeline.NodeContext, event *model.PolymorphicEvent) error {
 	n.eventBuffer = append(n.eventBuffer, event)
+	atomic.StoreInt64(&n.bufferSize, int64(len(n.eventBuffer)))
 	if len(n.eventBuffer) >= defaultSyncResolvedBatch {
 		if err := n.flushRow2Sink(ctx); err != nil {
 			return errors.Trace(err)
@@ -172,8 +179,10 @@ func (n *sinkNode) flushRow2Sink(ctx pipeline.NodeContext) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	atomic.AddUint64(&n.emittedRows, uint64(len(n.rowBuffer)))
 	n.rowBuffer = n.rowBuffer[:0]
 	n.eventBuffer = n.eventBuffer[:0]
+	atomic.StoreInt64(&n.bufferSize, 0)
 	return nil
 }
 
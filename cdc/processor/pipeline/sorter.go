@@ -16,6 +16,7 @@ package pipeline
 import (
 	"context"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -36,8 +37,18 @@ const (
 	flushMemoryMetricsDuration = time.Second * 5
 )
 
+// resumableSorter is a sorter which persists the events until they're flushed to the sink, so the puller
+// resumes pulling the events from the resume ts instead of the start ts of the table after a restart.
+type resumableSorter interface {
+	puller.EventSorter
+	// ResumeTs returns the ts from which the puller pulls the events
+	ResumeTs() (model.Ts, error)
+}
+
 type sorterNode struct {
 	sorter puller.EventSorter
+	// resumable is the sorter created before the node is initialized if the sort engine persists the events
+	resumable resumableSorter
 
 	tableID   model.TableID
 	tableName string // quoted schema and table, used in metircs only
@@ -46,17 +57,29 @@ type sorterNode struct {
 	flowController tableFlowController
 
 	mounter entry.Mounter
+	// pullerUsage is the memory of the events sent by the puller node, it's released when the events are received
+	pullerUsage *pullerUsage
+
+	// backlog is the number of row events added to the sorter but not output by the sorter yet
+	backlog int64
+	// outputBytes is the approximate size of the row events output by the sorter
+	outputBytes uint64
 
 	wg     errgroup.Group
 	cancel context.CancelFunc
 }
 
-func newSorterNode(tableName string, tableID model.TableID, flowController tableFlowController, mounter entry.Mounter) pipeline.Node {
+func newSorterNode(
+	tableName string, tableID model.TableID, flowController tableFlowController, mounter entry.Mounter, resumable resumableSorter,
+	pullerUsage *pullerUsage,
+) *sorterNode {
 	return &sorterNode{
 		tableName:      tableName,
 		tableID:        tableID,
 		flowController: flowController,
 		mounter:        mounter,
+		resumable:      resumable,
+		pullerUsage:    pullerUsage,
 	}
 }
 
@@ -93,6 +116,8 @@ func (n *sorterNode) Init(ctx pipeline.NodeContext) error {
 		if err != nil {
 			return errors.Trace(err)
 		}
+	case model.SortInLevelDB:
+		sorter = n.resumable
 	default:
 		return cerror.ErrUnknownSortEngine.GenWithStackByArgs(sortEngine)
 	}
@@ -135,7 +160,9 @@ func (n *sorterNode) Init(ctx pipeline.NodeContext) error {
 					log.Panic("unexpected empty msg", zap.Reflect("msg", msg))
 				}
 				if msg.RawKV.OpType != model.OpTypeResolved {
+					atomic.AddInt64(&n.backlog, -1)
 					size := uint64(msg.RawKV.ApproximateSize())
+					atomic.AddUint64(&n.outputBytes, size)
 					commitTs := msg.CRTs
 					// We interpolate a resolved-ts if none has been sent for some time.
 					if time.Since(lastSendResolvedTsTime) > resolvedTsInterpolateInterval {
@@ -204,6 +231,10 @@ func (n *sorterNode) Receive(ctx pipeline.NodeContext) error {
 	msg := ctx.Message()
 	switch msg.Tp {
 	case pipeline.MessageTypePolymorphicEvent:
+		if msg.PolymorphicEvent.RawKV.OpType != model.OpTypeResolved {
+			atomic.AddInt64(&n.backlog, 1)
+			n.pullerUsage.add(-msg.PolymorphicEvent.RawKV.ApproximateSize())
+		}
 		n.sorter.AddEntry(ctx, msg.PolymorphicEvent)
 	default:
 		ctx.SendToNextNode(msg)
@@ -211,6 +242,20 @@ func (n *sorterNode) Receive(ctx pipeline.NodeContext) error {
 	return nil
 }
 
+// Backlog returns the number of row events waiting in the sorter
+func (n *sorterNode) Backlog() uint64 {
+	backlog := atomic.LoadInt64(&n.backlog)
+	if backlog < 0 {
+		return 0
+	}
+	return uint64(backlog)
+}
+
+// OutputBytes returns the approximate size of the row events output by the sorter
+func (n *sorterNode) OutputBytes() uint64 {
+	return atomic.LoadUint64(&n.outputBytes)
+}
+
 func (n *sorterNode) Destroy(ctx pipeline.NodeContext) error {
 	defer tableMemoryGauge.DeleteLabelValues(ctx.ChangefeedVars().ID, ctx.GlobalVars().CaptureInfo.AdvertiseAddr, n.tableName)
 	n.cancel()
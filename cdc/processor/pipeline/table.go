@@ -21,12 +21,16 @@ import (
 	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/cdc/puller/dbsorter"
+	"github.com/pingcap/ticdc/cdc/recorder"
 	"github.com/pingcap/ticdc/cdc/sink"
 	"github.com/pingcap/ticdc/cdc/sink/common"
 	serverConfig "github.com/pingcap/ticdc/pkg/config"
 	cdcContext "github.com/pingcap/ticdc/pkg/context"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/memquota"
 	"github.com/pingcap/ticdc/pkg/pipeline"
+	"github.com/pingcap/ticdc/pkg/scheduler"
 	"go.uber.org/zap"
 )
 
@@ -54,6 +58,8 @@ type TablePipeline interface {
 	Workload() model.WorkloadInfo
 	// Status returns the status of this table pipeline
 	Status() TableStatus
+	// ReplicationStatus returns the replication status of this table
+	ReplicationStatus() *model.TableReplicationStatus
 	// Cancel stops this table pipeline immediately and destroy all resources created by this table pipeline
 	Cancel()
 	// Wait waits for table pipeline destroyed
@@ -63,12 +69,17 @@ type TablePipeline interface {
 type tablePipelineImpl struct {
 	p *pipeline.Pipeline
 
-	tableID     int64
-	markTableID int64
-	tableName   string // quoted schema and table, used in metircs only
-
-	sinkNode *sinkNode
-	cancel   context.CancelFunc
+	changefeedID model.ChangeFeedID
+	tableID      int64
+	markTableID  int64
+	tableName    string // quoted schema and table, used in metircs only
+
+	sorterNode     *sorterNode
+	sinkNode       *sinkNode
+	flowController tableFlowController
+	pullerUsage    *pullerUsage
+	workloadMeter  *scheduler.WorkloadMeter
+	cancel         context.CancelFunc
 }
 
 // TODO find a better name or avoid using an interface
@@ -117,13 +128,10 @@ func (t *tablePipelineImpl) AsyncStop(targetTs model.Ts) bool {
 	return true
 }
 
-var workload = model.WorkloadInfo{Workload: 1}
-
-// Workload returns the workload of this table
+// Workload returns the workload of this table, which is measured by
+// the rows emitted by the sink node and the bytes output by the sorter node.
 func (t *tablePipelineImpl) Workload() model.WorkloadInfo {
-	// TODO(leoppro) calculate the workload of this table
-	// We temporarily set the value to constant 1
-	return workload
+	return t.workloadMeter.Sample(time.Now(), t.sinkNode.EmittedRows(), t.sorterNode.OutputBytes())
 }
 
 // Status returns the status of this table pipeline
@@ -131,6 +139,18 @@ func (t *tablePipelineImpl) Status() TableStatus {
 	return t.sinkNode.Status()
 }
 
+// ReplicationStatus returns the replication status of this table
+func (t *tablePipelineImpl) ReplicationStatus() *model.TableReplicationStatus {
+	return &model.TableReplicationStatus{
+		Name:              t.tableName,
+		ResolvedTs:        t.ResolvedTs(),
+		CheckpointTs:      t.CheckpointTs(),
+		SorterBacklog:     t.sorterNode.Backlog(),
+		SinkBufferSize:    t.sinkNode.BufferSize(),
+		MemoryConsumption: t.flowController.GetConsumption(),
+	}
+}
+
 // ID returns the ID of source table and mark table
 func (t *tablePipelineImpl) ID() (tableID, markTableID int64) {
 	return t.tableID, t.markTableID
@@ -149,6 +169,9 @@ func (t *tablePipelineImpl) Cancel() {
 // Wait waits for table pipeline destroyed
 func (t *tablePipelineImpl) Wait() {
 	t.p.Wait()
+	// the events dropped by the canceled pipeline are never received by the sorter node
+	t.pullerUsage.releaseAll()
+	memquota.GetGlobalArbitrator().Unregister(t.changefeedID, t.tableID)
 }
 
 // NewTablePipeline creates a table pipeline
@@ -160,13 +183,17 @@ func NewTablePipeline(ctx cdcContext.Context,
 	tableName string,
 	replicaInfo *model.TableReplicaInfo,
 	sink sink.Sink,
-	targetTs model.Ts) TablePipeline {
+	targetTs model.Ts,
+	recordHeader *recorder.Header) TablePipeline {
 	ctx, cancel := cdcContext.WithCancel(ctx)
 	tablePipeline := &tablePipelineImpl{
-		tableID:     tableID,
-		markTableID: replicaInfo.MarkTableID,
-		tableName:   tableName,
-		cancel:      cancel,
+		changefeedID:  ctx.ChangefeedVars().ID,
+		tableID:       tableID,
+		markTableID:   replicaInfo.MarkTableID,
+		tableName:     tableName,
+		pullerUsage:   new(pullerUsage),
+		workloadMeter: scheduler.NewWorkloadMeter(),
+		cancel:        cancel,
 	}
 
 	perTableMemoryQuota := serverConfig.GetGlobalServerConfig().PerTableMemoryQuota
@@ -176,11 +203,28 @@ func NewTablePipeline(ctx cdcContext.Context,
 		zap.Int64("table-id", tableID),
 		zap.Uint64("quota", perTableMemoryQuota))
 	flowController := common.NewTableFlowController(perTableMemoryQuota)
+	config := ctx.ChangefeedVars().Info.Config
+	var memoryBudget uint64
+	if config.Resource != nil {
+		memoryBudget = config.Resource.MemoryQuota
+	}
+	memquota.GetGlobalArbitrator().Register(
+		ctx.ChangefeedVars().ID, tableID, config.Resource.PriorityWeight(), memoryBudget, flowController)
+	// the sorter persisting the events is shared by the puller node and the sorter node,
+	// because the puller pulls the events from the ts persisted by the sorter
+	var resumable resumableSorter
+	if ctx.ChangefeedVars().Info.Engine == model.SortInLevelDB {
+		resumable = dbsorter.NewSorter(
+			ctx.ChangefeedVars().ID, tableID, replicaInfo.StartTs, ctx.GlobalVars().CaptureInfo.AdvertiseAddr)
+	}
 	p := pipeline.NewPipeline(ctx, 500*time.Millisecond)
-	p.AppendNode(ctx, "puller", newPullerNode(limitter, tableID, replicaInfo, tableName))
-	p.AppendNode(ctx, "sorter", newSorterNode(tableName, tableID, flowController, mounter))
+	p.AppendNode(ctx, "puller", newPullerNode(limitter, tableID, replicaInfo, tableName, resumable, recordHeader,
+		tablePipeline.pullerUsage))
+	tablePipeline.flowController = flowController
+	tablePipeline.sorterNode = newSorterNode(tableName, tableID, flowController, mounter, resumable,
+		tablePipeline.pullerUsage)
+	p.AppendNode(ctx, "sorter", tablePipeline.sorterNode)
 	p.AppendNode(ctx, "mounter", newMounterNode())
-	config := ctx.ChangefeedVars().Info.Config
 	if config.Cyclic != nil && config.Cyclic.IsEnabled() {
 		p.AppendNode(ctx, "cyclic", newCyclicMarkNode(replicaInfo.MarkTableID))
 	}
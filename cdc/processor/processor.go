@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"reflect"
 	"strconv"
 	"sync"
 	"time"
@@ -30,6 +31,8 @@ import (
 	"github.com/pingcap/ticdc/cdc/model"
 	tablepipeline "github.com/pingcap/ticdc/cdc/processor/pipeline"
 	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/cdc/puller/dbsorter"
+	"github.com/pingcap/ticdc/cdc/recorder"
 	"github.com/pingcap/ticdc/cdc/sink"
 	"github.com/pingcap/ticdc/pkg/config"
 	cdcContext "github.com/pingcap/ticdc/pkg/context"
@@ -50,6 +53,9 @@ const (
 	defaultMemBufferCapacity int64 = 10 * 1024 * 1024 * 1024 // 10G
 
 	schemaStorageGCLag = time.Minute * 20
+
+	// tableStatusReportInterval is the interval of reporting the replication status of tables to etcd
+	tableStatusReportInterval = time.Second * 10
 )
 
 type processor struct {
@@ -70,6 +76,8 @@ type processor struct {
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
 
+	lastTableStatusReportTime time.Time
+
 	lazyInit            func(ctx cdcContext.Context) error
 	createTablePipeline func(ctx cdcContext.Context, tableID model.TableID, replicaInfo *model.TableReplicaInfo) (tablepipeline.TablePipeline, error)
 
@@ -92,6 +100,8 @@ func newProcessor(ctx cdcContext.Context) *processor {
 		changefeedID: changefeedID,
 		captureInfo:  ctx.GlobalVars().CaptureInfo,
 		cancel:       func() {},
+		// delay the first report until the tables have started running
+		lastTableStatusReportTime: time.Now(),
 
 		metricResolvedTsGauge:       resolvedTsGauge.WithLabelValues(changefeedID, advertiseAddr),
 		metricResolvedTsLagGauge:    resolvedTsLagGauge.WithLabelValues(changefeedID, advertiseAddr),
@@ -188,12 +198,25 @@ func (p *processor) tick(ctx cdcContext.Context, state *model.ChangefeedReactorS
 	if err := p.handleWorkload(); err != nil {
 		return nil, errors.Trace(err)
 	}
+	if err := p.handleTableStatus(); err != nil {
+		return nil, errors.Trace(err)
+	}
 	if err := p.doGCSchemaStorage(); err != nil {
 		return nil, errors.Trace(err)
 	}
+	p.updateSorterCheckpointTs()
 	return p.changefeed, nil
 }
 
+// updateSorterCheckpointTs tells the persistent sorter the global checkpoint ts of the changefeed,
+// the events before the checkpoint ts are never replicated again, so the sorter can clean them up.
+func (p *processor) updateSorterCheckpointTs() {
+	if p.changefeed.Info.Engine != model.SortInLevelDB || p.changefeed.Status == nil {
+		return
+	}
+	dbsorter.UpdateCheckpointTs(p.changefeedID, p.changefeed.Status.CheckpointTs)
+}
+
 // checkChangefeedNormal checks if the changefeed is runnable.
 func (p *processor) checkChangefeedNormal() bool {
 	// check the state in this tick, make sure that the admin job type of the changefeed is not stopped
@@ -270,7 +293,7 @@ func (p *processor) lazyInitImpl(ctx cdcContext.Context) error {
 
 	stdCtx := util.PutChangefeedIDInCtx(ctx, p.changefeed.ID)
 
-	p.mounter = entry.NewMounter(p.schemaStorage, p.changefeed.Info.Config.Mounter.WorkerNum, p.changefeed.Info.Config.EnableOldValue)
+	p.mounter = entry.NewMounter(p.schemaStorage, p.changefeed.Info.Config.MounterWorkerNum(), p.changefeed.Info.Config.EnableOldValue)
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
@@ -577,11 +600,18 @@ func (p *processor) handlePosition() error {
 	}
 
 	minCheckpointTs := minResolvedTs
-	for _, table := range p.tables {
+	var blockedTables map[model.TableID]model.Ts
+	for tableID, table := range p.tables {
 		ts := table.CheckpointTs()
 		if ts < minCheckpointTs {
 			minCheckpointTs = ts
 		}
+		if p.tableBarrierTs(tableID) != 0 {
+			if blockedTables == nil {
+				blockedTables = make(map[model.TableID]model.Ts)
+			}
+			blockedTables[tableID] = ts
+		}
 	}
 
 	resolvedPhyTs := oracle.ExtractPhysical(minResolvedTs)
@@ -598,7 +628,8 @@ func (p *processor) handlePosition() error {
 
 	// minResolvedTs and minCheckpointTs may less than global resolved ts and global checkpoint ts when a new table added, the startTs of the new table is less than global checkpoint ts.
 	if minResolvedTs != p.changefeed.TaskPositions[p.captureInfo.ID].ResolvedTs ||
-		minCheckpointTs != p.changefeed.TaskPositions[p.captureInfo.ID].CheckPointTs {
+		minCheckpointTs != p.changefeed.TaskPositions[p.captureInfo.ID].CheckPointTs ||
+		!reflect.DeepEqual(blockedTables, p.changefeed.TaskPositions[p.captureInfo.ID].BlockedTables) {
 		p.changefeed.PatchTaskPosition(p.captureInfo.ID, func(position *model.TaskPosition) (*model.TaskPosition, bool, error) {
 			failpoint.Inject("ProcessorUpdatePositionDelaying", nil)
 			if position == nil {
@@ -609,6 +640,7 @@ func (p *processor) handlePosition() error {
 			}
 			position.CheckPointTs = minCheckpointTs
 			position.ResolvedTs = minResolvedTs
+			position.BlockedTables = blockedTables
 			return position, true, nil
 		})
 	}
@@ -617,6 +649,12 @@ func (p *processor) handlePosition() error {
 
 // handleWorkload calculates the workload of all tables
 func (p *processor) handleWorkload() error {
+	// sampling the workload updates the meter of the table, so each table is sampled once per tick,
+	// outside of the patch function which may be applied more than once
+	tableWorkloads := make(model.TaskWorkload, len(p.tables))
+	for tableID, table := range p.tables {
+		tableWorkloads[tableID] = table.Workload()
+	}
 	p.changefeed.PatchTaskWorkload(p.captureInfo.ID, func(workloads model.TaskWorkload) (model.TaskWorkload, bool, error) {
 		changed := false
 		if workloads == nil {
@@ -628,9 +666,9 @@ func (p *processor) handleWorkload() error {
 				changed = true
 			}
 		}
-		for tableID, table := range p.tables {
-			if workloads[tableID] != table.Workload() {
-				workloads[tableID] = table.Workload()
+		for tableID, workload := range tableWorkloads {
+			if workloads[tableID] != workload {
+				workloads[tableID] = workload
 				changed = true
 			}
 		}
@@ -639,15 +677,50 @@ func (p *processor) handleWorkload() error {
 	return nil
 }
 
+// handleTableStatus reports the replication status of all tables periodically
+func (p *processor) handleTableStatus() error {
+	if time.Since(p.lastTableStatusReportTime) < tableStatusReportInterval {
+		return nil
+	}
+	p.lastTableStatusReportTime = time.Now()
+	tables := make(map[model.TableID]*model.TableReplicationStatus, len(p.tables))
+	for tableID, table := range p.tables {
+		tables[tableID] = table.ReplicationStatus()
+	}
+	p.changefeed.PatchTaskPosition(p.captureInfo.ID, func(position *model.TaskPosition) (*model.TaskPosition, bool, error) {
+		if position == nil {
+			log.Warn("task position is not exist, skip to update table status", zap.String("changefeed", p.changefeed.ID))
+			return nil, false, nil
+		}
+		position.Tables = tables
+		return position, true, nil
+	})
+	return nil
+}
+
 // pushResolvedTs2Table sends global resolved ts to all the table pipelines.
 func (p *processor) pushResolvedTs2Table() error {
 	resolvedTs := p.changefeed.Status.ResolvedTs
-	for _, table := range p.tables {
-		table.UpdateBarrierTs(resolvedTs)
+	for tableID, table := range p.tables {
+		barrierTs := resolvedTs
+		tableBarrierTs := p.tableBarrierTs(tableID)
+		if tableBarrierTs != 0 && tableBarrierTs < barrierTs {
+			barrierTs = tableBarrierTs
+		}
+		table.UpdateBarrierTs(barrierTs)
+		if p.sinkManager != nil {
+			p.sinkManager.SetTableBarrierTs(tableID, tableBarrierTs)
+		}
 	}
 	return nil
 }
 
+// tableBarrierTs returns the table barrier of the table set by the owner, 0 means the table is not blocked
+func (p *processor) tableBarrierTs(tableID model.TableID) model.Ts {
+	physicalTableID, _, _ := model.DecodeSpanTableID(tableID)
+	return p.changefeed.Status.TableBarriers[physicalTableID]
+}
+
 // addTable creates a new table pipeline and adds it to the `p.tables`
 func (p *processor) addTable(ctx cdcContext.Context, tableID model.TableID, replicaInfo *model.TableReplicaInfo) error {
 	if table, ok := p.tables[tableID]; ok {
@@ -688,22 +761,24 @@ func (p *processor) createTablePipelineImpl(ctx cdcContext.Context, tableID mode
 		p.sendError(err)
 		return nil
 	})
+	// the ID of a span of a split table is a span table ID, which is decoded to the ID of the table
+	physicalTableID, spanIndex, isSpan := model.DecodeSpanTableID(tableID)
 	var tableName *model.TableName
 	retry.Run(time.Millisecond*5, 3, func() error { //nolint:errcheck
-		if name, ok := p.schemaStorage.GetLastSnapshot().GetTableNameByID(tableID); ok {
+		if name, ok := p.schemaStorage.GetLastSnapshot().GetTableNameByID(physicalTableID); ok {
 			tableName = &name
 			return nil
 		}
-		return errors.Errorf("failed to get table name, fallback to use table id: %d", tableID)
+		return errors.Errorf("failed to get table name, fallback to use table id: %d", physicalTableID)
 	})
 	if p.changefeed.Info.Config.Cyclic.IsEnabled() {
 		// Retry to find mark table ID
 		var markTableID model.TableID
 		err := retry.Run(50*time.Millisecond, 20, func() error {
 			if tableName == nil {
-				name, exist := p.schemaStorage.GetLastSnapshot().GetTableNameByID(tableID)
+				name, exist := p.schemaStorage.GetLastSnapshot().GetTableNameByID(physicalTableID)
 				if !exist {
-					return cerror.ErrProcessorTableNotFound.GenWithStack("normal table(%s)", tableID)
+					return cerror.ErrProcessorTableNotFound.GenWithStack("normal table(%s)", physicalTableID)
 				}
 				tableName = &name
 			}
@@ -723,10 +798,14 @@ func (p *processor) createTablePipelineImpl(ctx cdcContext.Context, tableID mode
 	var tableNameStr string
 	if tableName == nil {
 		log.Warn("failed to get table name for metric")
-		tableNameStr = strconv.Itoa(int(tableID))
+		tableNameStr = strconv.Itoa(int(physicalTableID))
 	} else {
 		tableNameStr = tableName.QuoteString()
 	}
+	if isSpan {
+		// the spans of a split table are distinguished by their indexes in metrics
+		tableNameStr = fmt.Sprintf("%s#%d", tableNameStr, spanIndex)
+	}
 
 	sink := p.sinkManager.CreateTableSink(tableID, replicaInfo.StartTs)
 	table := tablepipeline.NewTablePipeline(
@@ -738,6 +817,7 @@ func (p *processor) createTablePipelineImpl(ctx cdcContext.Context, tableID mode
 		replicaInfo,
 		sink,
 		p.changefeed.Info.GetTargetTs(),
+		p.newRecordHeader(ctx, tableID, physicalTableID, tableName, replicaInfo.StartTs),
 	)
 	p.wg.Add(1)
 	p.metricSyncTableNumGauge.Inc()
@@ -760,6 +840,50 @@ func (p *processor) createTablePipelineImpl(ctx cdcContext.Context, tableID mode
 	return table, nil
 }
 
+// newRecordHeader returns the header of the file recording the events of the table,
+// nil is returned if the table is not recorded.
+func (p *processor) newRecordHeader(
+	ctx cdcContext.Context, tableID, physicalTableID model.TableID, tableName *model.TableName, startTs model.Ts,
+) *recorder.Header {
+	if tableName == nil || !recorder.ShouldRecord(config.GetGlobalServerConfig().Recorder, *tableName) {
+		return nil
+	}
+	snap, err := p.schemaStorage.GetSnapshot(ctx, startTs)
+	if err != nil {
+		log.Warn("failed to get the schema of the recorded table", zap.Stringer("table", tableName), zap.Error(err))
+		return nil
+	}
+	tableInfo, ok := snap.PhysicalTableByID(physicalTableID)
+	if !ok {
+		log.Warn("the recorded table is not found", zap.Stringer("table", tableName), zap.Uint64("ts", startTs))
+		return nil
+	}
+	dbInfo, ok := snap.SchemaByID(tableInfo.SchemaID)
+	if !ok {
+		log.Warn("the schema of the recorded table is not found", zap.Stringer("table", tableName), zap.Uint64("ts", startTs))
+		return nil
+	}
+	schemaStorage := p.schemaStorage
+	return &recorder.Header{
+		ChangefeedID:     p.changefeedID,
+		TableID:          tableID,
+		TableName:        *tableName,
+		EnableOldValue:   p.changefeed.Info.Config.EnableOldValue,
+		Schema:           dbInfo,
+		Table:            tableInfo.TableInfo,
+		TableInfoVersion: tableInfo.TableInfoVersion,
+		SchemaChangeTs: func() (uint64, bool) {
+			current, ok := schemaStorage.GetLastSnapshot().PhysicalTableByID(physicalTableID)
+			if !ok {
+				// the table is dropped, no event of it is written after the resolved ts of the schema
+				return schemaStorage.ResolvedTs(), true
+			}
+			// the version of the table info is the commit ts of the last DDL of the table
+			return current.TableInfoVersion, current.TableInfoVersion != tableInfo.TableInfoVersion
+		},
+	}
+}
+
 // doGCSchemaStorage trigger the schema storage GC
 func (p *processor) doGCSchemaStorage() error {
 	if p.schemaStorage == nil {
@@ -791,6 +915,7 @@ func (p *processor) Close() error {
 	checkpointTsLagGauge.DeleteLabelValues(p.changefeedID, p.captureInfo.AdvertiseAddr)
 	syncTableNumGauge.DeleteLabelValues(p.changefeedID, p.captureInfo.AdvertiseAddr)
 	processorErrorCounter.DeleteLabelValues(p.changefeedID, p.captureInfo.AdvertiseAddr)
+	dbsorter.RemoveChangefeed(p.changefeedID)
 	if p.sinkManager != nil {
 		return p.sinkManager.Close()
 	}
@@ -17,6 +17,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/pingcap/check"
 	"github.com/pingcap/errors"
@@ -63,6 +64,8 @@ type mockTablePipeline struct {
 	stopTs       model.Ts
 	status       tablepipeline.TableStatus
 	canceled     bool
+	// workloadSamples is the number of the calls to Workload
+	workloadSamples int
 }
 
 func (m *mockTablePipeline) ID() (tableID int64, markTableID int64) {
@@ -91,6 +94,7 @@ func (m *mockTablePipeline) AsyncStop(targetTs model.Ts) bool {
 }
 
 func (m *mockTablePipeline) Workload() model.WorkloadInfo {
+	m.workloadSamples++
 	return model.WorkloadInfo{Workload: 1}
 }
 
@@ -98,6 +102,14 @@ func (m *mockTablePipeline) Status() tablepipeline.TableStatus {
 	return m.status
 }
 
+func (m *mockTablePipeline) ReplicationStatus() *model.TableReplicationStatus {
+	return &model.TableReplicationStatus{
+		Name:         m.name,
+		ResolvedTs:   m.resolvedTs,
+		CheckpointTs: m.checkpointTs,
+	}
+}
+
 func (m *mockTablePipeline) Cancel() {
 	if m.canceled {
 		log.Panic("cancel a canceled table pipeline")
@@ -664,6 +676,64 @@ func (s *processorSuite) TestPositionDeleted(c *check.C) {
 	})
 }
 
+func (s *processorSuite) TestHandleTableStatus(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := cdcContext.NewBackendContext4Test(true)
+	p, tester := initProcessor4Test(ctx, c)
+	p.changefeed.PatchTaskStatus(p.captureInfo.ID, func(status *model.TaskStatus) (*model.TaskStatus, bool, error) {
+		status.Tables[1] = &model.TableReplicaInfo{StartTs: 30}
+		status.Tables[2] = &model.TableReplicaInfo{StartTs: 40}
+		return status, true, nil
+	})
+	var err error
+	// init tick
+	_, err = p.Tick(ctx, p.changefeed)
+	c.Assert(err, check.IsNil)
+	tester.MustApplyPatches()
+
+	// the table status is not reported until the report interval elapsed
+	_, err = p.Tick(ctx, p.changefeed)
+	c.Assert(err, check.IsNil)
+	tester.MustApplyPatches()
+	c.Assert(p.changefeed.TaskPositions[p.captureInfo.ID].Tables, check.IsNil)
+
+	p.tables[2].(*mockTablePipeline).checkpointTs = 45
+	p.lastTableStatusReportTime = time.Time{}
+	_, err = p.Tick(ctx, p.changefeed)
+	c.Assert(err, check.IsNil)
+	tester.MustApplyPatches()
+	c.Assert(p.changefeed.TaskPositions[p.captureInfo.ID].Tables, check.DeepEquals, map[model.TableID]*model.TableReplicationStatus{
+		1: {Name: "`test`.`table1`", ResolvedTs: 30, CheckpointTs: 30},
+		2: {Name: "`test`.`table2`", ResolvedTs: 40, CheckpointTs: 45},
+	})
+}
+
+func (s *processorSuite) TestHandleWorkload(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := cdcContext.NewBackendContext4Test(true)
+	p, tester := initProcessor4Test(ctx, c)
+	p.changefeed.PatchTaskStatus(p.captureInfo.ID, func(status *model.TaskStatus) (*model.TaskStatus, bool, error) {
+		status.Tables[1] = &model.TableReplicaInfo{StartTs: 30}
+		status.Tables[2] = &model.TableReplicaInfo{StartTs: 40}
+		return status, true, nil
+	})
+	var err error
+	// init tick
+	_, err = p.Tick(ctx, p.changefeed)
+	c.Assert(err, check.IsNil)
+	tester.MustApplyPatches()
+
+	// the workload of each table is sampled once per tick
+	for i := 1; i <= 3; i++ {
+		_, err = p.Tick(ctx, p.changefeed)
+		c.Assert(err, check.IsNil)
+		tester.MustApplyPatches()
+		c.Assert(p.tables[1].(*mockTablePipeline).workloadSamples, check.Equals, i)
+		c.Assert(p.tables[2].(*mockTablePipeline).workloadSamples, check.Equals, i)
+	}
+	c.Assert(p.changefeed.Workloads[p.captureInfo.ID], check.DeepEquals, model.TaskWorkload{1: {Workload: 1}, 2: {Workload: 1}})
+}
+
 func cleanUpFinishedOpOperation(state *model.ChangefeedReactorState, captureID model.CaptureID, tester *orchestrator.ReactorStateTester) {
 	state.PatchTaskStatus(captureID, func(status *model.TaskStatus) (*model.TaskStatus, bool, error) {
 		if status == nil || status.Operation == nil {
@@ -0,0 +1,459 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbsorter
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/goleveldb/leveldb"
+	"github.com/pingcap/goleveldb/leveldb/opt"
+	"github.com/pingcap/goleveldb/leveldb/util"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// dbDirName is the name of the directory of the DB in the sort-dir
+	dbDirName       = "leveldb"
+	writeBufferSize = 32 * 1024 * 1024 // 32MB
+	// backgroundJobInterval is the interval of updating the disk usage, removing the events before the checkpoints,
+	// compacting the DB and purging the stale tables
+	backgroundJobInterval = time.Second
+	// compactionThreshold is the size of the removed events of a table which triggers a compaction
+	compactionThreshold = 64 * 1024 * 1024 // 64MB
+	// staleTableTTL is the duration after which the events of a table not replicated by the capture are purged
+	staleTableTTL = 10 * time.Minute
+	// deleteBatchSize is the maximum number of the keys deleted in a batch
+	deleteBatchSize = 1024
+	// diskCheckInterval is the interval of checking the disk usage when the disk consumption reaches the limit
+	diskCheckInterval = 100 * time.Millisecond
+)
+
+type tableState struct {
+	// refs is the number of the sorters of the table
+	refs int
+	// inactiveSince is the time since which the table has no sorter
+	inactiveSince time.Time
+	// changefeedID is the changefeed of the table, the events before its checkpoint are removed
+	changefeedID model.ChangeFeedID
+	// meta is the meta of the table persisted in the DB, it's only valid when the table has a sorter
+	meta tableMeta
+	// removedSize is the size of the events removed since the last compaction of the table
+	removedSize int
+}
+
+// DB is an embedded LevelDB shared by the sorters of all the tables in a capture. The events of a table are
+// kept in the DB until the checkpoint of the changefeed passes them, and the events of the tables which are
+// not replicated by the capture any more are purged after staleTableTTL. Both are done by the background
+// goroutine, so the disk space is reclaimed even if all the sorters are blocked by the disk consumption limit.
+type DB struct {
+	db          *leveldb.DB
+	dir         string
+	captureAddr string
+	// maxDiskConsumption is the maximum disk consumption of the DB, 0 means unlimited
+	maxDiskConsumption uint64
+	diskUsage          int64
+
+	mu     sync.Mutex
+	tables map[string]*tableState
+	// staleTTL is staleTableTTL, it's only changed by the tests
+	staleTTL time.Duration
+	// compactions are the ranges to be compacted by the background goroutine
+	compactions []*util.Range
+
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+var (
+	globalDB   *DB
+	globalDBMu sync.Mutex
+)
+
+// GetDB returns the DB of the capture, the DB is opened in the sort-dir on the first call
+func GetDB(captureAddr string) (*DB, error) {
+	globalDBMu.Lock()
+	defer globalDBMu.Unlock()
+	if globalDB == nil {
+		sorterConfig := config.GetGlobalServerConfig().Sorter
+		db, err := openDB(filepath.Join(sorterConfig.SortDir, dbDirName), captureAddr, sorterConfig)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		globalDB = db
+	}
+	return globalDB, nil
+}
+
+// CloseDB closes the DB of the capture, the events in the DB are kept for the next start
+func CloseDB() {
+	globalDBMu.Lock()
+	defer globalDBMu.Unlock()
+	if globalDB != nil {
+		log.Info("closing the leveldb sorter", zap.String("dir", globalDB.dir))
+		if err := globalDB.close(); err != nil {
+			log.Warn("failed to close the leveldb sorter", zap.Error(err))
+		}
+		globalDB = nil
+	}
+}
+
+func openDB(dir string, captureAddr string, sorterConfig *config.SorterConfig) (*DB, error) {
+	db, err := leveldb.OpenFile(dir, &opt.Options{
+		BlockCacheCapacity: int(sorterConfig.LevelDBBlockCacheSize),
+		WriteBuffer:        writeBufferSize,
+	})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+	}
+	d := &DB{
+		db:                 db,
+		dir:                dir,
+		captureAddr:        captureAddr,
+		maxDiskConsumption: sorterConfig.LevelDBMaxDiskConsumption,
+		tables:             make(map[string]*tableState),
+		staleTTL:           staleTableTTL,
+		closeCh:            make(chan struct{}),
+	}
+	// the tables persisted before the restart are purged if they're not replicated by the capture again
+	now := time.Now()
+	iter := db.NewIterator(util.BytesPrefix([]byte{metaKeyPrefix}), nil)
+	for iter.Next() {
+		table := append([]byte{}, iter.Key()...)
+		table[0] = 0
+		d.tables[string(table)] = &tableState{inactiveSince: now}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		_ = db.Close()
+		return nil, cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+	}
+	d.updateDiskUsage()
+	log.Info("leveldb sorter opened", zap.String("dir", dir),
+		zap.Int("persistedTables", len(d.tables)), zap.Int64("diskUsage", d.DiskUsage()))
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.runBackgroundJobs()
+	}()
+	return d, nil
+}
+
+func (d *DB) close() error {
+	close(d.closeCh)
+	d.wg.Wait()
+	dbDiskUsageGauge.DeleteLabelValues(d.captureAddr)
+	return cerror.WrapError(cerror.ErrLevelDBSorterError, d.db.Close())
+}
+
+// DiskUsage returns the disk consumption of the DB
+func (d *DB) DiskUsage() int64 {
+	return atomic.LoadInt64(&d.diskUsage)
+}
+
+// IsFull returns true if the disk consumption of the DB reaches the limit
+func (d *DB) IsFull() bool {
+	return d.maxDiskConsumption != 0 && uint64(d.DiskUsage()) >= d.maxDiskConsumption
+}
+
+// acquire marks the table replicated by a sorter, so the table is not purged
+func (d *DB) acquire(table []byte, changefeedID model.ChangeFeedID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state, exist := d.tables[string(table)]
+	if !exist {
+		state = &tableState{}
+		d.tables[string(table)] = state
+	}
+	state.refs++
+	state.changefeedID = changefeedID
+}
+
+// release marks the table not replicated by a sorter, the table is purged if it's not replicated in staleTableTTL
+func (d *DB) release(table []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state, exist := d.tables[string(table)]
+	if !exist {
+		return
+	}
+	state.refs--
+	if state.refs <= 0 {
+		state.refs = 0
+		state.inactiveSince = time.Now()
+		state.meta = tableMeta{}
+	}
+}
+
+func (d *DB) getMeta(table []byte) (meta tableMeta, exist bool, err error) {
+	value, err := d.db.Get(metaKey(table), nil)
+	if err == leveldb.ErrNotFound {
+		return tableMeta{}, false, nil
+	}
+	if err != nil {
+		return tableMeta{}, false, cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+	}
+	meta, err = decodeMeta(value)
+	return meta, true, errors.Trace(err)
+}
+
+// initMeta writes the meta of an acquired table when its sorter starts
+func (d *DB) initMeta(table []byte, meta tableMeta) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.db.Put(metaKey(table), encodeMeta(meta), nil); err != nil {
+		return cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+	}
+	if state, exist := d.tables[string(table)]; exist {
+		state.meta = meta
+	}
+	return nil
+}
+
+// writeEvents writes the events in the batch into the DB, it blocks until the disk consumption of the DB
+// is below the limit.
+func (d *DB) writeEvents(ctx context.Context, batch *leveldb.Batch) error {
+	if err := d.waitForDiskSpace(ctx); err != nil {
+		return errors.Trace(err)
+	}
+	return cerror.WrapError(cerror.ErrLevelDBSorterError, d.db.Write(batch, nil))
+}
+
+// writeResolvedTs writes the events in the batch and the resolved ts of an acquired table into the DB.
+// If the batch contains any events, it blocks until the disk consumption of the DB is below the limit,
+// the resolved ts alone can always be written.
+func (d *DB) writeResolvedTs(
+	ctx context.Context, table []byte, batch *leveldb.Batch, hasEvents bool, resolvedTs model.Ts,
+) error {
+	if hasEvents {
+		if err := d.waitForDiskSpace(ctx); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	// the meta is written with the lock held, so the start ts updated by the background goroutine is not overwritten
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state, exist := d.tables[string(table)]
+	if !exist {
+		return cerror.ErrLevelDBSorterError.GenWithStack("table %x is not acquired", table)
+	}
+	meta := state.meta
+	meta.resolvedTs = resolvedTs
+	batch.Put(metaKey(table), encodeMeta(meta))
+	if err := d.db.Write(batch, nil); err != nil {
+		return cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+	}
+	state.meta = meta
+	return nil
+}
+
+func (d *DB) waitForDiskSpace(ctx context.Context) error {
+	if !d.IsFull() {
+		return nil
+	}
+	log.Warn("the disk consumption of the leveldb sorter reaches the limit, the writes are blocked",
+		zap.Int64("diskUsage", d.DiskUsage()), zap.Uint64("limit", d.maxDiskConsumption))
+	ticker := time.NewTicker(diskCheckInterval)
+	defer ticker.Stop()
+	for d.IsFull() {
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// deleteRange deletes all the keys in the range, and returns the size of the deleted keys and values
+func (d *DB) deleteRange(r *util.Range) (int, error) {
+	iter := d.db.NewIterator(r, &opt.ReadOptions{DontFillCache: true})
+	defer iter.Release()
+	batch := new(leveldb.Batch)
+	deleted := 0
+	for iter.Next() {
+		batch.Delete(iter.Key())
+		deleted += len(iter.Key()) + len(iter.Value())
+		if batch.Len() >= deleteBatchSize {
+			if err := d.db.Write(batch, nil); err != nil {
+				return deleted, cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return deleted, cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+	}
+	if batch.Len() > 0 {
+		if err := d.db.Write(batch, nil); err != nil {
+			return deleted, cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+		}
+	}
+	return deleted, nil
+}
+
+// purgeTable deletes all the events and the meta of a table, and returns the range to be compacted,
+// which is nil if no event is deleted. It doesn't schedule the compaction, because it may be called with d.mu held.
+func (d *DB) purgeTable(table []byte) (*util.Range, error) {
+	if err := d.db.Delete(metaKey(table), nil); err != nil {
+		return nil, cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+	}
+	r := dataRange(table, 0, math.MaxUint64)
+	deleted, err := d.deleteRange(r)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if deleted == 0 {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// scheduleCompaction compacts the range in the background, so the disk space of the deleted keys is reclaimed
+func (d *DB) scheduleCompaction(r *util.Range) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.compactions = append(d.compactions, r)
+}
+
+func (d *DB) runBackgroundJobs() {
+	ticker := time.NewTicker(backgroundJobInterval)
+	defer ticker.Stop()
+	metricCompactionDuration := dbCompactionDuration.WithLabelValues(d.captureAddr)
+	for {
+		select {
+		case <-d.closeCh:
+			return
+		case <-ticker.C:
+		}
+		d.updateDiskUsage()
+		d.cleanUpCheckpoints()
+
+		d.mu.Lock()
+		now := time.Now()
+		for key, state := range d.tables {
+			if state.refs > 0 || now.Sub(state.inactiveSince) < d.staleTTL {
+				continue
+			}
+			// the table is purged with the lock held, so it can't be acquired by a new sorter during the purge
+			r, err := d.purgeTable([]byte(key))
+			if err != nil {
+				log.Warn("failed to purge the stale table in the leveldb sorter", zap.Error(err))
+				continue
+			}
+			if r != nil {
+				d.compactions = append(d.compactions, r)
+			}
+			delete(d.tables, key)
+		}
+		compactions := d.compactions
+		d.compactions = nil
+		d.mu.Unlock()
+
+		for _, r := range compactions {
+			start := time.Now()
+			if err := d.db.CompactRange(*r); err != nil {
+				log.Warn("failed to compact the leveldb sorter", zap.Error(err))
+				continue
+			}
+			metricCompactionDuration.Observe(time.Since(start).Seconds())
+		}
+	}
+}
+
+// cleanUpCheckpoints removes the events before the checkpoints of the changefeeds, they're not replayed after
+// the restart. The removed ranges are compacted once they're large enough or the disk consumption reaches the
+// limit, so the sorters blocked by the limit can resume.
+func (d *DB) cleanUpCheckpoints() {
+	type cleanUp struct {
+		table string
+		r     *util.Range
+	}
+	var cleanUps []cleanUp
+	d.mu.Lock()
+	for key, state := range d.tables {
+		if state.refs == 0 {
+			continue
+		}
+		checkpointTs := getCheckpointTs(state.changefeedID)
+		if checkpointTs > state.meta.resolvedTs {
+			checkpointTs = state.meta.resolvedTs
+		}
+		if checkpointTs <= state.meta.startTs {
+			continue
+		}
+		meta := state.meta
+		meta.startTs = checkpointTs
+		if err := d.db.Put(metaKey([]byte(key)), encodeMeta(meta), nil); err != nil {
+			log.Warn("failed to update the table meta in the leveldb sorter", zap.Error(err))
+			continue
+		}
+		state.meta = meta
+		cleanUps = append(cleanUps, cleanUp{table: key, r: dataRange([]byte(key), 0, checkpointTs)})
+	}
+	d.mu.Unlock()
+
+	full := d.IsFull()
+	for _, c := range cleanUps {
+		// the events before the checkpoint are never written again, so they're removed without the lock held
+		removed, err := d.deleteRange(c.r)
+		if err != nil {
+			log.Warn("failed to remove the events before the checkpoint in the leveldb sorter", zap.Error(err))
+			continue
+		}
+		d.mu.Lock()
+		if state, exist := d.tables[c.table]; exist {
+			state.removedSize += removed
+			if state.removedSize >= compactionThreshold || (full && state.removedSize > 0) {
+				d.compactions = append(d.compactions, c.r)
+				state.removedSize = 0
+			}
+		}
+		d.mu.Unlock()
+	}
+}
+
+func (d *DB) updateDiskUsage() {
+	var usage int64
+	err := filepath.Walk(d.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// the files may be removed by the compactions
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			usage += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn("failed to get the disk usage of the leveldb sorter", zap.Error(err))
+		return
+	}
+	atomic.StoreInt64(&d.diskUsage, usage)
+	dbDiskUsageGauge.WithLabelValues(d.captureAddr).Set(float64(usage))
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbsorter
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pingcap/goleveldb/leveldb/util"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// The keys in the DB are prefixed by the type of the key:
+//
+//	meta key: 'm' | changefeed ID | 0x00 | table ID
+//	data key: 'd' | changefeed ID | 0x00 | table ID | commit ts | start ts | op type | key
+//
+// The changefeed ID never contains 0x00, and the integers are encoded in big endian,
+// so the data keys of a table are sorted by the commit ts, and the deletions go first
+// if the commit ts and the start ts are equal.
+const (
+	metaKeyPrefix = 'm'
+	dataKeyPrefix = 'd'
+
+	tableKeyLen = 1 + 1 + 8
+	tsLen       = 8
+	metaLen     = tsLen * 2
+)
+
+// tableKey returns the part of the keys identifying a table, without the type of the key
+func tableKey(changefeedID model.ChangeFeedID, tableID model.TableID) []byte {
+	buf := make([]byte, 0, len(changefeedID)+tableKeyLen)
+	buf = append(buf, 0)
+	buf = append(buf, changefeedID...)
+	buf = append(buf, 0)
+	return appendUint64(buf, uint64(tableID))
+}
+
+func metaKey(table []byte) []byte {
+	key := append([]byte{}, table...)
+	key[0] = metaKeyPrefix
+	return key
+}
+
+func dataKey(table []byte, event *model.PolymorphicEvent) []byte {
+	key := make([]byte, 0, len(table)+tsLen*2+1+len(event.RawKV.Key))
+	key = append(key, table...)
+	key[0] = dataKeyPrefix
+	key = appendUint64(key, event.CRTs)
+	key = appendUint64(key, event.StartTs)
+	// the deletions go first, which is the same as the unified sorter
+	if event.RawKV.OpType == model.OpTypeDelete {
+		key = append(key, 0)
+	} else {
+		key = append(key, 1)
+	}
+	return append(key, event.RawKV.Key...)
+}
+
+// dataRange returns the range of the data keys of a table whose commit ts is in (from, to]
+func dataRange(table []byte, from, to uint64) *util.Range {
+	prefix := append([]byte{}, table...)
+	prefix[0] = dataKeyPrefix
+	r := util.BytesPrefix(prefix)
+	r.Start = appendUint64(append([]byte{}, prefix...), from+1)
+	if to != math.MaxUint64 {
+		r.Limit = appendUint64(append([]byte{}, prefix...), to+1)
+	}
+	return r
+}
+
+type tableMeta struct {
+	// the events whose commit ts is in (startTs, resolvedTs] are all in the DB
+	startTs    uint64
+	resolvedTs uint64
+}
+
+func encodeMeta(meta tableMeta) []byte {
+	buf := make([]byte, 0, metaLen)
+	buf = appendUint64(buf, meta.startTs)
+	return appendUint64(buf, meta.resolvedTs)
+}
+
+func decodeMeta(value []byte) (tableMeta, error) {
+	if len(value) != metaLen {
+		return tableMeta{}, cerror.ErrLevelDBSorterError.GenWithStack("invalid table meta %x", value)
+	}
+	return tableMeta{
+		startTs:    binary.BigEndian.Uint64(value),
+		resolvedTs: binary.BigEndian.Uint64(value[tsLen:]),
+	}, nil
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
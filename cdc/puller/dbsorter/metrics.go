@@ -0,0 +1,57 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbsorter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dbDiskUsageGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ticdc",
+		Subsystem: "sorter",
+		Name:      "leveldb_disk_usage",
+		Help:      "the disk usage of the leveldb sort engine",
+	}, []string{"capture"})
+
+	dbWriteBytesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ticdc",
+		Subsystem: "sorter",
+		Name:      "leveldb_write_bytes",
+		Help:      "the bytes of the events written into the leveldb sort engine",
+	}, []string{"capture", "changefeed"})
+
+	dbResumedEventCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ticdc",
+		Subsystem: "sorter",
+		Name:      "leveldb_resumed_event_count",
+		Help:      "the number of the events persisted before the restart and output by the leveldb sort engine",
+	}, []string{"capture", "changefeed"})
+
+	dbCompactionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "ticdc",
+		Subsystem: "sorter",
+		Name:      "leveldb_compaction_duration",
+		Help:      "bucketed histogram of the duration of the compactions of the leveldb sort engine",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	}, []string{"capture"})
+)
+
+// InitMetrics registers all metrics in this file
+func InitMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(dbDiskUsageGauge)
+	registry.MustRegister(dbWriteBytesCounter)
+	registry.MustRegister(dbResumedEventCounter)
+	registry.MustRegister(dbCompactionDuration)
+}
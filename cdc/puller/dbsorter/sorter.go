@@ -0,0 +1,246 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbsorter
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/goleveldb/leveldb"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	inputChSize  = 128
+	outputChSize = 128
+	// maxBatchSize is the maximum size of the events written into the DB in a batch
+	maxBatchSize = 4 * 1024 * 1024 // 4MB
+)
+
+// checkpoints are the checkpoint ts of the changefeeds, the events before them are removed from the DB
+// by the background goroutine of the DB
+var checkpoints sync.Map
+
+// UpdateCheckpointTs updates the checkpoint ts of a changefeed, the events of the changefeed
+// committed before the checkpoint ts are removed from the DB.
+func UpdateCheckpointTs(changefeedID model.ChangeFeedID, checkpointTs model.Ts) {
+	checkpoints.Store(changefeedID, checkpointTs)
+}
+
+// RemoveChangefeed removes the checkpoint ts of a changefeed which is not replicated by the capture any more
+func RemoveChangefeed(changefeedID model.ChangeFeedID) {
+	checkpoints.Delete(changefeedID)
+}
+
+func getCheckpointTs(changefeedID model.ChangeFeedID) model.Ts {
+	if checkpointTs, ok := checkpoints.Load(changefeedID); ok {
+		return checkpointTs.(model.Ts)
+	}
+	return 0
+}
+
+// Sorter sorts the events of a table by the DB shared by all the tables in the capture. The events are keyed
+// by the commit ts and the start ts, so the resolved events are output by a range scan. As the events are kept
+// in the DB until the checkpoint of the changefeed passes them, the puller resumes pulling the events from the
+// resolved ts persisted in the DB after the capture restarts, instead of re-pulling all the unflushed events.
+type Sorter struct {
+	changefeedID model.ChangeFeedID
+	tableID      model.TableID
+	captureAddr  string
+	startTs      model.Ts
+	table        []byte
+
+	resumeOnce sync.Once
+	db         *DB
+	resumeTs   model.Ts
+	resumeErr  error
+
+	inputCh  chan *model.PolymorphicEvent
+	outputCh chan *model.PolymorphicEvent
+}
+
+// NewSorter creates a new Sorter of a table which is replicated from the start ts
+func NewSorter(changefeedID model.ChangeFeedID, tableID model.TableID, startTs model.Ts, captureAddr string) *Sorter {
+	return &Sorter{
+		changefeedID: changefeedID,
+		tableID:      tableID,
+		captureAddr:  captureAddr,
+		startTs:      startTs,
+		table:        tableKey(changefeedID, tableID),
+		inputCh:      make(chan *model.PolymorphicEvent, inputChSize),
+		outputCh:     make(chan *model.PolymorphicEvent, outputChSize),
+	}
+}
+
+// ResumeTs returns the ts from which the puller pulls the events of the table. If the DB has all the events
+// between the start ts and the resume ts, the sorter outputs them before the events pulled by the puller.
+func (s *Sorter) ResumeTs() (model.Ts, error) {
+	s.resumeOnce.Do(func() {
+		s.resumeTs, s.resumeErr = s.resume()
+	})
+	return s.resumeTs, s.resumeErr
+}
+
+func (s *Sorter) resume() (model.Ts, error) {
+	db, err := GetDB(s.captureAddr)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	s.db = db
+	db.acquire(s.table, s.changefeedID)
+	meta, exist, err := db.getMeta(s.table)
+	if err != nil {
+		db.release(s.table)
+		return 0, errors.Trace(err)
+	}
+	if exist && meta.startTs <= s.startTs && s.startTs < meta.resolvedTs {
+		log.Info("resume the table from the events persisted by the leveldb sorter",
+			zap.String("changefeed", s.changefeedID), zap.Int64("tableID", s.tableID),
+			zap.Uint64("startTs", s.startTs), zap.Uint64("resumeTs", meta.resolvedTs))
+		if err := db.initMeta(s.table, meta); err != nil {
+			db.release(s.table)
+			return 0, errors.Trace(err)
+		}
+		return meta.resolvedTs, nil
+	}
+	if exist {
+		// the events persisted are not continuous with the start ts
+		r, err := db.purgeTable(s.table)
+		if err != nil {
+			db.release(s.table)
+			return 0, errors.Trace(err)
+		}
+		if r != nil {
+			db.scheduleCompaction(r)
+		}
+	}
+	if err := db.initMeta(s.table, tableMeta{startTs: s.startTs, resolvedTs: s.startTs}); err != nil {
+		db.release(s.table)
+		return 0, errors.Trace(err)
+	}
+	return s.startTs, nil
+}
+
+// Run implements the EventSorter interface
+func (s *Sorter) Run(ctx context.Context) error {
+	resumeTs, err := s.ResumeTs()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer s.db.release(s.table)
+
+	metricWriteBytes := dbWriteBytesCounter.WithLabelValues(s.captureAddr, s.changefeedID)
+	resolvedTs := resumeTs
+	if resumeTs > s.startTs {
+		n, err := s.output(ctx, s.startTs, resumeTs)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		dbResumedEventCounter.WithLabelValues(s.captureAddr, s.changefeedID).Add(float64(n))
+		if err := s.outputResolvedTs(ctx, resumeTs); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	batch := new(leveldb.Batch)
+	batchSize := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case event := <-s.inputCh:
+			if event.RawKV.OpType != model.OpTypeResolved {
+				value, err := event.RawKV.MarshalMsg(nil)
+				if err != nil {
+					return cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+				}
+				key := dataKey(s.table, event)
+				batch.Put(key, value)
+				batchSize += len(key) + len(value)
+				if batchSize >= maxBatchSize {
+					if err := s.db.writeEvents(ctx, batch); err != nil {
+						return errors.Trace(err)
+					}
+					metricWriteBytes.Add(float64(batchSize))
+					batch.Reset()
+					batchSize = 0
+				}
+				continue
+			}
+			if event.CRTs <= resolvedTs {
+				continue
+			}
+			lastResolvedTs := resolvedTs
+			resolvedTs = event.CRTs
+			if err := s.db.writeResolvedTs(ctx, s.table, batch, batchSize > 0, resolvedTs); err != nil {
+				return errors.Trace(err)
+			}
+			metricWriteBytes.Add(float64(batchSize))
+			batch.Reset()
+			batchSize = 0
+			if _, err := s.output(ctx, lastResolvedTs, event.CRTs); err != nil {
+				return errors.Trace(err)
+			}
+			if err := s.outputResolvedTs(ctx, event.CRTs); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+}
+
+// output outputs the events whose commit ts is in (from, to], and returns the number of the events
+func (s *Sorter) output(ctx context.Context, from, to model.Ts) (int, error) {
+	iter := s.db.db.NewIterator(dataRange(s.table, from, to), nil)
+	defer iter.Release()
+	n := 0
+	for iter.Next() {
+		rawKV := new(model.RawKVEntry)
+		if _, err := rawKV.UnmarshalMsg(iter.Value()); err != nil {
+			return n, cerror.WrapError(cerror.ErrLevelDBSorterError, err)
+		}
+		select {
+		case <-ctx.Done():
+			return n, errors.Trace(ctx.Err())
+		case s.outputCh <- model.NewPolymorphicEvent(rawKV):
+		}
+		n++
+	}
+	return n, cerror.WrapError(cerror.ErrLevelDBSorterError, iter.Error())
+}
+
+func (s *Sorter) outputResolvedTs(ctx context.Context, resolvedTs model.Ts) error {
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case s.outputCh <- model.NewResolvedPolymorphicEvent(0, resolvedTs):
+	}
+	return nil
+}
+
+// AddEntry implements the EventSorter interface
+func (s *Sorter) AddEntry(ctx context.Context, entry *model.PolymorphicEvent) {
+	select {
+	case <-ctx.Done():
+	case s.inputCh <- entry:
+	}
+}
+
+// Output implements the EventSorter interface
+func (s *Sorter) Output() <-chan *model.PolymorphicEvent {
+	return s.outputCh
+}
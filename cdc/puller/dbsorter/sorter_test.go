@@ -0,0 +1,320 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbsorter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"golang.org/x/sync/errgroup"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type sorterSuite struct{}
+
+var _ = check.SerialSuites(&sorterSuite{})
+
+const testCaptureAddr = "127.0.0.1:8300"
+
+func (s *sorterSuite) setUpDB(c *check.C) {
+	conf := config.GetDefaultServerConfig()
+	conf.Sorter.SortDir = c.MkDir()
+	config.StoreGlobalServerConfig(conf)
+}
+
+func mockEvent(op model.OpType, key string, startTs, commitTs model.Ts) *model.PolymorphicEvent {
+	return model.NewPolymorphicEvent(&model.RawKVEntry{
+		OpType:  op,
+		Key:     []byte(key),
+		Value:   []byte(key),
+		StartTs: startTs,
+		CRTs:    commitTs,
+	})
+}
+
+// runSorter runs the sorter until cancel is called, the events output by the sorter are sent to outputCh
+func runSorter(ctx context.Context, sorter *Sorter) (*errgroup.Group, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	errg, ctx := errgroup.WithContext(ctx)
+	errg.Go(func() error {
+		err := sorter.Run(ctx)
+		if errors.Cause(err) == context.Canceled {
+			return nil
+		}
+		return err
+	})
+	return errg, cancel
+}
+
+// receiveUntil receives the events output by the sorter until the resolved ts reaches the given ts
+func receiveUntil(c *check.C, sorter *Sorter, resolvedTs model.Ts) []*model.PolymorphicEvent {
+	var events []*model.PolymorphicEvent
+	for {
+		select {
+		case event := <-sorter.Output():
+			if event.RawKV.OpType == model.OpTypeResolved {
+				if event.CRTs >= resolvedTs {
+					return events
+				}
+				continue
+			}
+			events = append(events, event)
+		case <-time.After(10 * time.Second):
+			c.Fatalf("timeout waiting for the resolved ts %d", resolvedTs)
+		}
+	}
+}
+
+func (s *sorterSuite) TestSort(c *check.C) {
+	defer testleak.AfterTest(c)()
+	s.setUpDB(c)
+	defer CloseDB()
+	ctx := context.Background()
+
+	sorter := NewSorter("test-cf", 1, 100, testCaptureAddr)
+	resumeTs, err := sorter.ResumeTs()
+	c.Assert(err, check.IsNil)
+	c.Assert(resumeTs, check.Equals, model.Ts(100))
+	errg, cancel := runSorter(ctx, sorter)
+
+	sorter.AddEntry(ctx, mockEvent(model.OpTypePut, "b", 105, 110))
+	sorter.AddEntry(ctx, mockEvent(model.OpTypePut, "a", 101, 103))
+	sorter.AddEntry(ctx, mockEvent(model.OpTypePut, "c", 105, 110))
+	sorter.AddEntry(ctx, mockEvent(model.OpTypeDelete, "c", 105, 110))
+	sorter.AddEntry(ctx, mockEvent(model.OpTypePut, "d", 115, 120))
+	sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 110))
+	events := receiveUntil(c, sorter, 110)
+	c.Assert(events, check.HasLen, 4)
+	expected := []struct {
+		op       model.OpType
+		key      string
+		commitTs model.Ts
+	}{
+		{model.OpTypePut, "a", 103},
+		{model.OpTypeDelete, "c", 110},
+		{model.OpTypePut, "b", 110},
+		{model.OpTypePut, "c", 110},
+	}
+	for i, event := range events {
+		c.Assert(event.RawKV.OpType, check.Equals, expected[i].op)
+		c.Assert(string(event.RawKV.Key), check.Equals, expected[i].key)
+		c.Assert(event.CRTs, check.Equals, expected[i].commitTs)
+	}
+
+	sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 120))
+	events = receiveUntil(c, sorter, 120)
+	c.Assert(events, check.HasLen, 1)
+	c.Assert(string(events[0].RawKV.Key), check.Equals, "d")
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+}
+
+func (s *sorterSuite) TestResume(c *check.C) {
+	defer testleak.AfterTest(c)()
+	s.setUpDB(c)
+	defer CloseDB()
+	ctx := context.Background()
+
+	sorter := NewSorter("test-cf", 1, 100, testCaptureAddr)
+	errg, cancel := runSorter(ctx, sorter)
+	sorter.AddEntry(ctx, mockEvent(model.OpTypePut, "a", 101, 103))
+	sorter.AddEntry(ctx, mockEvent(model.OpTypePut, "b", 105, 110))
+	sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 110))
+	c.Assert(receiveUntil(c, sorter, 110), check.HasLen, 2)
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+
+	// the events are replayed from the DB after the capture restarts
+	CloseDB()
+	sorter = NewSorter("test-cf", 1, 100, testCaptureAddr)
+	resumeTs, err := sorter.ResumeTs()
+	c.Assert(err, check.IsNil)
+	c.Assert(resumeTs, check.Equals, model.Ts(110))
+	errg, cancel = runSorter(ctx, sorter)
+	events := receiveUntil(c, sorter, 110)
+	c.Assert(events, check.HasLen, 2)
+	c.Assert(string(events[0].RawKV.Key), check.Equals, "a")
+	c.Assert(string(events[1].RawKV.Key), check.Equals, "b")
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+
+	// the events persisted are not continuous with the start ts, so they're purged
+	sorter = NewSorter("test-cf", 1, 90, testCaptureAddr)
+	resumeTs, err = sorter.ResumeTs()
+	c.Assert(err, check.IsNil)
+	c.Assert(resumeTs, check.Equals, model.Ts(90))
+	errg, cancel = runSorter(ctx, sorter)
+	sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 110))
+	c.Assert(receiveUntil(c, sorter, 110), check.HasLen, 0)
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+}
+
+func (s *sorterSuite) TestCleanUp(c *check.C) {
+	defer testleak.AfterTest(c)()
+	s.setUpDB(c)
+	defer CloseDB()
+	defer RemoveChangefeed("test-cf")
+	ctx := context.Background()
+
+	sorter := NewSorter("test-cf", 1, 100, testCaptureAddr)
+	errg, cancel := runSorter(ctx, sorter)
+	sorter.AddEntry(ctx, mockEvent(model.OpTypePut, "a", 101, 103))
+	sorter.AddEntry(ctx, mockEvent(model.OpTypePut, "b", 105, 110))
+	sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 110))
+	c.Assert(receiveUntil(c, sorter, 110), check.HasLen, 2)
+
+	UpdateCheckpointTs("test-cf", 105)
+	db, err := GetDB(testCaptureAddr)
+	c.Assert(err, check.IsNil)
+	table := tableKey("test-cf", 1)
+	for i := 0; ; i++ {
+		meta, exist, err := db.getMeta(table)
+		c.Assert(err, check.IsNil)
+		c.Assert(exist, check.IsTrue)
+		if meta.startTs == 105 {
+			break
+		}
+		c.Assert(i, check.Less, 100)
+		time.Sleep(100 * time.Millisecond)
+	}
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+
+	// only the events after the checkpoint ts are replayed
+	sorter = NewSorter("test-cf", 1, 105, testCaptureAddr)
+	resumeTs, err := sorter.ResumeTs()
+	c.Assert(err, check.IsNil)
+	c.Assert(resumeTs, check.Equals, model.Ts(110))
+	errg, cancel = runSorter(ctx, sorter)
+	events := receiveUntil(c, sorter, 110)
+	c.Assert(events, check.HasLen, 1)
+	c.Assert(string(events[0].RawKV.Key), check.Equals, "b")
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+}
+
+func (s *sorterSuite) TestPurgeStaleTable(c *check.C) {
+	defer testleak.AfterTest(c)()
+	s.setUpDB(c)
+	defer CloseDB()
+	ctx := context.Background()
+
+	sorter := NewSorter("test-cf", 1, 100, testCaptureAddr)
+	errg, cancel := runSorter(ctx, sorter)
+	sorter.AddEntry(ctx, mockEvent(model.OpTypePut, "a", 101, 103))
+	sorter.AddEntry(ctx, mockEvent(model.OpTypePut, "b", 105, 110))
+	sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 110))
+	c.Assert(receiveUntil(c, sorter, 110), check.HasLen, 2)
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+
+	db, err := GetDB(testCaptureAddr)
+	c.Assert(err, check.IsNil)
+	table := tableKey("test-cf", 1)
+	db.mu.Lock()
+	db.staleTTL = 0
+	db.mu.Unlock()
+	// the released table is purged by the background goroutine
+	for i := 0; ; i++ {
+		db.mu.Lock()
+		_, exist := db.tables[string(table)]
+		db.mu.Unlock()
+		if !exist {
+			break
+		}
+		c.Assert(i, check.Less, 100)
+		time.Sleep(100 * time.Millisecond)
+	}
+	_, exist, err := db.getMeta(table)
+	c.Assert(err, check.IsNil)
+	c.Assert(exist, check.IsFalse)
+
+	// the table can be acquired again after the purge
+	db.mu.Lock()
+	db.staleTTL = staleTableTTL
+	db.mu.Unlock()
+	sorter = NewSorter("test-cf", 1, 100, testCaptureAddr)
+	resumeCh := make(chan model.Ts, 1)
+	go func() {
+		resumeTs, err := sorter.ResumeTs()
+		c.Check(err, check.IsNil)
+		resumeCh <- resumeTs
+	}()
+	select {
+	case resumeTs := <-resumeCh:
+		c.Assert(resumeTs, check.Equals, model.Ts(100))
+	case <-time.After(10 * time.Second):
+		c.Fatal("timeout waiting for the sorter to acquire the table")
+	}
+	errg, cancel = runSorter(ctx, sorter)
+	sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 110))
+	c.Assert(receiveUntil(c, sorter, 110), check.HasLen, 0)
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+}
+
+func (s *sorterSuite) TestDiskConsumptionLimit(c *check.C) {
+	defer testleak.AfterTest(c)()
+	s.setUpDB(c)
+	defer CloseDB()
+	defer RemoveChangefeed("test-cf")
+	ctx := context.Background()
+
+	db, err := GetDB(testCaptureAddr)
+	c.Assert(err, check.IsNil)
+	addEvents := func(sorter *Sorter, prefix string, commitTs model.Ts) {
+		// the events are large enough to be written in several batches
+		for i := 0; i < 8; i++ {
+			event := mockEvent(model.OpTypePut, fmt.Sprintf("%s%d", prefix, i), commitTs-1, commitTs)
+			event.RawKV.Value = make([]byte, maxBatchSize/2)
+			sorter.AddEntry(ctx, event)
+		}
+	}
+	sorter := NewSorter("test-cf", 1, 100, testCaptureAddr)
+	errg, cancel := runSorter(ctx, sorter)
+	addEvents(sorter, "a", 110)
+	sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 110))
+	c.Assert(receiveUntil(c, sorter, 110), check.HasLen, 8)
+
+	// the disk consumption reaches the limit, so the writes of the events are blocked
+	db.updateDiskUsage()
+	db.maxDiskConsumption = uint64(db.DiskUsage())
+	c.Assert(db.IsFull(), check.IsTrue)
+	addEvents(sorter, "b", 120)
+	sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 120))
+	select {
+	case event := <-sorter.Output():
+		c.Fatalf("unexpected event %v output by the blocked sorter", event)
+	case <-time.After(2 * backgroundJobInterval):
+	}
+
+	// the events before the checkpoint are removed by the background goroutine, so the sorter resumes
+	UpdateCheckpointTs("test-cf", 110)
+	c.Assert(receiveUntil(c, sorter, 120), check.HasLen, 8)
+	meta, exist, err := db.getMeta(tableKey("test-cf", 1))
+	c.Assert(err, check.IsNil)
+	c.Assert(exist, check.IsTrue)
+	c.Assert(meta, check.Equals, tableMeta{startTs: 110, resolvedTs: 120})
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+}
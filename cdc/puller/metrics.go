@@ -103,6 +103,13 @@ var (
 			Help:      "Bucketed histogram of processing time (s) of merge in entry sorter.",
 			Buckets:   prometheus.ExponentialBuckets(0.000001, 10, 10),
 		}, []string{"capture", "changefeed", "table"})
+	sharedPullerGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "puller",
+			Name:      "shared_puller_count",
+			Help:      "The number of pullers shared by the changefeeds",
+		}, []string{"capture"})
 )
 
 // InitMetrics registers all metrics in this file
@@ -118,4 +125,5 @@ func InitMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(entrySorterUnsortedSizeGauge)
 	registry.MustRegister(entrySorterSortDuration)
 	registry.MustRegister(entrySorterMergeDuration)
+	registry.MustRegister(sharedPullerGauge)
 }
@@ -0,0 +1,568 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/memquota"
+	"github.com/pingcap/ticdc/pkg/regionspan"
+	"github.com/pingcap/ticdc/pkg/security"
+	"github.com/pingcap/ticdc/pkg/util"
+	tidbkv "github.com/pingcap/tidb/kv"
+	pd "github.com/tikv/pd/client"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// defaultSharedFeedHistorySize is the max size of the events and the resolved ts retained by a shared feed
+	// for the late subscribers. The events at or below a resolved ts are removed from the full history, and the feed
+	// can't be subscribed below the resolved ts any more.
+	defaultSharedFeedHistorySize = 64 * 1024 * 1024 // 64MB
+	// defaultSharedPullerPendingSize is the max size of the events pending in a subscriber, a subscriber lagging
+	// behind the feed is detached and pulls the events by itself. It's larger than the history size, so the history
+	// replayed to a late subscriber never exceeds it.
+	defaultSharedPullerPendingSize = 128 * 1024 * 1024 // 128MB
+
+	// sizeOfRawKVEntry is counted for every entry, so the resolved ts retained by a feed are bounded too
+	sizeOfRawKVEntry = int64(unsafe.Sizeof(model.RawKVEntry{}))
+)
+
+// sharedEntrySize returns the approximate memory of the entry buffered by the shared pullers
+func sharedEntrySize(raw *model.RawKVEntry) int64 {
+	return raw.ApproximateSize() + sizeOfRawKVEntry
+}
+
+// addSharedPullerUsage reports the memory buffered by the shared pullers to the memory arbitrator. The entries
+// both in the history of a feed and pending in a subscriber are counted twice, which overestimates the usage.
+func addSharedPullerUsage(delta int64) {
+	memquota.GetGlobalArbitrator().AddUsage(memquota.ComponentSharedPuller, delta)
+}
+
+// sharedFeed is a puller shared by the changefeeds pulling the same spans with the same options. The feed retains
+// a history of the events and the resolved ts it outputs, so that a late subscriber starting at or after the floor
+// ts of the history receives all the events after its start ts.
+type sharedFeed struct {
+	key         string
+	puller      Puller
+	cancel      context.CancelFunc
+	done        chan struct{}
+	err         error
+	historySize int64
+
+	mu          sync.Mutex
+	subscribers map[*sharedPuller]struct{}
+	// history contains all the events after the floor ts in the order they are output, except the ones dropped
+	// when the history is full of the unresolved events
+	history []*model.RawKVEntry
+	// historyBytes is the size of the entries in the history
+	historyBytes int64
+	floorTs      uint64
+	// lastResolved is the index of the last resolved ts in the history, -1 if there is none
+	lastResolved int
+	// droppedTs is the max commit ts of the events dropped from the full history
+	droppedTs uint64
+	closed    bool
+	// removed is protected by the lock of the manager
+	removed bool
+}
+
+// attachable returns whether a subscriber starting at the ts receives all the events after it, the feed is locked
+func (f *sharedFeed) attachable(startTs uint64) bool {
+	return !f.closed && f.floorTs <= startTs && f.droppedTs <= startTs
+}
+
+// record appends the entry to the history, the feed is locked
+func (f *sharedFeed) record(raw *model.RawKVEntry) {
+	if f.historyBytes >= f.historySize && f.lastResolved >= 0 {
+		f.evict()
+	}
+	// the resolved ts is always recorded, so that the history can be evicted later
+	if f.historyBytes >= f.historySize && raw.OpType != model.OpTypeResolved {
+		if raw.CRTs > f.droppedTs {
+			f.droppedTs = raw.CRTs
+		}
+		return
+	}
+	if raw.OpType == model.OpTypeResolved {
+		f.lastResolved = len(f.history)
+	}
+	f.history = append(f.history, raw)
+	size := sharedEntrySize(raw)
+	f.historyBytes += size
+	addSharedPullerUsage(size)
+}
+
+// evict removes the entries at or below the last resolved ts from the history and raises the floor ts to it,
+// the events above the resolved ts are kept for the subscribers starting at or after it.
+func (f *sharedFeed) evict() {
+	floorTs := f.history[f.lastResolved].CRTs
+	remained := make([]*model.RawKVEntry, 0, len(f.history)-f.lastResolved)
+	var evicted int64
+	for _, raw := range f.history[:f.lastResolved+1] {
+		if raw.OpType != model.OpTypeResolved && raw.CRTs > floorTs {
+			remained = append(remained, raw)
+		} else {
+			evicted += sharedEntrySize(raw)
+		}
+	}
+	f.history = append(remained, f.history[f.lastResolved+1:]...)
+	f.historyBytes -= evicted
+	addSharedPullerUsage(-evicted)
+	f.floorTs = floorTs
+	f.lastResolved = -1
+}
+
+// clearHistory removes all the entries from the history, the feed is locked
+func (f *sharedFeed) clearHistory() {
+	addSharedPullerUsage(-f.historyBytes)
+	f.history = nil
+	f.historyBytes = 0
+}
+
+func (f *sharedFeed) run(ctx context.Context) {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- f.puller.Run(ctx)
+	}()
+	err := f.dispatch(ctx, errCh)
+	f.mu.Lock()
+	f.err = err
+	f.closed = true
+	f.clearHistory()
+	f.mu.Unlock()
+	close(f.done)
+}
+
+// dispatch pushes the events to the subscribers, which never blocks, so a slow subscriber doesn't stall the others
+func (f *sharedFeed) dispatch(ctx context.Context, errCh <-chan error) error {
+	for {
+		var raw *model.RawKVEntry
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			return errors.Trace(err)
+		case raw = <-f.puller.Output():
+		}
+		if raw == nil {
+			continue
+		}
+		f.mu.Lock()
+		f.record(raw)
+		for sub := range f.subscribers {
+			if !sub.push(raw) {
+				delete(f.subscribers, sub)
+			}
+		}
+		f.mu.Unlock()
+	}
+}
+
+// SharedPullerManager shares the pullers among the changefeeds in the capture
+type SharedPullerManager struct {
+	historySize int64
+	pendingSize int64
+
+	mu    sync.Mutex
+	feeds map[string][]*sharedFeed
+}
+
+func newSharedPullerManager(historySize, pendingSize int64) *SharedPullerManager {
+	return &SharedPullerManager{
+		historySize: historySize,
+		pendingSize: pendingSize,
+		feeds:       make(map[string][]*sharedFeed),
+	}
+}
+
+var sharedPullerManager = newSharedPullerManager(defaultSharedFeedHistorySize, defaultSharedPullerPendingSize)
+
+// GetSharedPullerManager returns the shared puller manager of the capture
+func GetSharedPullerManager() *SharedPullerManager {
+	return sharedPullerManager
+}
+
+// sharedFeedKey returns the key of the feeds, the scan limits of the changefeed are read from the context
+func sharedFeedKey(ctx context.Context, spans []regionspan.ComparableSpan, enableOldValue bool) string {
+	var b strings.Builder
+	for _, span := range spans {
+		b.WriteString(span.String())
+		b.WriteByte(',')
+	}
+	fmt.Fprintf(&b, "old-value=%t,region-scan-limit=%d,scan-priority=%d,scan-bytes-per-second=%d", enableOldValue,
+		util.RegionScanLimitFromCtx(ctx), util.ScanPriorityFromCtx(ctx), util.ScanBytesPerSecondFromCtx(ctx))
+	return b.String()
+}
+
+// subscribe attaches the subscriber to a shared feed, a new feed is created if no feed is attachable.
+func (m *SharedPullerManager) subscribe(sub *sharedPuller) *sharedFeed {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, feed := range m.feeds[sub.key] {
+		feed.mu.Lock()
+		if feed.attachable(sub.startTs) {
+			sub.attach(feed)
+			feed.mu.Unlock()
+			return feed
+		}
+		feed.mu.Unlock()
+	}
+
+	// The shared feed outlives the changefeed creating it, so it doesn't inherit the context. The regions of the
+	// feed are scanned on behalf of the changefeed creating it, and the changefeeds sharing the feed have the same
+	// scan limits, which are a part of the key.
+	ctx := util.PutCaptureAddrInCtx(context.Background(), util.CaptureAddrFromCtx(sub.ctx))
+	tableID, tableName := util.TableIDFromCtx(sub.ctx)
+	ctx = util.PutTableInfoInCtx(ctx, tableID, tableName)
+	ctx = util.PutChangefeedIDInCtx(ctx, util.ChangefeedIDFromCtx(sub.ctx))
+	if limit := util.RegionScanLimitFromCtx(sub.ctx); limit > 0 {
+		ctx = util.PutRegionScanLimitInCtx(ctx, limit)
+	}
+	ctx = util.PutScanPriorityInCtx(ctx, util.ScanPriorityFromCtx(sub.ctx))
+	ctx = util.PutScanBytesPerSecondInCtx(ctx, util.ScanBytesPerSecondFromCtx(sub.ctx))
+	ctx, cancel := context.WithCancel(ctx)
+	feed := &sharedFeed{
+		key:          sub.key,
+		puller:       sub.newPuller(ctx, sub.startTs),
+		cancel:       cancel,
+		done:         make(chan struct{}),
+		historySize:  m.historySize,
+		subscribers:  make(map[*sharedPuller]struct{}),
+		floorTs:      sub.startTs,
+		lastResolved: -1,
+	}
+	sub.attach(feed)
+	go feed.run(ctx)
+	m.feeds[sub.key] = append(m.feeds[sub.key], feed)
+	sharedPullerGauge.WithLabelValues(util.CaptureAddrFromCtx(sub.ctx)).Inc()
+	log.Info("shared puller is created", zap.String("key", sub.key), zap.Uint64("startTs", sub.startTs),
+		zap.String("changefeed", util.ChangefeedIDFromCtx(sub.ctx)))
+	return feed
+}
+
+// unsubscribe detaches the subscriber, the feed is closed after the last subscriber detaches.
+func (m *SharedPullerManager) unsubscribe(sub *sharedPuller, feed *sharedFeed) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	feed.mu.Lock()
+	delete(feed.subscribers, sub)
+	if len(feed.subscribers) > 0 || feed.removed {
+		feed.mu.Unlock()
+		return
+	}
+	feed.closed = true
+	feed.removed = true
+	feed.clearHistory()
+	feed.mu.Unlock()
+
+	feed.cancel()
+	feeds := m.feeds[feed.key]
+	for i := range feeds {
+		if feeds[i] == feed {
+			feeds = append(feeds[:i], feeds[i+1:]...)
+			break
+		}
+	}
+	if len(feeds) == 0 {
+		delete(m.feeds, feed.key)
+	} else {
+		m.feeds[feed.key] = feeds
+	}
+	sharedPullerGauge.WithLabelValues(util.CaptureAddrFromCtx(sub.ctx)).Dec()
+	log.Info("shared puller is closed", zap.String("key", feed.key))
+}
+
+// sharedPuller is a Puller receiving the events from a shared feed, it only outputs the events after its start ts.
+// The events pushed by the feed are pending until they are output, if the subscriber lags behind the feed too much,
+// it's detached from the feed and pulls the events by a private puller from its resolved ts.
+type sharedPuller struct {
+	ctx         context.Context
+	key         string
+	startTs     uint64
+	newPuller   func(ctx context.Context, checkpointTs uint64) Puller
+	manager     *SharedPullerManager
+	pendingSize int64
+
+	outputCh   chan *model.RawKVEntry
+	resolvedTs uint64
+	feed       atomic.Value
+	private    atomic.Value
+
+	mu      sync.Mutex
+	pending []*model.RawKVEntry
+	// bufferedBytes is the size of the entries pushed by the feed but not output yet, including the pending
+	// ones and the ones being output
+	bufferedBytes int64
+	lagging       bool
+	notify        chan struct{}
+	// lagCh is closed after the subscriber is detached for lagging behind the feed
+	lagCh chan struct{}
+}
+
+// NewSharedPuller creates a puller sharing the events from TiKV with the other changefeeds pulling the same
+// spans with the same old value option and scan limits. Only the events from TiKV are shared, the
+// changefeeds sort the events by themselves, because they are sorted from their own resume ts, and the memory
+// and the disk of the sorters are limited per changefeed.
+func NewSharedPuller(
+	ctx context.Context,
+	pdCli pd.Client,
+	credential *security.Credential,
+	kvStorage tidbkv.Storage,
+	checkpointTs uint64,
+	spans []regionspan.Span,
+	enableOldValue bool,
+) Puller {
+	comparableSpans := make([]regionspan.ComparableSpan, len(spans))
+	for i := range spans {
+		comparableSpans[i] = regionspan.ToComparableSpan(spans[i])
+	}
+	key := sharedFeedKey(ctx, comparableSpans, enableOldValue)
+	return newSharedPuller(ctx, GetSharedPullerManager(), checkpointTs, key,
+		func(ctx context.Context, checkpointTs uint64) Puller {
+			return NewPuller(ctx, pdCli, credential, kvStorage, checkpointTs, spans, nil, enableOldValue)
+		})
+}
+
+func newSharedPuller(
+	ctx context.Context, manager *SharedPullerManager, checkpointTs uint64, key string,
+	newPuller func(ctx context.Context, checkpointTs uint64) Puller,
+) *sharedPuller {
+	return &sharedPuller{
+		ctx:         ctx,
+		key:         key,
+		startTs:     checkpointTs,
+		newPuller:   newPuller,
+		manager:     manager,
+		pendingSize: manager.pendingSize,
+		outputCh:    make(chan *model.RawKVEntry, defaultPullerOutputChanSize),
+		resolvedTs:  checkpointTs,
+		notify:      make(chan struct{}, 1),
+		lagCh:       make(chan struct{}),
+	}
+}
+
+// attach replays the history to the subscriber and adds it to the feed, the feed is locked.
+func (p *sharedPuller) attach(feed *sharedFeed) {
+	p.mu.Lock()
+	var size int64
+	for _, raw := range feed.history {
+		if p.accept(raw) {
+			p.pending = append(p.pending, raw)
+			size += sharedEntrySize(raw)
+		}
+	}
+	p.bufferedBytes += size
+	p.mu.Unlock()
+	addSharedPullerUsage(size)
+	p.wakeup()
+	feed.subscribers[p] = struct{}{}
+	p.feed.Store(feed)
+}
+
+// accept returns whether the entry is output by the subscriber
+func (p *sharedPuller) accept(raw *model.RawKVEntry) bool {
+	if raw.OpType == model.OpTypeResolved {
+		return raw.CRTs >= p.startTs
+	}
+	return raw.CRTs > p.startTs
+}
+
+func (p *sharedPuller) wakeup() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// push appends the entry to the pending events without blocking, false is returned if the size of the entries
+// buffered by the subscriber exceeds the limit, and the pending events are discarded.
+func (p *sharedPuller) push(raw *model.RawKVEntry) bool {
+	if !p.accept(raw) {
+		return true
+	}
+	p.mu.Lock()
+	if p.lagging {
+		p.mu.Unlock()
+		return false
+	}
+	if p.bufferedBytes >= p.pendingSize {
+		p.lagging = true
+		p.mu.Unlock()
+		p.discardPending()
+		close(p.lagCh)
+		return false
+	}
+	size := sharedEntrySize(raw)
+	p.pending = append(p.pending, raw)
+	p.bufferedBytes += size
+	p.mu.Unlock()
+	addSharedPullerUsage(size)
+	p.wakeup()
+	return true
+}
+
+// release releases the size of the entries output or discarded by the subscriber
+func (p *sharedPuller) release(size int64) {
+	p.mu.Lock()
+	p.bufferedBytes -= size
+	p.mu.Unlock()
+	addSharedPullerUsage(-size)
+}
+
+// discardPending discards the pending events, which are never output
+func (p *sharedPuller) discardPending() {
+	p.mu.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+	p.release(entriesSize(pending))
+}
+
+func entriesSize(entries []*model.RawKVEntry) int64 {
+	var size int64
+	for _, raw := range entries {
+		size += sharedEntrySize(raw)
+	}
+	return size
+}
+
+// send outputs the entry and advances the resolved ts
+func (p *sharedPuller) send(ctx context.Context, raw *model.RawKVEntry) error {
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case p.outputCh <- raw:
+	}
+	if raw.OpType == model.OpTypeResolved {
+		atomic.StoreUint64(&p.resolvedTs, raw.CRTs)
+	}
+	return nil
+}
+
+// output outputs the pending events until the context is done
+func (p *sharedPuller) output(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		pending := p.pending
+		p.pending = nil
+		p.mu.Unlock()
+		for i, raw := range pending {
+			if err := p.send(ctx, raw); err != nil {
+				p.release(entriesSize(pending[i:]))
+				return errors.Trace(err)
+			}
+			p.release(sharedEntrySize(raw))
+			pending[i] = nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-p.notify:
+		}
+	}
+}
+
+// Run implements the Puller interface, it attaches to a shared feed and outputs the events until the context
+// is done, or pulls the events by itself after it lags behind the feed.
+func (p *sharedPuller) Run(ctx context.Context) error {
+	// the feed created by the subscriber inherits the values of the context of Run
+	p.ctx = ctx
+	feed := p.manager.subscribe(p)
+	outputCtx, cancel := context.WithCancel(ctx)
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		p.output(outputCtx) //nolint:errcheck
+	}()
+	stop := func() {
+		cancel()
+		<-outputDone
+		p.manager.unsubscribe(p, feed)
+		// no event is pushed after the subscriber is detached
+		p.discardPending()
+	}
+	select {
+	case <-ctx.Done():
+		stop()
+		return errors.Trace(ctx.Err())
+	case <-feed.done:
+		stop()
+		// the feed is only closed by itself if the puller fails
+		return errors.Trace(feed.err)
+	case <-p.lagCh:
+		stop()
+		return p.runPrivately(ctx)
+	}
+}
+
+// runPrivately pulls the events by a private puller from the resolved ts of the subscriber, the events after
+// the resolved ts may be output again, as they are when the regions are reconnected.
+func (p *sharedPuller) runPrivately(ctx context.Context) error {
+	resolvedTs := p.GetResolvedTs()
+	log.Warn("shared puller lags behind the feed, pull the events by itself",
+		zap.String("key", p.key), zap.String("changefeed", util.ChangefeedIDFromCtx(ctx)),
+		zap.Uint64("resolvedTs", resolvedTs))
+	plr := p.newPuller(ctx, resolvedTs)
+	p.private.Store(plr)
+	errg, ctx := errgroup.WithContext(ctx)
+	errg.Go(func() error {
+		return plr.Run(ctx)
+	})
+	errg.Go(func() error {
+		for {
+			var raw *model.RawKVEntry
+			select {
+			case <-ctx.Done():
+				return errors.Trace(ctx.Err())
+			case raw = <-plr.Output():
+			}
+			if raw == nil || raw.CRTs < resolvedTs || (raw.CRTs == resolvedTs && raw.OpType != model.OpTypeResolved) {
+				continue
+			}
+			if err := p.send(ctx, raw); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	})
+	return errg.Wait()
+}
+
+// GetResolvedTs implements the Puller interface
+func (p *sharedPuller) GetResolvedTs() uint64 {
+	return atomic.LoadUint64(&p.resolvedTs)
+}
+
+// Output implements the Puller interface
+func (p *sharedPuller) Output() <-chan *model.RawKVEntry {
+	return p.outputCh
+}
+
+// IsInitialized implements the Puller interface
+func (p *sharedPuller) IsInitialized() bool {
+	if plr, ok := p.private.Load().(Puller); ok {
+		return plr.IsInitialized()
+	}
+	feed, ok := p.feed.Load().(*sharedFeed)
+	return ok && feed.puller.IsInitialized()
+}
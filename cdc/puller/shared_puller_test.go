@@ -0,0 +1,372 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/memquota"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type sharedPullerSuite struct{}
+
+var _ = check.Suite(&sharedPullerSuite{})
+
+// fakePuller outputs the events sent by the test
+type fakePuller struct {
+	outputCh chan *model.RawKVEntry
+	errCh    chan error
+}
+
+func (p *fakePuller) Run(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case err := <-p.errCh:
+		return err
+	}
+}
+
+func (p *fakePuller) GetResolvedTs() uint64 {
+	return 0
+}
+
+func (p *fakePuller) Output() <-chan *model.RawKVEntry {
+	return p.outputCh
+}
+
+func (p *fakePuller) IsInitialized() bool {
+	return true
+}
+
+type fakePullerFactory struct {
+	mu            sync.Mutex
+	pullers       []*fakePuller
+	checkpointTss []uint64
+	changefeedIDs []string
+}
+
+func (f *fakePullerFactory) newPuller(ctx context.Context, checkpointTs uint64) Puller {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := &fakePuller{outputCh: make(chan *model.RawKVEntry), errCh: make(chan error, 1)}
+	f.pullers = append(f.pullers, p)
+	f.checkpointTss = append(f.checkpointTss, checkpointTs)
+	f.changefeedIDs = append(f.changefeedIDs, util.ChangefeedIDFromCtx(ctx))
+	return p
+}
+
+func (f *fakePullerFactory) changefeedID(i int) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.changefeedIDs[i]
+}
+
+func (f *fakePullerFactory) checkpointTs(i int) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.checkpointTss[i]
+}
+
+func (f *fakePullerFactory) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.pullers)
+}
+
+func (f *fakePullerFactory) get(i int) *fakePuller {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pullers[i]
+}
+
+func (m *SharedPullerManager) feedCount(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.feeds[key])
+}
+
+func (m *SharedPullerManager) subscriberCount(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, feed := range m.feeds[key] {
+		feed.mu.Lock()
+		count += len(feed.subscribers)
+		feed.mu.Unlock()
+	}
+	return count
+}
+
+// runSharedPuller runs the puller in background, and returns the cancel function and the error channel of Run
+func runSharedPuller(p *sharedPuller) (context.CancelFunc, <-chan error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Run(ctx)
+	}()
+	return cancel, errCh
+}
+
+func waitFor(c *check.C, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatal("timeout")
+}
+
+func mustReceive(c *check.C, p Puller, opType model.OpType, crts uint64) {
+	select {
+	case raw := <-p.Output():
+		c.Assert(raw.OpType, check.Equals, opType)
+		c.Assert(raw.CRTs, check.Equals, crts)
+	case <-time.After(time.Second):
+		c.Fatalf("timeout to receive the event %d", crts)
+	}
+}
+
+func (s *sharedPullerSuite) TestSharedPuller(c *check.C) {
+	defer testleak.AfterTest(c)()
+	m := newSharedPullerManager(16*sizeOfRawKVEntry, 1024*sizeOfRawKVEntry)
+	factory := &fakePullerFactory{}
+	ctx := util.PutChangefeedIDInCtx(context.Background(), "cf1")
+
+	p1 := newSharedPuller(ctx, m, 100, "t1", factory.newPuller)
+	cancel1, errCh1 := runSharedPuller(p1)
+	waitFor(c, func() bool { return m.feedCount("t1") == 1 })
+	upstream := factory.get(0)
+	upstream.outputCh <- &model.RawKVEntry{OpType: model.OpTypePut, CRTs: 100}
+	upstream.outputCh <- &model.RawKVEntry{OpType: model.OpTypePut, CRTs: 105}
+	upstream.outputCh <- &model.RawKVEntry{OpType: model.OpTypePut, CRTs: 120}
+	upstream.outputCh <- &model.RawKVEntry{OpType: model.OpTypeResolved, CRTs: 110}
+	// the events at the start ts are filtered out
+	mustReceive(c, p1, model.OpTypePut, 105)
+	mustReceive(c, p1, model.OpTypePut, 120)
+	mustReceive(c, p1, model.OpTypeResolved, 110)
+	c.Assert(p1.GetResolvedTs(), check.Equals, uint64(110))
+
+	// the late subscriber receives the events after its start ts in the history
+	p2 := newSharedPuller(ctx, m, 115, "t1", factory.newPuller)
+	cancel2, errCh2 := runSharedPuller(p2)
+	mustReceive(c, p2, model.OpTypePut, 120)
+	c.Assert(p2.IsInitialized(), check.IsTrue)
+	upstream.outputCh <- &model.RawKVEntry{OpType: model.OpTypeResolved, CRTs: 130}
+	mustReceive(c, p1, model.OpTypeResolved, 130)
+	mustReceive(c, p2, model.OpTypeResolved, 130)
+
+	// the subscriber starting below the resolved ts but at or above the floor ts shares the feed too
+	p3 := newSharedPuller(ctx, m, 105, "t1", factory.newPuller)
+	cancel3, errCh3 := runSharedPuller(p3)
+	mustReceive(c, p3, model.OpTypePut, 120)
+	mustReceive(c, p3, model.OpTypeResolved, 110)
+	mustReceive(c, p3, model.OpTypeResolved, 130)
+	c.Assert(factory.count(), check.Equals, 1)
+
+	// the subscriber starting below the floor ts pulls the events by another feed
+	p4 := newSharedPuller(util.PutChangefeedIDInCtx(ctx, "cf2"), m, 90, "t1", factory.newPuller)
+	cancel4, errCh4 := runSharedPuller(p4)
+	waitFor(c, func() bool { return m.feedCount("t1") == 2 })
+	c.Assert(factory.count(), check.Equals, 2)
+	// the feed pulls the events on behalf of the changefeed creating it
+	c.Assert(factory.changefeedID(0), check.Equals, "cf1")
+	c.Assert(factory.changefeedID(1), check.Equals, "cf2")
+	// the other spans don't share the feed
+	p5 := newSharedPuller(ctx, m, 130, "t2", factory.newPuller)
+	cancel5, errCh5 := runSharedPuller(p5)
+	waitFor(c, func() bool { return m.feedCount("t2") == 1 })
+	c.Assert(factory.count(), check.Equals, 3)
+
+	// the feed is closed after all the subscribers exit
+	cancel1()
+	c.Assert(errors.Cause(<-errCh1), check.Equals, context.Canceled)
+	upstream.outputCh <- &model.RawKVEntry{OpType: model.OpTypePut, CRTs: 140}
+	mustReceive(c, p2, model.OpTypePut, 140)
+	mustReceive(c, p3, model.OpTypePut, 140)
+	c.Assert(m.feedCount("t1"), check.Equals, 2)
+	cancel2()
+	c.Assert(errors.Cause(<-errCh2), check.Equals, context.Canceled)
+	cancel3()
+	c.Assert(errors.Cause(<-errCh3), check.Equals, context.Canceled)
+	c.Assert(m.feedCount("t1"), check.Equals, 1)
+
+	// the error of the feed is returned to the subscribers
+	factory.get(1).errCh <- errors.New("injected error")
+	c.Assert(<-errCh4, check.ErrorMatches, ".*injected error")
+	c.Assert(m.feedCount("t1"), check.Equals, 0)
+	cancel4()
+
+	cancel5()
+	c.Assert(errors.Cause(<-errCh5), check.Equals, context.Canceled)
+	c.Assert(m.feeds, check.HasLen, 0)
+	c.Assert(sharedPullerUsage(), check.Equals, "0")
+}
+
+func (s *sharedPullerSuite) TestSharedFeedHistory(c *check.C) {
+	defer testleak.AfterTest(c)()
+	defer func() {
+		c.Assert(sharedPullerUsage(), check.Equals, "0")
+	}()
+	feed := &sharedFeed{historySize: 4 * sizeOfRawKVEntry, floorTs: 100, lastResolved: -1}
+	defer func() {
+		feed.mu.Lock()
+		feed.clearHistory()
+		feed.mu.Unlock()
+	}()
+	c.Assert(feed.attachable(100), check.IsTrue)
+	c.Assert(feed.attachable(99), check.IsFalse)
+	feed.record(&model.RawKVEntry{OpType: model.OpTypePut, CRTs: 110})
+	feed.record(&model.RawKVEntry{OpType: model.OpTypePut, CRTs: 120})
+	feed.record(&model.RawKVEntry{OpType: model.OpTypeResolved, CRTs: 105})
+	feed.record(&model.RawKVEntry{OpType: model.OpTypePut, CRTs: 130})
+	c.Assert(feed.history, check.HasLen, 4)
+	c.Assert(feed.historyBytes, check.Equals, int64(4*sizeOfRawKVEntry))
+	c.Assert(sharedPullerUsage(), check.Equals, fmt.Sprint(4*sizeOfRawKVEntry))
+	c.Assert(feed.attachable(100), check.IsTrue)
+
+	// the entries at or below the last resolved ts are evicted from the full history
+	feed.record(&model.RawKVEntry{OpType: model.OpTypePut, CRTs: 140})
+	c.Assert(historyTs(feed), check.DeepEquals, []uint64{110, 120, 130, 140})
+	c.Assert(feed.floorTs, check.Equals, uint64(105))
+	c.Assert(feed.attachable(100), check.IsFalse)
+	c.Assert(feed.attachable(105), check.IsTrue)
+
+	// the events are dropped if the history is full of the unresolved events
+	feed.record(&model.RawKVEntry{OpType: model.OpTypePut, CRTs: 150})
+	c.Assert(historyTs(feed), check.DeepEquals, []uint64{110, 120, 130, 140})
+	c.Assert(feed.attachable(120), check.IsFalse)
+	c.Assert(feed.attachable(150), check.IsTrue)
+	feed.record(&model.RawKVEntry{OpType: model.OpTypeResolved, CRTs: 125})
+	feed.record(&model.RawKVEntry{OpType: model.OpTypePut, CRTs: 160})
+	c.Assert(historyTs(feed), check.DeepEquals, []uint64{130, 140, 160})
+	c.Assert(feed.historyBytes, check.Equals, int64(3*sizeOfRawKVEntry))
+	c.Assert(feed.floorTs, check.Equals, uint64(125))
+
+	c.Assert(feed.attachable(125), check.IsFalse)
+	c.Assert(feed.attachable(150), check.IsTrue)
+
+	// the history is bounded by the size of the events
+	feed.record(&model.RawKVEntry{OpType: model.OpTypeResolved, CRTs: 135})
+	feed.record(&model.RawKVEntry{OpType: model.OpTypePut, CRTs: 170, Value: make([]byte, 4*sizeOfRawKVEntry)})
+	c.Assert(historyTs(feed), check.DeepEquals, []uint64{140, 160, 170})
+	feed.record(&model.RawKVEntry{OpType: model.OpTypePut, CRTs: 180})
+	c.Assert(historyTs(feed), check.DeepEquals, []uint64{140, 160, 170})
+	c.Assert(feed.attachable(170), check.IsFalse)
+	c.Assert(feed.attachable(180), check.IsTrue)
+
+	feed.closed = true
+	c.Assert(feed.attachable(150), check.IsFalse)
+}
+
+// sharedPullerUsage returns the memory usage of the shared pullers reported to the arbitrator
+func sharedPullerUsage() string {
+	var buf bytes.Buffer
+	memquota.GetGlobalArbitrator().WriteDebugInfo(&buf)
+	matches := regexp.MustCompile("component: " + memquota.ComponentSharedPuller + ", usage: (\\d+)").
+		FindStringSubmatch(buf.String())
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
+
+func historyTs(feed *sharedFeed) []uint64 {
+	ts := make([]uint64, 0, len(feed.history))
+	for _, raw := range feed.history {
+		ts = append(ts, raw.CRTs)
+	}
+	return ts
+}
+
+func (s *sharedPullerSuite) TestLaggingSubscriber(c *check.C) {
+	defer testleak.AfterTest(c)()
+	m := newSharedPullerManager(16*sizeOfRawKVEntry, 1024*sizeOfRawKVEntry)
+	factory := &fakePullerFactory{}
+	ctx := context.Background()
+
+	p1 := newSharedPuller(ctx, m, 100, "t1", factory.newPuller)
+	// the subscriber lags behind the feed once the size of its pending events exceeds the limit
+	p1.pendingSize = 4 * sizeOfRawKVEntry
+	cancel1, errCh1 := runSharedPuller(p1)
+	waitFor(c, func() bool { return m.feedCount("t1") == 1 })
+	p2 := newSharedPuller(ctx, m, 100, "t1", factory.newPuller)
+	cancel2, errCh2 := runSharedPuller(p2)
+	waitFor(c, func() bool { return m.subscriberCount("t1") == 2 })
+	received := make(chan uint64, 1)
+	go func() {
+		count := 0
+		for raw := range p2.Output() {
+			if raw.OpType == model.OpTypePut {
+				count++
+			}
+			if count == 200 {
+				received <- raw.CRTs
+				return
+			}
+		}
+	}()
+
+	// the slow subscriber doesn't block the others
+	upstream := factory.get(0)
+	upstream.outputCh <- &model.RawKVEntry{OpType: model.OpTypeResolved, CRTs: 110}
+	waitFor(c, func() bool { return p1.GetResolvedTs() == 110 })
+	for i := 0; i < 200; i++ {
+		upstream.outputCh <- &model.RawKVEntry{OpType: model.OpTypePut, CRTs: uint64(111 + i)}
+	}
+	select {
+	case crts := <-received:
+		c.Assert(crts, check.Equals, uint64(310))
+	case <-time.After(10 * time.Second):
+		c.Fatal("timeout to receive the events")
+	}
+
+	// the lagging subscriber is detached and pulls the events from its resolved ts by itself
+	waitFor(c, func() bool { return factory.count() == 2 })
+	c.Assert(factory.checkpointTs(1), check.Equals, uint64(110))
+	c.Assert(m.feedCount("t1"), check.Equals, 1)
+	c.Assert(m.subscriberCount("t1"), check.Equals, 1)
+	private := factory.get(1)
+	private.outputCh <- &model.RawKVEntry{OpType: model.OpTypePut, CRTs: 110}
+	private.outputCh <- &model.RawKVEntry{OpType: model.OpTypePut, CRTs: 400}
+	mustReceive(c, p1, model.OpTypeResolved, 110)
+	for {
+		raw := <-p1.Output()
+		c.Assert(raw.CRTs, check.Greater, uint64(110))
+		if raw.CRTs == 400 {
+			break
+		}
+	}
+	c.Assert(p1.IsInitialized(), check.IsTrue)
+
+	cancel1()
+	c.Assert(errors.Cause(<-errCh1), check.Equals, context.Canceled)
+	c.Assert(m.feedCount("t1"), check.Equals, 1)
+	cancel2()
+	c.Assert(errors.Cause(<-errCh2), check.Equals, context.Canceled)
+	c.Assert(m.feeds, check.HasLen, 0)
+	// the memory of the history and the events not output is released
+	c.Assert(sharedPullerUsage(), check.Equals, "0")
+}
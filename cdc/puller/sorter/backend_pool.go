@@ -33,6 +33,7 @@ import (
 	"github.com/pingcap/ticdc/pkg/config"
 	cerrors "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/filelock"
+	"github.com/pingcap/ticdc/pkg/memquota"
 	"github.com/pingcap/ticdc/pkg/util"
 	"go.uber.org/zap"
 )
@@ -53,9 +54,13 @@ type backEndPool struct {
 	onDiskDataSize    int64
 	fileNameCounter   uint64
 	memPressure       int32
-	cache             [256]unsafe.Pointer
-	dir               string
-	filePrefix        string
+	// diskState is the state of the disk quota, which is one of diskStateNormal, diskStateThrottled and diskStateExceeded
+	diskState  int32
+	cache      [256]unsafe.Pointer
+	dir        string
+	filePrefix string
+	// codec compresses and encrypts the files, it's nil if neither of them is enabled
+	codec *fileCodec
 
 	// to prevent `dir` from being accidentally used by another TiCDC server process.
 	fileLock *filelock.FileLock
@@ -76,7 +81,13 @@ func newBackEndPool(dir string, captureAddr string) (*backEndPool, error) {
 		filePrefix:        fmt.Sprintf("%s/%s-%d-", dir, sortDirDataFileMagicPrefix, os.Getpid()),
 	}
 
-	err := ret.lockSortDir()
+	codec, err := newFileCodec(config.GetGlobalServerConfig().Sorter, captureAddr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ret.codec = codec
+
+	err = ret.lockSortDir()
 	if err != nil {
 		log.Warn("failed to lock file prefix",
 			zap.String("prefix", ret.filePrefix),
@@ -89,20 +100,33 @@ func newBackEndPool(dir string, captureAddr string) (*backEndPool, error) {
 		log.Warn("Unified Sorter: failed to clean up stale temporary files. Report a bug if you believe this is unexpected", zap.Error(err))
 		return nil, errors.Trace(err)
 	}
+	memquota.GetGlobalArbitrator().SetUsageFunc(memquota.ComponentSorter, ret.sorterMemoryUsage)
 
 	go func() {
 		ticker := time.NewTicker(backgroundJobInterval)
 		defer ticker.Stop()
+		diskTicker := time.NewTicker(diskCheckInterval)
+		defer diskTicker.Stop()
 
 		metricSorterInMemoryDataSizeGauge := sorterInMemoryDataSizeGauge.WithLabelValues(captureAddr)
 		metricSorterOnDiskDataSizeGauge := sorterOnDiskDataSizeGauge.WithLabelValues(captureAddr)
 		metricSorterOpenFileCountGauge := sorterOpenFileCountGauge.WithLabelValues(captureAddr)
+		metricSorterDiskQuotaUsageGauge := sorterDiskQuotaUsageGauge.WithLabelValues(captureAddr)
+		metricSorterDiskAvailableGauge := sorterDiskAvailableGauge.WithLabelValues(captureAddr)
+		metricSorterDiskQuotaStateGauge := sorterDiskQuotaStateGauge.WithLabelValues(captureAddr)
 
 		for {
 			select {
 			case <-ret.cancelCh:
 				log.Info("Unified Sorter backEnd is being cancelled")
 				return
+			case <-diskTicker.C:
+				usage, fsAvail := ret.checkDiskQuota()
+				metricSorterDiskQuotaUsageGauge.Set(float64(usage))
+				metricSorterDiskAvailableGauge.Set(float64(fsAvail))
+				metricSorterDiskQuotaStateGauge.Set(float64(atomic.LoadInt32(&ret.diskState)))
+				metricSorterOnDiskDataSizeGauge.Set(float64(atomic.LoadInt64(&ret.onDiskDataSize)))
+				continue
 			case <-ticker.C:
 			}
 
@@ -175,7 +199,8 @@ func newBackEndPool(dir string, captureAddr string) (*backEndPool, error) {
 func (p *backEndPool) alloc(ctx context.Context) (backEnd, error) {
 	sorterConfig := config.GetGlobalServerConfig().Sorter
 	if p.sorterMemoryUsage() < int64(sorterConfig.MaxMemoryConsumption) &&
-		p.memoryPressure() < int32(sorterConfig.MaxMemoryPressure) {
+		p.memoryPressure() < int32(sorterConfig.MaxMemoryPressure) &&
+		!memquota.GetGlobalArbitrator().IsExhausted() {
 
 		ret := newMemoryBackEnd()
 		return ret, nil
@@ -206,8 +231,11 @@ func (p *backEndPool) alloc(ctx context.Context) (backEnd, error) {
 	if err := util.CheckDataDirSatisfied(); err != nil {
 		return nil, errors.Trace(err)
 	}
+	if err := p.checkDiskQuotaExceeded(); err != nil {
+		return nil, errors.Trace(err)
+	}
 
-	ret, err := newFileBackEnd(fname, &msgPackGenSerde{})
+	ret, err := newFileBackEnd(fname, &msgPackGenSerde{}, p.codec)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
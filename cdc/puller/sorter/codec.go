@@ -0,0 +1,173 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sorter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerrors "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fileBlockSize is the size of the events buffered before being compressed and encrypted as a block
+const fileBlockSize = 64 * 1024 // 64KB
+
+// fileCodec compresses and encrypts the blocks written into the files by the unified sorter.
+// It's safe to be used by multiple goroutines.
+type fileCodec struct {
+	compression string
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	// aead is nil if the encryption is disabled
+	aead cipher.AEAD
+
+	metricRawBytes        prometheus.Counter
+	metricCompressedBytes prometheus.Counter
+	metricEncryptedBytes  prometheus.Counter
+	metricCompress        prometheus.Observer
+	metricDecompress      prometheus.Observer
+	metricEncrypt         prometheus.Observer
+	metricDecrypt         prometheus.Observer
+}
+
+// codecBuffers are the buffers reused by a reader or a writer across the blocks
+type codecBuffers struct {
+	compressed []byte
+	encrypted  []byte
+}
+
+// newFileCodec creates a fileCodec by the sorter config, nil is returned if neither the
+// compression nor the encryption is enabled.
+func newFileCodec(sorterConfig *config.SorterConfig, captureAddr string) (*fileCodec, error) {
+	key, err := sorterConfig.EncryptionKeyBytes()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	compression := sorterConfig.Compression
+	if compression == "" {
+		compression = config.SorterCompressionNone
+	}
+	if compression == config.SorterCompressionNone && key == nil {
+		return nil, nil
+	}
+
+	c := &fileCodec{
+		compression:           compression,
+		metricRawBytes:        sorterFileBlockBytesCounter.WithLabelValues(captureAddr, "raw"),
+		metricCompressedBytes: sorterFileBlockBytesCounter.WithLabelValues(captureAddr, "compressed"),
+		metricEncryptedBytes:  sorterFileBlockBytesCounter.WithLabelValues(captureAddr, "encrypted"),
+		metricCompress:        sorterFileCodecDuration.WithLabelValues(captureAddr, "compress"),
+		metricDecompress:      sorterFileCodecDuration.WithLabelValues(captureAddr, "decompress"),
+		metricEncrypt:         sorterFileCodecDuration.WithLabelValues(captureAddr, "encrypt"),
+		metricDecrypt:         sorterFileCodecDuration.WithLabelValues(captureAddr, "decrypt"),
+	}
+	switch compression {
+	case config.SorterCompressionNone, config.SorterCompressionSnappy:
+	case config.SorterCompressionZstd:
+		c.zstdEncoder, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		c.zstdDecoder, err = zstd.NewReader(nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	default:
+		return nil, cerrors.ErrIllegalUnifiedSorterParameter.GenWithStackByArgs("unknown compression " + compression)
+	}
+	if key != nil {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		c.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return c, nil
+}
+
+// encode compresses and then encrypts a block, the result is valid until the next call with the same buffers
+func (c *fileCodec) encode(block []byte, bufs *codecBuffers) ([]byte, error) {
+	c.metricRawBytes.Add(float64(len(block)))
+	if c.compression != config.SorterCompressionNone {
+		start := time.Now()
+		switch c.compression {
+		case config.SorterCompressionSnappy:
+			bufs.compressed = snappy.Encode(bufs.compressed[:cap(bufs.compressed)], block)
+		case config.SorterCompressionZstd:
+			bufs.compressed = c.zstdEncoder.EncodeAll(block, bufs.compressed[:0])
+		}
+		c.metricCompress.Observe(time.Since(start).Seconds())
+		c.metricCompressedBytes.Add(float64(len(bufs.compressed)))
+		block = bufs.compressed
+	}
+	if c.aead != nil {
+		start := time.Now()
+		// the block is prefixed by the random nonce
+		var nonce [12]byte
+		if _, err := io.ReadFull(rand.Reader, nonce[:c.aead.NonceSize()]); err != nil {
+			return nil, errors.Trace(err)
+		}
+		bufs.encrypted = append(bufs.encrypted[:0], nonce[:c.aead.NonceSize()]...)
+		bufs.encrypted = c.aead.Seal(bufs.encrypted, nonce[:c.aead.NonceSize()], block, nil)
+		c.metricEncrypt.Observe(time.Since(start).Seconds())
+		c.metricEncryptedBytes.Add(float64(len(bufs.encrypted)))
+		block = bufs.encrypted
+	}
+	return block, nil
+}
+
+// decode decrypts and then decompresses a block, the result is valid until the next call with the same buffers
+func (c *fileCodec) decode(block []byte, bufs *codecBuffers) ([]byte, error) {
+	if c.aead != nil {
+		start := time.Now()
+		nonceSize := c.aead.NonceSize()
+		if len(block) < nonceSize {
+			return nil, cerrors.ErrFileSorterDecode.GenWithStackByArgs()
+		}
+		var err error
+		bufs.encrypted, err = c.aead.Open(bufs.encrypted[:0], block[:nonceSize], block[nonceSize:], nil)
+		if err != nil {
+			return nil, cerrors.WrapError(cerrors.ErrFileSorterDecode, err)
+		}
+		c.metricDecrypt.Observe(time.Since(start).Seconds())
+		block = bufs.encrypted
+	}
+	if c.compression != config.SorterCompressionNone {
+		start := time.Now()
+		var err error
+		switch c.compression {
+		case config.SorterCompressionSnappy:
+			bufs.compressed, err = snappy.Decode(bufs.compressed[:cap(bufs.compressed)], block)
+		case config.SorterCompressionZstd:
+			bufs.compressed, err = c.zstdDecoder.DecodeAll(block, bufs.compressed[:0])
+		}
+		if err != nil {
+			return nil, cerrors.WrapError(cerrors.ErrFileSorterDecode, err)
+		}
+		c.metricDecompress.Observe(time.Since(start).Seconds())
+		block = bufs.compressed
+	}
+	return block, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sorter
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/failpoint"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerrors "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// diskCheckInterval is the interval of checking the disk consumption of the sorter files
+	diskCheckInterval = time.Second
+	// diskThrottleRatio is the percentage of the disk quota from which the pullers are throttled
+	diskThrottleRatio = 80
+	// diskWaitInterval is the interval of checking the disk quota by the throttled pullers
+	diskWaitInterval = 100 * time.Millisecond
+)
+
+// the states of the disk quota
+const (
+	diskStateNormal int32 = iota
+	diskStateThrottled
+	diskStateExceeded
+)
+
+// diskQuotaUsage returns the percentage of the disk quota used, the quota is exceeded if it's at least 100.
+// usedBytes is the size of the sorter files, fsTotal and fsAvail are the total and available bytes of the
+// filesystem of the sort-dir. 0 is returned if the quota is unlimited.
+func diskQuotaUsage(sorterConfig *config.SorterConfig, usedBytes, fsTotal, fsAvail uint64) uint64 {
+	var usage uint64
+	if sorterConfig.MaxDiskConsumption > 0 {
+		usage = usedBytes * 100 / sorterConfig.MaxDiskConsumption
+	}
+	if sorterConfig.MaxDiskPercentage > 0 && fsTotal > 0 && fsAvail <= fsTotal {
+		fsUsage := (fsTotal - fsAvail) * 100 * 100 / fsTotal / uint64(sorterConfig.MaxDiskPercentage)
+		if fsUsage > usage {
+			usage = fsUsage
+		}
+	}
+	return usage
+}
+
+func diskStateOf(usage uint64) int32 {
+	switch {
+	case usage >= 100:
+		return diskStateExceeded
+	case usage >= diskThrottleRatio:
+		return diskStateThrottled
+	default:
+		return diskStateNormal
+	}
+}
+
+// diskSpace returns the total and available bytes of the filesystem of the directory
+func diskSpace(dir string) (total, avail uint64, err error) {
+	fs := syscall.Statfs_t{}
+	if err := syscall.Statfs(dir, &fs); err != nil {
+		return 0, 0, cerrors.WrapError(cerrors.ErrGetDiskInfo, err)
+	}
+	return fs.Blocks * uint64(fs.Bsize), fs.Bavail * uint64(fs.Bsize), nil
+}
+
+// checkDiskQuota updates the state of the disk quota by the size of the sorter files and the
+// available space of the filesystem, and returns the usage of the quota and the available space.
+func (p *backEndPool) checkDiskQuota() (usage uint64, fsAvail uint64) {
+	sorterConfig := config.GetGlobalServerConfig().Sorter
+	usedBytes := atomic.LoadInt64(&p.onDiskDataSize)
+	if usedBytes < 0 {
+		usedBytes = 0
+	}
+	fsTotal, fsAvail, err := diskSpace(p.dir)
+	if err != nil {
+		log.Warn("unified sorter: failed to get the disk space", zap.String("dir", p.dir), zap.Error(err))
+	}
+	usage = diskQuotaUsage(sorterConfig, uint64(usedBytes), fsTotal, fsAvail)
+	failpoint.Inject("sorterDiskQuotaUsage", func(val failpoint.Value) {
+		usage = uint64(val.(int))
+	})
+
+	state := diskStateOf(usage)
+	if old := atomic.SwapInt32(&p.diskState, state); old != state {
+		fields := []zap.Field{
+			zap.Uint64("usagePercentage", usage),
+			zap.Int64("sorterFileBytes", usedBytes),
+			zap.Uint64("filesystemAvailable", fsAvail),
+			zap.Uint64("maxDiskConsumption", sorterConfig.MaxDiskConsumption),
+			zap.Int("maxDiskPercentage", sorterConfig.MaxDiskPercentage),
+		}
+		switch state {
+		case diskStateExceeded:
+			log.Warn("unified sorter: the disk quota is exceeded, writing the sorter files fails", fields...)
+		case diskStateThrottled:
+			log.Warn("unified sorter: the disk quota is almost exhausted, the pullers are throttled", fields...)
+		default:
+			log.Info("unified sorter: the disk quota is available again", fields...)
+		}
+	}
+	return usage, fsAvail
+}
+
+// checkDiskQuotaExceeded returns an error if the disk quota of the sorter is exceeded
+func (p *backEndPool) checkDiskQuotaExceeded() error {
+	if atomic.LoadInt32(&p.diskState) == diskStateExceeded {
+		sorterConfig := config.GetGlobalServerConfig().Sorter
+		return cerrors.ErrUnifiedSorterDiskQuotaExceeded.GenWithStackByArgs(fmt.Sprintf(
+			"sorter files %d bytes, max-disk-consumption %d bytes, max-disk-percentage %d%%",
+			atomic.LoadInt64(&p.onDiskDataSize), sorterConfig.MaxDiskConsumption, sorterConfig.MaxDiskPercentage))
+	}
+	return nil
+}
+
+// WaitDiskAvailable blocks until the disk quota of the unified sorter is not almost exhausted. The pullers
+// call it to stop receiving the events before the sorter files exceed the quota.
+func WaitDiskAvailable(ctx context.Context) error {
+	p := pool
+	if p == nil {
+		return nil
+	}
+	if atomic.LoadInt32(&p.diskState) == diskStateNormal {
+		return nil
+	}
+	ticker := time.NewTicker(diskWaitInterval)
+	defer ticker.Stop()
+	for atomic.LoadInt32(&p.diskState) != diskStateNormal {
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
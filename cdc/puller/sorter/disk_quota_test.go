@@ -0,0 +1,100 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sorter
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerrors "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type diskQuotaSuite struct{}
+
+var _ = check.SerialSuites(&diskQuotaSuite{})
+
+func (s *diskQuotaSuite) TestDiskQuotaUsage(c *check.C) {
+	defer testleak.AfterTest(c)()
+
+	conf := &config.SorterConfig{}
+	c.Assert(diskQuotaUsage(conf, 1024, 100, 1), check.Equals, uint64(0))
+
+	conf.MaxDiskConsumption = 1000
+	c.Assert(diskQuotaUsage(conf, 500, 100, 90), check.Equals, uint64(50))
+	c.Assert(diskStateOf(50), check.Equals, diskStateNormal)
+	c.Assert(diskQuotaUsage(conf, 800, 100, 90), check.Equals, uint64(80))
+	c.Assert(diskStateOf(80), check.Equals, diskStateThrottled)
+	c.Assert(diskQuotaUsage(conf, 1000, 100, 90), check.Equals, uint64(100))
+	c.Assert(diskStateOf(100), check.Equals, diskStateExceeded)
+
+	// the filesystem is 45% used, which is 90% of the max-disk-percentage
+	conf.MaxDiskPercentage = 50
+	c.Assert(diskQuotaUsage(conf, 500, 100, 55), check.Equals, uint64(90))
+	c.Assert(diskQuotaUsage(conf, 950, 100, 55), check.Equals, uint64(95))
+}
+
+func (s *diskQuotaSuite) TestDiskQuotaExceeded(c *check.C) {
+	defer testleak.AfterTest(c)()
+
+	originConfig := config.GetGlobalServerConfig()
+	defer config.StoreGlobalServerConfig(originConfig)
+	conf := config.GetDefaultServerConfig()
+	conf.Sorter.MaxDiskConsumption = 1000
+	config.StoreGlobalServerConfig(conf)
+
+	dir := c.MkDir()
+	p := &backEndPool{dir: dir}
+	originPool := pool
+	pool = p
+	defer func() {
+		pool = originPool
+	}()
+
+	usage, _ := p.checkDiskQuota()
+	c.Assert(usage, check.Equals, uint64(0))
+	c.Assert(WaitDiskAvailable(context.Background()), check.IsNil)
+
+	fb, err := newFileBackEnd(filepath.Join(dir, "quota"), &msgPackGenSerde{}, nil)
+	c.Assert(err, check.IsNil)
+	w, err := fb.writer()
+	c.Assert(err, check.IsNil)
+	c.Assert(w.writeNext(model.NewPolymorphicEvent(generateMockRawKV(10))), check.IsNil)
+	c.Assert(atomic.LoadInt64(&p.onDiskDataSize), check.Greater, int64(0))
+
+	// the pullers are throttled when the quota is almost exhausted
+	atomic.StoreInt64(&p.onDiskDataSize, 900)
+	p.checkDiskQuota()
+	c.Assert(atomic.LoadInt32(&p.diskState), check.Equals, diskStateThrottled)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	c.Assert(WaitDiskAvailable(ctx), check.ErrorMatches, ".*deadline exceeded.*")
+
+	// writing the files fails when the quota is exceeded
+	atomic.StoreInt64(&p.onDiskDataSize, 1000)
+	p.checkDiskQuota()
+	err = w.writeNext(model.NewPolymorphicEvent(generateMockRawKV(11)))
+	c.Assert(cerrors.ErrUnifiedSorterDiskQuotaExceeded.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*max-disk-consumption 1000 bytes.*")
+
+	atomic.StoreInt64(&p.onDiskDataSize, 0)
+	p.checkDiskQuota()
+	c.Assert(WaitDiskAvailable(context.Background()), check.IsNil)
+	c.Assert(w.flushAndClose(), check.IsNil)
+}
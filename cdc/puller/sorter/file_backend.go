@@ -40,11 +40,13 @@ var openFDCount int64
 type fileBackEnd struct {
 	fileName string
 	serde    serializerDeserializer
+	// codec is nil if neither the compression nor the encryption is enabled
+	codec    *fileCodec
 	borrowed int32
 	size     int64
 }
 
-func newFileBackEnd(fileName string, serde serializerDeserializer) (*fileBackEnd, error) {
+func newFileBackEnd(fileName string, serde serializerDeserializer, codec *fileCodec) (*fileBackEnd, error) {
 	f, err := os.Create(fileName)
 	if err != nil {
 		return nil, errors.Trace(wrapIOError(err))
@@ -59,6 +61,7 @@ func newFileBackEnd(fileName string, serde serializerDeserializer) (*fileBackEnd
 	return &fileBackEnd{
 		fileName: fileName,
 		serde:    serde,
+		codec:    codec,
 		borrowed: 0,
 	}, nil
 }
@@ -106,6 +109,8 @@ func (f *fileBackEnd) writer() (backEndWriter, error) {
 	if err != nil {
 		return nil, errors.Trace(wrapIOError(err))
 	}
+	// the data accounted in the disk consumption is truncated
+	f.cleanStats()
 
 	atomic.AddInt64(&openFDCount, 1)
 
@@ -166,6 +171,11 @@ type fileBackEndReader struct {
 	rawBytesBuf []byte
 	isEOF       bool
 
+	// the decoded block and the offset of the next event in it, used only if the codec is enabled
+	blockBuf    []byte
+	blockOffset int
+	codecBufs   codecBuffers
+
 	// to prevent truncation-like corruption
 	totalEvents uint64
 	readEvents  uint64
@@ -210,6 +220,60 @@ func (r *fileBackEndReader) readNext() (*model.PolymorphicEvent, error) {
 		return nil, nil
 	}
 
+	var rawBytes []byte
+	var err error
+	if r.backEnd.codec == nil {
+		rawBytes, err = r.readBlock()
+	} else {
+		rawBytes, err = r.readFromEncodedBlock()
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if rawBytes == nil {
+		return nil, nil
+	}
+
+	event := new(model.PolymorphicEvent)
+	_, err = r.backEnd.serde.unmarshal(event, rawBytes)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	r.readEvents += 1
+	return event, nil
+}
+
+// readFromEncodedBlock returns the next event in the decoded block, and decodes the next block
+// if all the events in the current block have been read.
+func (r *fileBackEndReader) readFromEncodedBlock() ([]byte, error) {
+	if r.blockOffset >= len(r.blockBuf) {
+		encoded, err := r.readBlock()
+		if err != nil || encoded == nil {
+			return nil, errors.Trace(err)
+		}
+		r.blockBuf, err = r.backEnd.codec.decode(encoded, &r.codecBufs)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		r.blockOffset = 0
+	}
+
+	if len(r.blockBuf)-r.blockOffset < 4 {
+		log.Panic("fileSorterBackEnd: truncated block. Damaged file or bug?", zap.String("file", r.backEnd.fileName))
+	}
+	size := int(binary.LittleEndian.Uint32(r.blockBuf[r.blockOffset:]))
+	r.blockOffset += 4
+	if len(r.blockBuf)-r.blockOffset < size {
+		log.Panic("fileSorterBackEnd: truncated block. Damaged file or bug?", zap.String("file", r.backEnd.fileName))
+	}
+	rawBytes := r.blockBuf[r.blockOffset : r.blockOffset+size]
+	r.blockOffset += size
+	return rawBytes, nil
+}
+
+// readBlock reads the next block in the file, nil is returned if the end of the file is reached
+func (r *fileBackEndReader) readBlock() ([]byte, error) {
 	var m uint32
 	err := binary.Read(r.reader, binary.LittleEndian, &m)
 	if err != nil {
@@ -253,14 +317,6 @@ func (r *fileBackEndReader) readNext() (*model.PolymorphicEvent, error) {
 		return nil, errors.Errorf("fileSorterBackEnd: expected %d bytes, actually read %d bytes", size, n)
 	}
 
-	event := new(model.PolymorphicEvent)
-	_, err = r.backEnd.serde.unmarshal(event, r.rawBytesBuf)
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-
-	r.readEvents += 1
-
 	failpoint.Inject("sorterDebug", func() {
 		r.readBytes += int64(4 + 4 + int(size))
 		if r.readBytes > r.totalSize {
@@ -269,7 +325,7 @@ func (r *fileBackEndReader) readNext() (*model.PolymorphicEvent, error) {
 		}
 	})
 
-	return event, nil
+	return r.rawBytesBuf, nil
 }
 
 func (r *fileBackEndReader) resetAndClose() error {
@@ -328,6 +384,10 @@ type fileBackEndWriter struct {
 	writer      *bufio.Writer
 	rawBytesBuf []byte
 
+	// the events to be encoded as a block, used only if the codec is enabled
+	blockBuf  []byte
+	codecBufs codecBuffers
+
 	bytesWritten  int64
 	eventsWritten int64
 }
@@ -359,7 +419,48 @@ func (w *fileBackEndWriter) writeNext(event *model.PolymorphicEvent) error {
 		log.Panic("fileSorterBackEnd: serialized to empty byte array. Bug?")
 	}
 
-	err = binary.Write(w.writer, binary.LittleEndian, uint32(blockMagic))
+	if w.backEnd.codec == nil {
+		if err := w.writeBlock(w.rawBytesBuf); err != nil {
+			return errors.Trace(err)
+		}
+		w.eventsWritten++
+		return nil
+	}
+
+	// the events are prefixed by their sizes in the block
+	var sizeBuf [4]byte
+	binary.LittleEndian.PutUint32(sizeBuf[:], uint32(size))
+	w.blockBuf = append(w.blockBuf, sizeBuf[:]...)
+	w.blockBuf = append(w.blockBuf, w.rawBytesBuf...)
+	w.eventsWritten++
+	if len(w.blockBuf) >= fileBlockSize {
+		return errors.Trace(w.flushEncodedBlock())
+	}
+	return nil
+}
+
+// flushEncodedBlock compresses and encrypts the buffered events, and writes them as a block
+func (w *fileBackEndWriter) flushEncodedBlock() error {
+	if len(w.blockBuf) == 0 {
+		return nil
+	}
+	encoded, err := w.backEnd.codec.encode(w.blockBuf, &w.codecBufs)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	w.blockBuf = w.blockBuf[:0]
+	return errors.Trace(w.writeBlock(encoded))
+}
+
+func (w *fileBackEndWriter) writeBlock(data []byte) error {
+	size := len(data)
+	if pool != nil {
+		if err := pool.checkDiskQuotaExceeded(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	err := binary.Write(w.writer, binary.LittleEndian, uint32(blockMagic))
 	if err != nil {
 		return errors.Trace(wrapIOError(err))
 	}
@@ -372,7 +473,7 @@ func (w *fileBackEndWriter) writeNext(event *model.PolymorphicEvent) error {
 	// short writes are possible with bufio
 	offset := 0
 	for offset < size {
-		n, err := w.writer.Write(w.rawBytesBuf[offset:])
+		n, err := w.writer.Write(data[offset:])
 		if err != nil {
 			return errors.Trace(wrapIOError(err))
 		}
@@ -382,8 +483,12 @@ func (w *fileBackEndWriter) writeNext(event *model.PolymorphicEvent) error {
 		return errors.Errorf("fileSorterBackEnd: expected to write %d bytes, actually wrote %d bytes", size, offset)
 	}
 
-	w.eventsWritten++
 	w.bytesWritten += int64(size)
+	// the size is accounted before the file is closed, so the disk quota covers the files being written
+	w.backEnd.size += int64(size)
+	if pool != nil {
+		atomic.AddInt64(&pool.onDiskDataSize, int64(size))
+	}
 	return nil
 }
 
@@ -401,6 +506,12 @@ func (w *fileBackEndWriter) flushAndClose() error {
 		w.f = nil
 	}()
 
+	if w.backEnd.codec != nil {
+		if err := w.flushEncodedBlock(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	err := w.writer.Flush()
 	if err != nil {
 		return errors.Trace(wrapIOError(err))
@@ -427,8 +538,6 @@ func (w *fileBackEndWriter) flushAndClose() error {
 	}
 
 	atomic.AddInt64(&openFDCount, -1)
-	w.backEnd.size = w.bytesWritten
-	atomic.AddInt64(&pool.onDiskDataSize, w.bytesWritten)
 
 	failpoint.Inject("sorterDebug", func() {
 		atomic.StoreInt32(&w.backEnd.borrowed, 0)
@@ -14,11 +14,15 @@
 package sorter
 
 import (
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/pingcap/check"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
 	cerrors "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/util/testleak"
 )
@@ -62,3 +66,59 @@ func (s *fileBackendSuite) TestNoSpace(c *check.C) {
 	c.Assert(err, check.ErrorMatches, ".*review the settings.*no space.*")
 	c.Assert(cerrors.ErrUnifiedSorterIOError.Equal(err), check.IsTrue)
 }
+
+func (s *fileBackendSuite) TestCodec(c *check.C) {
+	defer testleak.AfterTest(c)()
+
+	codec, err := newFileCodec(&config.SorterConfig{Compression: config.SorterCompressionNone}, "")
+	c.Assert(err, check.IsNil)
+	c.Assert(codec, check.IsNil)
+
+	key := "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	dir := c.MkDir()
+	testCases := []*config.SorterConfig{
+		{Compression: config.SorterCompressionSnappy},
+		{Compression: config.SorterCompressionZstd},
+		{Compression: config.SorterCompressionNone, EncryptionKey: key},
+		{Compression: config.SorterCompressionZstd, EncryptionKey: key},
+	}
+	for i, tc := range testCases {
+		codec, err := newFileCodec(tc, "")
+		c.Assert(err, check.IsNil)
+		c.Assert(codec, check.NotNil)
+		fb, err := newFileBackEnd(filepath.Join(dir, fmt.Sprintf("codec-%d", i)), &msgPackGenSerde{}, codec)
+		c.Assert(err, check.IsNil)
+
+		const numEvents = 10000
+		w, err := fb.writer()
+		c.Assert(err, check.IsNil)
+		for ts := uint64(10); ts < numEvents+10; ts++ {
+			rawKV := generateMockRawKV(ts)
+			rawKV.Key = []byte(fmt.Sprintf("key-%d", ts))
+			rawKV.Value = []byte(fmt.Sprintf("value-%d", ts))
+			c.Assert(w.writeNext(model.NewPolymorphicEvent(rawKV)), check.IsNil)
+		}
+		c.Assert(w.flushAndClose(), check.IsNil)
+		// the events are compressed or encrypted in the file
+		content, err := ioutil.ReadFile(fb.fileName)
+		c.Assert(err, check.IsNil)
+		c.Assert(string(content), check.Not(check.Matches), "(?s).*value-100.*")
+
+		r, err := fb.reader()
+		c.Assert(err, check.IsNil)
+		for ts := uint64(10); ts < numEvents+10; ts++ {
+			event, err := r.readNext()
+			c.Assert(err, check.IsNil)
+			c.Assert(event.CRTs, check.Equals, ts)
+			c.Assert(string(event.RawKV.Value), check.Equals, fmt.Sprintf("value-%d", ts))
+		}
+		event, err := r.readNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(event, check.IsNil)
+		c.Assert(r.resetAndClose(), check.IsNil)
+		c.Assert(fb.free(), check.IsNil)
+	}
+
+	_, err = newFileCodec(&config.SorterConfig{EncryptionKey: "0011"}, "")
+	c.Assert(err, check.ErrorMatches, ".*16, 24 or 32 bytes.*")
+}
@@ -82,6 +82,42 @@ var (
 		Help:      "Bucketed histogram of the number of events in individual merges performed by the sorter",
 		Buckets:   prometheus.ExponentialBuckets(16, 4, 10),
 	}, []string{"capture", "changefeed", "table"})
+
+	sorterDiskQuotaUsageGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ticdc",
+		Subsystem: "sorter",
+		Name:      "disk_quota_usage",
+		Help:      "the used percentage of the disk quota of the sorter",
+	}, []string{"capture"})
+
+	sorterDiskAvailableGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ticdc",
+		Subsystem: "sorter",
+		Name:      "disk_available",
+		Help:      "the available bytes of the filesystem of the sort-dir",
+	}, []string{"capture"})
+
+	sorterDiskQuotaStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ticdc",
+		Subsystem: "sorter",
+		Name:      "disk_quota_state",
+		Help:      "the state of the disk quota of the sorter, 0: normal, 1: the pullers are throttled, 2: exceeded",
+	}, []string{"capture"})
+
+	sorterFileBlockBytesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ticdc",
+		Subsystem: "sorter",
+		Name:      "file_block_bytes",
+		Help:      "the bytes of the blocks written into the files by the sorter, before and after compression and encryption",
+	}, []string{"capture", "type"})
+
+	sorterFileCodecDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "ticdc",
+		Subsystem: "sorter",
+		Name:      "file_codec_duration_seconds",
+		Help:      "Bucketed histogram of the time spent compressing, decompressing, encrypting and decrypting a file block",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 2, 18),
+	}, []string{"capture", "type"})
 )
 
 // InitMetrics registers all metrics in this file
@@ -95,4 +131,9 @@ func InitMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(sorterOpenFileCountGauge)
 	registry.MustRegister(sorterFlushCountHistogram)
 	registry.MustRegister(sorterMergeCountHistogram)
+	registry.MustRegister(sorterDiskQuotaUsageGauge)
+	registry.MustRegister(sorterDiskAvailableGauge)
+	registry.MustRegister(sorterDiskQuotaStateGauge)
+	registry.MustRegister(sorterFileBlockBytesCounter)
+	registry.MustRegister(sorterFileCodecDuration)
 }
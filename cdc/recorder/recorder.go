@@ -0,0 +1,319 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recorder
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/snappy"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filterV2 "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"go.uber.org/zap"
+)
+
+// A record file starts with the magic and the format version, followed by a snappy stream of the
+// length-prefixed header in JSON and the length-prefixed records. A record is an event in msgpack or
+// the commit ts of a schema change in uvarint, prefixed by the record type.
+const (
+	fileMagic     = "TiCDCREC"
+	formatVersion = uint32(2)
+	// maxRecordSize protects the reader from allocating a huge buffer for a corrupted file
+	maxRecordSize = 1 << 30
+)
+
+const (
+	recordTypeEvent byte = iota
+	// recordTypeSchemaChange ends the file, the events committed at or after its ts are mounted
+	// with a schema different from the recorded one
+	recordTypeSchemaChange
+)
+
+// Header describes the recorded table, the events are mounted with the schema at the start ts when replaying.
+// The DDLs executed on the table during the recording are not recorded, the recording stops once the schema
+// of the table changes, and the replay fails after replaying the events before the change.
+// The table ID is the ID of the table pipeline, which is a span table ID for a span of a split table.
+type Header struct {
+	ChangefeedID   model.ChangeFeedID `json:"changefeed-id"`
+	TableID        model.TableID      `json:"table-id"`
+	TableName      model.TableName    `json:"table-name"`
+	StartTs        uint64             `json:"start-ts"`
+	EnableOldValue bool               `json:"enable-old-value"`
+	Schema         *timodel.DBInfo    `json:"schema"`
+	Table          *timodel.TableInfo `json:"table"`
+	// TableInfoVersion is the version of the recorded schema of the table
+	TableInfoVersion uint64 `json:"table-info-version"`
+
+	// SchemaChangeTs returns the commit ts of the schema change of the table if the schema is
+	// different from the recorded one, it's nil if the schema is not tracked
+	SchemaChangeTs func() (ts uint64, changed bool) `json:"-"`
+}
+
+// ShouldRecord returns whether the events of the table are recorded by the config
+func ShouldRecord(cfg *config.RecorderConfig, name model.TableName) bool {
+	if !cfg.IsEnabled() {
+		return false
+	}
+	// the rules have been validated by the server config
+	f, err := filterV2.Parse(cfg.Tables)
+	if err != nil {
+		log.Warn("invalid recorder table rules", zap.Strings("rules", cfg.Tables), zap.Error(err))
+		return false
+	}
+	return f.MatchTable(name.Schema, name.Table)
+}
+
+// FileName returns the name of the file recording the table from the start ts
+func FileName(changefeedID model.ChangeFeedID, tableID model.TableID, startTs uint64) string {
+	return fmt.Sprintf("%s_%d_%d.rec", changefeedID, tableID, startTs)
+}
+
+// Writer writes the events pulled from TiKV to a record file
+type Writer struct {
+	path    string
+	file    *os.File
+	buf     *bufio.Writer
+	snappy  *snappy.Writer
+	size    uint64
+	maxSize uint64
+	lenBuf  [binary.MaxVarintLen64]byte
+	data    []byte
+}
+
+// NewWriter creates a record file in the dir and writes the header, the file can't exceed the max size.
+func NewWriter(dir string, header *Header, maxSize uint64) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Trace(err)
+	}
+	path := filepath.Join(dir, FileName(header.ChangefeedID, header.TableID, header.StartTs))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	w := &Writer{
+		path:    path,
+		file:    file,
+		buf:     bufio.NewWriter(file),
+		maxSize: maxSize,
+	}
+	w.snappy = snappy.NewBufferedWriter(w.buf)
+	prefix := make([]byte, len(fileMagic)+4)
+	copy(prefix, fileMagic)
+	binary.BigEndian.PutUint32(prefix[len(fileMagic):], formatVersion)
+	if _, err := w.buf.Write(prefix); err != nil {
+		w.Close() //nolint:errcheck
+		return nil, errors.Trace(err)
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		w.Close() //nolint:errcheck
+		return nil, cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+	if err := w.writeRecord(data); err != nil {
+		w.Close() //nolint:errcheck
+		return nil, errors.Trace(err)
+	}
+	return w, nil
+}
+
+// Path returns the path of the record file
+func (w *Writer) Path() string {
+	return w.path
+}
+
+func (w *Writer) writeRecord(data []byte) error {
+	n := binary.PutUvarint(w.lenBuf[:], uint64(len(data)))
+	size := uint64(n + len(data))
+	if w.maxSize > 0 && w.size+size > w.maxSize {
+		return cerror.ErrRecordFileTooLarge.GenWithStackByArgs(w.path, w.maxSize)
+	}
+	if _, err := w.snappy.Write(w.lenBuf[:n]); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := w.snappy.Write(data); err != nil {
+		return errors.Trace(err)
+	}
+	w.size += size
+	return nil
+}
+
+// Write writes an event to the file, the file is flushed on the resolved events.
+func (w *Writer) Write(raw *model.RawKVEntry) error {
+	var err error
+	w.data, err = raw.MarshalMsg(append(w.data[:0], recordTypeEvent))
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+	if err := w.writeRecord(w.data); err != nil {
+		return errors.Trace(err)
+	}
+	if raw.OpType == model.OpTypeResolved {
+		return w.Flush()
+	}
+	return nil
+}
+
+// WriteSchemaChange ends the file with the commit ts of the schema change of the table, the events committed
+// at or after it can't be mounted with the recorded schema.
+func (w *Writer) WriteSchemaChange(ts uint64) error {
+	n := binary.PutUvarint(w.lenBuf[:], ts)
+	w.data = append(append(w.data[:0], recordTypeSchemaChange), w.lenBuf[:n]...)
+	if err := w.writeRecord(w.data); err != nil {
+		return errors.Trace(err)
+	}
+	return w.Flush()
+}
+
+// Flush flushes the written events to the file
+func (w *Writer) Flush() error {
+	if err := w.snappy.Flush(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(w.buf.Flush())
+}
+
+// Close flushes and closes the file
+func (w *Writer) Close() error {
+	err := w.snappy.Close()
+	if err == nil {
+		err = w.buf.Flush()
+	}
+	if err1 := w.file.Close(); err == nil {
+		err = err1
+	}
+	return errors.Trace(err)
+}
+
+// Reader reads the events from a record file
+type Reader struct {
+	path   string
+	file   *os.File
+	reader *bufio.Reader
+	header *Header
+	data   []byte
+	// schemaChangeTs is the commit ts of the schema change ending the file, 0 if it's not reached
+	schemaChangeTs uint64
+}
+
+// NewReader opens a record file and reads its header
+func NewReader(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r := &Reader{path: path, file: file}
+	prefix := make([]byte, len(fileMagic)+4)
+	if _, err := io.ReadFull(file, prefix); err != nil || string(prefix[:len(fileMagic)]) != fileMagic {
+		file.Close() //nolint:errcheck
+		return nil, cerror.ErrInvalidRecordFile.GenWithStackByArgs(path)
+	}
+	if version := binary.BigEndian.Uint32(prefix[len(fileMagic):]); version != formatVersion {
+		file.Close() //nolint:errcheck
+		return nil, cerror.ErrInvalidRecordFile.GenWithStack("record file %s has unsupported version %d", path, version)
+	}
+	r.reader = bufio.NewReader(snappy.NewReader(file))
+	data, err := r.readRecord()
+	if err != nil {
+		file.Close() //nolint:errcheck
+		return nil, cerror.WrapError(cerror.ErrInvalidRecordFile, err)
+	}
+	r.header = new(Header)
+	if err := json.Unmarshal(data, r.header); err != nil {
+		file.Close() //nolint:errcheck
+		return nil, cerror.WrapError(cerror.ErrInvalidRecordFile, err)
+	}
+	return r, nil
+}
+
+// Header returns the header of the record file
+func (r *Reader) Header() *Header {
+	return r.header
+}
+
+// SchemaChangeTs returns the commit ts of the schema change ending the file, it's 0 until
+// ErrRecordSchemaChanged is returned by Next.
+func (r *Reader) SchemaChangeTs() uint64 {
+	return r.schemaChangeTs
+}
+
+func (r *Reader) readRecord() ([]byte, error) {
+	size, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxRecordSize {
+		return nil, cerror.ErrInvalidRecordFile.GenWithStack("record file %s has a record of %d bytes", r.path, size)
+	}
+	if uint64(cap(r.data)) < size {
+		r.data = make([]byte, size)
+	}
+	r.data = r.data[:size]
+	if _, err := io.ReadFull(r.reader, r.data); err != nil {
+		return nil, err
+	}
+	return r.data, nil
+}
+
+// Next returns the next event in the file, io.EOF is returned at the end of the file, and ErrRecordSchemaChanged
+// is returned if the recording stopped for a schema change of the table.
+// The file may be truncated if the capture crashed while recording, the incomplete tail is ignored.
+// Note a truncated snappy chunk can't be told from a corrupted one, so both end the file.
+func (r *Reader) Next() (*model.RawKVEntry, error) {
+	data, err := r.readRecord()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err == io.ErrUnexpectedEOF || err == snappy.ErrCorrupt {
+		log.Warn("the record file is truncated", zap.String("path", r.path), zap.Error(err))
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrInvalidRecordFile, err)
+	}
+	if len(data) == 0 {
+		return nil, cerror.ErrInvalidRecordFile.GenWithStack("record file %s has an empty record", r.path)
+	}
+	switch data[0] {
+	case recordTypeEvent:
+		raw := new(model.RawKVEntry)
+		if _, err := raw.UnmarshalMsg(data[1:]); err != nil {
+			return nil, cerror.WrapError(cerror.ErrInvalidRecordFile, err)
+		}
+		return raw, nil
+	case recordTypeSchemaChange:
+		ts, n := binary.Uvarint(data[1:])
+		if n <= 0 {
+			return nil, cerror.ErrInvalidRecordFile.GenWithStack("record file %s has an invalid schema change", r.path)
+		}
+		r.schemaChangeTs = ts
+		return nil, cerror.ErrRecordSchemaChanged.GenWithStackByArgs(r.path, ts)
+	default:
+		return nil, cerror.ErrInvalidRecordFile.GenWithStack("record file %s has an unknown record type %d", r.path, data[0])
+	}
+}
+
+// Close closes the file
+func (r *Reader) Close() error {
+	return errors.Trace(r.file.Close())
+}
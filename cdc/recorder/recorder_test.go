@@ -0,0 +1,315 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recorder
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/types"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type recorderSuite struct{}
+
+var _ = check.Suite(&recorderSuite{})
+
+func (s *recorderSuite) TestWriteAndRead(c *check.C) {
+	defer testleak.AfterTest(c)()
+	dir := c.MkDir()
+	header := &Header{ChangefeedID: "cf", TableID: 52, StartTs: 100, EnableOldValue: true}
+	w, err := NewWriter(dir, header, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(w.Path(), check.Equals, filepath.Join(dir, "cf_52_100.rec"))
+	events := []*model.RawKVEntry{
+		{OpType: model.OpTypePut, Key: []byte("k1"), Value: []byte("v1"), StartTs: 101, CRTs: 102, RegionID: 1},
+		{OpType: model.OpTypeDelete, Key: []byte("k2"), OldValue: []byte("v2"), StartTs: 103, CRTs: 104, RegionID: 2},
+		{OpType: model.OpTypeResolved, CRTs: 105},
+	}
+	for _, e := range events {
+		c.Assert(w.Write(e), check.IsNil)
+	}
+	c.Assert(w.Close(), check.IsNil)
+
+	r, err := NewReader(w.Path())
+	c.Assert(err, check.IsNil)
+	defer r.Close() //nolint:errcheck
+	c.Assert(r.Header(), check.DeepEquals, header)
+	for _, e := range events {
+		raw, err := r.Next()
+		c.Assert(err, check.IsNil)
+		c.Assert(raw.OpType, check.Equals, e.OpType)
+		c.Assert(raw.Key, check.BytesEquals, e.Key)
+		c.Assert(raw.Value, check.BytesEquals, e.Value)
+		c.Assert(raw.OldValue, check.BytesEquals, e.OldValue)
+		c.Assert(raw.StartTs, check.Equals, e.StartTs)
+		c.Assert(raw.CRTs, check.Equals, e.CRTs)
+		c.Assert(raw.RegionID, check.Equals, e.RegionID)
+	}
+	_, err = r.Next()
+	c.Assert(err, check.Equals, io.EOF)
+}
+
+func (s *recorderSuite) TestMaxSize(c *check.C) {
+	defer testleak.AfterTest(c)()
+	w, err := NewWriter(c.MkDir(), &Header{ChangefeedID: "cf", TableID: 1, StartTs: 1}, 256)
+	c.Assert(err, check.IsNil)
+	defer w.Close() //nolint:errcheck
+	raw := &model.RawKVEntry{OpType: model.OpTypePut, Key: []byte("key"), Value: make([]byte, 64), CRTs: 2}
+	for {
+		err = w.Write(raw)
+		if err != nil {
+			break
+		}
+	}
+	c.Assert(cerror.ErrRecordFileTooLarge.Equal(err), check.IsTrue)
+}
+
+func (s *recorderSuite) TestTruncatedFile(c *check.C) {
+	defer testleak.AfterTest(c)()
+	w, err := NewWriter(c.MkDir(), &Header{ChangefeedID: "cf", TableID: 1, StartTs: 1}, 0)
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 10; i++ {
+		c.Assert(w.Write(&model.RawKVEntry{OpType: model.OpTypeResolved, CRTs: uint64(i)}), check.IsNil)
+	}
+	c.Assert(w.Close(), check.IsNil)
+	info, err := os.Stat(w.Path())
+	c.Assert(err, check.IsNil)
+	// the last chunk is incomplete as if the capture crashed while writing it
+	c.Assert(os.Truncate(w.Path(), info.Size()-3), check.IsNil)
+
+	r, err := NewReader(w.Path())
+	c.Assert(err, check.IsNil)
+	defer r.Close() //nolint:errcheck
+	count := 0
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, check.IsNil)
+		count++
+	}
+	c.Assert(count, check.Equals, 9)
+
+	// the file without the magic is not a record file
+	path := filepath.Join(c.MkDir(), "invalid.rec")
+	c.Assert(os.WriteFile(path, []byte("not a record file"), 0o644), check.IsNil)
+	_, err = NewReader(path)
+	c.Assert(cerror.ErrInvalidRecordFile.Equal(err), check.IsTrue)
+}
+
+func (s *recorderSuite) TestShouldRecord(c *check.C) {
+	defer testleak.AfterTest(c)()
+	name := model.TableName{Schema: "test", Table: "t1"}
+	c.Assert(ShouldRecord(&config.RecorderConfig{Tables: []string{"*.*"}}, name), check.IsFalse)
+	cfg := &config.RecorderConfig{Dir: "/tmp", Tables: []string{"test.t*", "!test.t2"}}
+	c.Assert(ShouldRecord(cfg, name), check.IsTrue)
+	c.Assert(ShouldRecord(cfg, model.TableName{Schema: "test", Table: "t2"}), check.IsFalse)
+	c.Assert(ShouldRecord(cfg, model.TableName{Schema: "other", Table: "t1"}), check.IsFalse)
+}
+
+type mockSink struct {
+	mu         sync.Mutex
+	rows       []*model.RowChangedEvent
+	resolvedTs uint64
+}
+
+func (s *mockSink) Initialize(ctx context.Context, tableInfo []*model.SimpleTableInfo) error {
+	return nil
+}
+
+func (s *mockSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = append(s.rows, rows...)
+	return nil
+}
+
+func (s *mockSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	return nil
+}
+
+func (s *mockSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolvedTs = resolvedTs
+	return resolvedTs, nil
+}
+
+func (s *mockSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
+	return nil
+}
+
+func (s *mockSink) Close() error {
+	return nil
+}
+
+func (s *recorderSuite) TestReplay(c *check.C) {
+	defer testleak.AfterTest(c)()
+	helper := entry.NewSchemaTestHelper(c)
+	defer helper.Close()
+	job := helper.DDL2Job("create table test.t(id int primary key, v int)")
+	dbInfo, err := helper.GetCurrentMeta().GetDatabase(job.SchemaID)
+	c.Assert(err, check.IsNil)
+	tableInfo := job.BinlogInfo.TableInfo
+	header := &Header{
+		ChangefeedID: "cf",
+		TableID:      tableInfo.ID,
+		TableName:    model.TableName{Schema: "test", Table: "t"},
+		StartTs:      job.BinlogInfo.FinishedTS,
+		Schema:       dbInfo,
+		Table:        tableInfo,
+	}
+	w, err := NewWriter(c.MkDir(), header, 0)
+	c.Assert(err, check.IsNil)
+	colIDs := []int64{tableInfo.Columns[0].ID, tableInfo.Columns[1].ID}
+	ts := header.StartTs
+	for i := int64(1); i <= 3; i++ {
+		value, err := tablecodec.EncodeOldRow(
+			&stmtctx.StatementContext{}, []types.Datum{types.NewIntDatum(i), types.NewIntDatum(i * 10)}, colIDs, nil, nil)
+		c.Assert(err, check.IsNil)
+		ts++
+		c.Assert(w.Write(&model.RawKVEntry{
+			OpType:  model.OpTypePut,
+			Key:     tablecodec.EncodeRowKeyWithHandle(tableInfo.ID, kv.IntHandle(i)),
+			Value:   value,
+			StartTs: ts - 1,
+			CRTs:    ts,
+		}), check.IsNil)
+		if i == 2 {
+			c.Assert(w.Write(&model.RawKVEntry{OpType: model.OpTypeResolved, CRTs: ts}), check.IsNil)
+		}
+	}
+	// the events of the other tables are skipped
+	c.Assert(w.Write(&model.RawKVEntry{
+		OpType: model.OpTypePut,
+		Key:    tablecodec.EncodeRowKeyWithHandle(tableInfo.ID+1, kv.IntHandle(1)),
+		CRTs:   ts,
+	}), check.IsNil)
+	c.Assert(w.Write(&model.RawKVEntry{OpType: model.OpTypeResolved, CRTs: ts}), check.IsNil)
+	// the rows after the last resolved ts are not emitted
+	c.Assert(w.Write(&model.RawKVEntry{
+		OpType: model.OpTypePut,
+		Key:    tablecodec.EncodeRowKeyWithHandle(tableInfo.ID, kv.IntHandle(4)),
+		CRTs:   ts + 1,
+	}), check.IsNil)
+	c.Assert(w.Close(), check.IsNil)
+
+	r, err := NewReader(w.Path())
+	c.Assert(err, check.IsNil)
+	defer r.Close() //nolint:errcheck
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = util.PutTimezoneInCtx(ctx, time.UTC)
+	mockSink := &mockSink{}
+	stats, err := Replay(ctx, r, puller.NewEntrySorter(), mockSink, 2)
+	c.Assert(err, check.IsNil)
+	c.Assert(stats, check.DeepEquals, &ReplayStats{Events: 6, Rows: 3, ResolvedTs: ts})
+	c.Assert(mockSink.resolvedTs, check.Equals, ts)
+	c.Assert(mockSink.rows, check.HasLen, 3)
+	for i, row := range mockSink.rows {
+		c.Assert(row.Table.Table, check.Equals, "t")
+		c.Assert(row.CommitTs, check.Equals, header.StartTs+uint64(i)+1)
+		for _, col := range row.Columns {
+			if col.Name == "v" {
+				c.Assert(col.Value, check.Equals, int64(i+1)*10)
+			}
+		}
+	}
+}
+
+func (s *recorderSuite) TestReplaySchemaChanged(c *check.C) {
+	defer testleak.AfterTest(c)()
+	helper := entry.NewSchemaTestHelper(c)
+	defer helper.Close()
+	job := helper.DDL2Job("create table test.t(id int primary key, v int)")
+	dbInfo, err := helper.GetCurrentMeta().GetDatabase(job.SchemaID)
+	c.Assert(err, check.IsNil)
+	tableInfo := job.BinlogInfo.TableInfo
+	header := &Header{
+		ChangefeedID: "cf",
+		TableID:      tableInfo.ID,
+		TableName:    model.TableName{Schema: "test", Table: "t"},
+		StartTs:      job.BinlogInfo.FinishedTS,
+		Schema:       dbInfo,
+		Table:        tableInfo,
+	}
+	w, err := NewWriter(c.MkDir(), header, 0)
+	c.Assert(err, check.IsNil)
+	colIDs := []int64{tableInfo.Columns[0].ID, tableInfo.Columns[1].ID}
+	ts := header.StartTs
+	for i := int64(1); i <= 3; i++ {
+		value, err := tablecodec.EncodeOldRow(
+			&stmtctx.StatementContext{}, []types.Datum{types.NewIntDatum(i), types.NewIntDatum(i * 10)}, colIDs, nil, nil)
+		c.Assert(err, check.IsNil)
+		ts++
+		c.Assert(w.Write(&model.RawKVEntry{
+			OpType:  model.OpTypePut,
+			Key:     tablecodec.EncodeRowKeyWithHandle(tableInfo.ID, kv.IntHandle(i)),
+			Value:   value,
+			StartTs: ts - 1,
+			CRTs:    ts,
+		}), check.IsNil)
+	}
+	c.Assert(w.Write(&model.RawKVEntry{OpType: model.OpTypeResolved, CRTs: ts}), check.IsNil)
+	// the schema changed at the commit ts of the second row, which is recorded before the change is noticed
+	schemaChangeTs := header.StartTs + 2
+	c.Assert(w.WriteSchemaChange(schemaChangeTs), check.IsNil)
+	c.Assert(w.Close(), check.IsNil)
+
+	r, err := NewReader(w.Path())
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 4; i++ {
+		_, err := r.Next()
+		c.Assert(err, check.IsNil)
+	}
+	c.Assert(r.SchemaChangeTs(), check.Equals, uint64(0))
+	_, err = r.Next()
+	c.Assert(cerror.ErrRecordSchemaChanged.Equal(err), check.IsTrue)
+	c.Assert(r.SchemaChangeTs(), check.Equals, schemaChangeTs)
+	c.Assert(r.Close(), check.IsNil)
+
+	r, err = NewReader(w.Path())
+	c.Assert(err, check.IsNil)
+	defer r.Close() //nolint:errcheck
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = util.PutTimezoneInCtx(ctx, time.UTC)
+	mockSink := &mockSink{}
+	// only the rows before the schema change are emitted
+	stats, err := Replay(ctx, r, puller.NewEntrySorter(), mockSink, 2)
+	c.Assert(cerror.ErrRecordSchemaChanged.Equal(err), check.IsTrue)
+	c.Assert(stats, check.DeepEquals, &ReplayStats{Events: 2, Rows: 1, ResolvedTs: schemaChangeTs - 1})
+	c.Assert(mockSink.resolvedTs, check.Equals, schemaChangeTs-1)
+	c.Assert(mockSink.rows, check.HasLen, 1)
+	c.Assert(mockSink.rows[0].CommitTs, check.Equals, header.StartTs+1)
+}
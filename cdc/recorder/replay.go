@@ -0,0 +1,222 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recorder
+
+import (
+	"context"
+	"io"
+	"math"
+
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/cdc/sink"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// ReplayStats is the summary of a replay
+type ReplayStats struct {
+	Events     int    `json:"events"`
+	Rows       int    `json:"rows"`
+	ResolvedTs uint64 `json:"resolved-ts"`
+}
+
+// newSchemaStorage creates a schema storage only containing the recorded table at the start ts
+func newSchemaStorage(header *Header) (entry.SchemaStorage, error) {
+	if header.Schema == nil || header.Table == nil || header.StartTs == 0 {
+		return nil, cerror.ErrInvalidRecordFile.GenWithStack("the schema of the table is not recorded")
+	}
+	storage, err := entry.NewSchemaStorage(nil, 0, nil, true)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	jobs := []*timodel.Job{{
+		Type:       timodel.ActionCreateSchema,
+		State:      timodel.JobStateSynced,
+		SchemaID:   header.Schema.ID,
+		SchemaName: header.Schema.Name.O,
+		BinlogInfo: &timodel.HistoryInfo{DBInfo: header.Schema, FinishedTS: header.StartTs - 1},
+	}, {
+		Type:       timodel.ActionCreateTable,
+		State:      timodel.JobStateSynced,
+		SchemaID:   header.Schema.ID,
+		SchemaName: header.Schema.Name.O,
+		TableID:    header.Table.ID,
+		BinlogInfo: &timodel.HistoryInfo{TableInfo: header.Table, FinishedTS: header.StartTs},
+	}}
+	for _, job := range jobs {
+		if err := storage.HandleDDLJob(job); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	// no DDL is recorded, the schema never changes
+	storage.AdvanceResolvedTs(math.MaxUint64)
+	return storage, nil
+}
+
+// scanSchemaChangeTs returns the commit ts of the schema change ending the record file, 0 if the schema never changed
+func scanSchemaChangeTs(path string) (uint64, error) {
+	reader, err := NewReader(path)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer reader.Close() //nolint:errcheck
+	for {
+		_, err := reader.Next()
+		if err == io.EOF {
+			return 0, nil
+		}
+		if cerror.ErrRecordSchemaChanged.Equal(err) {
+			return reader.SchemaChangeTs(), nil
+		}
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+}
+
+// Replay feeds the events in the record file through the sorter and the mounter, and emits the rows to the sink.
+// The rows are flushed on every resolved ts, the rows after the last resolved ts in the file are not emitted.
+// If the schema of the table changed during the recording, the rows before the change are replayed, and
+// ErrRecordSchemaChanged is returned with the stats of them.
+func Replay(
+	ctx context.Context, reader *Reader, sorter puller.EventSorter, s sink.Sink, mounterWorkerNum int,
+) (*ReplayStats, error) {
+	header := reader.Header()
+	schemaStorage, err := newSchemaStorage(header)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	mounter := entry.NewMounter(schemaStorage, mounterWorkerNum, header.EnableOldValue)
+	// the table ID of a span of a split table is a span table ID
+	physicalTableID, _, _ := model.DecodeSpanTableID(header.TableID)
+	// the events at or after the schema change may be recorded before the change is noticed,
+	// they can't be mounted with the recorded schema
+	schemaChangeTs, err := scanSchemaChangeTs(reader.path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	stats := new(ReplayStats)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errg, ctx := errgroup.WithContext(ctx)
+	errg.Go(func() error {
+		return ignoreCanceled(sorter.Run(ctx))
+	})
+	errg.Go(func() error {
+		return ignoreCanceled(mounter.Run(ctx))
+	})
+
+	// lastResolvedCh receives the last resolved ts in the file after all the events are fed to the sorter
+	lastResolvedCh := make(chan uint64, 1)
+	errg.Go(func() error {
+		lastResolvedTs := uint64(0)
+		for {
+			raw, err := reader.Next()
+			if err == io.EOF || cerror.ErrRecordSchemaChanged.Equal(err) {
+				lastResolvedCh <- lastResolvedTs
+				return nil
+			}
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if schemaChangeTs != 0 && raw.CRTs >= schemaChangeTs {
+				if raw.OpType != model.OpTypeResolved {
+					continue
+				}
+				// all the events before the schema change are received, the replay ends before it
+				if schemaChangeTs-1 > lastResolvedTs {
+					lastResolvedTs = schemaChangeTs - 1
+					stats.Events++
+					sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, lastResolvedTs))
+				}
+				lastResolvedCh <- lastResolvedTs
+				return nil
+			}
+			if raw.OpType == model.OpTypeResolved {
+				lastResolvedTs = raw.CRTs
+			} else if tableID, err := entry.DecodeTableID(raw.Key); err == nil && tableID != physicalTableID {
+				// skip the events of the mark table of the cyclic replication
+				continue
+			}
+			stats.Events++
+			sorter.AddEntry(ctx, model.NewPolymorphicEvent(raw))
+		}
+	})
+
+	errg.Go(func() error {
+		// the sorter and the mounter exit after the sorted events are emitted
+		defer cancel()
+		var pending []*model.PolymorphicEvent
+		lastResolvedTs := uint64(math.MaxUint64)
+		for stats.ResolvedTs < lastResolvedTs {
+			var event *model.PolymorphicEvent
+			select {
+			case <-ctx.Done():
+				return errors.Trace(ctx.Err())
+			case lastResolvedTs = <-lastResolvedCh:
+				continue
+			case event = <-sorter.Output():
+			}
+			if event.RawKV.OpType != model.OpTypeResolved {
+				event.SetUpFinishedChan()
+				select {
+				case <-ctx.Done():
+					return errors.Trace(ctx.Err())
+				case mounter.Input() <- event:
+				}
+				pending = append(pending, event)
+				continue
+			}
+			rows := make([]*model.RowChangedEvent, 0, len(pending))
+			for _, e := range pending {
+				if err := e.WaitPrepare(ctx); err != nil {
+					return errors.Trace(err)
+				}
+				// the mounter skips some events, such as the keys of the other tables
+				if e.Row != nil {
+					rows = append(rows, e.Row)
+				}
+			}
+			pending = pending[:0]
+			if err := s.EmitRowChangedEvents(ctx, rows...); err != nil {
+				return errors.Trace(err)
+			}
+			if _, err := s.FlushRowChangedEvents(ctx, event.CRTs); err != nil {
+				return errors.Trace(err)
+			}
+			stats.Rows += len(rows)
+			stats.ResolvedTs = event.CRTs
+		}
+		return nil
+	})
+	if err := errg.Wait(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if schemaChangeTs != 0 {
+		return stats, cerror.ErrRecordSchemaChanged.GenWithStackByArgs(reader.path, schemaChangeTs)
+	}
+	return stats, nil
+}
+
+func ignoreCanceled(err error) error {
+	if errors.Cause(err) == context.Canceled {
+		return nil
+	}
+	return errors.Trace(err)
+}
@@ -26,11 +26,13 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/capture"
+	"github.com/pingcap/ticdc/cdc/entry"
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/puller/sorter"
 	"github.com/pingcap/ticdc/pkg/config"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/httputil"
+	"github.com/pingcap/ticdc/pkg/memquota"
 	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/ticdc/pkg/version"
 	tidbkv "github.com/pingcap/tidb/kv"
@@ -52,6 +54,8 @@ const (
 	defaultDataDir   = "/tmp/cdc_data"
 	// dataDirThreshold is used to warn if the free space of the specified data-dir is lower than it, unit is GB
 	dataDirThreshold = 500
+	// drainCheckInterval is the interval of checking whether the tables have been moved away from a draining capture
+	drainCheckInterval = time.Second
 )
 
 // Server is the capture server
@@ -171,6 +175,7 @@ func (s *Server) Run(ctx context.Context) error {
 		}
 	}()
 	s.kvStorage = kvStore
+	entry.SetSchemaSnapshotCacheUpstream(kvStore.UUID())
 	ctx = util.PutKVStorageInCtx(ctx, kvStore)
 	if config.NewReplicaImpl {
 		s.captureV2 = capture.NewCapture(s.pdClient, s.kvStorage, s.etcdClient)
@@ -333,9 +338,54 @@ func (s *Server) run(ctx context.Context) (err error) {
 		return kv.RunWorkerPool(cctx)
 	})
 
+	wg.Go(func() error {
+		arbitrator := memquota.GetGlobalArbitrator()
+		arbitrator.SetLimit(config.GetGlobalServerConfig().MemoryQuota)
+		return arbitrator.Run(cctx)
+	})
+
 	return wg.Wait()
 }
 
+// Drain moves the tables of the capture to the other captures before the server exits,
+// it returns once the tables are replicating on the other captures or the context is done.
+func (s *Server) Drain(ctx context.Context) error {
+	if !config.NewReplicaImpl {
+		return cerror.ErrDrainCaptureNotSupported.GenWithStackByArgs()
+	}
+	if s.captureV2 == nil {
+		return cerror.ErrCaptureNotRunning.GenWithStackByArgs()
+	}
+	canBeDrained, err := s.captureV2.CanBeDrained(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !canBeDrained {
+		log.Warn("skip draining the capture", zap.Error(cerror.ErrNoSchedulableCapture.GenWithStackByArgs()))
+		return nil
+	}
+	if err := s.captureV2.Drain(ctx); err != nil {
+		return errors.Trace(err)
+	}
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	for {
+		status, err := s.captureV2.DrainStatus(ctx)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if status.Drained() {
+			log.Info("capture is drained", zap.String("capture-id", status.CaptureID))
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return cerror.ErrDrainCaptureTimeout.GenWithStackByArgs(status.CaptureID, status.RemainingTables)
+		case <-ticker.C:
+		}
+	}
+}
+
 // Close closes the server.
 func (s *Server) Close() {
 	if s.capture != nil {
@@ -218,3 +218,56 @@ func (s *testCausalitySuite) TestGenKeys(c *check.C) {
 		c.Assert(keys, check.DeepEquals, tc.expected)
 	}
 }
+
+func (s *testCausalitySuite) TestGenKeysPartialUpdate(c *check.C) {
+	defer testleak.AfterTest(c)()
+	// unique index (a, b), the update only changes a from 1 to 2
+	update := &model.RowChangedEvent{
+		Table: &model.TableName{Schema: "test", Table: "t", TableID: 47},
+		PreColumns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+			{Name: "a", Type: mysql.TypeLong, Flag: model.UniqueKeyFlag, Value: 1},
+			{Name: "b", Type: mysql.TypeLong, Flag: model.UniqueKeyFlag, Value: 10},
+			{Name: "c", Type: mysql.TypeLong, Value: 100},
+		},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+			{Name: "a", Type: mysql.TypeLong, Flag: model.UniqueKeyFlag, Value: 2},
+			{Name: "b", Type: mysql.TypeLong, Flag: model.UniqueKeyFlag, Value: 10},
+			{Name: "c", Type: mysql.TypeLong, Value: 100},
+		},
+		IndexColumns: [][]int{{0}, {1, 2}},
+	}
+	trimmed := update.TrimUnchangedColumns()
+	c.Assert(trimmed.PartialUpdate, check.IsTrue)
+	c.Assert(trimmed.Columns[3], check.IsNil)
+	c.Assert(trimmed.Columns[2], check.NotNil)
+
+	// the conflict keys of the partial update are the same as the ones of the full update
+	fullKeys := genTxnKeys(&model.SingleTableTxn{Rows: []*model.RowChangedEvent{update}})
+	trimmedKeys := genTxnKeys(&model.SingleTableTxn{Rows: []*model.RowChangedEvent{trimmed}})
+	// the keys are deduplicated by a map, so their order is random
+	sortedKeys := func(keys [][]byte) [][]byte {
+		sorted := append([][]byte{}, keys...)
+		sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) > 0 })
+		return sorted
+	}
+	c.Assert(sortedKeys(trimmedKeys), check.DeepEquals, sortedKeys(fullKeys))
+
+	// a later insert of the old unique key value conflicts with the partial update
+	insert := &model.RowChangedEvent{
+		Table: &model.TableName{Schema: "test", Table: "t", TableID: 47},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 2},
+			{Name: "a", Type: mysql.TypeLong, Flag: model.UniqueKeyFlag, Value: 1},
+			{Name: "b", Type: mysql.TypeLong, Flag: model.UniqueKeyFlag, Value: 10},
+			{Name: "c", Type: mysql.TypeLong, Value: 200},
+		},
+		IndexColumns: [][]int{{0}, {1, 2}},
+	}
+	ca := newCausality()
+	ca.add(trimmedKeys, 0)
+	conflict, idx := ca.detectConflict(genTxnKeys(&model.SingleTableTxn{Rows: []*model.RowChangedEvent{insert}}))
+	c.Assert(conflict, check.IsTrue)
+	c.Assert(idx, check.Equals, 0)
+}
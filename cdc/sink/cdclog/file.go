@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/ticdc/cdc/sink/codec"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/quotes"
+	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/uber-go/atomic"
 	"go.uber.org/zap"
 )
@@ -348,6 +349,29 @@ func (f *fileSink) Close() error {
 	return nil
 }
 
+// CheckLocalFileSinkPath checks whether the directories of the local file sink can be created
+// without creating them, it's used to validate the sink URI.
+func CheckLocalFileSinkPath(sinkURI *url.URL) error {
+	path := filepath.Clean(sinkURI.Path)
+	for {
+		st, err := os.Stat(path)
+		if err == nil {
+			if !st.IsDir() {
+				return cerror.ErrFileSinkCreateDir.GenWithStack("%s is not a directory", path)
+			}
+			return util.IsDirWritable(path)
+		}
+		if !os.IsNotExist(err) {
+			return cerror.WrapError(cerror.ErrFileSinkCreateDir, err)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return cerror.WrapError(cerror.ErrFileSinkCreateDir, err)
+		}
+		path = parent
+	}
+}
+
 // NewLocalFileSink support log data to file.
 func NewLocalFileSink(ctx context.Context, sinkURI *url.URL, errCh chan error) (*fileSink, error) {
 	log.Info("[NewLocalFileSink]",
@@ -346,8 +346,9 @@ func (s *s3Sink) Close() error {
 	return nil
 }
 
-// NewS3Sink creates new sink support log data to s3 directly
-func NewS3Sink(ctx context.Context, sinkURI *url.URL, errCh chan error) (*s3Sink, error) {
+// newS3Storage creates the external storage of the bucket and the prefix in the sink URI, the bucket is
+// checked to be accessible if skipCheckPath is false.
+func newS3Storage(ctx context.Context, sinkURI *url.URL, skipCheckPath bool) (storage.ExternalStorage, error) {
 	if len(sinkURI.Host) == 0 {
 		return nil, errors.Errorf("please specify the bucket for s3 in %s", sinkURI)
 	}
@@ -365,12 +366,29 @@ func NewS3Sink(ctx context.Context, sinkURI *url.URL, errCh chan error) (*s3Sink
 	}
 	s3storage, err := storage.New(ctx, backend, &storage.ExternalStorageOptions{
 		SendCredentials: false,
-		SkipCheckPath:   true,
+		SkipCheckPath:   skipCheckPath,
 		HTTPClient:      nil,
 	})
 	if err != nil {
 		return nil, cerror.WrapError(cerror.ErrS3SinkInitialize, err)
 	}
+	return s3storage, nil
+}
+
+// CheckS3SinkPath checks whether the bucket of the s3 sink is accessible without writing anything,
+// it's used to validate the sink URI.
+func CheckS3SinkPath(ctx context.Context, sinkURI *url.URL) error {
+	_, err := newS3Storage(ctx, sinkURI, false)
+	return err
+}
+
+// NewS3Sink creates new sink support log data to s3 directly
+func NewS3Sink(ctx context.Context, sinkURI *url.URL, errCh chan error) (*s3Sink, error) {
+	s3storage, err := newS3Storage(ctx, sinkURI, true)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.Trim(sinkURI.Path, "/")
 
 	s := &s3Sink{
 		prefix:  prefix,
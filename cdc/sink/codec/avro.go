@@ -41,6 +41,8 @@ type AvroEventBatchEncoder struct {
 	resultBuf          []*MQMessage
 
 	tz *time.Location
+	// configs
+	enableColumnMeta bool
 }
 
 type avroEncodeResult struct {
@@ -89,7 +91,7 @@ func (a *AvroEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent)
 	mqMessage := NewMQMessage(ProtocolAvro, nil, nil, e.CommitTs, model.MqMessageTypeRow, &e.Table.Schema, &e.Table.Table)
 
 	if !e.IsDelete() {
-		res, err := avroEncode(e.Table, a.valueSchemaManager, e.TableInfoVersion, e.Columns, a.tz)
+		res, err := avroEncode(e.Table, a.valueSchemaManager, e.TableInfoVersion, e.Columns, a.tz, a.enableColumnMeta)
 		if err != nil {
 			log.Warn("AppendRowChangedEvent: avro encoding failed", zap.String("table", e.Table.String()))
 			return EncoderNoOperation, errors.Annotate(err, "AppendRowChangedEvent could not encode to Avro")
@@ -108,7 +110,7 @@ func (a *AvroEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent)
 
 	pkeyCols := e.HandleKeyColumns()
 
-	res, err := avroEncode(e.Table, a.keySchemaManager, e.TableInfoVersion, pkeyCols, a.tz)
+	res, err := avroEncode(e.Table, a.keySchemaManager, e.TableInfoVersion, pkeyCols, a.tz, false)
 	if err != nil {
 		log.Warn("AppendRowChangedEvent: avro encoding failed", zap.String("table", e.Table.String()))
 		return EncoderNoOperation, errors.Annotate(err, "AppendRowChangedEvent could not encode to Avro")
@@ -136,7 +138,7 @@ func (a *AvroEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, er
 	return nil, nil
 }
 
-// EncodeDDLEvent is no-op now
+// EncodeDDLEvent is no-op now, the consumers get the table schemas from the schema registry
 func (a *AvroEventBatchEncoder) EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error) {
 	return nil, nil
 }
@@ -171,15 +173,19 @@ func (a *AvroEventBatchEncoder) Size() int {
 	return sum
 }
 
-// SetParams is no-op for now
+// SetParams reads relevant parameters for Avro protocol
 func (a *AvroEventBatchEncoder) SetParams(params map[string]string) error {
-	// no op
-	return nil
+	var err error
+	a.enableColumnMeta, err = enableColumnMeta(params)
+	return err
 }
 
-func avroEncode(table *model.TableName, manager *AvroSchemaManager, tableVersion uint64, cols []*model.Column, tz *time.Location) (*avroEncodeResult, error) {
+func avroEncode(
+	table *model.TableName, manager *AvroSchemaManager, tableVersion uint64, cols []*model.Column,
+	tz *time.Location, withColumnMeta bool,
+) (*avroEncodeResult, error) {
 	schemaGen := func() (string, error) {
-		schema, err := ColumnInfoToAvroSchema(table.Table, cols)
+		schema, err := columnInfoToAvroSchema(table.Table, cols, withColumnMeta)
 		if err != nil {
 			return "", errors.Annotate(err, "AvroEventBatchEncoder: generating schema failed")
 		}
@@ -232,6 +238,12 @@ const (
 
 // ColumnInfoToAvroSchema generates the Avro schema JSON for the corresponding columns
 func ColumnInfoToAvroSchema(name string, columnInfo []*model.Column) (string, error) {
+	return columnInfoToAvroSchema(name, columnInfo, false)
+}
+
+// columnInfoToAvroSchema generates the Avro schema JSON, the metadata of the columns is attached to the fields
+// as an extra attribute if withColumnMeta is true
+func columnInfoToAvroSchema(name string, columnInfo []*model.Column, withColumnMeta bool) (string, error) {
 	top := avroSchemaTop{
 		Tp:     "record",
 		Name:   name,
@@ -251,6 +263,9 @@ func ColumnInfoToAvroSchema(name string, columnInfo []*model.Column) (string, er
 			field["type"] = []interface{}{"null", avroType}
 			field["default"] = nil
 		}
+		if withColumnMeta && col.Meta != nil {
+			field["tidbColumnMeta"] = col.Meta
+		}
 
 		top.Fields = append(top.Fields, field)
 	}
@@ -15,6 +15,7 @@ package codec
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/linkedin/goavro/v2"
@@ -88,7 +89,7 @@ func (s *avroBatchEncoderSuite) TestAvroEncodeOnly(c *check.C) {
 		{Name: "myfloat", Value: float64(3.14), Type: mysql.TypeFloat},
 		{Name: "mybytes", Value: []byte("Hello World"), Type: mysql.TypeBlob},
 		{Name: "ts", Value: time.Now().Format(types.TimeFSPFormat), Type: mysql.TypeTimestamp},
-	}, time.Local)
+	}, time.Local, false)
 	c.Assert(err, check.IsNil)
 
 	res, _, err := avroCodec.NativeFromBinary(r.data)
@@ -134,7 +135,7 @@ func (s *avroBatchEncoderSuite) TestAvroTimeZone(c *check.C) {
 		{Name: "myfloat", Value: float64(3.14), Type: mysql.TypeFloat},
 		{Name: "mybytes", Value: []byte("Hello World"), Type: mysql.TypeBlob},
 		{Name: "ts", Value: timestamp.In(location).Format(types.TimeFSPFormat), Type: mysql.TypeTimestamp},
-	}, location)
+	}, location, false)
 	c.Assert(err, check.IsNil)
 
 	res, _, err := avroCodec.NativeFromBinary(r.data)
@@ -238,3 +239,34 @@ func (s *avroBatchEncoderSuite) TestAvroEncode(c *check.C) {
 	_, err = s.encoder.AppendRowChangedEvent(testCaseUpdate)
 	c.Check(err, check.IsNil)
 }
+
+func (s *avroBatchEncoderSuite) TestAvroSchemaWithColumnMeta(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cols := []*model.Column{
+		{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Meta: &model.ColumnMeta{SQLType: "int(11)", Length: 11}},
+		{Name: "name", Type: mysql.TypeVarchar, Meta: &model.ColumnMeta{
+			SQLType: "varchar(32)", Length: 32, Decimal: -1, Charset: "utf8mb4", Collation: "utf8mb4_bin",
+		}},
+	}
+	schema, err := columnInfoToAvroSchema("person", cols, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(schema, check.Not(check.Matches), ".*tidbColumnMeta.*")
+
+	schema, err = columnInfoToAvroSchema("person", cols, true)
+	c.Assert(err, check.IsNil)
+	top := struct {
+		Fields []struct {
+			Name string            `json:"name"`
+			Meta *model.ColumnMeta `json:"tidbColumnMeta"`
+		} `json:"fields"`
+	}{}
+	c.Assert(json.Unmarshal([]byte(schema), &top), check.IsNil)
+	c.Assert(top.Fields, check.HasLen, 2)
+	for i, field := range top.Fields {
+		c.Assert(field.Name, check.Equals, cols[i].Name)
+		c.Assert(field.Meta, check.DeepEquals, cols[i].Meta)
+	}
+	// the extra attributes are allowed by the Avro specification
+	_, err = goavro.NewCodec(schema)
+	c.Assert(err, check.IsNil)
+}
@@ -274,6 +274,27 @@ func (b *canalEntryBuilder) FromRowEvent(e *model.RowChangedEvent) (*canal.Entry
 	return entry, nil
 }
 
+// build the columns without values describing the schema of a table
+func (b *canalEntryBuilder) buildSchemaColumns(info *model.SimpleTableInfo) ([]*canal.Column, error) {
+	if info == nil {
+		return nil, nil
+	}
+	columns := make([]*canal.Column, 0, len(info.ColumnInfo))
+	for i, colInfo := range info.ColumnInfo {
+		c, err := b.buildColumn(&model.Column{Name: colInfo.Name, Type: colInfo.Type, Flag: colInfo.Flag}, colInfo.Name, false)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		c.Index = int32(i)
+		if colInfo.Meta != nil {
+			c.MysqlType = colInfo.Meta.SQLType
+			c.Length = int32(colInfo.Meta.Length)
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
 // FromDdlEvent builds canal entry from cdc DDLEvent
 func (b *canalEntryBuilder) FromDdlEvent(e *model.DDLEvent) (*canal.Entry, error) {
 	eventType := convertDdlEventType(e)
@@ -286,6 +307,18 @@ func (b *canalEntryBuilder) FromDdlEvent(e *model.DDLEvent) (*canal.Entry, error
 		RowDatas:         nil,
 		DdlSchemaName:    e.TableInfo.Schema,
 	}
+	// the schemas of the table before and after the DDL are carried by the columns without values
+	preColumns, err := b.buildSchemaColumns(e.PreTableInfo)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	columns, err := b.buildSchemaColumns(e.TableInfo)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(preColumns) > 0 || len(columns) > 0 {
+		rc.RowDatas = []*canal.RowData{{BeforeColumns: preColumns, AfterColumns: columns}}
+	}
 	rcBytes, err := proto.Marshal(rc)
 	if err != nil {
 		return nil, cerror.WrapError(cerror.ErrCanalEncodeFailed, err)
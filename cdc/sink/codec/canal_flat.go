@@ -29,6 +29,8 @@ type CanalFlatEventBatchEncoder struct {
 	builder       *canalEntryBuilder
 	unresolvedBuf []*canalFlatMessage
 	resolvedBuf   []*canalFlatMessage
+	// configs
+	enableColumnMeta bool
 }
 
 // NewCanalFlatEventBatchEncoder creates a new CanalFlatEventBatchEncoder
@@ -58,6 +60,14 @@ type canalFlatMessage struct {
 	// A Datum should be a string or nil
 	Data []map[string]interface{} `json:"data"`
 	Old  []map[string]interface{} `json:"old"`
+	// An extension to the canal flat message, only included when the column meta is enabled
+	ColumnMeta map[string]*model.ColumnMeta `json:"columnMeta,omitempty"`
+	// Extensions to the canal flat message, the whole schemas of the table after and before a DDL
+	TableSchema    *model.SimpleTableInfo `json:"tableSchema,omitempty"`
+	PreTableSchema *model.SimpleTableInfo `json:"preTableSchema,omitempty"`
+	// An extension to the canal flat message, true if the data and the old data
+	// only contain the handle key columns and the changed columns
+	PartialUpdate bool `json:"partialUpdate,omitempty"`
 	// Used internally by CanalFlatEventBatchEncoder
 	tikvTs uint64
 }
@@ -135,6 +145,7 @@ func (c *CanalFlatEventBatchEncoder) newFlatMessageForDML(e *model.RowChangedEve
 		MySQLType:     mysqlType,
 		Data:          make([]map[string]interface{}, 0),
 		Old:           make([]map[string]interface{}, 0),
+		PartialUpdate: e.PartialUpdate,
 		tikvTs:        e.CommitTs,
 	}
 
@@ -143,6 +154,18 @@ func (c *CanalFlatEventBatchEncoder) newFlatMessageForDML(e *model.RowChangedEve
 	ret.Data = append(ret.Data, data)
 	ret.Old = append(ret.Old, oldData)
 
+	if c.enableColumnMeta {
+		cols := e.Columns
+		if e.IsDelete() {
+			cols = e.PreColumns
+		}
+		ret.ColumnMeta = make(map[string]*model.ColumnMeta, len(cols))
+		for _, col := range cols {
+			if col != nil && col.Meta != nil {
+				ret.ColumnMeta[col.Name] = col.Meta
+			}
+		}
+	}
 	return ret, nil
 }
 
@@ -160,6 +183,26 @@ func (c *CanalFlatEventBatchEncoder) newFlatMessageForDDL(e *model.DDLEvent) (*c
 		Query:         e.Query,
 		tikvTs:        e.CommitTs,
 	}
+	if len(e.TableInfo.ColumnInfo) == 0 {
+		// the DDL doesn't change a table
+		return ret, nil
+	}
+	columns, err := c.builder.buildSchemaColumns(e.TableInfo)
+	if err != nil {
+		return nil, cerrors.WrapError(cerrors.ErrCanalEncodeFailed, err)
+	}
+	ret.PKNames = make([]string, 0)
+	ret.SQLType = make(map[string]int32, len(columns))
+	ret.MySQLType = make(map[string]string, len(columns))
+	for _, column := range columns {
+		if column.IsKey {
+			ret.PKNames = append(ret.PKNames, column.Name)
+		}
+		ret.SQLType[column.Name] = column.SqlType
+		ret.MySQLType[column.Name] = column.MysqlType
+	}
+	ret.TableSchema = e.TableInfo
+	ret.PreTableSchema = e.PreTableInfo
 	return ret, nil
 }
 
@@ -242,8 +285,9 @@ func (c *CanalFlatEventBatchEncoder) Reset() {
 	panic("not supported")
 }
 
-// SetParams is no-op for now
+// SetParams reads relevant parameters for canal flat protocol
 func (c *CanalFlatEventBatchEncoder) SetParams(params map[string]string) error {
-	// no op
-	return nil
+	var err error
+	c.enableColumnMeta, err = enableColumnMeta(params)
+	return err
 }
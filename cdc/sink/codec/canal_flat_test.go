@@ -94,6 +94,16 @@ func (s *canalFlatSuite) TestNewCanalFlatMessageFromDDL(c *check.C) {
 	c.Assert(msg.Table, check.Equals, "person")
 	c.Assert(msg.Query, check.Equals, testCaseDdl.Query)
 	c.Assert(msg.EventType, check.Equals, "CREATE")
+	c.Assert(msg.TableSchema, check.IsNil)
+
+	ddl := codecDDLCases[0][1]
+	msg, err = encoder.newFlatMessageForDDL(ddl)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.PKNames, check.DeepEquals, []string{"id"})
+	c.Assert(msg.SQLType, check.DeepEquals, map[string]int32{"id": int32(JavaSQLTypeBIGINT), "name": int32(JavaSQLTypeVARCHAR)})
+	c.Assert(msg.MySQLType, check.DeepEquals, map[string]string{"id": "int(11)", "name": "varchar(32)"})
+	c.Assert(msg.TableSchema, check.Equals, ddl.TableInfo)
+	c.Assert(msg.PreTableSchema, check.Equals, ddl.PreTableInfo)
 }
 
 func (s *canalFlatSuite) TestBatching(c *check.C) {
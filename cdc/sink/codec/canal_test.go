@@ -367,4 +367,25 @@ func testDdl(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(rc.GetIsDdl(), check.IsTrue)
 	c.Assert(rc.GetDdlSchemaName(), check.Equals, testCaseDdl.TableInfo.Schema)
+	c.Assert(rc.GetRowDatas(), check.HasLen, 0)
+
+	// the table schemas are carried by the columns without values
+	entry, err = builder.FromDdlEvent(codecDDLCases[0][1])
+	c.Assert(err, check.IsNil)
+	rc = &canal.RowChange{}
+	err = proto.Unmarshal(entry.GetStoreValue(), rc)
+	c.Assert(err, check.IsNil)
+	c.Assert(rc.GetRowDatas(), check.HasLen, 1)
+	c.Assert(rc.GetRowDatas()[0].GetBeforeColumns(), check.HasLen, 1)
+	columns := rc.GetRowDatas()[0].GetAfterColumns()
+	c.Assert(columns, check.HasLen, 2)
+	c.Assert(columns[0].GetName(), check.Equals, "id")
+	c.Assert(columns[0].GetIsKey(), check.IsTrue)
+	c.Assert(columns[0].GetMysqlType(), check.Equals, "int(11)")
+	c.Assert(columns[1].GetName(), check.Equals, "name")
+	c.Assert(columns[1].GetIndex(), check.Equals, int32(1))
+	c.Assert(columns[1].GetIsKey(), check.IsFalse)
+	c.Assert(columns[1].GetIsNull(), check.IsTrue)
+	c.Assert(columns[1].GetLength(), check.Equals, int32(32))
+	c.Assert(columns[1].GetSqlType(), check.Equals, int32(JavaSQLTypeVARCHAR))
 }
@@ -16,6 +16,9 @@ package codec
 import (
 	"bytes"
 	"compress/zlib"
+	"encoding/json"
+	"sort"
+	"strconv"
 	"testing"
 
 	"github.com/pingcap/check"
@@ -151,6 +154,30 @@ var (
 		},
 		Query: "create table a",
 		Type:  1,
+	}, {
+		CommitTs: 424316555979653122,
+		TableInfo: &model.SimpleTableInfo{
+			Schema: "a", Table: "b", TableID: 47,
+			ColumnInfo: []*model.ColumnInfo{
+				{
+					Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag,
+					Meta: &model.ColumnMeta{SQLType: "int(11)", Length: 11},
+				},
+				{
+					Name: "name", Type: mysql.TypeVarchar, Flag: model.NullableFlag,
+					Meta: &model.ColumnMeta{SQLType: "varchar(32)", Length: 32, Charset: "utf8mb4", Collation: "utf8mb4_bin"},
+				},
+			},
+			Keys: []*model.KeyInfo{{Name: "PRIMARY", Columns: []string{"id"}, Primary: true, Unique: true}},
+		},
+		PreTableInfo: &model.SimpleTableInfo{
+			Schema: "a", Table: "b", TableID: 47,
+			ColumnInfo: []*model.ColumnInfo{
+				{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag},
+			},
+		},
+		Query: "alter table b add column name varchar(32)",
+		Type:  5,
 	}}, {{
 		CommitTs: 424316583965360129,
 		TableInfo: &model.SimpleTableInfo{
@@ -239,6 +266,165 @@ func (s *codecTestSuite) TestJsonVsCraftVsPB(c *check.C) {
 	}
 }
 
+func (s *codecTestSuite) TestColumnMeta(c *check.C) {
+	defer testleak.AfterTest(c)()
+	defaultValue := "0.00"
+	idMeta := &model.ColumnMeta{SQLType: "int(11)", Length: 11, Decimal: 0, Charset: "binary", Collation: "binary"}
+	priceMeta := &model.ColumnMeta{
+		SQLType: "decimal(10,2)", Length: 10, Decimal: 2, Charset: "binary", Collation: "binary", DefaultValue: &defaultValue,
+	}
+	event := &model.RowChangedEvent{
+		CommitTs: 424316552636792833,
+		Table:    &model.TableName{Schema: "a", Table: "b"},
+		PreColumns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: int64(1), Meta: idMeta},
+			{Name: "price", Type: mysql.TypeNewDecimal, Value: "1.00", Meta: priceMeta},
+		},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: int64(1), Meta: idMeta},
+			{Name: "price", Type: mysql.TypeNewDecimal, Value: "2.00", Meta: priceMeta},
+		},
+	}
+	checkColumnMeta := func(cols []*model.Column, enabled bool) {
+		c.Assert(cols, check.HasLen, 2)
+		for _, col := range cols {
+			if !enabled {
+				c.Assert(col.Meta, check.IsNil)
+				continue
+			}
+			switch col.Name {
+			case "id":
+				c.Assert(col.Meta, check.DeepEquals, idMeta)
+			case "price":
+				c.Assert(col.Meta, check.DeepEquals, priceMeta)
+			}
+		}
+	}
+
+	encode := func(encoder EventBatchEncoder) []*MQMessage {
+		_, err := encoder.AppendRowChangedEvent(event)
+		c.Assert(err, check.IsNil)
+		_, err = encoder.AppendResolvedEvent(event.CommitTs)
+		c.Assert(err, check.IsNil)
+		messages := encoder.Build()
+		c.Assert(messages, check.HasLen, 1)
+		return messages
+	}
+
+	for _, enabled := range []bool{true, false} {
+		params := map[string]string{"enable-column-meta": strconv.FormatBool(enabled)}
+
+		jsonEncoder := NewJSONEventBatchEncoder()
+		c.Assert(jsonEncoder.SetParams(params), check.IsNil)
+		messages := encode(jsonEncoder)
+		jsonDecoder, err := NewJSONEventBatchDecoder(messages[0].Key, messages[0].Value)
+		c.Assert(err, check.IsNil)
+		decoded, err := jsonDecoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		checkColumnMeta(decoded.Columns, enabled)
+		// the meta of the old values is the same as the one of the new values
+		checkColumnMeta(decoded.PreColumns, false)
+
+		craftEncoder := NewCraftEventBatchEncoder()
+		c.Assert(craftEncoder.SetParams(params), check.IsNil)
+		messages = encode(craftEncoder)
+		craftDecoder, err := NewCraftEventBatchDecoder(messages[0].Value)
+		c.Assert(err, check.IsNil)
+		decoded, err = craftDecoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		checkColumnMeta(decoded.Columns, enabled)
+		checkColumnMeta(decoded.PreColumns, enabled)
+
+		canalFlatEncoder := NewCanalFlatEventBatchEncoder()
+		c.Assert(canalFlatEncoder.SetParams(params), check.IsNil)
+		messages = encode(canalFlatEncoder)
+		flatMessage := &canalFlatMessage{}
+		c.Assert(json.Unmarshal(messages[0].Value, flatMessage), check.IsNil)
+		if enabled {
+			c.Assert(flatMessage.ColumnMeta, check.DeepEquals, map[string]*model.ColumnMeta{"id": idMeta, "price": priceMeta})
+		} else {
+			c.Assert(flatMessage.ColumnMeta, check.IsNil)
+		}
+	}
+
+	err := NewJSONEventBatchEncoder().SetParams(map[string]string{"enable-column-meta": "invalid"})
+	c.Assert(err, check.ErrorMatches, ".*invalid.*")
+}
+
+func (s *codecTestSuite) TestPartialUpdate(c *check.C) {
+	defer testleak.AfterTest(c)()
+	event := &model.RowChangedEvent{
+		CommitTs: 424316552636792833,
+		Table:    &model.TableName{Schema: "a", Table: "b"},
+		PreColumns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: int64(1)},
+			{Name: "name", Type: mysql.TypeVarchar, Value: []byte("a")},
+			{Name: "price", Type: mysql.TypeLong, Value: int64(1)},
+		},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: int64(1)},
+			{Name: "name", Type: mysql.TypeVarchar, Value: []byte("a")},
+			{Name: "price", Type: mysql.TypeLong, Value: int64(2)},
+		},
+	}
+	event = event.TrimUnchangedColumns()
+	c.Assert(event.PartialUpdate, check.IsTrue)
+
+	encode := func(encoder EventBatchEncoder) []*MQMessage {
+		_, err := encoder.AppendRowChangedEvent(event)
+		c.Assert(err, check.IsNil)
+		_, err = encoder.AppendResolvedEvent(event.CommitTs)
+		c.Assert(err, check.IsNil)
+		messages := encoder.Build()
+		c.Assert(messages, check.HasLen, 1)
+		return messages
+	}
+	checkColumns := func(cols []*model.Column) {
+		c.Assert(cols, check.HasLen, 2)
+		names := make([]string, 0, len(cols))
+		for _, col := range cols {
+			names = append(names, col.Name)
+		}
+		sort.Strings(names)
+		c.Assert(names, check.DeepEquals, []string{"id", "price"})
+	}
+
+	messages := encode(NewJSONEventBatchEncoder())
+	jsonDecoder, err := NewJSONEventBatchDecoder(messages[0].Key, messages[0].Value)
+	c.Assert(err, check.IsNil)
+	decoded, err := jsonDecoder.NextRowChangedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded.PartialUpdate, check.IsTrue)
+	checkColumns(decoded.Columns)
+	checkColumns(decoded.PreColumns)
+
+	messages = encode(NewCraftEventBatchEncoder())
+	craftDecoder, err := NewCraftEventBatchDecoder(messages[0].Value)
+	c.Assert(err, check.IsNil)
+	decoded, err = craftDecoder.NextRowChangedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded.PartialUpdate, check.IsTrue)
+	checkColumns(decoded.Columns)
+	checkColumns(decoded.PreColumns)
+
+	messages = encode(NewCanalFlatEventBatchEncoder())
+	flatMessage := &canalFlatMessage{}
+	c.Assert(json.Unmarshal(messages[0].Value, flatMessage), check.IsNil)
+	c.Assert(flatMessage.PartialUpdate, check.IsTrue)
+	c.Assert(flatMessage.PKNames, check.DeepEquals, []string{"id"})
+	c.Assert(flatMessage.Data, check.DeepEquals, []map[string]interface{}{{"id": "1", "price": "2"}})
+	c.Assert(flatMessage.Old, check.DeepEquals, []map[string]interface{}{{"id": "1", "price": "1"}})
+
+	// the full updates aren't marked
+	event.PartialUpdate = false
+	messages = encode(NewCraftEventBatchEncoder())
+	craftDecoder, err = NewCraftEventBatchDecoder(messages[0].Value)
+	c.Assert(err, check.IsNil)
+	decoded, err = craftDecoder.NextRowChangedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded.PartialUpdate, check.IsFalse)
+}
+
 func codecEncodeKeyPB(event *model.RowChangedEvent) []byte {
 	key := &benchmark.Key{
 		Ts:        event.CommitTs,
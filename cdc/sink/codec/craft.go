@@ -121,6 +121,12 @@ func (e *CraftEventBatchEncoder) SetParams(params map[string]string) error {
 	if e.maxBatchSize <= 0 || e.maxBatchSize > math.MaxUint16 {
 		return cerror.ErrSinkInvalidConfig.Wrap(errors.Errorf("invalid max-batch-size %d", e.maxBatchSize))
 	}
+
+	enabled, err := enableColumnMeta(params)
+	if err != nil {
+		return err
+	}
+	e.rowChangedBuffer.SetEnableColumnMeta(enabled)
 	return nil
 }
 
@@ -181,11 +187,11 @@ func (b *CraftEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent,
 	if !hasNext || ty != model.MqMessageTypeRow {
 		return nil, cerror.ErrCraftCodecInvalidData.GenWithStack("not found row changed event message")
 	}
-	oldValue, newValue, err := b.decoder.RowChangedEvent(b.index)
+	oldValue, newValue, columnMeta, partialUpdate, err := b.decoder.RowChangedEvent(b.index)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	ev := &model.RowChangedEvent{}
+	ev := &model.RowChangedEvent{PartialUpdate: partialUpdate}
 	if oldValue != nil {
 		if ev.PreColumns, err = oldValue.ToModel(); err != nil {
 			return nil, errors.Trace(err)
@@ -196,6 +202,17 @@ func (b *CraftEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent,
 			return nil, errors.Trace(err)
 		}
 	}
+	if columnMeta != nil {
+		metas, err := columnMeta.ToColumnMetas()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, cols := range [][]*model.Column{ev.Columns, ev.PreColumns} {
+			for _, col := range cols {
+				col.Meta = metas[col.Name]
+			}
+		}
+	}
 	ev.CommitTs = b.headers.GetTs(b.index)
 	ev.Table = &model.TableName{
 		Schema: b.headers.GetSchema(b.index),
@@ -219,18 +236,22 @@ func (b *CraftEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
 	if !hasNext || ty != model.MqMessageTypeDDL {
 		return nil, cerror.ErrCraftCodecInvalidData.GenWithStack("not found ddl event message")
 	}
-	ddlType, query, err := b.decoder.DDLEvent(b.index)
+	ddlType, query, tableInfo, preTableInfo, err := b.decoder.DDLEvent(b.index)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	event := &model.DDLEvent{
-		CommitTs: b.headers.GetTs(b.index),
-		Query:    query,
-		Type:     ddlType,
-		TableInfo: &model.SimpleTableInfo{
+	if tableInfo == nil {
+		tableInfo = &model.SimpleTableInfo{
 			Schema: b.headers.GetSchema(b.index),
 			Table:  b.headers.GetTable(b.index),
-		},
+		}
+	}
+	event := &model.DDLEvent{
+		CommitTs:     b.headers.GetTs(b.index),
+		Query:        query,
+		Type:         ddlType,
+		TableInfo:    tableInfo,
+		PreTableInfo: preTableInfo,
 	}
 	b.index++
 	return event, nil
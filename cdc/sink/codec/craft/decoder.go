@@ -15,6 +15,7 @@ package craft
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"math"
 	"unsafe"
 
@@ -450,17 +451,45 @@ func (d *MessageDecoder) bodyBits(index int) []byte {
 }
 
 // DDLEvent decode a DDL event
-func (d *MessageDecoder) DDLEvent(index int) (pmodel.ActionType, string, error) {
-	bits, ty, err := decodeUvarint(d.bodyBits(index))
+func (d *MessageDecoder) DDLEvent(index int) (ty pmodel.ActionType, query string, tableInfo, preTableInfo *model.SimpleTableInfo, err error) {
+	bits, u64, err := decodeUvarint(d.bodyBits(index))
 	if err != nil {
-		return pmodel.ActionNone, "", errors.Trace(err)
+		return pmodel.ActionNone, "", nil, nil, errors.Trace(err)
 	}
-	_, query, err := decodeString(bits)
-	return pmodel.ActionType(ty), query, err
+	ty = pmodel.ActionType(u64)
+	bits, query, err = decodeString(bits)
+	if err != nil {
+		return pmodel.ActionNone, "", nil, nil, errors.Trace(err)
+	}
+	// the table schemas are absent in the messages encoded by the old versions
+	if len(bits) == 0 {
+		return ty, query, nil, nil, nil
+	}
+	bits, tableInfo, err = decodeTableSchema(bits)
+	if err != nil {
+		return pmodel.ActionNone, "", nil, nil, errors.Trace(err)
+	}
+	_, preTableInfo, err = decodeTableSchema(bits)
+	if err != nil {
+		return pmodel.ActionNone, "", nil, nil, errors.Trace(err)
+	}
+	return ty, query, tableInfo, preTableInfo, nil
+}
+
+func decodeTableSchema(bits []byte) ([]byte, *model.SimpleTableInfo, error) {
+	bits, data, err := decodeBytes(bits)
+	if err != nil || len(data) == 0 {
+		return bits, nil, errors.Trace(err)
+	}
+	info := new(model.SimpleTableInfo)
+	if err := json.Unmarshal(data, info); err != nil {
+		return bits, nil, cerror.WrapError(cerror.ErrCraftCodecInvalidData, err)
+	}
+	return bits, info, nil
 }
 
 // RowChangedEvent decode a row changeded event
-func (d *MessageDecoder) RowChangedEvent(index int) (preColumns, columns *columnGroup, err error) {
+func (d *MessageDecoder) RowChangedEvent(index int) (preColumns, columns, columnMeta *columnGroup, partialUpdate bool, err error) {
 	bits := d.bodyBits(index)
 	columnGroupSizeTable := d.sizeTables[columnGroupSizeTableStartIndex+index]
 	columnGroupIndex := 0
@@ -470,14 +499,18 @@ func (d *MessageDecoder) RowChangedEvent(index int) (preColumns, columns *column
 		bits = bits[columnGroupSize:]
 		columnGroupIndex++
 		if err != nil {
-			return nil, nil, errors.Trace(err)
+			return nil, nil, nil, false, errors.Trace(err)
 		}
 		switch columnGroup.ty {
 		case columnGroupTypeOld:
 			preColumns = columnGroup
 		case columnGroupTypeNew:
 			columns = columnGroup
+		case columnGroupTypeMeta:
+			columnMeta = columnGroup
+		case columnGroupTypePartialUpdate:
+			partialUpdate = true
 		}
 	}
-	return preColumns, columns, nil
+	return preColumns, columns, columnMeta, partialUpdate, nil
 }
@@ -15,11 +15,14 @@ package craft
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"math"
 	"unsafe"
 
+	"github.com/pingcap/log"
 	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/model"
+	"go.uber.org/zap"
 )
 
 // create byte slice from string without copying
@@ -261,6 +264,11 @@ func (e *MessageEncoder) encodeString(s string) *MessageEncoder {
 	return e
 }
 
+func (e *MessageEncoder) encodeBytes(b []byte) *MessageEncoder {
+	e.bits = encodeBytes(e.bits, b)
+	return e
+}
+
 func (e *MessageEncoder) encodeHeaders(headers *Headers) *MessageEncoder {
 	oldSize := len(e.bits)
 	e.bodySize = e.allocator.int64Slice(headers.count)
@@ -326,5 +334,21 @@ func NewDDLEventEncoder(allocator *SliceAllocator, ev *model.DDLEvent) *MessageE
 		schema:    allocator.oneNullableStringSlice(schema),
 		table:     allocator.oneNullableStringSlice(table),
 		count:     1,
-	}).encodeUvarint(ty).encodeString(query).encodeBodySize()
+	}).encodeUvarint(ty).encodeString(query).
+		encodeBytes(encodeTableSchema(ev.TableInfo)).
+		encodeBytes(encodeTableSchema(ev.PreTableInfo)).
+		encodeBodySize()
+}
+
+// encodeTableSchema encodes the whole schema of a table in a DDL event as JSON,
+// a table without columns is encoded as empty bytes
+func encodeTableSchema(info *model.SimpleTableInfo) []byte {
+	if info == nil || len(info.ColumnInfo) == 0 {
+		return nil
+	}
+	bits, err := json.Marshal(info)
+	if err != nil {
+		log.Panic("failed to encode the table schema", zap.String("table", info.Table), zap.Error(err))
+	}
+	return bits
 }
@@ -14,9 +14,13 @@
 package craft
 
 import (
+	"encoding/json"
+
 	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/model"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
 )
 
 const (
@@ -29,6 +33,11 @@ const (
 	// Column group types
 	columnGroupTypeOld = 0x2
 	columnGroupTypeNew = 0x1
+	// the values of the meta column group are the column metas in JSON, it's ignored by the old decoders
+	columnGroupTypeMeta = 0x3
+	// the partial update column group lists the handle key columns without values, it marks the update
+	// only carries the handle key columns and the changed columns, it's ignored by the old decoders
+	columnGroupTypePartialUpdate = 0x4
 
 	// Size tables index
 	metaSizeTableIndex             = 0
@@ -323,6 +332,22 @@ func (g *columnGroup) ToModel() ([]*model.Column, error) {
 	return columns, nil
 }
 
+// ToColumnMetas converts the meta column group into the column metas by the column names
+func (g *columnGroup) ToColumnMetas() (map[string]*model.ColumnMeta, error) {
+	metas := make(map[string]*model.ColumnMeta, len(g.names))
+	for i, name := range g.names {
+		if g.values[i] == nil {
+			continue
+		}
+		meta := new(model.ColumnMeta)
+		if err := json.Unmarshal(g.values[i], meta); err != nil {
+			return nil, cerror.WrapError(cerror.ErrCraftCodecInvalidData, err)
+		}
+		metas[name] = meta
+	}
+	return metas, nil
+}
+
 func decodeColumnGroup(bits []byte, allocator *SliceAllocator, dict *termDictionary) (*columnGroup, error) {
 	var numColumns int
 	bits, ty, err := decodeUint8(bits)
@@ -401,10 +426,85 @@ func newColumnGroup(allocator *SliceAllocator, ty byte, columns []*model.Column)
 	return estimatedSize, nil
 }
 
+func newColumnMetaGroup(allocator *SliceAllocator, columns []*model.Column) (int, *columnGroup) {
+	l := len(columns)
+	if l == 0 {
+		return 0, nil
+	}
+	values := allocator.bytesSlice(l)
+	names := allocator.stringSlice(l)
+	types := allocator.uint64Slice(l)
+	flags := allocator.uint64Slice(l)
+	estimatedSize := 0
+	idx := 0
+	for _, col := range columns {
+		if col == nil {
+			continue
+		}
+		names[idx] = col.Name
+		types[idx] = uint64(col.Type)
+		flags[idx] = uint64(col.Flag)
+		values[idx] = nil
+		if col.Meta != nil {
+			value, err := json.Marshal(col.Meta)
+			if err != nil {
+				log.Panic("failed to encode the column meta", zap.String("column", col.Name), zap.Error(err))
+			}
+			values[idx] = value
+		}
+		estimatedSize += len(col.Name) + len(values[idx]) + 16 /* two 64-bits integers */
+		idx++
+	}
+	if idx > 0 {
+		return estimatedSize, &columnGroup{
+			ty:     columnGroupTypeMeta,
+			names:  names[:idx],
+			types:  types[:idx],
+			flags:  flags[:idx],
+			values: values[:idx],
+		}
+	}
+	return estimatedSize, nil
+}
+
+func newPartialUpdateGroup(allocator *SliceAllocator, columns []*model.Column) (int, *columnGroup) {
+	l := len(columns)
+	if l == 0 {
+		return 0, nil
+	}
+	values := allocator.bytesSlice(l)
+	names := allocator.stringSlice(l)
+	types := allocator.uint64Slice(l)
+	flags := allocator.uint64Slice(l)
+	estimatedSize := 0
+	idx := 0
+	for _, col := range columns {
+		if col == nil || !col.Flag.IsHandleKey() {
+			continue
+		}
+		names[idx] = col.Name
+		types[idx] = uint64(col.Type)
+		flags[idx] = uint64(col.Flag)
+		values[idx] = nil
+		estimatedSize += len(col.Name) + 16 /* two 64-bits integers */
+		idx++
+	}
+	if idx > 0 {
+		return estimatedSize, &columnGroup{
+			ty:     columnGroupTypePartialUpdate,
+			names:  names[:idx],
+			types:  types[:idx],
+			flags:  flags[:idx],
+			values: values[:idx],
+		}
+	}
+	return estimatedSize, nil
+}
+
 // Row changed message is basically an array of column groups
 type rowChangedEvent = []*columnGroup
 
-func newRowChangedMessage(allocator *SliceAllocator, ev *model.RowChangedEvent) (int, rowChangedEvent) {
+func newRowChangedMessage(allocator *SliceAllocator, ev *model.RowChangedEvent, withColumnMeta bool) (int, rowChangedEvent) {
 	numGroups := 0
 	if ev.PreColumns != nil {
 		numGroups++
@@ -412,6 +512,12 @@ func newRowChangedMessage(allocator *SliceAllocator, ev *model.RowChangedEvent)
 	if ev.Columns != nil {
 		numGroups++
 	}
+	if withColumnMeta {
+		numGroups++
+	}
+	if ev.PartialUpdate {
+		numGroups++
+	}
 	groups := allocator.columnGroupSlice(numGroups)
 	estimatedSize := 0
 	idx := 0
@@ -422,9 +528,28 @@ func newRowChangedMessage(allocator *SliceAllocator, ev *model.RowChangedEvent)
 	}
 	if size, group := newColumnGroup(allocator, columnGroupTypeOld, ev.PreColumns); group != nil {
 		groups[idx] = group
+		idx++
 		estimatedSize += size
 	}
-	return estimatedSize, groups
+	if withColumnMeta {
+		columns := ev.Columns
+		if columns == nil {
+			columns = ev.PreColumns
+		}
+		if size, group := newColumnMetaGroup(allocator, columns); group != nil {
+			groups[idx] = group
+			idx++
+			estimatedSize += size
+		}
+	}
+	if ev.PartialUpdate {
+		if size, group := newPartialUpdateGroup(allocator, ev.Columns); group != nil {
+			groups[idx] = group
+			idx++
+			estimatedSize += size
+		}
+	}
+	return estimatedSize, groups[:idx]
 }
 
 // RowChangedEventBuffer is a buffer to save row changed events in batch
@@ -435,6 +560,8 @@ type RowChangedEventBuffer struct {
 	eventsCount   int
 	estimatedSize int
 
+	enableColumnMeta bool
+
 	allocator *SliceAllocator
 }
 
@@ -446,6 +573,11 @@ func NewRowChangedEventBuffer(allocator *SliceAllocator) *RowChangedEventBuffer
 	}
 }
 
+// SetEnableColumnMeta sets whether the metadata of the columns is encoded
+func (b *RowChangedEventBuffer) SetEnableColumnMeta(enabled bool) {
+	b.enableColumnMeta = enabled
+}
+
 // Encode row changed event buffer into bits
 func (b *RowChangedEventBuffer) Encode() []byte {
 	bits := NewMessageEncoder(b.allocator).encodeHeaders(b.headers).encodeRowChangeEvents(b.events[:b.eventsCount]).Encode()
@@ -479,7 +611,7 @@ func (b *RowChangedEventBuffer) AppendRowChangedEvent(ev *model.RowChangedEvent)
 	if b.eventsCount+1 > len(b.events) {
 		b.events = b.allocator.resizeRowChangedEventSlice(b.events, newBufferSize(b.eventsCount))
 	}
-	size, message := newRowChangedMessage(b.allocator, ev)
+	size, message := newRowChangedMessage(b.allocator, ev, b.enableColumnMeta)
 	b.events[b.eventsCount] = message
 	b.eventsCount++
 	b.estimatedSize += size
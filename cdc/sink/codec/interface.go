@@ -14,11 +14,13 @@
 package codec
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/tikv/client-go/v2/oracle"
 	"go.uber.org/zap"
 )
@@ -167,6 +169,19 @@ func (p *Protocol) FromString(protocol string) {
 	}
 }
 
+// enableColumnMeta reads whether the encoder should include the metadata of the columns in the messages
+func enableColumnMeta(params map[string]string) (bool, error) {
+	s, ok := params["enable-column-meta"]
+	if !ok {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, cerror.ErrSinkInvalidConfig.Wrap(err)
+	}
+	return enabled, nil
+}
+
 // NewEventBatchEncoder returns a function of creating an EventBatchEncoder
 func NewEventBatchEncoder(p Protocol) func() EventBatchEncoder {
 	switch p {
@@ -49,6 +49,8 @@ type column struct {
 	WhereHandle *bool                `json:"h,omitempty"`
 	Flag        model.ColumnFlagType `json:"f"`
 	Value       interface{}          `json:"v"`
+	// Meta is only encoded when the column meta is enabled
+	Meta *model.ColumnMeta `json:"m,omitempty"`
 }
 
 func (c *column) FromSinkColumn(col *model.Column) {
@@ -81,6 +83,7 @@ func (c *column) ToSinkColumn(name string) *model.Column {
 	col.Flag = c.Flag
 	col.Name = name
 	col.Value = c.Value
+	col.Meta = c.Meta
 	if c.Value == nil {
 		return col
 	}
@@ -173,6 +176,8 @@ type mqMessageRow struct {
 	Update     map[string]column `json:"u,omitempty"`
 	PreColumns map[string]column `json:"p,omitempty"`
 	Delete     map[string]column `json:"d,omitempty"`
+	// PartialUpdate is true if the update only carries the handle key columns and the changed columns
+	PartialUpdate bool `json:"pu,omitempty"`
 }
 
 func (m *mqMessageRow) Encode() ([]byte, error) {
@@ -202,6 +207,9 @@ func (m *mqMessageRow) Decode(data []byte) error {
 type mqMessageDDL struct {
 	Query string             `json:"q"`
 	Type  timodel.ActionType `json:"t"`
+	// the whole schemas of the table after and before the DDL, omitted in the DDLs not changing a table
+	TableInfo    *model.SimpleTableInfo `json:"tbl,omitempty"`
+	PreTableInfo *model.SimpleTableInfo `json:"pre-tbl,omitempty"`
 }
 
 func (m *mqMessageDDL) Encode() ([]byte, error) {
@@ -220,7 +228,7 @@ func newResolvedMessage(ts uint64) *mqMessageKey {
 	}
 }
 
-func rowEventToMqMessage(e *model.RowChangedEvent) (*mqMessageKey, *mqMessageRow) {
+func rowEventToMqMessage(e *model.RowChangedEvent, withColumnMeta bool) (*mqMessageKey, *mqMessageRow) {
 	var partition *int64
 	if e.Table.IsPartition {
 		partition = &e.Table.TableID
@@ -235,15 +243,17 @@ func rowEventToMqMessage(e *model.RowChangedEvent) (*mqMessageKey, *mqMessageRow
 	}
 	value := &mqMessageRow{}
 	if e.IsDelete() {
-		value.Delete = sinkColumns2JsonColumns(e.PreColumns)
+		value.Delete = sinkColumns2JsonColumns(e.PreColumns, withColumnMeta)
 	} else {
-		value.Update = sinkColumns2JsonColumns(e.Columns)
-		value.PreColumns = sinkColumns2JsonColumns(e.PreColumns)
+		value.Update = sinkColumns2JsonColumns(e.Columns, withColumnMeta)
+		// the meta of the old values is the same as the one of the new values
+		value.PreColumns = sinkColumns2JsonColumns(e.PreColumns, false)
+		value.PartialUpdate = e.PartialUpdate
 	}
 	return key, value
 }
 
-func sinkColumns2JsonColumns(cols []*model.Column) map[string]column {
+func sinkColumns2JsonColumns(cols []*model.Column, withColumnMeta bool) map[string]column {
 	jsonCols := make(map[string]column, len(cols))
 	for _, col := range cols {
 		if col == nil {
@@ -251,6 +261,9 @@ func sinkColumns2JsonColumns(cols []*model.Column) map[string]column {
 		}
 		c := column{}
 		c.FromSinkColumn(col)
+		if withColumnMeta {
+			c.Meta = col.Meta
+		}
 		jsonCols[col.Name] = c
 	}
 	if len(jsonCols) == 0 {
@@ -294,6 +307,7 @@ func mqMessageToRowEvent(key *mqMessageKey, value *mqMessageRow) *model.RowChang
 	} else {
 		e.Columns = jsonColumns2SinkColumns(value.Update)
 		e.PreColumns = jsonColumns2SinkColumns(value.PreColumns)
+		e.PartialUpdate = value.PartialUpdate
 	}
 	return e
 }
@@ -306,15 +320,23 @@ func ddlEventtoMqMessage(e *model.DDLEvent) (*mqMessageKey, *mqMessageDDL) {
 		Type:   model.MqMessageTypeDDL,
 	}
 	value := &mqMessageDDL{
-		Query: e.Query,
-		Type:  e.Type,
+		Query:        e.Query,
+		Type:         e.Type,
+		PreTableInfo: e.PreTableInfo,
+	}
+	if len(e.TableInfo.ColumnInfo) > 0 {
+		value.TableInfo = e.TableInfo
 	}
 	return key, value
 }
 
 func mqMessageToDDLEvent(key *mqMessageKey, value *mqMessageDDL) *model.DDLEvent {
 	e := new(model.DDLEvent)
-	e.TableInfo = new(model.SimpleTableInfo)
+	e.TableInfo = value.TableInfo
+	if e.TableInfo == nil {
+		e.TableInfo = new(model.SimpleTableInfo)
+	}
+	e.PreTableInfo = value.PreTableInfo
 	// TODO: we lost the startTs from kafka message
 	// startTs-based txn filter is out of work
 	e.CommitTs = key.Ts
@@ -337,6 +359,7 @@ type JSONEventBatchEncoder struct {
 	// configs
 	maxKafkaMessageSize int
 	maxBatchSize        int
+	enableColumnMeta    bool
 }
 
 // GetMaxKafkaMessageSize is only for unit testing.
@@ -395,7 +418,7 @@ func (d *JSONEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, er
 
 // AppendRowChangedEvent implements the EventBatchEncoder interface
 func (d *JSONEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
-	keyMsg, valueMsg := rowEventToMqMessage(e)
+	keyMsg, valueMsg := rowEventToMqMessage(e, d.enableColumnMeta)
 	key, err := keyMsg.Encode()
 	if err != nil {
 		return EncoderNoOperation, errors.Trace(err)
@@ -583,7 +606,9 @@ func (d *JSONEventBatchEncoder) SetParams(params map[string]string) error {
 	if d.maxBatchSize <= 0 {
 		return cerror.ErrSinkInvalidConfig.Wrap(errors.Errorf("invalid max-batch-size %d", d.maxBatchSize))
 	}
-	return nil
+
+	d.enableColumnMeta, err = enableColumnMeta(params)
+	return err
 }
 
 // NewJSONEventBatchEncoder creates a new JSONEventBatchEncoder.
@@ -174,9 +174,8 @@ func (d *MaxwellEventBatchEncoder) SetParams(params map[string]string) error {
 
 // Column represents a column in maxwell
 type Column struct {
-	Type string `json:"type"`
-	Name string `json:"name"`
-	// Do not mark the unique key temporarily
+	Type         string `json:"type"`
+	Name         string `json:"name"`
 	Signed       bool   `json:"signed,omitempty"`
 	ColumnLength int    `json:"column-length,omitempty"`
 	Charset      string `json:"charset,omitempty"`
@@ -184,12 +183,11 @@ type Column struct {
 
 // TableStruct represents a table structure includes some table info
 type TableStruct struct {
-	Database string    `json:"database"`
-	Charset  string    `json:"charset,omitempty"`
-	Table    string    `json:"table"`
-	Columns  []*Column `json:"columns"`
-	// Do not output whether it is a primary key temporarily
-	PrimaryKey []string `json:"primary-key"`
+	Database   string    `json:"database"`
+	Charset    string    `json:"charset,omitempty"`
+	Table      string    `json:"table"`
+	Columns    []*Column `json:"columns"`
+	PrimaryKey []string  `json:"primary-key"`
 }
 
 // DdlMaxwellMessage represents a DDL maxwell message
@@ -230,11 +228,9 @@ func ddlEventtoMaxwellMessage(e *model.DDLEvent) (*mqMessageKey, *DdlMaxwellMess
 		value.Old.Table = e.PreTableInfo.Table
 		for _, v := range e.PreTableInfo.ColumnInfo {
 			maxwellcolumntype, _ := columnToMaxwellType(v.Type)
-			value.Old.Columns = append(value.Old.Columns, &Column{
-				Name: v.Name,
-				Type: maxwellcolumntype,
-			})
+			value.Old.Columns = append(value.Old.Columns, columnInfoToMaxwellColumn(v, maxwellcolumntype))
 		}
+		value.Old.PrimaryKey = primaryKeyColumnNames(e.PreTableInfo)
 	}
 
 	value.Def.Database = e.TableInfo.Schema
@@ -247,14 +243,38 @@ func ddlEventtoMaxwellMessage(e *model.DDLEvent) (*mqMessageKey, *DdlMaxwellMess
 				Type: err.Error(),
 			})
 		}
-		value.Def.Columns = append(value.Def.Columns, &Column{
-			Name: v.Name,
-			Type: maxwellcolumntype,
-		})
+		value.Def.Columns = append(value.Def.Columns, columnInfoToMaxwellColumn(v, maxwellcolumntype))
 	}
+	value.Def.PrimaryKey = primaryKeyColumnNames(e.TableInfo)
 	return key, value
 }
 
+func columnInfoToMaxwellColumn(v *model.ColumnInfo, maxwellcolumntype string) *Column {
+	column := &Column{
+		Name: v.Name,
+		Type: maxwellcolumntype,
+	}
+	switch maxwellcolumntype {
+	case "int", "bigint", "float", "decimal":
+		column.Signed = !v.Flag.IsUnsigned()
+	}
+	if v.Meta != nil {
+		column.ColumnLength = v.Meta.Length
+		column.Charset = v.Meta.Charset
+	}
+	return column
+}
+
+func primaryKeyColumnNames(info *model.SimpleTableInfo) []string {
+	names := make([]string, 0)
+	for _, v := range info.ColumnInfo {
+		if v.Flag.IsPrimaryKey() {
+			names = append(names, v.Name)
+		}
+	}
+	return names
+}
+
 // EncodeDDLEvent implements the EventBatchEncoder interface
 // DDL message unresolved tso
 func (d *MaxwellEventBatchEncoder) EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error) {
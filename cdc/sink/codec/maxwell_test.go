@@ -73,6 +73,18 @@ func (s *maxwellbatchSuite) TestmaxwellEventBatchCodec(c *check.C) {
 	s.testmaxwellBatchCodec(c, NewMaxwellEventBatchEncoder)
 }
 
+func (s *maxwellbatchSuite) TestMaxwellDDLTableSchema(c *check.C) {
+	defer testleak.AfterTest(c)()
+	_, msg := ddlEventtoMaxwellMessage(codecDDLCases[0][1])
+	c.Assert(msg.Def.Columns, check.DeepEquals, []*Column{
+		{Name: "id", Type: "int", Signed: true, ColumnLength: 11},
+		{Name: "name", Type: "string", ColumnLength: 32, Charset: "utf8mb4"},
+	})
+	c.Assert(msg.Def.PrimaryKey, check.DeepEquals, []string{"id"})
+	c.Assert(msg.Old.Columns, check.DeepEquals, []*Column{{Name: "id", Type: "int", Signed: true}})
+	c.Assert(msg.Old.PrimaryKey, check.DeepEquals, []string{"id"})
+}
+
 var _ = check.Suite(&maxwellcolumnSuite{})
 
 type maxwellcolumnSuite struct{}
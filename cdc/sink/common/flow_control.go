@@ -34,6 +34,10 @@ type TableMemoryQuota struct {
 
 	mu       sync.Mutex
 	Consumed uint64
+	// limit is the quota allocated by the memory arbitrator, it's equal to Quota if the memory is not arbitrated
+	limit uint64
+	// blocked is the number of the ConsumeWithBlocking calls waiting for the memory to be released
+	blocked int
 
 	cond *sync.Cond
 }
@@ -45,6 +49,7 @@ func NewTableMemoryQuota(quota uint64) *TableMemoryQuota {
 		Quota:    quota,
 		mu:       sync.Mutex{},
 		Consumed: 0,
+		limit:    quota,
 	}
 
 	ret.cond = sync.NewCond(&ret.mu)
@@ -61,7 +66,7 @@ func (c *TableMemoryQuota) ConsumeWithBlocking(nBytes uint64, blockCallBack func
 	}
 
 	c.mu.Lock()
-	if c.Consumed+nBytes >= c.Quota {
+	if !c.canConsume(nBytes) {
 		c.mu.Unlock()
 		err := blockCallBack()
 		if err != nil {
@@ -79,16 +84,39 @@ func (c *TableMemoryQuota) ConsumeWithBlocking(nBytes uint64, blockCallBack func
 			return cerrors.ErrFlowControllerAborted.GenWithStackByArgs()
 		}
 
-		if c.Consumed+nBytes < c.Quota {
+		if c.canConsume(nBytes) {
 			break
 		}
+		c.blocked++
 		c.cond.Wait()
+		c.blocked--
 	}
 
 	c.Consumed += nBytes
 	return nil
 }
 
+// canConsume returns true if the memory can be consumed within the limit, the table can always consume
+// the memory if it consumes nothing, so that the table is not blocked forever when the limit is lowered.
+func (c *TableMemoryQuota) canConsume(nBytes uint64) bool {
+	return c.Consumed == 0 || c.Consumed+nBytes < c.limit
+}
+
+// SetLimit updates the quota allocated by the memory arbitrator
+func (c *TableMemoryQuota) SetLimit(limit uint64) {
+	c.mu.Lock()
+	c.limit = limit
+	c.mu.Unlock()
+	c.cond.Broadcast()
+}
+
+// IsBlocked returns true if any ConsumeWithBlocking call is waiting for the memory to be released
+func (c *TableMemoryQuota) IsBlocked() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blocked > 0
+}
+
 // ForceConsume is called when blocking is not acceptable and the limit can be violated
 // for the sake of avoid deadlock. It merely records the increased memory consumption.
 func (c *TableMemoryQuota) ForceConsume(nBytes uint64) error {
@@ -115,7 +143,7 @@ func (c *TableMemoryQuota) Release(nBytes uint64) {
 	}
 
 	c.Consumed -= nBytes
-	if c.Consumed < c.Quota {
+	if c.Consumed < c.limit {
 		c.mu.Unlock()
 		c.cond.Signal()
 		return
@@ -227,3 +255,13 @@ func (c *TableFlowController) Abort() {
 func (c *TableFlowController) GetConsumption() uint64 {
 	return c.memoryQuota.GetConsumption()
 }
+
+// IsBlocked returns true if the event stream is blocked by the memory quota
+func (c *TableFlowController) IsBlocked() bool {
+	return c.memoryQuota.IsBlocked()
+}
+
+// SetQuota updates the memory quota allocated by the memory arbitrator
+func (c *TableFlowController) SetQuota(quota uint64) {
+	c.memoryQuota.SetLimit(quota)
+}
@@ -166,6 +166,37 @@ func (s *flowControlSuite) TestMemoryQuotaReleaseZero(c *check.C) {
 	controller.Release(0)
 }
 
+// TestMemoryQuotaSetLimit verifies that the quota allocated by the memory arbitrator takes effect
+func (s *flowControlSuite) TestMemoryQuotaSetLimit(c *check.C) {
+	defer testleak.AfterTest(c)()
+
+	controller := NewTableMemoryQuota(1024)
+	controller.SetLimit(512)
+	err := controller.ConsumeWithBlocking(400, dummyCallBack)
+	c.Assert(err, check.IsNil)
+
+	consumed := make(chan struct{})
+	go func() {
+		err := controller.ConsumeWithBlocking(400, dummyCallBack)
+		c.Assert(err, check.IsNil)
+		close(consumed)
+	}()
+	for !controller.IsBlocked() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// the blocked consumer is woken up once the limit is raised
+	controller.SetLimit(1024)
+	<-consumed
+	c.Assert(controller.IsBlocked(), check.IsFalse)
+	c.Assert(controller.GetConsumption(), check.Equals, uint64(800))
+
+	// the table can always consume the memory if it consumes nothing
+	controller.Release(800)
+	controller.SetLimit(0)
+	err = controller.ConsumeWithBlocking(400, dummyCallBack)
+	c.Assert(err, check.IsNil)
+}
+
 type mockedEvent struct {
 	resolvedTs uint64
 	size       uint64
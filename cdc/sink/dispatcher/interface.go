@@ -126,3 +126,26 @@ func NewDispatcher(cfg *config.ReplicaConfig, partitionNum int32) (Dispatcher, e
 		rules: rules,
 	}, nil
 }
+
+// IsKeyBased returns true if the rows of the table are dispatched by their keys, so the changes of a row
+// are always sent to the same partition even if the table is split and replicated by several captures.
+func IsKeyBased(cfg *config.ReplicaConfig, schema, table string) (bool, error) {
+	for _, ruleConfig := range cfg.Sink.DispatchRules {
+		f, err := filter.Parse(ruleConfig.Matcher)
+		if err != nil {
+			return false, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			f = filter.CaseInsensitive(f)
+		}
+		if !f.MatchTable(schema, table) {
+			continue
+		}
+		var rule dispatchRule
+		rule.fromString(ruleConfig.Dispatcher)
+		return rule == dispatchRuleRowID || rule == dispatchRuleIndexValue, nil
+	}
+	// the default dispatcher dispatches the rows by the table if the old value is enabled
+	// or the table doesn't have exactly one index column
+	return false, nil
+}
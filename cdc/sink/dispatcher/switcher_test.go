@@ -77,3 +77,33 @@ func (s SwitcherSuite) TestSwitcher(c *check.C) {
 		},
 	}), check.FitsTypeOf, &indexValueDispatcher{})
 }
+
+func (s SwitcherSuite) TestIsKeyBased(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := &config.ReplicaConfig{
+		Sink: &config.SinkConfig{
+			DispatchRules: []*config.DispatchRule{
+				{Matcher: []string{"test_table.*"}, Dispatcher: "table"},
+				{Matcher: []string{"test_index_value.*"}, Dispatcher: "index-value"},
+				{Matcher: []string{"test.*"}, Dispatcher: "rowid"},
+				{Matcher: []string{"*.*", "!*.test"}, Dispatcher: "ts"},
+			},
+		},
+	}
+	for _, tc := range []struct {
+		schema   string
+		keyBased bool
+	}{
+		{"test_table", false},
+		{"test_index_value", true},
+		{"test", true},
+		{"sbs", false},
+	} {
+		keyBased, err := IsKeyBased(cfg, tc.schema, "t1")
+		c.Assert(err, check.IsNil)
+		c.Assert(keyBased, check.Equals, tc.keyBased, check.Commentf("schema %s", tc.schema))
+	}
+	keyBased, err := IsKeyBased(cfg, "sbs", "test")
+	c.Assert(err, check.IsNil)
+	c.Assert(keyBased, check.IsFalse)
+}
@@ -85,12 +85,23 @@ func (m *Manager) getMinEmittedTs() model.Ts {
 		return m.getCheckpointTs()
 	}
 	minTs := model.Ts(math.MaxUint64)
+	minBlockedTs := model.Ts(math.MaxUint64)
 	for _, tableSink := range m.tableSinks {
 		emittedTs := tableSink.getEmittedTs()
+		if barrierTs := tableSink.getBarrierTs(); barrierTs != 0 && emittedTs >= barrierTs {
+			// the table is blocked by its table barrier, the other tables are not blocked by it
+			if minBlockedTs > emittedTs {
+				minBlockedTs = emittedTs
+			}
+			continue
+		}
 		if minTs > emittedTs {
 			minTs = emittedTs
 		}
 	}
+	if minTs == math.MaxUint64 {
+		return minBlockedTs
+	}
 	return minTs
 }
 
@@ -106,6 +117,16 @@ func (m *Manager) flushBackendSink(ctx context.Context) (model.Ts, error) {
 	return checkpointTs, nil
 }
 
+// SetTableBarrierTs sets the table barrier of the table sink, 0 means the table is not blocked by a table barrier.
+// The backend sink is flushed without waiting for the table once the table has emitted the events before its barrier.
+func (m *Manager) SetTableBarrierTs(tableID model.TableID, barrierTs model.Ts) {
+	m.tableSinksMu.Lock()
+	defer m.tableSinksMu.Unlock()
+	if tableSink, exist := m.tableSinks[tableID]; exist {
+		atomic.StoreUint64(&tableSink.barrierTs, barrierTs)
+	}
+}
+
 func (m *Manager) destroyTableSink(tableID model.TableID) {
 	m.tableSinksMu.Lock()
 	defer m.tableSinksMu.Unlock()
@@ -122,6 +143,8 @@ type tableSink struct {
 	buffer  []*model.RowChangedEvent
 	// emittedTs means all of events which of commitTs less than or equal to emittedTs is sent to backendSink
 	emittedTs model.Ts
+	// barrierTs is the table barrier of the table, it's 0 if the table is not blocked by a table barrier
+	barrierTs model.Ts
 }
 
 func (t *tableSink) Initialize(ctx context.Context, tableInfo []*model.SimpleTableInfo) error {
@@ -145,7 +168,7 @@ func (t *tableSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64
 	})
 	if i == 0 {
 		atomic.StoreUint64(&t.emittedTs, resolvedTs)
-		return t.manager.flushBackendSink(ctx)
+		return t.flushBackendSink(ctx)
 	}
 	resolvedRows := t.buffer[:i]
 	t.buffer = append(make([]*model.RowChangedEvent, 0, len(t.buffer[i:])), t.buffer[i:]...)
@@ -155,13 +178,27 @@ func (t *tableSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64
 		return t.manager.getCheckpointTs(), errors.Trace(err)
 	}
 	atomic.StoreUint64(&t.emittedTs, resolvedTs)
-	return t.manager.flushBackendSink(ctx)
+	return t.flushBackendSink(ctx)
+}
+
+// flushBackendSink flushes the backend sink and returns the checkpoint ts of the table, which may be less than the
+// checkpoint ts of the backend sink if the backend sink is flushed without waiting for the table blocked by its barrier.
+func (t *tableSink) flushBackendSink(ctx context.Context) (uint64, error) {
+	checkpointTs, err := t.manager.flushBackendSink(ctx)
+	if emittedTs := t.getEmittedTs(); checkpointTs > emittedTs {
+		checkpointTs = emittedTs
+	}
+	return checkpointTs, err
 }
 
 func (t *tableSink) getEmittedTs() uint64 {
 	return atomic.LoadUint64(&t.emittedTs)
 }
 
+func (t *tableSink) getBarrierTs() uint64 {
+	return atomic.LoadUint64(&t.barrierTs)
+}
+
 func (t *tableSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
 	// the table sink doesn't receive the checkpoint event
 	return nil
@@ -238,6 +238,40 @@ func (e *errorSink) Close() error {
 	return nil
 }
 
+func (s *managerSuite) TestManagerTableBarrier(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 16)
+	manager := NewManager(ctx, &checkSink{C: c}, errCh, 0)
+	defer manager.Close()
+	blockedSink := manager.CreateTableSink(1, 0)
+	tableSink := manager.CreateTableSink(2, 0)
+	manager.SetTableBarrierTs(1, 10)
+	waitCheckpointTs := func(sink Sink, resolvedTs, expected uint64) {
+		for {
+			checkpointTs, err := sink.FlushRowChangedEvents(ctx, resolvedTs)
+			c.Assert(err, check.IsNil)
+			c.Assert(checkpointTs, check.LessEqual, expected)
+			if checkpointTs == expected {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// the table blocked by its barrier doesn't block the other tables
+	waitCheckpointTs(blockedSink, 10, 0)
+	waitCheckpointTs(tableSink, 20, 20)
+	// the checkpoint ts of the blocked table doesn't exceed its barrier
+	waitCheckpointTs(blockedSink, 10, 10)
+
+	// the table blocks the other tables once the barrier is removed
+	manager.SetTableBarrierTs(1, 0)
+	waitCheckpointTs(blockedSink, 30, 20)
+	waitCheckpointTs(tableSink, 25, 25)
+}
+
 func (s *managerSuite) TestManagerError(c *check.C) {
 	defer testleak.AfterTest(c)()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -46,6 +46,8 @@ type mqSink struct {
 	filter     *filter.Filter
 	protocol   codec.Protocol
 
+	partialUpdate *partialUpdateFilter
+
 	partitionNum   int32
 	partitionInput []chan struct {
 		row        *model.RowChangedEvent
@@ -118,6 +120,20 @@ func newMqSink(
 		return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
 	}
 
+	var partialUpdate *partialUpdateFilter
+	switch protocol {
+	case codec.ProtocolDefault, codec.ProtocolCanalJSON, codec.ProtocolCraft:
+		partialUpdate, err = newPartialUpdateFilter(config)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	default:
+		if len(config.Sink.PartialUpdateTables) != 0 {
+			log.Warn("partial update is not supported by the protocol, ignore it",
+				zap.String("protocol", config.Sink.Protocol))
+		}
+	}
+
 	newEncoder1 := newEncoder
 	newEncoder = func() codec.EventBatchEncoder {
 		ret := newEncoder1()
@@ -139,6 +155,8 @@ func newMqSink(
 		filter:     filter,
 		protocol:   protocol,
 
+		partialUpdate: partialUpdate,
+
 		partitionNum:        partitionNum,
 		partitionInput:      partitionInput,
 		partitionResolvedTs: make([]uint64, partitionNum),
@@ -176,7 +194,7 @@ func (k *mqSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowCha
 		case k.partitionInput[partition] <- struct {
 			row        *model.RowChangedEvent
 			resolvedTs uint64
-		}{row: row}:
+		}{row: k.partialUpdate.trim(row)}:
 		}
 		rowsCount++
 	}
@@ -435,6 +453,11 @@ func newKafkaSaramaSink(ctx context.Context, sinkURI *url.URL, filter *filter.Fi
 		opts["max-batch-size"] = s
 	}
 
+	s = sinkURI.Query().Get("enable-column-meta")
+	if s != "" {
+		opts["enable-column-meta"] = s
+	}
+
 	s = sinkURI.Query().Get("compression")
 	if s != "" {
 		config.Compression = s
@@ -486,6 +509,10 @@ func newKafkaSaramaSink(ctx context.Context, sinkURI *url.URL, filter *filter.Fi
 		config.TopicPreProcess = autoCreate
 	}
 
+	if _, ok := opts[OptDryRun]; ok {
+		config.DryRun = true
+	}
+
 	topic := strings.TrimFunc(sinkURI.Path, func(r rune) bool {
 		return r == '/'
 	})
@@ -505,11 +532,50 @@ func newPulsarSink(ctx context.Context, sinkURI *url.URL, filter *filter.Filter,
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	parsePulsarSinkOptions(sinkURI, replicaConfig, opts)
+	// For now, it's a place holder. Avro format have to make connection to Schema Registery,
+	// and it may needs credential.
+	credential := &security.Credential{}
+	sink, err := newMqSink(ctx, credential, producer, filter, replicaConfig, opts, errCh)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return sink, nil
+}
+
+// validatePulsarSink checks the sink URI and the encoder parameters in the dry-run mode. Unlike Kafka, Pulsar
+// can't validate the topic without creating it, so the producer isn't created and the broker isn't connected.
+func validatePulsarSink(sinkURI *url.URL, replicaConfig *config.ReplicaConfig, opts map[string]string) error {
+	if err := pulsar.ValidateSinkURI(sinkURI); err != nil {
+		return errors.Trace(err)
+	}
+	parsePulsarSinkOptions(sinkURI, replicaConfig, opts)
+	var protocol codec.Protocol
+	protocol.FromString(replicaConfig.Sink.Protocol)
+	switch protocol {
+	case codec.ProtocolAvro:
+		if _, ok := opts["registry"]; !ok {
+			return cerror.ErrPrepareAvroFailed.GenWithStack(`Avro protocol requires parameter "registry"`)
+		}
+	case codec.ProtocolCanal, codec.ProtocolCanalJSON:
+		if !replicaConfig.EnableOldValue {
+			return cerror.WrapError(cerror.ErrKafkaInvalidConfig, errors.New("Canal requires old value to be enabled"))
+		}
+	}
+	if err := codec.NewEventBatchEncoder(protocol)().SetParams(opts); err != nil {
+		return cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
+	}
+	_, err := dispatcher.NewDispatcher(replicaConfig, 1)
+	return errors.Trace(err)
+}
+
+// parsePulsarSinkOptions sets the protocol and the encoder options in the sink URI
+func parsePulsarSinkOptions(sinkURI *url.URL, replicaConfig *config.ReplicaConfig, opts map[string]string) {
 	s := sinkURI.Query().Get("protocol")
 	if s != "" {
 		replicaConfig.Sink.Protocol = s
 	}
-	// These two options are not used by Pulsar producer itself, but the encoders
+	// These options are not used by Pulsar producer itself, but the encoders
 	s = sinkURI.Query().Get("max-message-bytes")
 	if s != "" {
 		opts["max-message-bytes"] = s
@@ -519,12 +585,9 @@ func newPulsarSink(ctx context.Context, sinkURI *url.URL, filter *filter.Filter,
 	if s != "" {
 		opts["max-batch-size"] = s
 	}
-	// For now, it's a place holder. Avro format have to make connection to Schema Registery,
-	// and it may needs credential.
-	credential := &security.Credential{}
-	sink, err := newMqSink(ctx, credential, producer, filter, replicaConfig, opts, errCh)
-	if err != nil {
-		return nil, errors.Trace(err)
+
+	s = sinkURI.Query().Get("enable-column-meta")
+	if s != "" {
+		opts["enable-column-meta"] = s
 	}
-	return sink, nil
 }
@@ -217,3 +217,25 @@ func (s mqSinkSuite) TestPulsarSinkEncoderConfig(c *check.C) {
 	c.Assert(encoder.(*codec.JSONEventBatchEncoder).GetMaxBatchSize(), check.Equals, 1)
 	c.Assert(encoder.(*codec.JSONEventBatchEncoder).GetMaxKafkaMessageSize(), check.Equals, 4194304)
 }
+
+func (s mqSinkSuite) TestPulsarSinkDryRun(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	replicaConfig := config.GetDefaultReplicaConfig()
+	fr, err := filter.NewFilter(replicaConfig)
+	c.Assert(err, check.IsNil)
+	// the broker isn't connected in the dry-run mode, so the topic isn't created
+	uri := "pulsar://127.0.0.1:1234/kafka-test?max-message-bytes=4194304&max-batch-size=1"
+	c.Assert(Validate(ctx, "test-cf", uri, fr, replicaConfig, nil), check.IsNil)
+
+	uri = "pulsar://127.0.0.1:1234/kafka-test?max-batch-size=invalid"
+	c.Assert(Validate(ctx, "test-cf", uri, fr, replicaConfig, nil), check.ErrorMatches, ".*invalid.*")
+
+	uri = "pulsar://127.0.0.1:1234/kafka-test?protocol=canal-json"
+	replicaConfig.EnableOldValue = false
+	c.Assert(Validate(ctx, "test-cf", uri, fr, replicaConfig, nil), check.ErrorMatches, ".*Canal requires old value.*")
+	// the protocol in the sink URI doesn't change the replica config
+	c.Assert(replicaConfig.Sink.Protocol, check.Equals, "default")
+}
@@ -103,9 +103,18 @@ type mysqlSink struct {
 	metricBucketSizeCounters        []prometheus.Counter
 
 	forceReplicate bool
+	partialUpdate  *partialUpdateFilter
 }
 
 func (s *mysqlSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	if s.partialUpdate != nil {
+		// the rows are trimmed into copies, the rows of the caller are kept intact
+		trimmed := make([]*model.RowChangedEvent, len(rows))
+		for i, row := range rows {
+			trimmed[i] = s.partialUpdate.trim(row)
+		}
+		rows = trimmed
+	}
 	count := s.txnCache.Append(s.filter, rows...)
 	s.statistics.AddRowsCount(count)
 	return nil
@@ -328,6 +337,71 @@ func checkTiDBVariable(ctx context.Context, db *sql.DB, variableName, defaultVal
 	return "", nil
 }
 
+// replicationPrivileges are the privileges which the downstream user needs to replicate DMLs and DDLs
+var replicationPrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "DROP", "ALTER", "INDEX"}
+
+// checkPrivileges checks whether the downstream user is granted the privileges to replicate data.
+// The grants on all databases are merged, so the privileges missing on a specific database are not found.
+func checkPrivileges(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "SHOW GRANTS")
+	if err != nil {
+		return errors.Annotate(cerror.WrapError(cerror.ErrMySQLQueryError, err), "fail to query grants")
+	}
+	defer rows.Close()
+	granted := make(map[string]struct{})
+	for rows.Next() {
+		var grant string
+		if err := rows.Scan(&grant); err != nil {
+			return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+		}
+		for _, privilege := range parseGrantedPrivileges(grant) {
+			granted[privilege] = struct{}{}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	if _, ok := granted["ALL PRIVILEGES"]; ok {
+		return nil
+	}
+	var missing []string
+	for _, privilege := range replicationPrivileges {
+		if _, ok := granted[privilege]; !ok {
+			missing = append(missing, privilege)
+		}
+	}
+	if len(missing) > 0 {
+		return cerror.ErrMySQLMissingPrivileges.GenWithStackByArgs(strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// parseGrantedPrivileges parses the privileges from a grant like "GRANT SELECT,INSERT ON *.* TO 'root'@'%'"
+func parseGrantedPrivileges(grant string) []string {
+	grant = strings.ToUpper(grant)
+	if !strings.HasPrefix(grant, "GRANT ") {
+		return nil
+	}
+	end := strings.Index(grant, " ON ")
+	if end < 0 {
+		// the grant of a role
+		return nil
+	}
+	privileges := strings.Split(grant[len("GRANT "):end], ",")
+	for i, privilege := range privileges {
+		// remove the columns of a column privilege like "SELECT (c1)"
+		if idx := strings.Index(privilege, "("); idx >= 0 {
+			privilege = privilege[:idx]
+		}
+		privilege = strings.TrimSpace(privilege)
+		if privilege == "ALL" {
+			privilege = "ALL PRIVILEGES"
+		}
+		privileges[i] = privilege
+	}
+	return privileges
+}
+
 func configureSinkURI(
 	ctx context.Context,
 	dsnCfg *dmysql.Config,
@@ -527,6 +601,20 @@ func newMySQLSink(
 	}
 
 	params.enableOldValue = replicaConfig.EnableOldValue
+	if replicaConfig.Resource != nil && replicaConfig.Resource.SinkWorkerCount > 0 {
+		params.workerCount = replicaConfig.Resource.SinkWorkerCount
+	}
+
+	// the partial updates can only be translated to UPDATE statements
+	var partialUpdate *partialUpdateFilter
+	if params.enableOldValue && !params.safeMode {
+		partialUpdate, err = newPartialUpdateFilter(replicaConfig)
+		if err != nil {
+			return nil, err
+		}
+	} else if replicaConfig.Sink != nil && len(replicaConfig.Sink.PartialUpdateTables) != 0 {
+		log.Warn("partial update requires the old value to be enabled and the safe mode to be disabled, ignore it")
+	}
 
 	// dsn format of the driver:
 	// [username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]
@@ -566,6 +654,11 @@ func newMySQLSink(
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if _, ok := opts[OptDryRun]; ok {
+		if err := checkPrivileges(ctx, testDB); err != nil {
+			return nil, err
+		}
+	}
 	db, err := getDBConnImpl(ctx, dsnStr)
 	if err != nil {
 		return nil, err
@@ -594,6 +687,7 @@ func newMySQLSink(
 		metricBucketSizeCounters:        metricBucketSizeCounters,
 		errCh:                           make(chan error, 1),
 		forceReplicate:                  replicaConfig.ForceReplicate,
+		partialUpdate:                   partialUpdate,
 	}
 
 	if val, ok := opts[mark.OptCyclicConfig]; ok {
@@ -445,6 +445,22 @@ func (s MySQLSinkSuite) TestPrepareUpdate(c *check.C) {
 			expectedSQL:  "UPDATE `test`.`t1` SET `a`=?,`b`=? WHERE `a`=? AND `b`=? LIMIT 1;",
 			expectedArgs: []interface{}{2, "test2", 1, "test"},
 		},
+		{
+			// partial update, the unchanged columns are nil
+			quoteTable: "`test`.`t1`",
+			preCols: []*model.Column{
+				{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+				nil,
+				{Name: "c", Type: mysql.TypeVarchar, Flag: 0, Value: "test"},
+			},
+			cols: []*model.Column{
+				{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+				nil,
+				{Name: "c", Type: mysql.TypeVarchar, Flag: 0, Value: "test2"},
+			},
+			expectedSQL:  "UPDATE `test`.`t1` SET `a`=?,`c`=? WHERE `a`=? LIMIT 1;",
+			expectedArgs: []interface{}{1, "test2", 1},
+		},
 	}
 	for _, tc := range testCases {
 		query, args := prepareUpdate(tc.quoteTable, tc.preCols, tc.cols, false)
@@ -937,6 +953,32 @@ func (s MySQLSinkSuite) TestCheckTiDBVariable(c *check.C) {
 	c.Assert(err, check.ErrorMatches, ".*"+sql.ErrConnDone.Error())
 }
 
+func (s MySQLSinkSuite) TestCheckPrivileges(c *check.C) {
+	defer testleak.AfterTest(c)()
+	db, mock, err := sqlmock.New()
+	c.Assert(err, check.IsNil)
+	defer db.Close() //nolint:errcheck
+	columns := []string{"Grants for root@%"}
+
+	mock.ExpectQuery("SHOW GRANTS").WillReturnRows(
+		sqlmock.NewRows(columns).AddRow("GRANT ALL PRIVILEGES ON *.* TO 'root'@'%'"),
+	)
+	c.Assert(checkPrivileges(context.TODO(), db), check.IsNil)
+
+	mock.ExpectQuery("SHOW GRANTS").WillReturnRows(
+		sqlmock.NewRows(columns).
+			AddRow("GRANT USAGE ON *.* TO 'cdc'@'%'").
+			AddRow("GRANT Select,Insert,Update (c1) ON test.* TO 'cdc'@'%'").
+			AddRow("GRANT `role1`@`%` TO 'cdc'@'%'"),
+	)
+	err = checkPrivileges(context.TODO(), db)
+	c.Assert(err, check.ErrorMatches, ".*lacks the privileges: DELETE, CREATE, DROP, ALTER, INDEX.*")
+
+	mock.ExpectQuery("SHOW GRANTS").WillReturnError(sql.ErrConnDone)
+	err = checkPrivileges(context.TODO(), db)
+	c.Assert(err, check.ErrorMatches, ".*"+sql.ErrConnDone.Error())
+}
+
 func mockTestDB() (*sql.DB, error) {
 	// mock for test db, which is used querying TiDB session variable
 	db, mock, err := sqlmock.New()
@@ -1386,8 +1428,11 @@ func (s MySQLSinkSuite) TestNewMySQLSink(c *check.C) {
 	rc := config.GetDefaultReplicaConfig()
 	f, err := filter.NewFilter(rc)
 	c.Assert(err, check.IsNil)
+	rc.Resource.SinkWorkerCount = 2
 	sink, err := newMySQLSink(ctx, changefeed, sinkURI, f, rc, map[string]string{})
 	c.Assert(err, check.IsNil)
+	// the sink worker count of the changefeed overrides the worker count in the sink uri
+	c.Assert(sink.(*mysqlSink).params.workerCount, check.Equals, 2)
 	err = sink.Close()
 	c.Assert(err, check.IsNil)
 }
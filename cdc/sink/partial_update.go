@@ -0,0 +1,53 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+)
+
+// partialUpdateFilter trims the unchanged columns of the update events of the matched tables
+type partialUpdateFilter struct {
+	filter filter.Filter
+}
+
+// newPartialUpdateFilter returns nil if no table is configured to emit the partial updates
+func newPartialUpdateFilter(cfg *config.ReplicaConfig) (*partialUpdateFilter, error) {
+	if cfg == nil || cfg.Sink == nil || len(cfg.Sink.PartialUpdateTables) == 0 {
+		return nil, nil
+	}
+	f, err := filter.Parse(cfg.Sink.PartialUpdateTables)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+	}
+	if !cfg.CaseSensitive {
+		f = filter.CaseInsensitive(f)
+	}
+	return &partialUpdateFilter{filter: f}, nil
+}
+
+// trim returns a partial update copy of the row if its table is matched, otherwise the row itself.
+// The row passed in is never modified, and it's safe to call on a nil filter.
+func (p *partialUpdateFilter) trim(row *model.RowChangedEvent) *model.RowChangedEvent {
+	if p == nil || row.Table == nil || !row.IsUpdate() {
+		return row
+	}
+	if p.filter.MatchTable(row.Table.Schema, row.Table.Table) {
+		return row.TrimUnchangedColumns()
+	}
+	return row
+}
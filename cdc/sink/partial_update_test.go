@@ -0,0 +1,106 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/dispatcher"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type partialUpdateSuite struct{}
+
+var _ = check.Suite(&partialUpdateSuite{})
+
+func newPartialUpdateTestRow(schema, table string) *model.RowChangedEvent {
+	return &model.RowChangedEvent{
+		Table: &model.TableName{Schema: schema, Table: table, TableID: 47},
+		PreColumns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+			{Name: "a", Type: mysql.TypeLong, Value: 1},
+			{Name: "b", Type: mysql.TypeLong, Value: 10},
+		},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+			{Name: "a", Type: mysql.TypeLong, Value: 2},
+			{Name: "b", Type: mysql.TypeLong, Value: 10},
+		},
+		IndexColumns: [][]int{{0}},
+	}
+}
+
+func (s *partialUpdateSuite) TestFilter(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	f, err := newPartialUpdateFilter(cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(f, check.IsNil)
+	// a nil filter keeps all the rows
+	row := newPartialUpdateTestRow("test", "t1")
+	c.Assert(f.trim(row), check.Equals, row)
+
+	cfg.Sink.PartialUpdateTables = []string{"test.[a"}
+	_, err = newPartialUpdateFilter(cfg)
+	c.Assert(err, check.ErrorMatches, ".*ErrFilterRuleInvalid.*")
+
+	cfg.Sink.PartialUpdateTables = []string{"test.t1"}
+	f, err = newPartialUpdateFilter(cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(f, check.NotNil)
+
+	row = newPartialUpdateTestRow("TEST", "T1")
+	c.Assert(f.trim(row), check.Equals, row)
+
+	cfg.CaseSensitive = false
+	f, err = newPartialUpdateFilter(cfg)
+	c.Assert(err, check.IsNil)
+	row = newPartialUpdateTestRow("TEST", "T1")
+	trimmed := f.trim(row)
+	c.Assert(trimmed, check.Not(check.Equals), row)
+	c.Assert(trimmed.PartialUpdate, check.IsTrue)
+	c.Assert(trimmed.Columns[2], check.IsNil)
+	// the row passed in is kept intact for the other stages
+	c.Assert(row, check.DeepEquals, newPartialUpdateTestRow("TEST", "T1"))
+
+	row = newPartialUpdateTestRow("test", "t2")
+	c.Assert(f.trim(row), check.Equals, row)
+}
+
+func (s *partialUpdateSuite) TestDispatch(c *check.C) {
+	defer testleak.AfterTest(c)()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.PartialUpdateTables = []string{"*.*"}
+	f, err := newPartialUpdateFilter(cfg)
+	c.Assert(err, check.IsNil)
+	for _, rule := range []string{"default", "index-value", "table", "ts", "rowid"} {
+		cfg.Sink.DispatchRules = []*config.DispatchRule{{Matcher: []string{"*.*"}, Dispatcher: rule}}
+		for _, enableOldValue := range []bool{true, false} {
+			cfg.EnableOldValue = enableOldValue
+			d, err := dispatcher.NewDispatcher(cfg, 16)
+			c.Assert(err, check.IsNil)
+			for i := 0; i < 10; i++ {
+				row := newPartialUpdateTestRow("test", "t1")
+				row.PreColumns[0].Value = i
+				row.Columns[0].Value = i
+				row.RowID = int64(i)
+				row.CommitTs = uint64(i)
+				// the partial update is dispatched to the same partition as the full update
+				c.Assert(d.Dispatch(f.trim(row)), check.Equals, d.Dispatch(row), check.Commentf("rule: %s", rule))
+			}
+		}
+	}
+}
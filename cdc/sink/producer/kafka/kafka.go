@@ -47,6 +47,8 @@ type Config struct {
 	SaslScram       *security.SaslScram
 	// control whether to create topic and verify partition number
 	TopicPreProcess bool
+	// DryRun only validates the creation of the topic without creating it
+	DryRun bool
 }
 
 // NewKafkaConfig returns a default Kafka configuration
@@ -299,11 +301,13 @@ func kafkaTopicPreProcess(topic, address string, config Config, cfg *sarama.Conf
 		}
 		log.Info("create a topic", zap.String("topic", topic),
 			zap.Int32("partition_num", partitionNum),
-			zap.Int16("replication_factor", config.ReplicationFactor))
+			zap.Int16("replication_factor", config.ReplicationFactor),
+			zap.Bool("validate_only", config.DryRun))
+		// the broker checks the permission and the parameters of the topic but doesn't create it if validateOnly is set
 		err := admin.CreateTopic(topic, &sarama.TopicDetail{
 			NumPartitions:     partitionNum,
 			ReplicationFactor: config.ReplicationFactor,
-		}, false)
+		}, config.DryRun)
 		// TODO idenfity the cause of "Topic with this name already exists"
 		if err != nil && !strings.Contains(err.Error(), "already exists") {
 			return 0, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
@@ -26,6 +26,12 @@ import (
 	"go.uber.org/zap"
 )
 
+// ValidateSinkURI checks the options in the sink URI without connecting to the broker.
+func ValidateSinkURI(u *url.URL) error {
+	_, err := parseSinkOptions(u)
+	return cerror.WrapError(cerror.ErrPulsarNewProducer, err)
+}
+
 // NewProducer create a pulsar producer.
 func NewProducer(u *url.URL, errCh chan error) (*Producer, error) {
 	failpoint.Inject("MockPulsar", func() {
@@ -29,6 +29,9 @@ import (
 const (
 	OptChangefeedID = "_changefeed_id"
 	OptCaptureAddr  = "_capture_addr"
+	// OptDryRun is set if the sink is created only to validate the sink URI,
+	// the sink should check the downstream but must not change anything in it.
+	OptDryRun = "_dry_run"
 )
 
 // Sink is an abstraction for anything that a changefeed may emit into.
@@ -85,6 +88,13 @@ func init() {
 	// register pulsar sink
 	sinkIniterMap["pulsar"] = func(ctx context.Context, changefeedID model.ChangeFeedID, sinkURI *url.URL,
 		filter *filter.Filter, config *config.ReplicaConfig, opts map[string]string, errCh chan error) (Sink, error) {
+		if _, ok := opts[OptDryRun]; ok {
+			// the pulsar producer may create the topic once it's created
+			if err := validatePulsarSink(sinkURI, config, opts); err != nil {
+				return nil, err
+			}
+			return newBlackHoleSink(ctx, opts), nil
+		}
 		return newPulsarSink(ctx, sinkURI, filter, config, opts, errCh)
 	}
 	sinkIniterMap["pulsar+ssl"] = sinkIniterMap["pulsar"]
@@ -92,12 +102,26 @@ func init() {
 	// register local sink
 	sinkIniterMap["local"] = func(ctx context.Context, changefeedID model.ChangeFeedID, sinkURI *url.URL,
 		filter *filter.Filter, config *config.ReplicaConfig, opts map[string]string, errCh chan error) (Sink, error) {
+		if _, ok := opts[OptDryRun]; ok {
+			// the local file sink creates the directories once it's created
+			if err := cdclog.CheckLocalFileSinkPath(sinkURI); err != nil {
+				return nil, err
+			}
+			return newBlackHoleSink(ctx, opts), nil
+		}
 		return cdclog.NewLocalFileSink(ctx, sinkURI, errCh)
 	}
 
 	// register s3 sink
 	sinkIniterMap["s3"] = func(ctx context.Context, changefeedID model.ChangeFeedID, sinkURI *url.URL,
 		filter *filter.Filter, config *config.ReplicaConfig, opts map[string]string, errCh chan error) (Sink, error) {
+		if _, ok := opts[OptDryRun]; ok {
+			// the s3 sink starts flushing the logs once it's created
+			if err := cdclog.CheckS3SinkPath(ctx, sinkURI); err != nil {
+				return nil, err
+			}
+			return newBlackHoleSink(ctx, opts), nil
+		}
 		return cdclog.NewS3Sink(ctx, sinkURI, errCh)
 	}
 }
@@ -114,3 +138,43 @@ func NewSink(ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr st
 	}
 	return nil, cerror.ErrSinkURIInvalid.GenWithStack("the sink scheme (%s) is not supported", sinkURI.Scheme)
 }
+
+// IsMQSink returns true if the sink URI refers to a message queue, whose rows are dispatched to partitions
+func IsMQSink(sinkURIStr string) bool {
+	sinkURI, err := url.Parse(sinkURIStr)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(sinkURI.Scheme) {
+	case "kafka", "kafka+ssl", "pulsar", "pulsar+ssl":
+		return true
+	}
+	return false
+}
+
+// Validate creates the sink in the dry-run mode and closes it immediately. It checks the sink URI,
+// the connectivity and the permissions of the downstream without any side effect on the downstream.
+func Validate(ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr string, filter *filter.Filter, config *config.ReplicaConfig, opts map[string]string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sinkOpts := make(map[string]string, len(opts)+1)
+	for k, v := range opts {
+		sinkOpts[k] = v
+	}
+	sinkOpts[OptDryRun] = "true"
+	errCh := make(chan error, 1)
+	// some sinks change the replica config according to the sink URI
+	s, err := NewSink(ctx, changefeedID, sinkURIStr, filter, config.Clone(), sinkOpts, errCh)
+	if err != nil {
+		return err
+	}
+	if err := s.Close(); err != nil {
+		return err
+	}
+	select {
+	case err := <-errCh:
+		return err
+	default:
+	}
+	return nil
+}
@@ -57,6 +57,7 @@ var (
 	configFile string
 	cliPdAddr  string
 	noConfirm  bool
+	dryRun     bool
 	sortEngine string
 	sortDir    string
 	timezone   string
@@ -71,6 +72,7 @@ var (
 
 	interact          bool
 	simplified        bool
+	slowestTables     int
 	cliLogLevel       string
 	changefeedListAll bool
 
@@ -98,6 +100,7 @@ type capture struct {
 	ID            string `json:"id"`
 	IsOwner       bool   `json:"is-owner"`
 	AdvertiseAddr string `json:"address"`
+	Draining      bool   `json:"draining,omitempty"`
 }
 
 // cfMeta holds changefeed info and changefeed status
@@ -106,6 +109,8 @@ type cfMeta struct {
 	Status     *model.ChangeFeedStatus `json:"status"`
 	Count      uint64                  `json:"count"`
 	TaskStatus []captureTaskStatus     `json:"task-status"`
+	// Tables are the slowest tables, only output with the --slowest-tables flag
+	Tables []*model.CaptureTableStatus `json:"tables,omitempty"`
 }
 
 type captureTaskStatus struct {
@@ -130,7 +135,7 @@ func newCliCommand() *cobra.Command {
 		Use:   "cli",
 		Short: "Manage replication task and TiCDC cluster",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			initCmd(cmd, &logutil.Config{Level: cliLogLevel})
+			initCmd(cmd, &logutil.Config{Level: cliLogLevel}, nil)
 
 			credential := getCredential()
 			tlsConfig, err := credential.ToTLSConfig()
@@ -14,10 +14,27 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
 	_ "github.com/go-sql-driver/mysql" // mysql driver
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/spf13/cobra"
 )
 
+var (
+	drainCaptureID      string
+	drainCaptureTimeout time.Duration
+	drainCaptureNoWait  bool
+)
+
 func newCaptureCommand() *cobra.Command {
 	command := &cobra.Command{
 		Use:   "capture",
@@ -25,6 +42,7 @@ func newCaptureCommand() *cobra.Command {
 	}
 	command.AddCommand(
 		newListCaptureCommand(),
+		newDrainCaptureCommand(),
 		// TODO: add resign owner command
 	)
 	return command
@@ -45,3 +63,93 @@ func newListCaptureCommand() *cobra.Command {
 	}
 	return command
 }
+
+func newDrainCaptureCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "drain",
+		Short: "Move all tables of a capture to the other captures, so that the capture can be stopped without a lag spike",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := defaultContext
+			captures, err := getAllCaptures(ctx)
+			if err != nil {
+				return err
+			}
+			var target *capture
+			for _, c := range captures {
+				if c.ID == drainCaptureID {
+					target = c
+				}
+			}
+			if target == nil {
+				return cerror.ErrCaptureNotExist.GenWithStackByArgs(drainCaptureID)
+			}
+			credential := getCredential()
+			status, err := applyDrainCapture(ctx, target, http.MethodPost, credential)
+			if err != nil {
+				return err
+			}
+			if drainCaptureNoWait {
+				return jsonPrint(cmd, status)
+			}
+			ctx, cancel := context.WithTimeout(ctx, drainCaptureTimeout)
+			defer cancel()
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for !status.Drained() {
+				cmd.Printf("draining capture %s, %d tables remain\n", status.CaptureID, status.RemainingTables)
+				select {
+				case <-ctx.Done():
+					return cerror.ErrDrainCaptureTimeout.GenWithStackByArgs(status.CaptureID, status.RemainingTables)
+				case <-ticker.C:
+				}
+				status, err = applyDrainCapture(ctx, target, http.MethodGet, credential)
+				if err != nil {
+					return err
+				}
+			}
+			return jsonPrint(cmd, status)
+		},
+	}
+	command.PersistentFlags().StringVar(&drainCaptureID, "capture-id", "", "ID of the capture to drain")
+	command.PersistentFlags().DurationVar(&drainCaptureTimeout, "timeout", 10*time.Minute, "maximum duration of waiting for the tables to be moved")
+	command.PersistentFlags().BoolVar(&drainCaptureNoWait, "no-wait", false, "only mark the capture as draining, without waiting for the tables to be moved")
+	_ = command.MarkPersistentFlagRequired("capture-id")
+	return command
+}
+
+// applyDrainCapture sends a drain request to the capture, a POST request starts draining the capture,
+// and a GET request queries the progress of draining.
+func applyDrainCapture(
+	ctx context.Context, target *capture, method string, credential *security.Credential,
+) (*model.DrainStatus, error) {
+	scheme := "http"
+	if credential.IsTLSEnabled() {
+		scheme = "https"
+	}
+	addr := fmt.Sprintf("%s://%s/capture/drain", scheme, target.AdvertiseAddr)
+	cli, err := newHTTPClient(credential)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, addr, nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.BadRequestf("drain capture %s", target.ID)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.BadRequestf("%s", string(body))
+	}
+	status := &model.DrainStatus{}
+	if err := json.Unmarshal(body, status); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return status, nil
+}
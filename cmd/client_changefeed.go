@@ -26,11 +26,14 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/config"
 	"github.com/pingcap/ticdc/pkg/cyclic"
 	"github.com/pingcap/ticdc/pkg/cyclic/mark"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
 	"github.com/pingcap/ticdc/pkg/security"
 	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/ticdc/pkg/version"
@@ -57,7 +60,10 @@ func newChangefeedCommand() *cobra.Command {
 		newCreateChangefeedCommand(),
 		newUpdateChangefeedCommand(),
 		newStatisticsChangefeedCommand(),
+		newSchemaChangefeedCommand(),
 		newCreateChangefeedCyclicCommand(),
+		newExportChangefeedCommand(),
+		newImportChangefeedCommand(),
 	)
 	// Add pause, resume, remove changefeed
 	for _, cmd := range newAdminChangefeedCommand() {
@@ -67,7 +73,7 @@ func newChangefeedCommand() *cobra.Command {
 }
 
 func resumeChangefeedCheck(ctx context.Context, cmd *cobra.Command) error {
-	resp, err := applyOwnerChangefeedQuery(ctx, changefeedID, getCredential())
+	resp, err := applyOwnerChangefeedQuery(ctx, changefeedID, 0, getCredential())
 	if err != nil {
 		return err
 	}
@@ -164,7 +170,7 @@ func newListChangefeedCommand() *cobra.Command {
 			cfs := make([]*changefeedCommonInfo, 0, len(changefeedIDs))
 			for id := range changefeedIDs {
 				cfci := &changefeedCommonInfo{ID: id}
-				resp, err := applyOwnerChangefeedQuery(ctx, id, getCredential())
+				resp, err := applyOwnerChangefeedQuery(ctx, id, 0, getCredential())
 				if err != nil {
 					// if no capture is available, the query will fail, just add a warning here
 					log.Warn("query changefeed info failed", zap.String("error", err.Error()))
@@ -193,7 +199,7 @@ func newQueryChangefeedCommand() *cobra.Command {
 			ctx := defaultContext
 
 			if simplified {
-				resp, err := applyOwnerChangefeedQuery(ctx, changefeedID, getCredential())
+				resp, err := applyOwnerChangefeedQuery(ctx, changefeedID, slowestTables, getCredential())
 				if err != nil {
 					return err
 				}
@@ -230,6 +236,9 @@ func newQueryChangefeedCommand() *cobra.Command {
 				taskStatus = append(taskStatus, captureTaskStatus{CaptureID: captureID, TaskStatus: status})
 			}
 			meta := &cfMeta{Info: info, Status: status, Count: count, TaskStatus: taskStatus}
+			if slowestTables > 0 {
+				meta.Tables = model.SlowestTables(taskPositions, slowestTables)
+			}
 			if info == nil {
 				log.Warn("This changefeed has been deleted, the residual meta data will be completely deleted within 24 hours.", zap.String("changgefeed", changefeedID))
 			}
@@ -237,6 +246,7 @@ func newQueryChangefeedCommand() *cobra.Command {
 		},
 	}
 	command.PersistentFlags().BoolVarP(&simplified, "simple", "s", false, "Output simplified replication status")
+	command.PersistentFlags().IntVar(&slowestTables, "slowest-tables", 0, "Output the status of the N slowest tables")
 	command.PersistentFlags().StringVarP(&changefeedID, "changefeed-id", "c", "", "Replication task (changefeed) ID")
 	_ = command.MarkPersistentFlagRequired("changefeed-id")
 	return command
@@ -244,7 +254,7 @@ func newQueryChangefeedCommand() *cobra.Command {
 
 func verifyChangefeedParameters(ctx context.Context, cmd *cobra.Command, isCreate bool, credential *security.Credential, captureInfos []*model.CaptureInfo) (*model.ChangeFeedInfo, error) {
 	if isCreate {
-		if sinkURI == "" {
+		if sinkURI == "" && !dryRun {
 			return nil, errors.New("Creating changefeed without a sink-uri")
 		}
 		if startTs == 0 {
@@ -254,6 +264,9 @@ func verifyChangefeedParameters(ctx context.Context, cmd *cobra.Command, isCreat
 			}
 			startTs = oracle.ComposeTS(ts, logical)
 		}
+	}
+	// the start-ts, the tables and the sink are checked by the validation in the dry-run mode
+	if isCreate && !dryRun {
 		if err := verifyStartTs(ctx, changefeedID, startTs); err != nil {
 			return nil, err
 		}
@@ -323,6 +336,10 @@ func verifyChangefeedParameters(ctx context.Context, cmd *cobra.Command, isCreat
 			log.Error("if use force replicate, old value feature must be enabled")
 			return nil, cerror.ErrOldValueNotEnabled.GenWithStackByArgs()
 		}
+		if len(cfg.Sink.PartialUpdateTables) != 0 {
+			log.Error("if use partial update, old value feature must be enabled")
+			return nil, cerror.ErrOldValueNotEnabled.GenWithStackByArgs()
+		}
 	}
 
 	for _, rules := range cfg.Sink.DispatchRules {
@@ -336,10 +353,11 @@ func verifyChangefeedParameters(ctx context.Context, cmd *cobra.Command, isCreat
 		}
 	}
 	switch sortEngine {
-	case model.SortUnified, model.SortInMemory, model.SortInFile:
+	case model.SortUnified, model.SortInMemory, model.SortInFile, model.SortInLevelDB:
 	default:
 		return nil, errors.Errorf("Creating changefeed with an invalid sort engine(%s), "+
-			"`%s`,`%s` and `%s` are optional.", sortEngine, model.SortUnified, model.SortInMemory, model.SortInFile)
+			"`%s`,`%s`, `%s` and `%s` are optional.", sortEngine,
+			model.SortUnified, model.SortInMemory, model.SortInFile, model.SortInLevelDB)
 	}
 	info := &model.ChangeFeedInfo{
 		SinkURI:           sinkURI,
@@ -374,8 +392,8 @@ func verifyChangefeedParameters(ctx context.Context, cmd *cobra.Command, isCreat
 		return nil, errors.Annotate(err, "can not load timezone, Please specify the time zone through environment variable `TZ` or command line parameters `--tz`")
 	}
 
-	if isCreate {
-		ctx = util.PutTimezoneInCtx(ctx, tz)
+	ctx = util.PutTimezoneInCtx(ctx, tz)
+	if isCreate && !dryRun {
 		ineligibleTables, eligibleTables, err := verifyTables(ctx, credential, cfg, startTs)
 		if err != nil {
 			return nil, err
@@ -422,6 +440,9 @@ func verifyChangefeedParameters(ctx context.Context, cmd *cobra.Command, isCreat
 		info.Opts[key] = value
 	}
 
+	if dryRun {
+		return info, nil
+	}
 	err = verifySink(ctx, info.SinkURI, info.Config, info.Opts)
 	if err != nil {
 		return nil, err
@@ -435,7 +456,7 @@ func changefeedConfigVariables(command *cobra.Command) {
 	command.PersistentFlags().StringVar(&sinkURI, "sink-uri", "", "sink uri")
 	command.PersistentFlags().StringVar(&configFile, "config", "", "Path of the configuration file")
 	command.PersistentFlags().StringSliceVar(&opts, "opts", nil, "Extra options, in the `key=value` format")
-	command.PersistentFlags().StringVar(&sortEngine, "sort-engine", model.SortUnified, "sort engine used for data sort")
+	command.PersistentFlags().StringVar(&sortEngine, "sort-engine", model.SortUnified, "sort engine used for data sort, one of memory, file, unified and leveldb")
 	command.PersistentFlags().StringVar(&sortDir, "sort-dir", "", "directory used for data sort")
 	command.PersistentFlags().StringVar(&timezone, "tz", "SYSTEM", "timezone used when checking sink uri (changefeed timezone is determined by cdc server)")
 	command.PersistentFlags().Uint64Var(&cyclicReplicaID, "cyclic-replica-id", 0, "(Experimental) Cyclic replication replica ID of changefeed")
@@ -473,6 +494,9 @@ func newCreateChangefeedCommand() *cobra.Command {
 			if info == nil {
 				return nil
 			}
+			if dryRun {
+				return validateChangefeed(ctx, cmd, getCredential(), id, info, info.StartTs)
+			}
 
 			infoStr, err := info.Marshal()
 			if err != nil {
@@ -490,6 +514,7 @@ func newCreateChangefeedCommand() *cobra.Command {
 	command.PersistentFlags().BoolVar(&noConfirm, "no-confirm", false, "Don't ask user whether to ignore ineligible table")
 	command.PersistentFlags().StringVarP(&changefeedID, "changefeed-id", "c", "", "Replication task (changefeed) ID")
 	command.PersistentFlags().BoolVarP(&disableGCSafePointCheck, "disable-gc-check", "", false, "Disable GC safe point check")
+	command.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Validate the changefeed and report all the problems found without creating it")
 
 	return command
 }
@@ -553,7 +578,7 @@ func newUpdateChangefeedCommand() *cobra.Command {
 					info.SyncPointEnabled = syncPointEnabled
 				case "sync-interval":
 					info.SyncPointInterval = syncPointInterval
-				case "pd", "tz", "start-ts", "changefeed-id", "no-confirm":
+				case "pd", "tz", "start-ts", "changefeed-id", "no-confirm", "dry-run":
 					// do nothing
 				default:
 					// use this default branch to prevent new added parameter is not added
@@ -563,8 +588,25 @@ func newUpdateChangefeedCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if dryRun {
+				// the tables of an existing changefeed are selected at its checkpoint
+				status, _, err := cdcEtcdCli.GetChangeFeedStatus(ctx, changefeedID)
+				if err != nil && cerror.ErrChangeFeedNotExists.NotEqual(err) {
+					return err
+				}
+				checkpointTs := info.StartTs
+				if status != nil {
+					checkpointTs = status.CheckpointTs
+				}
+				tz, err := util.GetTimezone(timezone)
+				if err != nil {
+					return errors.Annotate(err, "can not load timezone")
+				}
+				ctx = util.PutTimezoneInCtx(ctx, tz)
+				return validateChangefeed(ctx, cmd, getCredential(), changefeedID, info, checkpointTs)
+			}
 
-			resp, err := applyOwnerChangefeedQuery(ctx, changefeedID, getCredential())
+			resp, err := applyOwnerChangefeedQuery(ctx, changefeedID, 0, getCredential())
 			// if no cdc owner exists, allow user to update changefeed config
 			if err != nil && errors.Cause(err) != errOwnerNotFound {
 				return err
@@ -618,6 +660,7 @@ func newUpdateChangefeedCommand() *cobra.Command {
 	changefeedConfigVariables(command)
 	command.PersistentFlags().StringVarP(&changefeedID, "changefeed-id", "c", "", "Replication task (changefeed) ID")
 	command.PersistentFlags().BoolVar(&noConfirm, "no-confirm", false, "Don't ask user whether to confirm update changefeed config")
+	command.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Validate the updated changefeed and report all the problems found without updating it")
 	_ = command.MarkPersistentFlagRequired("changefeed-id")
 
 	return command
@@ -677,6 +720,70 @@ func newStatisticsChangefeedCommand() *cobra.Command {
 	return command
 }
 
+var (
+	schemaTable   string
+	schemaTs      uint64
+	schemaStartTs uint64
+)
+
+func newSchemaChangefeedCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "schema",
+		Short: "Query the definition of a table used by a replication task (changefeed) at a ts and the DDL jobs applied to the table",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := defaultContext
+			names := strings.SplitN(schemaTable, ".", 2)
+			if len(names) != 2 || names[0] == "" || names[1] == "" {
+				return errors.Errorf("invalid table %s, the format is schema.table", schemaTable)
+			}
+			info, err := cdcEtcdCli.GetChangeFeedInfo(ctx, changefeedID)
+			if err != nil {
+				return err
+			}
+			ts := schemaTs
+			if ts == 0 {
+				ts = info.StartTs
+				status, _, err := cdcEtcdCli.GetChangeFeedStatus(ctx, changefeedID)
+				if err != nil && cerror.ErrChangeFeedNotExists.NotEqual(err) {
+					return err
+				}
+				if status != nil {
+					ts = status.CheckpointTs
+				}
+			}
+			startTs := schemaStartTs
+			if startTs == 0 {
+				startTs = ts
+			}
+			if info.Config == nil {
+				info.Config = config.GetDefaultReplicaConfig()
+			}
+			f, err := filter.NewFilter(info.Config)
+			if err != nil {
+				return err
+			}
+			kvStore, err := kv.CreateTiStore(cliPdAddr, getCredential())
+			if err != nil {
+				return err
+			}
+			defer kvStore.Close() //nolint:errcheck
+			history, err := entry.QueryTableSchemaHistory(ctx, kvStore, nil, f, info.Config.ForceReplicate,
+				model.TableName{Schema: names[0], Table: names[1]}, startTs, ts)
+			if err != nil {
+				return err
+			}
+			return jsonPrint(cmd, history)
+		},
+	}
+	command.PersistentFlags().StringVarP(&changefeedID, "changefeed-id", "c", "", "Replication task (changefeed) ID")
+	command.PersistentFlags().StringVar(&schemaTable, "table", "", "Table to query in the schema.table format")
+	command.PersistentFlags().Uint64Var(&schemaTs, "ts", 0, "Ts of the schema snapshot, the checkpoint of the changefeed if it's not specified")
+	command.PersistentFlags().Uint64Var(&schemaStartTs, "start-ts", 0, "The DDL jobs finished after the start ts are listed, the ts if it's not specified")
+	_ = command.MarkPersistentFlagRequired("changefeed-id")
+	_ = command.MarkPersistentFlagRequired("table")
+	return command
+}
+
 func newCreateChangefeedCyclicCommand() *cobra.Command {
 	command := &cobra.Command{
 		Use:   "cyclic",
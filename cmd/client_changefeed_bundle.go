@@ -0,0 +1,310 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/version"
+	"github.com/spf13/cobra"
+	"github.com/tikv/client-go/v2/oracle"
+	"sigs.k8s.io/yaml"
+)
+
+// formats of the changefeed bundle
+const (
+	bundleFormatJSON = "json"
+	bundleFormatYAML = "yaml"
+)
+
+// the ts from which the imported changefeeds start
+const (
+	importStartFromNow        = "now"
+	importStartFromCheckpoint = "checkpoint"
+	importStartFromStartTs    = "start-ts"
+)
+
+// the ways to handle an imported changefeed which already exists
+const (
+	importOnConflictFail = "fail"
+	importOnConflictSkip = "skip"
+)
+
+var (
+	bundleFile          string
+	bundleFormat        string
+	bundleChangefeedIDs []string
+	exportCheckpoint    bool
+	exportRedact        bool
+	importStartFrom     string
+	importOnConflict    string
+)
+
+// importResult is the summary of importing a changefeed bundle
+type importResult struct {
+	Created []model.ChangeFeedID `json:"created"`
+	Skipped []model.ChangeFeedID `json:"skipped"`
+}
+
+// getBundleFormat returns the format specified by the flag, or infers the format by the file extension
+func getBundleFormat(format, path string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			return bundleFormatYAML, nil
+		default:
+			return bundleFormatJSON, nil
+		}
+	}
+	switch format {
+	case bundleFormatJSON, bundleFormatYAML:
+		return format, nil
+	default:
+		return "", errors.Errorf("unsupported bundle format %s, `%s` and `%s` are optional", format, bundleFormatJSON, bundleFormatYAML)
+	}
+}
+
+func encodeChangefeedBundle(bundle *model.ChangefeedBundle, format string) ([]byte, error) {
+	if format == bundleFormatYAML {
+		data, err := yaml.Marshal(bundle)
+		return data, cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	return data, cerror.WrapError(cerror.ErrMarshalFailed, err)
+}
+
+func decodeChangefeedBundle(data []byte, format string) (*model.ChangefeedBundle, error) {
+	bundle := new(model.ChangefeedBundle)
+	var err error
+	if format == bundleFormatYAML {
+		err = yaml.Unmarshal(data, bundle)
+	} else {
+		err = json.Unmarshal(data, bundle)
+	}
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrInvalidChangefeedBundle, err)
+	}
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+func exportChangefeeds(ctx context.Context, ids []model.ChangeFeedID) (*model.ChangefeedBundle, error) {
+	if len(ids) == 0 {
+		_, raw, err := cdcEtcdCli.GetChangeFeeds(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for id := range raw {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+	}
+	bundle := &model.ChangefeedBundle{
+		Version:     model.ChangefeedBundleVersion,
+		ExportTime:  time.Now(),
+		Changefeeds: make([]*model.ExportedChangefeed, 0, len(ids)),
+	}
+	for _, id := range ids {
+		info, err := cdcEtcdCli.GetChangeFeedInfo(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if info.State == model.StateRemoved {
+			continue
+		}
+		var checkpointTs uint64
+		if exportCheckpoint {
+			status, _, err := cdcEtcdCli.GetChangeFeedStatus(ctx, id)
+			if err != nil && cerror.ErrChangeFeedNotExists.NotEqual(err) {
+				return nil, err
+			}
+			if status != nil {
+				checkpointTs = status.CheckpointTs
+			}
+		}
+		exported, err := model.NewExportedChangefeed(id, info, checkpointTs, exportRedact)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Changefeeds = append(bundle.Changefeeds, exported)
+	}
+	return bundle, nil
+}
+
+// newImportedChangefeedInfo returns the changefeed info which is created by the import
+func newImportedChangefeedInfo(cf *model.ExportedChangefeed, currentTs uint64) (*model.ChangeFeedInfo, error) {
+	if cf.IsRedacted() {
+		return nil, cerror.ErrInvalidChangefeedBundle.GenWithStack(
+			"the secrets in the sink uri of changefeed %s are redacted, please fill them in the bundle", cf.ID)
+	}
+	info, err := cf.Info.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if err := info.VerifyAndFix(); err != nil {
+		return nil, err
+	}
+	switch importStartFrom {
+	case importStartFromNow:
+		info.StartTs = currentTs
+	case importStartFromCheckpoint:
+		if cf.CheckpointTs == 0 {
+			return nil, cerror.ErrInvalidChangefeedBundle.GenWithStack(
+				"the checkpoint of changefeed %s is not exported", cf.ID)
+		}
+		info.StartTs = cf.CheckpointTs
+	case importStartFromStartTs:
+	default:
+		return nil, errors.Errorf("unsupported start point %s, `%s`, `%s` and `%s` are optional",
+			importStartFrom, importStartFromNow, importStartFromCheckpoint, importStartFromStartTs)
+	}
+	if disableGCSafePointCheck {
+		info.Config.CheckGCSafePoint = false
+	}
+	info.CreateTime = time.Now()
+	info.State = model.StateNormal
+	info.CreatorVersion = version.ReleaseVersion
+	return info, nil
+}
+
+func newExportChangefeedCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Export the definitions of replication tasks (changefeeds) into a bundle",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := defaultContext
+			format, err := getBundleFormat(bundleFormat, bundleFile)
+			if err != nil {
+				return err
+			}
+			bundle, err := exportChangefeeds(ctx, bundleChangefeedIDs)
+			if err != nil {
+				return err
+			}
+			data, err := encodeChangefeedBundle(bundle, format)
+			if err != nil {
+				return err
+			}
+			if bundleFile == "" {
+				cmd.Printf("%s\n", data)
+				return nil
+			}
+			// the bundle may contain secrets if they are not redacted
+			if err := ioutil.WriteFile(bundleFile, data, 0o600); err != nil {
+				return errors.Trace(err)
+			}
+			cmd.Printf("Export %d changefeeds to %s successfully!\n", len(bundle.Changefeeds), bundleFile)
+			return nil
+		},
+	}
+	command.PersistentFlags().StringVarP(&bundleFile, "output", "o", "", "Path of the exported bundle, print the bundle if it's not specified")
+	command.PersistentFlags().StringVar(&bundleFormat, "format", "", "Format of the bundle, json or yaml, inferred by the file extension if not specified")
+	command.PersistentFlags().StringSliceVar(&bundleChangefeedIDs, "changefeed-ids", nil, "Replication tasks (changefeeds) to export, all the changefeeds are exported if not specified")
+	command.PersistentFlags().BoolVar(&exportCheckpoint, "with-checkpoint", false, "Export the current checkpoints of the changefeeds")
+	command.PersistentFlags().BoolVar(&exportRedact, "redact", true, "Redact the passwords and the secret keys in the sink uris")
+	return command
+}
+
+func newImportChangefeedCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "import",
+		Short: "Create the replication tasks (changefeeds) defined in a bundle",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := defaultContext
+			format, err := getBundleFormat(bundleFormat, bundleFile)
+			if err != nil {
+				return err
+			}
+			data, err := ioutil.ReadFile(bundleFile)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			bundle, err := decodeChangefeedBundle(data, format)
+			if err != nil {
+				return err
+			}
+			ts, logical, err := pdCli.GetTS(ctx)
+			if err != nil {
+				return err
+			}
+			currentTs := oracle.ComposeTS(ts, logical)
+
+			// check all the changefeeds before creating any of them
+			result := &importResult{}
+			ids := make([]model.ChangeFeedID, 0, len(bundle.Changefeeds))
+			infos := make(map[model.ChangeFeedID]*model.ChangeFeedInfo, len(bundle.Changefeeds))
+			for _, cf := range bundle.Changefeeds {
+				_, err := cdcEtcdCli.GetChangeFeedInfo(ctx, cf.ID)
+				if err == nil {
+					if importOnConflict != importOnConflictSkip {
+						return cerror.ErrChangeFeedAlreadyExists.GenWithStackByArgs(cf.ID)
+					}
+					result.Skipped = append(result.Skipped, cf.ID)
+					continue
+				}
+				if cerror.ErrChangeFeedNotExists.NotEqual(err) {
+					return err
+				}
+				info, err := newImportedChangefeedInfo(cf, currentTs)
+				if err != nil {
+					return err
+				}
+				if err := verifyStartTs(ctx, cf.ID, info.StartTs); err != nil {
+					return errors.Annotatef(err, "changefeed %s", cf.ID)
+				}
+				if err := verifyTargetTs(ctx, info.StartTs, info.TargetTs); err != nil {
+					return errors.Annotatef(err, "changefeed %s", cf.ID)
+				}
+				if err := verifySink(ctx, info.SinkURI, info.Config, info.Opts); err != nil {
+					return errors.Annotatef(err, "changefeed %s", cf.ID)
+				}
+				ids = append(ids, cf.ID)
+				infos[cf.ID] = info
+			}
+
+			for _, id := range ids {
+				err := cdcEtcdCli.CreateChangefeedInfo(ctx, infos[id], id)
+				if cerror.ErrChangeFeedAlreadyExists.Equal(errors.Cause(err)) && importOnConflict == importOnConflictSkip {
+					result.Skipped = append(result.Skipped, id)
+					continue
+				}
+				if err != nil {
+					return errors.Annotatef(err, "changefeed %s, created changefeeds: %v", id, result.Created)
+				}
+				result.Created = append(result.Created, id)
+			}
+			return jsonPrint(cmd, result)
+		},
+	}
+	command.PersistentFlags().StringVarP(&bundleFile, "input", "i", "", "Path of the bundle to import")
+	command.PersistentFlags().StringVar(&bundleFormat, "format", "", "Format of the bundle, json or yaml, inferred by the file extension if not specified")
+	command.PersistentFlags().StringVar(&importStartFrom, "start-from", importStartFromNow,
+		"Where the changefeeds start from, `now`, `checkpoint` (the exported checkpoint) or `start-ts` (the exported start-ts)")
+	command.PersistentFlags().StringVar(&importOnConflict, "on-conflict", importOnConflictFail,
+		"How to handle the changefeeds which already exist, `fail` or `skip`")
+	command.PersistentFlags().BoolVarP(&disableGCSafePointCheck, "disable-gc-check", "", false, "Disable GC safe point check")
+	_ = command.MarkPersistentFlagRequired("input")
+	return command
+}
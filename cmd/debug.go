@@ -0,0 +1,143 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
+	psorter "github.com/pingcap/ticdc/cdc/puller/sorter"
+	"github.com/pingcap/ticdc/cdc/recorder"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/logutil"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newDebugCommand())
+}
+
+func newDebugCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "debug",
+		Short: "Debug tools",
+	}
+	command.AddCommand(
+		newReplayCommand(),
+	)
+	return command
+}
+
+func newReplayCommand() *cobra.Command {
+	var (
+		recordFile       string
+		replaySinkURI    string
+		replaySortEngine string
+		replaySortDir    string
+		replayTimezone   string
+		replayConfigFile string
+		mounterWorkerNum int
+		replayLogLevel   string
+	)
+	command := &cobra.Command{
+		Use:   "replay",
+		Short: "Replay the events recorded by a capture through the sorter, the mounter and a sink",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stop := initCmd(cmd, &logutil.Config{Level: replayLogLevel}, nil)
+			defer stop()
+			reader, err := recorder.NewReader(recordFile)
+			if err != nil {
+				return err
+			}
+			defer reader.Close() //nolint:errcheck
+			header := reader.Header()
+
+			replicaConfig := config.GetDefaultReplicaConfig()
+			if replayConfigFile != "" {
+				if err := strictDecodeFile(replayConfigFile, "TiCDC changefeed", replicaConfig); err != nil {
+					return err
+				}
+			}
+			// the events are recorded with or without the old values
+			replicaConfig.EnableOldValue = header.EnableOldValue
+			f, err := filter.NewFilter(replicaConfig)
+			if err != nil {
+				return err
+			}
+
+			tz, err := util.GetTimezone(replayTimezone)
+			if err != nil {
+				return errors.Annotate(err, "can not load timezone")
+			}
+			ctx, cancel := context.WithCancel(util.PutTimezoneInCtx(defaultContext, tz))
+			defer cancel()
+
+			var sorter puller.EventSorter
+			switch model.SortEngine(replaySortEngine) {
+			case model.SortInMemory:
+				sorter = puller.NewEntrySorter()
+			case model.SortUnified:
+				sorter, err = psorter.NewUnifiedSorter(
+					replaySortDir, header.ChangefeedID, header.TableName.QuoteString(), header.TableID, "")
+				if err != nil {
+					return err
+				}
+				defer psorter.UnifiedSorterCleanUp()
+			default:
+				return errors.Errorf("unsupported sort engine %s", replaySortEngine)
+			}
+
+			errCh := make(chan error, 1)
+			s, err := sink.NewSink(ctx, header.ChangefeedID, replaySinkURI, f, replicaConfig, map[string]string{}, errCh)
+			if err != nil {
+				return err
+			}
+			defer s.Close() //nolint:errcheck
+			go func() {
+				select {
+				case <-ctx.Done():
+				case err := <-errCh:
+					cmd.PrintErrf("sink error: %v\n", err)
+					cancel()
+				}
+			}()
+
+			stats, err := recorder.Replay(ctx, reader, sorter, s, mounterWorkerNum)
+			if stats != nil {
+				// the stats of the rows before the schema change are printed with the error
+				if err := jsonPrint(cmd, stats); err != nil {
+					return err
+				}
+			}
+			return err
+		},
+	}
+	command.PersistentFlags().StringVar(&recordFile, "file", "", "Record file")
+	command.PersistentFlags().StringVar(&replaySinkURI, "sink-uri", "blackhole://", "Sink URI")
+	command.PersistentFlags().StringVar(&replaySortEngine, "sort-engine", string(model.SortUnified), "sort engine used by the replay, memory or unified")
+	command.PersistentFlags().StringVar(&replaySortDir, "sort-dir", os.TempDir(), "directory used by the unified sorter")
+	command.PersistentFlags().StringVar(&replayTimezone, "tz", "SYSTEM", "timezone used when mounting the rows")
+	command.PersistentFlags().StringVar(&replayConfigFile, "config", "", "Path of the changefeed configuration file")
+	command.PersistentFlags().IntVar(&mounterWorkerNum, "mounter-worker-num", 16, "number of the mounter workers")
+	command.PersistentFlags().StringVar(&replayLogLevel, "log-level", "warn", "log level (etc: debug|info|warn|error)")
+	_ = command.MarkPersistentFlagRequired("file")
+	command.SetOutput(os.Stdout)
+	return command
+}
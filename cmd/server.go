@@ -15,7 +15,10 @@ package cmd
 
 import (
 	"context"
+	"os"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/fatih/color"
@@ -23,6 +26,7 @@ import (
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc"
+	"github.com/pingcap/ticdc/cdc/puller/dbsorter"
 	"github.com/pingcap/ticdc/cdc/puller/sorter"
 	"github.com/pingcap/ticdc/pkg/config"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
@@ -81,7 +85,10 @@ func initServerCmd(cmd *cobra.Command) {
 	cmd.Flags().IntVar(&serverConfig.Sorter.MaxMemoryPressure, "sorter-max-memory-percentage", defaultServerConfig.Sorter.MaxMemoryPressure, "system memory usage threshold for forcing in-disk sort")
 	// We use 8GB as a safe default before we support local configuration file.
 	cmd.Flags().Uint64Var(&serverConfig.Sorter.MaxMemoryConsumption, "sorter-max-memory-consumption", defaultServerConfig.Sorter.MaxMemoryConsumption, "maximum memory consumption of in-memory sort")
+	cmd.Flags().Uint64Var(&serverConfig.MemoryQuota, "memory-quota", defaultServerConfig.MemoryQuota, "maximum memory consumption of the replication in bytes, 0 means unlimited")
 	cmd.Flags().StringVar(&serverConfig.Sorter.SortDir, "sort-dir", defaultServerConfig.Sorter.SortDir, "sorter's temporary file directory")
+	cmd.Flags().DurationVar((*time.Duration)(&serverConfig.DrainTimeout), "drain-timeout", time.Duration(defaultServerConfig.DrainTimeout), "maximum duration of moving the tables to the other captures on SIGTERM, 0 means the tables are not moved")
+	cmd.Flags().StringToStringVar(&serverConfig.Labels, "labels", nil, "Labels of the capture, used by the affinity rules of changefeeds, e.g. zone=z1,host=h1")
 
 	addSecurityFlags(cmd.Flags(), true /* isServer */)
 
@@ -95,12 +102,25 @@ func runEServer(cmd *cobra.Command, args []string) error {
 		return errors.Trace(err)
 	}
 
+	// runningServer holds the server once it's created, the capture is drained by it on SIGTERM
+	var runningServer atomic.Value
 	cancel := initCmd(cmd, &logutil.Config{
 		File:           conf.LogFile,
 		Level:          conf.LogLevel,
 		FileMaxSize:    conf.Log.File.MaxSize,
 		FileMaxDays:    conf.Log.File.MaxDays,
 		FileMaxBackups: conf.Log.File.MaxBackups,
+	}, func(sig os.Signal) {
+		server, ok := runningServer.Load().(*cdc.Server)
+		if sig != syscall.SIGTERM || conf.DrainTimeout == 0 || !ok {
+			return
+		}
+		log.Info("drain the capture before exiting", zap.Duration("timeout", time.Duration(conf.DrainTimeout)))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(conf.DrainTimeout))
+		defer cancel()
+		if err := server.Drain(ctx); err != nil {
+			log.Warn("failed to drain the capture", zap.Error(err))
+		}
 	})
 	defer cancel()
 	tz, err := util.GetTimezone(conf.TZ)
@@ -127,6 +147,7 @@ func runEServer(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return errors.Annotate(err, "new server")
 	}
+	runningServer.Store(server)
 	err = server.Run(ctx)
 	if err != nil && errors.Cause(err) != context.Canceled {
 		log.Error("run server", zap.String("error", errors.ErrorStack(err)))
@@ -134,6 +155,7 @@ func runEServer(cmd *cobra.Command, args []string) error {
 	}
 	server.Close()
 	sorter.UnifiedSorterCleanUp()
+	dbsorter.CloseDB()
 	log.Info("cdc server exits successfully")
 
 	return nil
@@ -178,6 +200,8 @@ func loadAndVerifyServerConfig(cmd *cobra.Command) (*config.ServerConfig, error)
 			conf.Sorter.MaxMemoryPressure = serverConfig.Sorter.MaxMemoryPressure
 		case "sorter-max-memory-consumption":
 			conf.Sorter.MaxMemoryConsumption = serverConfig.Sorter.MaxMemoryConsumption
+		case "memory-quota":
+			conf.MemoryQuota = serverConfig.MemoryQuota
 		case "ca":
 			conf.Security.CAPath = serverConfig.Security.CAPath
 		case "cert":
@@ -193,6 +217,10 @@ func loadAndVerifyServerConfig(cmd *cobra.Command) (*config.ServerConfig, error)
 					"sort-dir will be set to `{data-dir}/tmp/sorter`. The sort-dir here will be no-op\n"))
 			}
 			conf.Sorter.SortDir = config.DefaultSortDir
+		case "labels":
+			conf.Labels = serverConfig.Labels
+		case "drain-timeout":
+			conf.DrainTimeout = serverConfig.DrainTimeout
 		case "pd", "config":
 			// do nothing
 		default:
@@ -162,6 +162,8 @@ func (s *serverSuite) TestLoadAndVerifyServerConfig(c *check.C) {
 			MaxMemoryConsumption:   60000,
 			NumWorkerPoolGoroutine: 90,
 			SortDir:                config.DefaultSortDir,
+			LevelDBBlockCacheSize:  64 * 1024 * 1024,
+			Compression:            "none",
 		},
 		Security: &config.SecurityConfig{
 			CertPath:      "bb",
@@ -173,6 +175,22 @@ func (s *serverSuite) TestLoadAndVerifyServerConfig(c *check.C) {
 			WorkerConcurrent: 8,
 			WorkerPoolSize:   0,
 			RegionScanLimit:  40,
+
+			ResolveLockInterval:     config.TomlDuration(20 * time.Second),
+			RegionReconnectInterval: config.TomlDuration(30 * time.Minute),
+		},
+		Auth: &config.AuthConfig{
+			Enable: false,
+		},
+		Notification: &config.NotificationConfig{},
+		DrainTimeout: config.TomlDuration(5 * time.Minute),
+		Recorder: &config.RecorderConfig{
+			MaxFileSize: 1024 * 1024 * 1024,
+		},
+		SchemaSnapshotCache: &config.SchemaSnapshotCacheConfig{
+			Enable:       true,
+			MaxFiles:     4,
+			SaveInterval: config.TomlDuration(time.Hour),
 		},
 	})
 
@@ -240,6 +258,8 @@ sort-dir = "/tmp/just_a_test"
 			MaxMemoryConsumption:   2000000,
 			NumWorkerPoolGoroutine: 5,
 			SortDir:                config.DefaultSortDir,
+			LevelDBBlockCacheSize:  64 * 1024 * 1024,
+			Compression:            "none",
 		},
 		Security:            &config.SecurityConfig{},
 		PerTableMemoryQuota: 20 * 1024 * 1024, // 20M
@@ -247,6 +267,22 @@ sort-dir = "/tmp/just_a_test"
 			WorkerConcurrent: 8,
 			WorkerPoolSize:   0,
 			RegionScanLimit:  40,
+
+			ResolveLockInterval:     config.TomlDuration(20 * time.Second),
+			RegionReconnectInterval: config.TomlDuration(30 * time.Minute),
+		},
+		Auth: &config.AuthConfig{
+			Enable: false,
+		},
+		Notification: &config.NotificationConfig{},
+		DrainTimeout: config.TomlDuration(5 * time.Minute),
+		Recorder: &config.RecorderConfig{
+			MaxFileSize: 1024 * 1024 * 1024,
+		},
+		SchemaSnapshotCache: &config.SchemaSnapshotCacheConfig{
+			Enable:       true,
+			MaxFiles:     4,
+			SaveInterval: config.TomlDuration(time.Hour),
 		},
 	})
 
@@ -304,6 +340,8 @@ cert-allowed-cn = ["dd","ee"]
 			MaxMemoryConsumption:   60000000,
 			NumWorkerPoolGoroutine: 5,
 			SortDir:                config.DefaultSortDir,
+			LevelDBBlockCacheSize:  64 * 1024 * 1024,
+			Compression:            "none",
 		},
 		Security: &config.SecurityConfig{
 			CertPath:      "bb",
@@ -315,6 +353,22 @@ cert-allowed-cn = ["dd","ee"]
 			WorkerConcurrent: 8,
 			WorkerPoolSize:   0,
 			RegionScanLimit:  40,
+
+			ResolveLockInterval:     config.TomlDuration(20 * time.Second),
+			RegionReconnectInterval: config.TomlDuration(30 * time.Minute),
+		},
+		Auth: &config.AuthConfig{
+			Enable: false,
+		},
+		Notification: &config.NotificationConfig{},
+		DrainTimeout: config.TomlDuration(5 * time.Minute),
+		Recorder: &config.RecorderConfig{
+			MaxFileSize: 1024 * 1024 * 1024,
+		},
+		SchemaSnapshotCache: &config.SchemaSnapshotCacheConfig{
+			Enable:       true,
+			MaxFiles:     4,
+			SaveInterval: config.TomlDuration(time.Hour),
 		},
 	})
 }
@@ -14,22 +14,36 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/kv/fakekv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/regionspan"
+	"github.com/pingcap/ticdc/pkg/security"
+	"github.com/pingcap/ticdc/pkg/txnutil"
 	"github.com/spf13/cobra"
 	"github.com/tikv/client-go/v2/tikv"
 	pd "github.com/tikv/pd/client"
 )
 
-var testPdAddr string
+var (
+	testPdAddr     string
+	testScript     string
+	testStoreCount int
+)
 
 func init() {
 	rootCmd.AddCommand(testKVCmd)
 
 	testKVCmd.Flags().StringVar(&testPdAddr, "pd", "http://127.0.0.1:2379", "address of PD")
+	testKVCmd.Flags().StringVar(&testScript, "script", "", "run the script against a fake TiKV cluster instead of a real one")
+	testKVCmd.Flags().IntVar(&testStoreCount, "stores", 3, "number of the stores in the fake TiKV cluster")
 }
 
 type testingT struct {
@@ -51,6 +65,14 @@ var testKVCmd = &cobra.Command{
 	Short:  "test kv",
 	Long:   ``,
 	Run: func(cmd *cobra.Command, args []string) {
+		if testScript != "" {
+			if err := runTestKVScript(testScript, testStoreCount); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		}
+
 		addrs := strings.Split(testPdAddr, ",")
 		cli, err := pd.NewClient(addrs, getCredential().PDSecurityOption())
 		if err != nil {
@@ -71,3 +93,76 @@ var testKVCmd = &cobra.Command{
 		kv.TestSplit(t, cli, tikvStorage, storage)
 	},
 }
+
+type testPullerInit struct{}
+
+// IsInitialized implements kv.PullerInitialization
+func (*testPullerInit) IsInitialized() bool {
+	return true
+}
+
+// testScriptIdleTimeout is how long the feed receives no event before it's considered idle after the script finishes
+const testScriptIdleTimeout = time.Second
+
+// runTestKVScript runs the kv client against a fake TiKV cluster scripted by the file,
+// and prints the events received by the kv client until the feed goes idle after the script finishes.
+func runTestKVScript(path string, storeCount int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer f.Close()
+
+	cluster, err := fakekv.NewCluster(storeCount)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer cluster.Close()
+	for _, store := range cluster.Stores() {
+		fmt.Printf("store %d: %s\n", store.ID(), store.Addr())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	kv.InitWorkerPool()
+	go func() {
+		kv.RunWorkerPool(ctx) //nolint:errcheck
+	}()
+
+	cli := kv.NewCDCClient(ctx, cluster.PDClient(), cluster.KVStorage(), &security.Credential{})
+	defer cli.Close() //nolint:errcheck
+	eventCh := make(chan *model.RegionFeedEvent, 128)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cli.EventFeed(ctx, regionspan.ComparableSpan{Start: nil, End: nil}, 0, false,
+			txnutil.NewLockerResolver(cluster.KVStorage()), &testPullerInit{}, eventCh)
+	}()
+	scriptErrCh := make(chan error, 1)
+	go func() {
+		scriptErrCh <- fakekv.RunScript(ctx, cluster, f)
+	}()
+	// idleCh is set after the script finishes, the events still in flight are printed until the feed goes idle
+	var idleCh <-chan time.Time
+	for {
+		select {
+		case err := <-errCh:
+			return errors.Annotate(err, "event feed exits")
+		case err := <-scriptErrCh:
+			if err != nil {
+				return errors.Trace(err)
+			}
+			idleCh = time.After(testScriptIdleTimeout)
+		case <-idleCh:
+			return nil
+		case event := <-eventCh:
+			if event.Val != nil {
+				fmt.Printf("region %d: %s\n", event.RegionID, event.Val)
+			} else if event.Resolved != nil {
+				fmt.Printf("region %d: resolved ts %d, span %s\n", event.RegionID, event.Resolved.ResolvedTs, event.Resolved.Span)
+			}
+			if idleCh != nil {
+				idleCh = time.After(testScriptIdleTimeout)
+			}
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -54,6 +55,10 @@ var (
 	certPath      string
 	keyPath       string
 	allowedCertCN string
+
+	authToken    string
+	authUser     string
+	authPassword string
 )
 
 var errOwnerNotFound = liberrors.New("owner not found")
@@ -72,9 +77,28 @@ func addSecurityFlags(flags *pflag.FlagSet, isServer bool) {
 	flags.StringVar(&keyPath, "key", "", "Private key path for TLS connection")
 	if isServer {
 		flags.StringVar(&allowedCertCN, "cert-allowed-cn", "", "Verify caller's identity (cert Common Name). Use ',' to separate multiple CN")
+	} else {
+		flags.StringVar(&authToken, "auth-token", "", "Bearer token used to authenticate to the TiCDC HTTP API")
+		flags.StringVar(&authUser, "auth-user", "", "User name used to authenticate to the TiCDC HTTP API")
+		flags.StringVar(&authPassword, "auth-password", "", "Password used to authenticate to the TiCDC HTTP API")
 	}
 }
 
+// newHTTPClient creates a client of the TiCDC HTTP API with the given
+// credential and the authentication specified by command line flags.
+func newHTTPClient(credential *security.Credential) (*httputil.Client, error) {
+	cli, err := httputil.NewClient(credential)
+	if err != nil {
+		return nil, err
+	}
+	if authToken != "" {
+		cli.SetBearerToken(authToken)
+	} else if authUser != "" {
+		cli.SetBasicAuth(authUser, authPassword)
+	}
+	return cli, nil
+}
+
 func getCredential() *security.Credential {
 	var certAllowedCN []string
 	if len(allowedCertCN) != 0 {
@@ -89,7 +113,9 @@ func getCredential() *security.Credential {
 }
 
 // initCmd initializes the logger, the default context and returns its cancel function.
-func initCmd(cmd *cobra.Command, logCfg *logutil.Config) context.CancelFunc {
+// If beforeExit is not nil, it's called before the default context is canceled by a signal,
+// and a second signal cancels the context immediately.
+func initCmd(cmd *cobra.Command, logCfg *logutil.Config, beforeExit func(sig os.Signal)) context.CancelFunc {
 	// Init log.
 	err := logutil.InitLogger(logCfg)
 	if err != nil {
@@ -109,6 +135,18 @@ func initCmd(cmd *cobra.Command, logCfg *logutil.Config) context.CancelFunc {
 	go func() {
 		sig := <-sc
 		log.Info("got signal to exit", zap.Stringer("signal", sig))
+		if beforeExit != nil {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				beforeExit(sig)
+			}()
+			select {
+			case <-done:
+			case sig := <-sc:
+				log.Info("got signal again, exit immediately", zap.Stringer("signal", sig))
+			}
+		}
 		cancel()
 	}()
 	defaultContext = ctx
@@ -128,7 +166,7 @@ func getAllCaptures(ctx context.Context) ([]*capture, error) {
 	for _, c := range raw {
 		isOwner := c.ID == ownerID
 		captures = append(captures,
-			&capture{ID: c.ID, IsOwner: isOwner, AdvertiseAddr: c.AdvertiseAddr})
+			&capture{ID: c.ID, IsOwner: isOwner, AdvertiseAddr: c.AdvertiseAddr, Draining: c.Draining})
 	}
 	return captures, nil
 }
@@ -156,7 +194,7 @@ func applyAdminChangefeed(ctx context.Context, job model.AdminJob, credential *s
 		scheme = "https"
 	}
 	addr := fmt.Sprintf("%s://%s/capture/owner/admin", scheme, owner.AdvertiseAddr)
-	cli, err := httputil.NewClient(credential)
+	cli, err := newHTTPClient(credential)
 	if err != nil {
 		return err
 	}
@@ -183,7 +221,7 @@ func applyAdminChangefeed(ctx context.Context, job model.AdminJob, credential *s
 }
 
 func applyOwnerChangefeedQuery(
-	ctx context.Context, cid model.ChangeFeedID, credential *security.Credential,
+	ctx context.Context, cid model.ChangeFeedID, slowestTables int, credential *security.Credential,
 ) (string, error) {
 	owner, err := getOwnerCapture(ctx)
 	if err != nil {
@@ -194,13 +232,17 @@ func applyOwnerChangefeedQuery(
 		scheme = "https"
 	}
 	addr := fmt.Sprintf("%s://%s/capture/owner/changefeed/query", scheme, owner.AdvertiseAddr)
-	cli, err := httputil.NewClient(credential)
+	cli, err := newHTTPClient(credential)
 	if err != nil {
 		return "", err
 	}
-	resp, err := cli.PostForm(addr, url.Values(map[string][]string{
+	form := url.Values(map[string][]string{
 		cdc.APIOpVarChangefeedID: {cid},
-	}))
+	})
+	if slowestTables > 0 {
+		form.Set(cdc.APIOpVarSlowestTables, strconv.Itoa(slowestTables))
+	}
+	resp, err := cli.PostForm(addr, form)
 	if err != nil {
 		return "", err
 	}
@@ -242,36 +284,28 @@ func verifyTables(ctx context.Context, credential *security.Credential, cfg *con
 	if err != nil {
 		return nil, nil, err
 	}
-	meta, err := kv.GetSnapshotMeta(kvStore, startTs)
-	if err != nil {
-		return nil, nil, errors.Trace(err)
-	}
-
 	filter, err := filter.NewFilter(cfg)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
+	return entry.VerifyTables(filter, kvStore, startTs)
+}
 
-	snap, err := entry.NewSingleSchemaSnapshotFromMeta(meta, startTs, false /* explicitTables */)
+// validateChangefeed validates the changefeed without creating or updating it and prints the result
+func validateChangefeed(ctx context.Context, cmd *cobra.Command, credential *security.Credential, id model.ChangeFeedID, info *model.ChangeFeedInfo, checkpointTs uint64) error {
+	kvStore, err := kv.CreateTiStore(cliPdAddr, credential)
 	if err != nil {
-		return nil, nil, errors.Trace(err)
+		return err
 	}
-
-	for tID, tableName := range snap.CloneTables() {
-		tableInfo, exist := snap.TableByID(tID)
-		if !exist {
-			return nil, nil, errors.NotFoundf("table %d", tID)
-		}
-		if filter.ShouldIgnoreTable(tableName.Schema, tableName.Table) {
-			continue
-		}
-		if !tableInfo.IsEligible(false /* forceReplicate */) {
-			ineligibleTables = append(ineligibleTables, tableName)
-		} else {
-			eligibleTables = append(eligibleTables, tableName)
-		}
+	defer kvStore.Close() //nolint:errcheck
+	result := cdc.ValidateChangefeed(ctx, pdCli, kvStore, id, info, checkpointTs)
+	if err := jsonPrint(cmd, result); err != nil {
+		return err
+	}
+	if !result.Passed() {
+		return errors.Errorf("%d problems are found in changefeed %s", len(result.Problems), id)
 	}
-	return
+	return nil
 }
 
 func verifySink(
@@ -306,6 +340,26 @@ func verifyReplicaConfig(path, component string, cfg *config.ReplicaConfig) erro
 	if err != nil {
 		return err
 	}
+	if cfg.Notification != nil {
+		if err := cfg.Notification.Validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.Scheduler != nil {
+		if err := cfg.Scheduler.Validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.Resource != nil {
+		if err := cfg.Resource.Validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.Sink != nil {
+		if err := cfg.Sink.Validate(); err != nil {
+			return err
+		}
+	}
 	_, err = filter.VerifyRules(cfg)
 	return err
 }
@@ -0,0 +1,112 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/security"
+)
+
+const (
+	// AuthRoleReadOnly is allowed to call the HTTP APIs that do not change any state
+	AuthRoleReadOnly = "read-only"
+	// AuthRoleAdmin is allowed to call all HTTP APIs
+	AuthRoleAdmin = "admin"
+
+	redactedSecret = "******"
+)
+
+// AuthConfig represents authentication and authorization config for the HTTP API
+type AuthConfig struct {
+	// Enable turns on authentication for every HTTP API
+	Enable bool `toml:"enable" json:"enable"`
+	// AdminCertCN and ReadOnlyCertCN grant roles to the client certificate common names,
+	// they only take effect when TLS is enabled
+	AdminCertCN    []string `toml:"admin-cert-cn" json:"admin-cert-cn"`
+	ReadOnlyCertCN []string `toml:"read-only-cert-cn" json:"read-only-cert-cn"`
+	// Tokens are static bearer tokens
+	Tokens []*AuthToken `toml:"tokens" json:"tokens"`
+	// Users are users authenticated by HTTP basic auth
+	Users []*AuthUser `toml:"users" json:"users"`
+}
+
+// AuthToken represents a static bearer token and its role
+type AuthToken struct {
+	Token string `toml:"token" json:"token"`
+	Role  string `toml:"role" json:"role"`
+}
+
+// AuthUser represents a user authenticated by HTTP basic auth and its role.
+// Password is a htpasswd-style hash, bcrypt (`$2y$...`) and SHA1 (`{SHA}...`) are supported.
+type AuthUser struct {
+	Name     string `toml:"name" json:"name"`
+	Password string `toml:"password" json:"password"`
+	Role     string `toml:"role" json:"role"`
+}
+
+func validateAuthRole(role string) error {
+	switch role {
+	case AuthRoleReadOnly, AuthRoleAdmin:
+		return nil
+	}
+	return cerror.ErrInvalidServerOption.GenWithStack("invalid auth role: %s", role)
+}
+
+// ValidateAndAdjust validates the auth config
+func (c *AuthConfig) ValidateAndAdjust(tlsEnabled bool) error {
+	if !c.Enable {
+		return nil
+	}
+	if !tlsEnabled && (len(c.AdminCertCN) != 0 || len(c.ReadOnlyCertCN) != 0) {
+		return cerror.ErrInvalidServerOption.GenWithStack("auth by cert common name requires TLS")
+	}
+	for _, t := range c.Tokens {
+		if t.Token == "" {
+			return cerror.ErrInvalidServerOption.GenWithStack("empty auth token is not allowed")
+		}
+		if err := validateAuthRole(t.Role); err != nil {
+			return err
+		}
+	}
+	names := make(map[string]struct{}, len(c.Users))
+	for _, u := range c.Users {
+		if u.Name == "" || u.Password == "" {
+			return cerror.ErrInvalidServerOption.GenWithStack("auth user name and password must be specified")
+		}
+		if _, ok := names[u.Name]; ok {
+			return cerror.ErrInvalidServerOption.GenWithStack("duplicated auth user: %s", u.Name)
+		}
+		names[u.Name] = struct{}{}
+		if !security.IsSupportedHtpasswdHash(u.Password) {
+			return cerror.ErrInvalidServerOption.GenWithStack("unsupported password hash of auth user %s", u.Name)
+		}
+		if err := validateAuthRole(u.Role); err != nil {
+			return err
+		}
+	}
+	if len(c.AdminCertCN) == 0 && len(c.ReadOnlyCertCN) == 0 && len(c.Tokens) == 0 && len(c.Users) == 0 {
+		return cerror.ErrInvalidServerOption.GenWithStack("auth is enabled but no credential is configured")
+	}
+	return nil
+}
+
+// redact hides the secrets in the auth config, it is used before the config is logged
+func (c *AuthConfig) redact() {
+	for _, t := range c.Tokens {
+		t.Token = redactedSecret
+	}
+	for _, u := range c.Users {
+		u.Password = redactedSecret
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"regexp"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -37,6 +38,9 @@ const (
 	DefaultSortDir = "/tmp/sorter"
 )
 
+// labelRegexp matches the valid keys and values of the capture labels
+var labelRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9\-_.]*[a-zA-Z0-9])?$`)
+
 func init() {
 	StoreGlobalServerConfig(GetDefaultServerConfig())
 }
@@ -61,21 +65,27 @@ var defaultReplicaConfig = &ReplicaConfig{
 		Tp:          "table-number",
 		PollingTime: -1,
 	},
+	Notification: &NotificationConfig{},
+	Resource: &ResourceConfig{
+		Priority: PriorityNormal,
+	},
 }
 
 // ReplicaConfig represents some addition replication config for a changefeed
 type ReplicaConfig replicaConfig
 
 type replicaConfig struct {
-	CaseSensitive    bool             `toml:"case-sensitive" json:"case-sensitive"`
-	EnableOldValue   bool             `toml:"enable-old-value" json:"enable-old-value"`
-	ForceReplicate   bool             `toml:"force-replicate" json:"force-replicate"`
-	CheckGCSafePoint bool             `toml:"check-gc-safe-point" json:"check-gc-safe-point"`
-	Filter           *FilterConfig    `toml:"filter" json:"filter"`
-	Mounter          *MounterConfig   `toml:"mounter" json:"mounter"`
-	Sink             *SinkConfig      `toml:"sink" json:"sink"`
-	Cyclic           *CyclicConfig    `toml:"cyclic-replication" json:"cyclic-replication"`
-	Scheduler        *SchedulerConfig `toml:"scheduler" json:"scheduler"`
+	CaseSensitive    bool                `toml:"case-sensitive" json:"case-sensitive"`
+	EnableOldValue   bool                `toml:"enable-old-value" json:"enable-old-value"`
+	ForceReplicate   bool                `toml:"force-replicate" json:"force-replicate"`
+	CheckGCSafePoint bool                `toml:"check-gc-safe-point" json:"check-gc-safe-point"`
+	Filter           *FilterConfig       `toml:"filter" json:"filter"`
+	Mounter          *MounterConfig      `toml:"mounter" json:"mounter"`
+	Sink             *SinkConfig         `toml:"sink" json:"sink"`
+	Cyclic           *CyclicConfig       `toml:"cyclic-replication" json:"cyclic-replication"`
+	Scheduler        *SchedulerConfig    `toml:"scheduler" json:"scheduler"`
+	Notification     *NotificationConfig `toml:"notification" json:"notification"`
+	Resource         *ResourceConfig     `toml:"resource" json:"resource"`
 }
 
 // Marshal returns the json marshal format of a ReplicationConfig
@@ -126,6 +136,15 @@ func (c *ReplicaConfig) Clone() *ReplicaConfig {
 	return clone
 }
 
+// MounterWorkerNum returns the number of the mounter workers of the changefeed,
+// the mounter-worker-num in the resource config takes precedence over the worker-num of the mounter
+func (c *ReplicaConfig) MounterWorkerNum() int {
+	if c.Resource != nil && c.Resource.MounterWorkerNum > 0 {
+		return c.Resource.MounterWorkerNum
+	}
+	return c.Mounter.WorkerNum
+}
+
 func (c *replicaConfig) fillFromV1(v1 *outdated.ReplicaConfigV1) {
 	if v1 == nil || v1.Sink == nil {
 		return
@@ -187,6 +206,8 @@ var defaultServerConfig = &ServerConfig{
 		MaxMemoryConsumption:   16 * 1024 * 1024 * 1024, // 16GB
 		NumWorkerPoolGoroutine: 16,
 		SortDir:                DefaultSortDir,
+		LevelDBBlockCacheSize:  64 * 1024 * 1024, // 64MB
+		Compression:            SorterCompressionNone,
 	},
 	Security:            &SecurityConfig{},
 	PerTableMemoryQuota: 20 * 1024 * 1024, // 20MB
@@ -194,6 +215,22 @@ var defaultServerConfig = &ServerConfig{
 		WorkerConcurrent: 8,
 		WorkerPoolSize:   0, // 0 will use NumCPU() * 2
 		RegionScanLimit:  40,
+
+		ResolveLockInterval:     TomlDuration(20 * time.Second),
+		RegionReconnectInterval: TomlDuration(30 * time.Minute),
+	},
+	Auth: &AuthConfig{
+		Enable: false,
+	},
+	Notification: &NotificationConfig{},
+	DrainTimeout: TomlDuration(5 * time.Minute),
+	Recorder: &RecorderConfig{
+		MaxFileSize: 1024 * 1024 * 1024, // 1GB
+	},
+	SchemaSnapshotCache: &SchemaSnapshotCacheConfig{
+		Enable:       true,
+		MaxFiles:     4,
+		SaveInterval: TomlDuration(time.Hour),
 	},
 }
 
@@ -219,7 +256,26 @@ type ServerConfig struct {
 	Sorter              *SorterConfig   `toml:"sorter" json:"sorter"`
 	Security            *SecurityConfig `toml:"security" json:"security"`
 	PerTableMemoryQuota uint64          `toml:"per-table-memory-quota" json:"per-table-memory-quota"`
-	KVClient            *KVClientConfig `toml:"kv-client" json:"kv-client"`
+	// MemoryQuota is the maximum memory consumption of the puller, the sorter, the mounter and the sink buffers
+	// in the capture, the memory is allocated to the tables by their demands, 0 means unlimited
+	MemoryQuota uint64          `toml:"memory-quota" json:"memory-quota"`
+	KVClient    *KVClientConfig `toml:"kv-client" json:"kv-client"`
+	// SharedPuller shares the events pulled from TiKV among the changefeeds replicating the same tables with
+	// the same old value option and scan limits, the changefeeds still sort the events by themselves.
+	// The events buffered for the changefeeds are counted in the memory quota.
+	SharedPuller bool        `toml:"shared-puller" json:"shared-puller"`
+	Auth         *AuthConfig `toml:"auth" json:"auth"`
+	// Notification is applied to all changefeeds
+	Notification *NotificationConfig `toml:"notification" json:"notification"`
+	// Labels describe the capture, such as the zone and the host, they are used by the affinity rules of changefeeds
+	Labels map[string]string `toml:"labels" json:"labels"`
+	// DrainTimeout is the maximum duration of moving the tables to the other captures when the server
+	// receives SIGTERM, 0 means the tables are not moved before exiting
+	DrainTimeout TomlDuration `toml:"drain-timeout" json:"drain-timeout"`
+	// Recorder records the events pulled from TiKV of the selected tables for debugging
+	Recorder *RecorderConfig `toml:"recorder" json:"recorder"`
+	// SchemaSnapshotCache caches the schema snapshots in the data dir to speed up the startup
+	SchemaSnapshotCache *SchemaSnapshotCacheConfig `toml:"schema-snapshot-cache" json:"schema-snapshot-cache"`
 }
 
 // Marshal returns the json marshal format of a ServerConfig
@@ -240,9 +296,13 @@ func (c *ServerConfig) Unmarshal(data []byte) error {
 	return nil
 }
 
-// String implements the Stringer interface
+// String implements the Stringer interface, secrets in the config are redacted
 func (c *ServerConfig) String() string {
-	s, _ := c.Marshal()
+	clone := c.Clone()
+	if clone.Auth != nil {
+		clone.Auth.redact()
+	}
+	s, _ := clone.Marshal()
 	return s
 }
 
@@ -324,6 +384,22 @@ func (c *ServerConfig) ValidateAndAdjust() error {
 	if c.Sorter.MaxMemoryPressure < 0 || c.Sorter.MaxMemoryPressure > 100 {
 		return cerror.ErrIllegalUnifiedSorterParameter.GenWithStackByArgs("max-memory-percentage should be a percentage")
 	}
+	if c.Sorter.MaxDiskPercentage < 0 || c.Sorter.MaxDiskPercentage > 100 {
+		return cerror.ErrIllegalUnifiedSorterParameter.GenWithStackByArgs("max-disk-percentage should be a percentage")
+	}
+	if c.Sorter.LevelDBBlockCacheSize == 0 {
+		c.Sorter.LevelDBBlockCacheSize = defaultServerConfig.Sorter.LevelDBBlockCacheSize
+	}
+	switch c.Sorter.Compression {
+	case "":
+		c.Sorter.Compression = SorterCompressionNone
+	case SorterCompressionNone, SorterCompressionSnappy, SorterCompressionZstd:
+	default:
+		return cerror.ErrIllegalUnifiedSorterParameter.GenWithStackByArgs("compression should be one of none, snappy and zstd")
+	}
+	if _, err := c.Sorter.EncryptionKeyBytes(); err != nil {
+		return errors.Trace(err)
+	}
 
 	if c.PerTableMemoryQuota == 0 {
 		c.PerTableMemoryQuota = defaultServerConfig.PerTableMemoryQuota
@@ -331,6 +407,9 @@ func (c *ServerConfig) ValidateAndAdjust() error {
 	if c.PerTableMemoryQuota < 6*1024*1024 {
 		return cerror.ErrInvalidServerOption.GenWithStackByArgs("per-table-memory-quota should be at least 6MB")
 	}
+	if c.MemoryQuota != 0 && c.MemoryQuota < c.PerTableMemoryQuota {
+		return cerror.ErrInvalidServerOption.GenWithStackByArgs("memory-quota should be at least per-table-memory-quota")
+	}
 
 	if c.KVClient == nil {
 		c.KVClient = defaultServerConfig.KVClient
@@ -341,6 +420,58 @@ func (c *ServerConfig) ValidateAndAdjust() error {
 	if c.KVClient.RegionScanLimit <= 0 {
 		return cerror.ErrInvalidServerOption.GenWithStackByArgs("region-scan-limit should be at least 1")
 	}
+	if c.KVClient.StoreScanConcurrency < 0 {
+		return cerror.ErrInvalidServerOption.GenWithStackByArgs("store-scan-concurrency should not be negative")
+	}
+	if c.KVClient.ResolveLockInterval <= 0 {
+		c.KVClient.ResolveLockInterval = defaultServerConfig.KVClient.ResolveLockInterval
+	}
+	if c.KVClient.RegionReconnectInterval <= 0 {
+		c.KVClient.RegionReconnectInterval = defaultServerConfig.KVClient.RegionReconnectInterval
+	}
+	if c.KVClient.RegionReconnectInterval <= c.KVClient.ResolveLockInterval {
+		return cerror.ErrInvalidServerOption.GenWithStackByArgs(
+			"region-reconnect-interval should be larger than resolve-lock-interval")
+	}
+
+	if c.Auth == nil {
+		c.Auth = defaultServerConfig.Auth
+	}
+	if err := c.Auth.ValidateAndAdjust(c.Security != nil && c.Security.IsTLSEnabled()); err != nil {
+		return errors.Trace(err)
+	}
+
+	if c.Notification == nil {
+		c.Notification = defaultServerConfig.Notification
+	}
+	if err := c.Notification.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+
+	if c.Recorder == nil {
+		c.Recorder = defaultServerConfig.Recorder
+	}
+	if err := c.Recorder.ValidateAndAdjust(); err != nil {
+		return errors.Trace(err)
+	}
+
+	if c.SchemaSnapshotCache == nil {
+		c.SchemaSnapshotCache = defaultServerConfig.SchemaSnapshotCache
+	}
+	if err := c.SchemaSnapshotCache.ValidateAndAdjust(); err != nil {
+		return errors.Trace(err)
+	}
+
+	if c.DrainTimeout < 0 {
+		return cerror.ErrInvalidServerOption.GenWithStack("drain-timeout should not be negative")
+	}
+
+	for key, value := range c.Labels {
+		if !labelRegexp.MatchString(key) || !labelRegexp.MatchString(value) {
+			return cerror.ErrInvalidServerOption.GenWithStack("invalid label %s=%s, "+
+				"a label should consist of letters, digits, '-', '_' and '.', and start and end with a letter or a digit", key, value)
+		}
+	}
 
 	return nil
 }
@@ -14,7 +14,10 @@
 package config
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/pingcap/check"
 	"github.com/pingcap/ticdc/pkg/util/testleak"
@@ -35,9 +38,9 @@ func (s *replicaConfigSuite) TestMarshal(c *check.C) {
 	conf.Mounter.WorkerNum = 3
 	b, err := conf.Marshal()
 	c.Assert(err, check.IsNil)
-	c.Assert(b, check.Equals, `{"case-sensitive":false,"enable-old-value":true,"force-replicate":true,"check-gc-safe-point":true,"filter":{"rules":["1.1"],"ignore-txn-start-ts":null},"mounter":{"worker-num":3},"sink":{"dispatchers":null,"protocol":"default"},"cyclic-replication":{"enable":false,"replica-id":0,"filter-replica-ids":null,"id-buckets":0,"sync-ddl":false},"scheduler":{"type":"table-number","polling-time":-1}}`)
+	c.Assert(b, check.Equals, `{"case-sensitive":false,"enable-old-value":true,"force-replicate":true,"check-gc-safe-point":true,"filter":{"rules":["1.1"],"ignore-txn-start-ts":null},"mounter":{"worker-num":3},"sink":{"dispatchers":null,"protocol":"default"},"cyclic-replication":{"enable":false,"replica-id":0,"filter-replica-ids":null,"id-buckets":0,"sync-ddl":false},"scheduler":{"type":"table-number","polling-time":-1},"notification":{"webhook-urls":null,"checkpoint-lag-threshold":0},"resource":{"priority":"normal","memory-quota":0,"sink-worker-count":0,"region-scan-limit":0,"scan-bytes-per-second":0,"mounter-worker-num":0}}`)
 	conf2 := new(ReplicaConfig)
-	err = conf2.Unmarshal([]byte(`{"case-sensitive":false,"enable-old-value":true,"force-replicate":true,"check-gc-safe-point":true,"filter":{"rules":["1.1"],"ignore-txn-start-ts":null},"mounter":{"worker-num":3},"sink":{"dispatchers":null,"protocol":"default"},"cyclic-replication":{"enable":false,"replica-id":0,"filter-replica-ids":null,"id-buckets":0,"sync-ddl":false},"scheduler":{"type":"table-number","polling-time":-1}}`))
+	err = conf2.Unmarshal([]byte(`{"case-sensitive":false,"enable-old-value":true,"force-replicate":true,"check-gc-safe-point":true,"filter":{"rules":["1.1"],"ignore-txn-start-ts":null},"mounter":{"worker-num":3},"sink":{"dispatchers":null,"protocol":"default"},"cyclic-replication":{"enable":false,"replica-id":0,"filter-replica-ids":null,"id-buckets":0,"sync-ddl":false},"scheduler":{"type":"table-number","polling-time":-1},"notification":{"webhook-urls":null,"checkpoint-lag-threshold":0},"resource":{"priority":"normal","memory-quota":0,"sink-worker-count":0,"region-scan-limit":0}}`))
 	c.Assert(err, check.IsNil)
 	c.Assert(conf2, check.DeepEquals, conf)
 }
@@ -55,10 +58,72 @@ func (s *replicaConfigSuite) TestClone(c *check.C) {
 	c.Assert(conf.Mounter.WorkerNum, check.Equals, 3)
 }
 
+func (s *replicaConfigSuite) TestValidateScheduler(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := GetDefaultReplicaConfig()
+	c.Assert(conf.Scheduler.Validate(), check.IsNil)
+	conf.Scheduler.AffinityRules = []*AffinityRule{{Labels: map[string]string{"zone": "z1"}}}
+	c.Assert(conf.Scheduler.Validate(), check.ErrorMatches, ".*matcher of an affinity rule is empty.*")
+	conf.Scheduler.AffinityRules[0].Matcher = []string{"test.[a"}
+	c.Assert(conf.Scheduler.Validate(), check.ErrorMatches, ".*ErrInvalidSchedulerConfig.*syntax error.*")
+	conf.Scheduler.AffinityRules[0].Matcher = []string{"test.*"}
+	c.Assert(conf.Scheduler.Validate(), check.IsNil)
+	conf.Scheduler.AffinityRules[0].Labels = nil
+	c.Assert(conf.Scheduler.Validate(), check.ErrorMatches, ".*neither labels nor anti-labels.*")
+	conf.Scheduler.AffinityRules[0].AntiLabels = map[string]string{"zone": "z2"}
+	c.Assert(conf.Scheduler.Validate(), check.IsNil)
+	conf.Scheduler.SplitRules = []*SplitRule{{Spans: 4}}
+	c.Assert(conf.Scheduler.Validate(), check.ErrorMatches, ".*matcher of a split rule is empty.*")
+	conf.Scheduler.SplitRules[0].Matcher = []string{"test.hot"}
+	c.Assert(conf.Scheduler.Validate(), check.IsNil)
+	conf.Scheduler.SplitRules[0].Spans = 1
+	c.Assert(conf.Scheduler.Validate(), check.ErrorMatches, ".*specifies 1 spans.*")
+	conf.Scheduler.SplitRules[0].Spans = MaxTableSpans + 1
+	c.Assert(conf.Scheduler.Validate(), check.ErrorMatches, ".*specifies 1025 spans.*")
+}
+
+func (s *replicaConfigSuite) TestValidateResource(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := GetDefaultReplicaConfig()
+	c.Assert(conf.Resource.Validate(), check.IsNil)
+	c.Assert(conf.Resource.PriorityWeight(), check.Equals, 2)
+	conf.Resource.Priority = PriorityHigh
+	c.Assert(conf.Resource.Validate(), check.IsNil)
+	c.Assert(conf.Resource.PriorityWeight(), check.Equals, 4)
+	conf.Resource.Priority = "urgent"
+	c.Assert(conf.Resource.Validate(), check.ErrorMatches, ".*unknown priority \"urgent\".*")
+	c.Assert(conf.Resource.PriorityWeight(), check.Equals, 2)
+	conf.Resource.Priority = PriorityLow
+	conf.Resource.SinkWorkerCount = -1
+	c.Assert(conf.Resource.Validate(), check.ErrorMatches, ".*sink-worker-count should not be negative.*")
+	conf.Resource.SinkWorkerCount = 4
+	conf.Resource.RegionScanLimit = -1
+	c.Assert(conf.Resource.Validate(), check.ErrorMatches, ".*region-scan-limit should not be negative.*")
+	conf.Resource.RegionScanLimit = 0
+	conf.Resource.MounterWorkerNum = -1
+	c.Assert(conf.Resource.Validate(), check.ErrorMatches, ".*mounter-worker-num should not be negative.*")
+
+	// the mounter-worker-num in the resource config takes precedence over the worker-num of the mounter
+	conf.Resource.MounterWorkerNum = 0
+	c.Assert(conf.MounterWorkerNum(), check.Equals, conf.Mounter.WorkerNum)
+	conf.Resource.MounterWorkerNum = 4
+	c.Assert(conf.MounterWorkerNum(), check.Equals, 4)
+}
+
+func (s *replicaConfigSuite) TestValidateSink(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := GetDefaultReplicaConfig()
+	c.Assert(conf.Sink.Validate(), check.IsNil)
+	conf.Sink.PartialUpdateTables = []string{"test.[a"}
+	c.Assert(conf.Sink.Validate(), check.ErrorMatches, ".*ErrSinkInvalidConfig.*syntax error.*")
+	conf.Sink.PartialUpdateTables = []string{"test.*", "!test.t1"}
+	c.Assert(conf.Sink.Validate(), check.IsNil)
+}
+
 func (s *replicaConfigSuite) TestOutDated(c *check.C) {
 	defer testleak.AfterTest(c)()
 	conf2 := new(ReplicaConfig)
-	err := conf2.Unmarshal([]byte(`{"case-sensitive":false,"enable-old-value":true,"force-replicate":true,"check-gc-safe-point":true,"filter":{"rules":["1.1"],"ignore-txn-start-ts":null,"ddl-allow-list":null},"mounter":{"worker-num":3},"sink":{"dispatch-rules":[{"db-name":"a","tbl-name":"b","rule":"r1"},{"db-name":"a","tbl-name":"c","rule":"r2"},{"db-name":"a","tbl-name":"d","rule":"r2"}],"protocol":"default"},"cyclic-replication":{"enable":false,"replica-id":0,"filter-replica-ids":null,"id-buckets":0,"sync-ddl":false},"scheduler":{"type":"table-number","polling-time":-1}}`))
+	err := conf2.Unmarshal([]byte(`{"case-sensitive":false,"enable-old-value":true,"force-replicate":true,"check-gc-safe-point":true,"filter":{"rules":["1.1"],"ignore-txn-start-ts":null,"ddl-allow-list":null},"mounter":{"worker-num":3},"sink":{"dispatch-rules":[{"db-name":"a","tbl-name":"b","rule":"r1"},{"db-name":"a","tbl-name":"c","rule":"r2"},{"db-name":"a","tbl-name":"d","rule":"r2"}],"protocol":"default"},"cyclic-replication":{"enable":false,"replica-id":0,"filter-replica-ids":null,"id-buckets":0,"sync-ddl":false},"scheduler":{"type":"table-number","polling-time":-1},"notification":{"webhook-urls":null,"checkpoint-lag-threshold":0},"resource":{"priority":"normal","memory-quota":0,"sink-worker-count":0,"region-scan-limit":0}}`))
 	c.Assert(err, check.IsNil)
 
 	conf := GetDefaultReplicaConfig()
@@ -80,7 +145,7 @@ var _ = check.Suite(&serverConfigSuite{})
 
 func (s *serverConfigSuite) TestMarshal(c *check.C) {
 	defer testleak.AfterTest(c)()
-	rawConfig := `{"addr":"192.155.22.33:8887","advertise-addr":"","log-file":"","log-level":"info","log":{"file":{"max-size":300,"max-days":0,"max-backups":0}},"data-dir":"","gc-ttl":86400,"tz":"System","capture-session-ttl":10,"owner-flush-interval":200000000,"processor-flush-interval":100000000,"sorter":{"num-concurrent-worker":4,"chunk-size-limit":999,"max-memory-percentage":30,"max-memory-consumption":17179869184,"num-workerpool-goroutine":16,"sort-dir":"/tmp/sorter"},"security":{"ca-path":"","cert-path":"","key-path":"","cert-allowed-cn":null},"per-table-memory-quota":20971520,"kv-client":{"worker-concurrent":8,"worker-pool-size":0,"region-scan-limit":40}}`
+	rawConfig := `{"addr":"192.155.22.33:8887","advertise-addr":"","log-file":"","log-level":"info","log":{"file":{"max-size":300,"max-days":0,"max-backups":0}},"data-dir":"","gc-ttl":86400,"tz":"System","capture-session-ttl":10,"owner-flush-interval":200000000,"processor-flush-interval":100000000,"sorter":{"num-concurrent-worker":4,"chunk-size-limit":999,"max-memory-percentage":30,"max-memory-consumption":17179869184,"num-workerpool-goroutine":16,"sort-dir":"/tmp/sorter","max-disk-consumption":0,"max-disk-percentage":0,"leveldb-block-cache-size":67108864,"leveldb-max-disk-consumption":0,"compression":"none","encryption-key":"","encryption-key-path":""},"security":{"ca-path":"","cert-path":"","key-path":"","cert-allowed-cn":null},"per-table-memory-quota":20971520,"memory-quota":0,"kv-client":{"worker-concurrent":8,"worker-pool-size":0,"region-scan-limit":40,"store-scan-concurrency":0,"store-scan-bytes-per-second":0,"resolve-lock-interval":20000000000,"region-reconnect-interval":1800000000000},"shared-puller":false,"auth":{"enable":false,"admin-cert-cn":null,"read-only-cert-cn":null,"tokens":null,"users":null},"notification":{"webhook-urls":null,"checkpoint-lag-threshold":0},"labels":null,"drain-timeout":300000000000,"recorder":{"dir":"","tables":null,"max-file-size":1073741824},"schema-snapshot-cache":{"enable":true,"max-files":4,"save-interval":3600000000000}}`
 
 	conf := GetDefaultServerConfig()
 	conf.Addr = "192.155.22.33:8887"
@@ -129,4 +194,134 @@ func (s *serverConfigSuite) TestValidateAndAdjust(c *check.C) {
 	conf.AdvertiseAddr = "advertise:1234"
 	conf.PerTableMemoryQuota = 1
 	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*should be at least.*")
+	conf.PerTableMemoryQuota = 20 * 1024 * 1024
+	conf.MemoryQuota = 1024 * 1024
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*memory-quota should be at least per-table-memory-quota.*")
+	conf.MemoryQuota = 1024 * 1024 * 1024
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+}
+
+func (s *serverConfigSuite) TestValidateAndAdjustAuth(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := GetDefaultServerConfig()
+	conf.Auth.Enable = true
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*no credential is configured.*")
+	conf.Auth.AdminCertCN = []string{"admin"}
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*requires TLS.*")
+	conf.Auth.AdminCertCN = nil
+	conf.Auth.Tokens = []*AuthToken{{Token: "secret-token", Role: "root"}}
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*invalid auth role: root.*")
+	conf.Auth.Tokens[0].Role = AuthRoleAdmin
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	conf.Auth.Users = []*AuthUser{{Name: "user", Password: "plain", Role: AuthRoleReadOnly}}
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*unsupported password hash.*")
+	conf.Auth.Users[0].Password = "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+
+	// secrets are redacted when the config is printed
+	str := conf.String()
+	c.Assert(str, check.Not(check.Matches), ".*secret-token.*")
+	c.Assert(str, check.Not(check.Matches), ".*W6ph5Mm5Pz8GgiULbPgzG37mj9g=.*")
+	c.Assert(conf.Auth.Tokens[0].Token, check.Equals, "secret-token")
+}
+
+func (s *serverConfigSuite) TestValidateAndAdjustNotification(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := GetDefaultServerConfig()
+	conf.Notification.WebhookURLs = []string{"ftp://127.0.0.1/hook"}
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*unsupported webhook url.*")
+	conf.Notification.WebhookURLs = []string{"https://127.0.0.1/hook"}
+	conf.Notification.CheckpointLagThreshold = -1
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*should not be negative.*")
+	conf.Notification.CheckpointLagThreshold = TomlDuration(time.Minute)
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	conf.Notification = nil
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	c.Assert(conf.Notification, check.NotNil)
+}
+
+func (s *serverConfigSuite) TestValidateAndAdjustLabels(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := GetDefaultServerConfig()
+	conf.Labels = map[string]string{"zone": "us-west-1a", "host": "h1.example"}
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	conf.Labels = map[string]string{"zone": ""}
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*invalid label zone=.*")
+	conf.Labels = map[string]string{"-zone": "z1"}
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*invalid label -zone=z1.*")
+}
+
+func (s *serverConfigSuite) TestValidateAndAdjustKVClient(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := GetDefaultServerConfig()
+	conf.KVClient.ResolveLockInterval = 0
+	conf.KVClient.RegionReconnectInterval = 0
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	c.Assert(conf.KVClient.ResolveLockInterval, check.Equals, TomlDuration(20*time.Second))
+	c.Assert(conf.KVClient.RegionReconnectInterval, check.Equals, TomlDuration(30*time.Minute))
+	conf.KVClient.RegionReconnectInterval = TomlDuration(10 * time.Second)
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*region-reconnect-interval should be larger than resolve-lock-interval.*")
+	conf.KVClient.RegionReconnectInterval = TomlDuration(30 * time.Minute)
+	conf.KVClient.StoreScanConcurrency = -1
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*store-scan-concurrency should not be negative.*")
+}
+
+func (s *serverConfigSuite) TestValidateAndAdjustRecorder(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := GetDefaultServerConfig()
+	conf.Recorder.Tables = []string{"test.*"}
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	c.Assert(conf.Recorder.IsEnabled(), check.IsFalse)
+	conf.Recorder.Dir = "/tmp/recorder"
+	conf.Recorder.MaxFileSize = 0
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	c.Assert(conf.Recorder.IsEnabled(), check.IsTrue)
+	c.Assert(conf.Recorder.MaxFileSize, check.Equals, uint64(1024*1024*1024))
+	conf.Recorder.Tables = []string{"test.["}
+	c.Assert(conf.ValidateAndAdjust(), check.NotNil)
+	conf.Recorder.Tables = nil
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*recorder.tables should be set.*")
+}
+
+func (s *serverConfigSuite) TestValidateAndAdjustSchemaSnapshotCache(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := GetDefaultServerConfig()
+	conf.SchemaSnapshotCache = nil
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	c.Assert(conf.SchemaSnapshotCache.Enable, check.IsTrue)
+	conf = GetDefaultServerConfig()
+	conf.SchemaSnapshotCache.MaxFiles = 0
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	c.Assert(conf.SchemaSnapshotCache.MaxFiles, check.Equals, 4)
+	conf.SchemaSnapshotCache.MaxFiles = -1
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*max-files should not be negative.*")
+}
+
+func (s *serverConfigSuite) TestValidateAndAdjustSorterCodec(c *check.C) {
+	defer testleak.AfterTest(c)()
+	conf := GetDefaultServerConfig()
+	conf.Sorter.Compression = ""
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	c.Assert(conf.Sorter.Compression, check.Equals, SorterCompressionNone)
+	conf.Sorter.Compression = "lz4"
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*compression should be one of.*")
+	conf.Sorter.Compression = SorterCompressionZstd
+
+	key := "000102030405060708090a0b0c0d0e0f"
+	conf.Sorter.EncryptionKey = "not-hex"
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*should be hex encoded.*")
+	conf.Sorter.EncryptionKey = "0011"
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*should be 16, 24 or 32 bytes.*")
+	conf.Sorter.EncryptionKey = key
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+
+	keyPath := filepath.Join(c.MkDir(), "sorter.key")
+	c.Assert(ioutil.WriteFile(keyPath, []byte(key+"\n"), 0o600), check.IsNil)
+	conf.Sorter.EncryptionKeyPath = keyPath
+	c.Assert(conf.ValidateAndAdjust(), check.ErrorMatches, ".*can't be set at the same time.*")
+	conf.Sorter.EncryptionKey = ""
+	c.Assert(conf.ValidateAndAdjust(), check.IsNil)
+	keyBytes, err := conf.Sorter.EncryptionKeyBytes()
+	c.Assert(err, check.IsNil)
+	c.Assert(keyBytes, check.HasLen, 16)
 }
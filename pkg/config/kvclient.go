@@ -21,4 +21,12 @@ type KVClientConfig struct {
 	WorkerPoolSize int `toml:"worker-pool-size" json:"worker-pool-size"`
 	// region incremental scan limit for one table in a single store
 	RegionScanLimit int `toml:"region-scan-limit" json:"region-scan-limit"`
+	// region incremental scan limit for all the tables in a single store, 0 means unlimited
+	StoreScanConcurrency int `toml:"store-scan-concurrency" json:"store-scan-concurrency"`
+	// the maximum bytes scanned per second for all the tables in a single store, 0 means unlimited
+	StoreScanBytesPerSecond uint64 `toml:"store-scan-bytes-per-second" json:"store-scan-bytes-per-second"`
+	// the locks in a region are resolved if its resolved ts doesn't advance for the interval
+	ResolveLockInterval TomlDuration `toml:"resolve-lock-interval" json:"resolve-lock-interval"`
+	// the region is subscribed again if its resolved ts still doesn't advance for the interval
+	RegionReconnectInterval TomlDuration `toml:"region-reconnect-interval" json:"region-reconnect-interval"`
 }
@@ -0,0 +1,45 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"net/url"
+
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// NotificationConfig represents the targets and conditions of the changefeed notifications
+type NotificationConfig struct {
+	// WebhookURLs are the HTTP endpoints which receive the notification events by POST
+	WebhookURLs []string `toml:"webhook-urls" json:"webhook-urls"`
+	// CheckpointLagThreshold is the checkpoint lag which triggers a notification, 0 means disabled
+	CheckpointLagThreshold TomlDuration `toml:"checkpoint-lag-threshold" json:"checkpoint-lag-threshold"`
+}
+
+// Validate validates the notification config
+func (c *NotificationConfig) Validate() error {
+	for _, rawURL := range c.WebhookURLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return cerror.WrapError(cerror.ErrInvalidNotificationConfig, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return cerror.ErrInvalidNotificationConfig.GenWithStack("unsupported webhook url: %s", rawURL)
+		}
+	}
+	if c.CheckpointLagThreshold < 0 {
+		return cerror.ErrInvalidNotificationConfig.GenWithStack("checkpoint-lag-threshold should not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filterV2 "github.com/pingcap/tidb-tools/pkg/table-filter"
+)
+
+// RecorderConfig represents the config of recording the events pulled from TiKV, the record files are used to
+// reproduce the bugs offline by `cdc debug replay`
+type RecorderConfig struct {
+	// Dir is the directory of the record files, the events are not recorded if it's empty
+	Dir string `toml:"dir" json:"dir"`
+	// Tables are the filter rules of the recorded tables, such as "db.tbl" and "db.*"
+	Tables []string `toml:"tables" json:"tables"`
+	// MaxFileSize is the maximum size of a record file, the recording of a table stops when its file is full
+	MaxFileSize uint64 `toml:"max-file-size" json:"max-file-size"`
+}
+
+// IsEnabled returns whether the events are recorded
+func (c *RecorderConfig) IsEnabled() bool {
+	return c != nil && c.Dir != ""
+}
+
+// ValidateAndAdjust validates and adjusts the recorder config
+func (c *RecorderConfig) ValidateAndAdjust() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	if len(c.Tables) == 0 {
+		return cerror.ErrInvalidServerOption.GenWithStack("recorder.tables should be set if recorder.dir is set")
+	}
+	if _, err := filterV2.Parse(c.Tables); err != nil {
+		return cerror.WrapError(cerror.ErrInvalidServerOption, err)
+	}
+	if c.MaxFileSize == 0 {
+		c.MaxFileSize = defaultServerConfig.Recorder.MaxFileSize
+	}
+	return nil
+}
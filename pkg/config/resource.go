@@ -0,0 +1,82 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// The priority classes of the changefeeds
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+var priorityWeights = map[string]int{
+	PriorityLow:    1,
+	PriorityNormal: 2,
+	PriorityHigh:   4,
+}
+
+// ResourceConfig represents the resource limits and the priority of a changefeed in a capture
+type ResourceConfig struct {
+	// Priority is the priority class of the changefeed, the changefeeds with lower priorities yield the memory
+	// and the incremental scans in a TiKV store to the higher priority ones under contention
+	Priority string `toml:"priority" json:"priority"`
+	// MemoryQuota is the memory budget of the sink buffers of the changefeed, 0 means unlimited
+	MemoryQuota uint64 `toml:"memory-quota" json:"memory-quota"`
+	// SinkWorkerCount is the number of the workers writing to the downstream,
+	// 0 means the worker-count in the sink uri is used
+	SinkWorkerCount int `toml:"sink-worker-count" json:"sink-worker-count"`
+	// RegionScanLimit is the maximum number of the concurrent incremental scans of a table in a TiKV store,
+	// 0 means the region-scan-limit of the kv client is used
+	RegionScanLimit int `toml:"region-scan-limit" json:"region-scan-limit"`
+	// ScanBytesPerSecond is the maximum bytes of the incremental scans of the changefeed per second in a capture,
+	// 0 means unlimited
+	ScanBytesPerSecond uint64 `toml:"scan-bytes-per-second" json:"scan-bytes-per-second"`
+	// MounterWorkerNum is the number of the workers decoding the events of the changefeed,
+	// 0 means the worker-num of the mounter is used
+	MounterWorkerNum int `toml:"mounter-worker-num" json:"mounter-worker-num"`
+}
+
+// Validate validates the resource config
+func (c *ResourceConfig) Validate() error {
+	if _, ok := priorityWeights[c.Priority]; !ok {
+		return cerror.ErrInvalidResourceConfig.GenWithStack(
+			"unknown priority %q, it should be one of %q, %q and %q", c.Priority, PriorityLow, PriorityNormal, PriorityHigh)
+	}
+	if c.SinkWorkerCount < 0 {
+		return cerror.ErrInvalidResourceConfig.GenWithStack("sink-worker-count should not be negative")
+	}
+	if c.RegionScanLimit < 0 {
+		return cerror.ErrInvalidResourceConfig.GenWithStack("region-scan-limit should not be negative")
+	}
+	if c.MounterWorkerNum < 0 {
+		return cerror.ErrInvalidResourceConfig.GenWithStack("mounter-worker-num should not be negative")
+	}
+	return nil
+}
+
+// PriorityWeight returns the weight of the priority class, by which the memory is shared between the changefeeds,
+// and the incremental scans of the changefeeds with higher weights are admitted first
+func (c *ResourceConfig) PriorityWeight() int {
+	if c == nil {
+		return priorityWeights[PriorityNormal]
+	}
+	if weight, ok := priorityWeights[c.Priority]; ok {
+		return weight
+	}
+	return priorityWeights[PriorityNormal]
+}
@@ -13,9 +13,74 @@
 
 package config
 
+import (
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+)
+
 // SchedulerConfig represents scheduler config for a changefeed
 type SchedulerConfig struct {
 	Tp string `toml:"type" json:"type"`
 	// PollingTime represents the polling cycle of checking the skewness of workload and try to do schedule if needed
 	PollingTime int `toml:"polling-time" json:"polling-time"`
+	// AffinityRules pin tables to the captures with specified labels, the first rule matching a table is applied
+	AffinityRules []*AffinityRule `toml:"affinity-rules" json:"affinity-rules,omitempty"`
+	// SpreadLabel is the key of the capture label by which the tables are spread evenly,
+	// e.g. "zone" spreads the tables across the zones before balancing the captures in a zone.
+	SpreadLabel string `toml:"spread-label" json:"spread-label,omitempty"`
+	// SplitRules split the hot tables into key range spans, which are scheduled independently,
+	// it requires the old value to be enabled
+	SplitRules []*SplitRule `toml:"split-rules" json:"split-rules,omitempty"`
+}
+
+// MaxTableSpans is the maximum number of spans a table can be split into
+const MaxTableSpans = 1024
+
+// SplitRule represents the number of spans a set of tables are split into. The tables are split
+// by the region distribution, so a table may be split into fewer spans if it has fewer regions.
+type SplitRule struct {
+	Matcher []string `toml:"matcher" json:"matcher"`
+	Spans   int      `toml:"spans" json:"spans"`
+}
+
+// AffinityRule represents the captures which a set of tables can be dispatched to
+type AffinityRule struct {
+	Matcher []string `toml:"matcher" json:"matcher"`
+	// Labels are the labels which a capture must have to replicate the matched tables
+	Labels map[string]string `toml:"labels" json:"labels,omitempty"`
+	// AntiLabels are the labels which a capture must not have to replicate the matched tables
+	AntiLabels map[string]string `toml:"anti-labels" json:"anti-labels,omitempty"`
+	// Required decides what happens if no matching capture is alive. If it's true, the tables are not
+	// replicated until a matching capture is alive, which blocks the changefeed. Otherwise the tables
+	// are dispatched to the other captures and moved back once a matching capture is alive.
+	Required bool `toml:"required" json:"required,omitempty"`
+}
+
+// Validate validates the scheduler config
+func (c *SchedulerConfig) Validate() error {
+	for _, rule := range c.AffinityRules {
+		if len(rule.Matcher) == 0 {
+			return cerror.ErrInvalidSchedulerConfig.GenWithStack("the matcher of an affinity rule is empty")
+		}
+		if _, err := filter.Parse(rule.Matcher); err != nil {
+			return cerror.WrapError(cerror.ErrInvalidSchedulerConfig, err)
+		}
+		if len(rule.Labels) == 0 && len(rule.AntiLabels) == 0 {
+			return cerror.ErrInvalidSchedulerConfig.GenWithStack(
+				"the affinity rule of %v specifies neither labels nor anti-labels", rule.Matcher)
+		}
+	}
+	for _, rule := range c.SplitRules {
+		if len(rule.Matcher) == 0 {
+			return cerror.ErrInvalidSchedulerConfig.GenWithStack("the matcher of a split rule is empty")
+		}
+		if _, err := filter.Parse(rule.Matcher); err != nil {
+			return cerror.WrapError(cerror.ErrInvalidSchedulerConfig, err)
+		}
+		if rule.Spans < 2 || rule.Spans > MaxTableSpans {
+			return cerror.ErrInvalidSchedulerConfig.GenWithStack(
+				"the split rule of %v specifies %d spans, which should be in [2, %d]", rule.Matcher, rule.Spans, MaxTableSpans)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,42 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// SchemaSnapshotCacheConfig represents the config of caching the schema snapshots in the data dir,
+// the owner and the processors load the newest cached snapshot instead of listing all the tables when they start
+type SchemaSnapshotCacheConfig struct {
+	Enable bool `toml:"enable" json:"enable"`
+	// MaxFiles is the maximum number of the cached snapshots, the older snapshots are removed
+	MaxFiles int `toml:"max-files" json:"max-files"`
+	// SaveInterval is the minimum interval between the ts of two cached snapshots
+	SaveInterval TomlDuration `toml:"save-interval" json:"save-interval"`
+}
+
+// ValidateAndAdjust validates and adjusts the schema snapshot cache config
+func (c *SchemaSnapshotCacheConfig) ValidateAndAdjust() error {
+	if c.MaxFiles < 0 {
+		return cerror.ErrInvalidServerOption.GenWithStack("schema-snapshot-cache.max-files should not be negative")
+	}
+	if c.MaxFiles == 0 {
+		c.MaxFiles = defaultServerConfig.SchemaSnapshotCache.MaxFiles
+	}
+	if c.SaveInterval < 0 {
+		return cerror.ErrInvalidServerOption.GenWithStack("schema-snapshot-cache.save-interval should not be negative")
+	}
+	return nil
+}
@@ -13,10 +13,18 @@
 
 package config
 
+import (
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+)
+
 // SinkConfig represents sink config for a changefeed
 type SinkConfig struct {
 	DispatchRules []*DispatchRule `toml:"dispatchers" json:"dispatchers"`
 	Protocol      string          `toml:"protocol" json:"protocol"`
+	// PartialUpdateTables matches the tables whose update events only carry the handle key columns
+	// and the changed columns, e.g. ["*.*"] applies to all the tables. It requires the old value.
+	PartialUpdateTables []string `toml:"partial-update-tables" json:"partial-update-tables,omitempty"`
 }
 
 // DispatchRule represents partition rule for a table
@@ -24,3 +32,13 @@ type DispatchRule struct {
 	Matcher    []string `toml:"matcher" json:"matcher"`
 	Dispatcher string   `toml:"dispatcher" json:"dispatcher"`
 }
+
+// Validate validates the sink config
+func (c *SinkConfig) Validate() error {
+	if len(c.PartialUpdateTables) != 0 {
+		if _, err := filter.Parse(c.PartialUpdateTables); err != nil {
+			return cerror.WrapError(cerror.ErrSinkInvalidConfig, err)
+		}
+	}
+	return nil
+}
@@ -13,6 +13,21 @@
 
 package config
 
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// compression algorithms of the sorter files
+const (
+	SorterCompressionNone   = "none"
+	SorterCompressionSnappy = "snappy"
+	SorterCompressionZstd   = "zstd"
+)
+
 // SorterConfig represents sorter config for a changefeed
 type SorterConfig struct {
 	// number of concurrent heap sorts
@@ -27,4 +42,50 @@ type SorterConfig struct {
 	NumWorkerPoolGoroutine int `toml:"num-workerpool-goroutine" json:"num-workerpool-goroutine"`
 	// the directory used to store the temporary files generated by the sorter
 	SortDir string `toml:"sort-dir" json:"sort-dir"`
+	// the maximum disk consumption of the files written by the unified sorter, 0 means unlimited
+	MaxDiskConsumption uint64 `toml:"max-disk-consumption" json:"max-disk-consumption"`
+	// the maximum used percentage of the filesystem of the sort-dir that allows writing the files, 0 means unlimited
+	MaxDiskPercentage int `toml:"max-disk-percentage" json:"max-disk-percentage"`
+	// the size of the block cache of the leveldb sort engine
+	LevelDBBlockCacheSize uint64 `toml:"leveldb-block-cache-size" json:"leveldb-block-cache-size"`
+	// the maximum disk consumption of the leveldb sort engine, 0 means unlimited
+	LevelDBMaxDiskConsumption uint64 `toml:"leveldb-max-disk-consumption" json:"leveldb-max-disk-consumption"`
+	// the compression algorithm of the files written by the unified sorter, "none", "snappy" or "zstd"
+	Compression string `toml:"compression" json:"compression"`
+	// the hex encoded AES key used to encrypt the files written by the unified sorter
+	EncryptionKey string `toml:"encryption-key" json:"encryption-key"`
+	// the path of the file which contains the hex encoded AES key, it can't be set with encryption-key
+	EncryptionKeyPath string `toml:"encryption-key-path" json:"encryption-key-path"`
+}
+
+// EncryptionKeyBytes returns the AES key used to encrypt the files written by the unified sorter,
+// nil is returned if the encryption is disabled.
+func (c *SorterConfig) EncryptionKeyBytes() ([]byte, error) {
+	hexKey := c.EncryptionKey
+	if c.EncryptionKeyPath != "" {
+		if hexKey != "" {
+			return nil, cerror.ErrIllegalUnifiedSorterParameter.GenWithStackByArgs(
+				"encryption-key and encryption-key-path can't be set at the same time")
+		}
+		content, err := ioutil.ReadFile(c.EncryptionKeyPath)
+		if err != nil {
+			return nil, cerror.ErrIllegalUnifiedSorterParameter.GenWithStackByArgs(
+				"failed to read encryption-key-path: " + err.Error())
+		}
+		hexKey = strings.TrimSpace(string(content))
+	}
+	if hexKey == "" {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, cerror.ErrIllegalUnifiedSorterParameter.GenWithStackByArgs("encryption key should be hex encoded")
+	}
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, cerror.ErrIllegalUnifiedSorterParameter.GenWithStackByArgs(
+			"encryption key should be 16, 24 or 32 bytes for AES-128, AES-192 or AES-256")
+	}
+	return key, nil
 }
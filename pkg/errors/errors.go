@@ -147,6 +147,7 @@ var (
 	ErrSnapshotTableNotFound   = errors.Normalize("table %d not found in schema snapshot", errors.RFCCodeText("CDC:ErrSnapshotTableNotFound"))
 	ErrSnapshotSchemaExists    = errors.Normalize("schema %s(%d) already exists", errors.RFCCodeText("CDC:ErrSnapshotSchemaExists"))
 	ErrSnapshotTableExists     = errors.Normalize("table %s.%s already exists", errors.RFCCodeText("CDC:ErrSnapshotTableExists"))
+	ErrInvalidSnapshotFile     = errors.Normalize("invalid schema snapshot file %s", errors.RFCCodeText("CDC:ErrInvalidSnapshotFile"))
 
 	// puller related errors
 	ErrBufferReachLimit      = errors.Normalize("puller mem buffer reach size limit", errors.RFCCodeText("CDC:ErrBufferReachLimit"))
@@ -179,6 +180,8 @@ var (
 	ErrSupportGetOnly               = errors.Normalize("this api supports GET method only", errors.RFCCodeText("CDC:ErrSupportGetOnly"))
 	ErrAPIInvalidParam              = errors.Normalize("invalid api parameter", errors.RFCCodeText("CDC:ErrAPIInvalidParam"))
 	ErrInternalServerError          = errors.Normalize("internal server error", errors.RFCCodeText("CDC:ErrInternalServerError"))
+	ErrAPIUnauthorized              = errors.Normalize("unauthorized, please provide a valid credential", errors.RFCCodeText("CDC:ErrAPIUnauthorized"))
+	ErrAPIForbidden                 = errors.Normalize("role %s is not allowed to access %s", errors.RFCCodeText("CDC:ErrAPIForbidden"))
 	ErrOwnerSortDir                 = errors.Normalize("owner sort dir", errors.RFCCodeText("CDC:ErrOwnerSortDir"))
 	ErrOwnerChangefeedNotFound      = errors.Normalize("changefeed %s not found in owner cache", errors.RFCCodeText("CDC:ErrOwnerChangefeedNotFound"))
 	ErrChangefeedAbnormalState      = errors.Normalize("changefeed in abnormal state: %s, replication status: %+v", errors.RFCCodeText("CDC:ErrChangefeedAbnormalState"))
@@ -217,10 +220,14 @@ var (
 	ErrUnifiedSorterBackendTerminating = errors.Normalize("unified sorter backend is terminating", errors.RFCCodeText("CDC:ErrUnifiedSorterBackendTerminating"))
 	ErrIllegalUnifiedSorterParameter   = errors.Normalize("illegal parameter for unified sorter: %s", errors.RFCCodeText("CDC:ErrIllegalUnifiedSorterParameter"))
 	ErrAsyncIOCancelled                = errors.Normalize("asynchronous IO operation is cancelled. Internal use only, report a bug if seen in log", errors.RFCCodeText("CDC:ErrAsyncIOCancelled"))
+	ErrUnifiedSorterDiskQuotaExceeded  = errors.Normalize("the disk quota of unified sorter is exceeded, %s", errors.RFCCodeText("CDC:ErrUnifiedSorterDiskQuotaExceeded"))
 	ErrUnifiedSorterIOError            = errors.Normalize("unified sorter IO error. Make sure your sort-dir is configured correctly by passing a valid argument or toml file to `cdc server`, or if you use TiUP, review the settings in `tiup cluster edit-config`. Details: %s", errors.RFCCodeText("CDC:ErrUnifiedSorterIOError"))
 	ErrConflictingFileLocks            = errors.Normalize("file lock conflict: %s", errors.RFCCodeText("ErrConflictingFileLocks"))
 	ErrSortDirLockError                = errors.Normalize("error encountered when locking sort-dir", errors.RFCCodeText("ErrSortDirLockError"))
 
+	// leveldb sorter errors
+	ErrLevelDBSorterError = errors.Normalize("leveldb sorter error", errors.RFCCodeText("CDC:ErrLevelDBSorterError"))
+
 	// processor errors
 	ErrTableProcessorStoppedSafely = errors.Normalize("table processor stopped safely", errors.RFCCodeText("CDC:ErrTableProcessorStoppedSafely"))
 
@@ -235,4 +242,33 @@ var (
 
 	// retry error
 	ErrReachMaxTry = errors.Normalize("reach maximum try: %d", errors.RFCCodeText("CDC:ErrReachMaxTry"))
+
+	// notification related errors
+	ErrInvalidNotificationConfig = errors.Normalize("invalid notification config", errors.RFCCodeText("CDC:ErrInvalidNotificationConfig"))
+	ErrWebhookRequestFailed      = errors.Normalize("webhook %s responded with status code %d", errors.RFCCodeText("CDC:ErrWebhookRequestFailed"))
+
+	// changefeed validation related errors
+	ErrMySQLMissingPrivileges    = errors.Normalize("the downstream user lacks the privileges: %s", errors.RFCCodeText("CDC:ErrMySQLMissingPrivileges"))
+	ErrTargetTsBeforeStartTs     = errors.Normalize("target-ts %d must be larger than start-ts %d", errors.RFCCodeText("CDC:ErrTargetTsBeforeStartTs"))
+	ErrCyclicMarkTablesNotPaired = errors.Normalize("normal tables and mark tables are not paired, please run `cdc cli changefeed cyclic create-marktables`", errors.RFCCodeText("CDC:ErrCyclicMarkTablesNotPaired"))
+
+	// changefeed bundle related errors
+	ErrInvalidChangefeedBundle = errors.Normalize("invalid changefeed bundle", errors.RFCCodeText("CDC:ErrInvalidChangefeedBundle"))
+
+	// scheduler related errors
+	ErrInvalidSchedulerConfig = errors.Normalize("invalid scheduler config", errors.RFCCodeText("CDC:ErrInvalidSchedulerConfig"))
+
+	// changefeed resource related errors
+	ErrInvalidResourceConfig = errors.Normalize("invalid resource config", errors.RFCCodeText("CDC:ErrInvalidResourceConfig"))
+
+	// capture drain related errors
+	ErrCaptureNotRunning        = errors.Normalize("the capture is not running", errors.RFCCodeText("CDC:ErrCaptureNotRunning"))
+	ErrDrainCaptureNotSupported = errors.Normalize("draining a capture is only supported by the new replication implementation", errors.RFCCodeText("CDC:ErrDrainCaptureNotSupported"))
+	ErrNoSchedulableCapture     = errors.Normalize("no other schedulable capture is alive, the tables can't be moved away", errors.RFCCodeText("CDC:ErrNoSchedulableCapture"))
+	ErrDrainCaptureTimeout      = errors.Normalize("draining capture %s timed out, %d tables remain", errors.RFCCodeText("CDC:ErrDrainCaptureTimeout"))
+
+	// recorder related errors
+	ErrInvalidRecordFile   = errors.Normalize("invalid record file %s", errors.RFCCodeText("CDC:ErrInvalidRecordFile"))
+	ErrRecordFileTooLarge  = errors.Normalize("record file %s exceeds the max size %d", errors.RFCCodeText("CDC:ErrRecordFileTooLarge"))
+	ErrRecordSchemaChanged = errors.Normalize("the schema of the table recorded in %s changed at %d, the events after it are not recorded", errors.RFCCodeText("CDC:ErrRecordSchemaChanged"))
 )
@@ -45,6 +45,52 @@ func NewClient(credential *security.Credential) (*Client, error) {
 	}, nil
 }
 
+// authTransport sets the Authorization header of every request.
+type authTransport struct {
+	http.RoundTripper
+	setAuth func(req *http.Request)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrip should not modify the request, so we set the header on a clone.
+	req = req.Clone(req.Context())
+	t.setAuth(req)
+	return t.RoundTripper.RoundTrip(req)
+}
+
+// CloseIdleConnections implements the interface used by http.Client.CloseIdleConnections
+func (t *authTransport) CloseIdleConnections() {
+	type closeIdler interface {
+		CloseIdleConnections()
+	}
+	if ci, ok := t.RoundTripper.(closeIdler); ok {
+		ci.CloseIdleConnections()
+	}
+}
+
+// SetBearerToken makes the client authenticate every request by the bearer token.
+func (c *Client) SetBearerToken(token string) {
+	c.setAuth(func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	})
+}
+
+// SetBasicAuth makes the client authenticate every request by the user name and password.
+func (c *Client) SetBasicAuth(user, password string) {
+	c.setAuth(func(req *http.Request) {
+		req.SetBasicAuth(user, password)
+	})
+}
+
+func (c *Client) setAuth(setAuth func(req *http.Request)) {
+	transport := c.Transport
+	if t, ok := transport.(*authTransport); ok {
+		transport = t.RoundTripper
+	}
+	c.Transport = &authTransport{RoundTripper: transport, setAuth: setAuth}
+}
+
 // IsFiltered return true if the given feedState matches the whiteList.
 func IsFiltered(whiteList string, feedState model.FeedState) bool {
 	if whiteList == "all" {
@@ -0,0 +1,468 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memquota
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"go.uber.org/zap"
+)
+
+// The components whose memory consumption is tracked by the Arbitrator
+const (
+	// ComponentPuller is the events pulled from TiKV and not received by the sorter yet
+	ComponentPuller = "puller"
+	// ComponentSorter is the events sorted in memory by the sorter
+	ComponentSorter = "sorter"
+	// ComponentSharedPuller is the events buffered by the shared pullers for the subscribers
+	ComponentSharedPuller = "shared-puller"
+	// ComponentSink is the events in the mounter and the sink buffers, which are consumed by the table quotas
+	ComponentSink = "sink"
+)
+
+const (
+	// DefaultPriority is the priority of the changefeeds without any specified priority
+	DefaultPriority = 1
+
+	rebalanceInterval = 100 * time.Millisecond
+	// minTableDemand is the minimum memory demand of a table, it makes the idle tables
+	// be able to replicate small transactions without waiting for a rebalance
+	minTableDemand = 1024 * 1024 // 1MB
+	// minSinkPoolRatio is the minimum ratio of the memory limit shared by the table quotas,
+	// which keeps the sink making progress when the puller and the sorter consume most of the memory
+	minSinkPoolRatio = 4
+	// yieldPressureRatio is the percentage of the memory limit, beyond which the pullers of the changefeeds
+	// with lower priorities stop receiving the events, to yield the memory to the higher priority ones
+	yieldPressureRatio = 75
+)
+
+// Consumer consumes the memory within the quota allocated by the Arbitrator
+type Consumer interface {
+	// GetConsumption returns the memory consumed by the consumer
+	GetConsumption() uint64
+	// IsBlocked returns true if the consumer is waiting for more quota
+	IsBlocked() bool
+	// SetQuota updates the quota of the consumer
+	SetQuota(quota uint64)
+}
+
+type tableConsumer struct {
+	consumer Consumer
+	quota    uint64
+	consumed uint64
+	blocked  bool
+}
+
+type changefeedConsumers struct {
+	priority int
+	// budget is the maximum memory quota of the changefeed, 0 means unlimited
+	budget uint64
+	tables map[model.TableID]*tableConsumer
+}
+
+// gate blocks the pullers while it's closed
+type gate struct {
+	closed int32
+	// openCh is closed once the gate is opened
+	openCh chan struct{}
+}
+
+func newGate() *gate {
+	g := &gate{openCh: make(chan struct{})}
+	close(g.openCh)
+	return g
+}
+
+func (g *gate) isClosed() bool {
+	return atomic.LoadInt32(&g.closed) == 1
+}
+
+// setClosedLocked closes or opens the gate, it must be called with the lock of the Arbitrator held
+func (g *gate) setClosedLocked(closed bool) {
+	if closed == g.isClosed() {
+		return
+	}
+	if closed {
+		g.openCh = make(chan struct{})
+		atomic.StoreInt32(&g.closed, 1)
+		return
+	}
+	atomic.StoreInt32(&g.closed, 0)
+	close(g.openCh)
+}
+
+// Arbitrator tracks the memory consumption of all the components in a capture, and allocates the memory
+// quotas to the tables of all the changefeeds by their demands and the priorities of the changefeeds.
+// When the memory is exhausted, the sorter spills the events to disk, and the pullers stop receiving the
+// events from TiKV until the events pending in the mounters and the sinks are released.
+type Arbitrator struct {
+	// limit is the maximum memory consumption of the capture, 0 means the memory is not arbitrated
+	limit uint64
+
+	mu          sync.Mutex
+	changefeeds map[model.ChangeFeedID]*changefeedConsumers
+	// usageFuncs returns the consumption of the components which track the consumption by themselves
+	usageFuncs map[string]func() int64
+	// counters are the consumption of the components which report the consumption to the Arbitrator
+	counters map[string]*int64
+
+	exhausted int32
+	// pressured is closed if the pullers should stop receiving the events
+	pressured *gate
+	// yielding is closed if the pullers of the changefeeds with lower priorities
+	// should stop receiving the events
+	yielding *gate
+	// maxPriority is the highest priority of the registered changefeeds
+	maxPriority int32
+}
+
+var globalArbitrator = NewArbitrator(0)
+
+// NewArbitrator creates a new Arbitrator, limit 0 means the memory is only tracked but not arbitrated.
+func NewArbitrator(limit uint64) *Arbitrator {
+	a := &Arbitrator{
+		limit:       limit,
+		changefeeds: make(map[model.ChangeFeedID]*changefeedConsumers),
+		usageFuncs:  make(map[string]func() int64),
+		counters:    make(map[string]*int64),
+		pressured:   newGate(),
+		yielding:    newGate(),
+	}
+	a.counters[ComponentPuller] = new(int64)
+	a.counters[ComponentSharedPuller] = new(int64)
+	return a
+}
+
+// GetGlobalArbitrator returns the Arbitrator of the capture
+func GetGlobalArbitrator() *Arbitrator {
+	return globalArbitrator
+}
+
+// SetLimit sets the maximum memory consumption of the capture, 0 means the memory is not arbitrated
+func (a *Arbitrator) SetLimit(limit uint64) {
+	atomic.StoreUint64(&a.limit, limit)
+}
+
+// Limit returns the maximum memory consumption of the capture
+func (a *Arbitrator) Limit() uint64 {
+	return atomic.LoadUint64(&a.limit)
+}
+
+// Register registers the consumer of a table, the quota of the consumer is updated by the Arbitrator
+// periodically if the memory is arbitrated or the changefeed has a memory budget.
+func (a *Arbitrator) Register(
+	changefeedID model.ChangeFeedID, tableID model.TableID, priority int, budget uint64, consumer Consumer,
+) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cf, exist := a.changefeeds[changefeedID]
+	if !exist {
+		cf = &changefeedConsumers{tables: make(map[model.TableID]*tableConsumer)}
+		a.changefeeds[changefeedID] = cf
+	}
+	if priority <= 0 {
+		priority = DefaultPriority
+	}
+	cf.priority = priority
+	cf.budget = budget
+	cf.tables[tableID] = &tableConsumer{consumer: consumer}
+}
+
+// Unregister removes the consumer of a table
+func (a *Arbitrator) Unregister(changefeedID model.ChangeFeedID, tableID model.TableID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cf, exist := a.changefeeds[changefeedID]
+	if !exist {
+		return
+	}
+	delete(cf.tables, tableID)
+	if len(cf.tables) == 0 {
+		delete(a.changefeeds, changefeedID)
+		changefeedMemoryQuotaGauge.DeleteLabelValues(changefeedID)
+		changefeedMemoryUsageGauge.DeleteLabelValues(changefeedID)
+	}
+}
+
+// SetUsageFunc sets the function returning the memory consumption of a component
+// which tracks the consumption by itself.
+func (a *Arbitrator) SetUsageFunc(component string, usage func() int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.usageFuncs[component] = usage
+}
+
+// AddUsage adds the delta to the memory consumption of a component, the delta is negative if the memory is released
+func (a *Arbitrator) AddUsage(component string, delta int64) {
+	counter, exist := a.counters[component]
+	if !exist {
+		log.Panic("unknown memory component", zap.String("component", component))
+	}
+	atomic.AddInt64(counter, delta)
+}
+
+// IsExhausted returns true if the memory consumption of the capture reaches the limit,
+// the components should avoid consuming more memory, for example the sorter spills the events to disk.
+func (a *Arbitrator) IsExhausted() bool {
+	return atomic.LoadInt32(&a.exhausted) == 1
+}
+
+// WaitAvailable blocks until the pullers of a changefeed with the priority are allowed to receive more
+// events from TiKV. The consumption of the sorter is not considered, because the sorted events are released
+// only if the pullers send the resolved events to the sorter, and the sorter spills the events to disk once
+// the memory is exhausted.
+func (a *Arbitrator) WaitAvailable(ctx context.Context, priority int) error {
+	g := a.pressured
+	if !g.isClosed() {
+		if priority >= int(atomic.LoadInt32(&a.maxPriority)) || !a.yielding.isClosed() {
+			return nil
+		}
+		g = a.yielding
+	}
+	a.mu.Lock()
+	openCh := g.openCh
+	a.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case <-openCh:
+		return nil
+	}
+}
+
+// Run rebalances the quotas of the tables periodically until the context is done
+func (a *Arbitrator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(rebalanceInterval)
+	defer ticker.Stop()
+	defer func() {
+		// release the pullers waiting for the memory
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.pressured.setClosedLocked(false)
+		a.yielding.setClosedLocked(false)
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-ticker.C:
+		}
+		a.rebalance()
+	}
+}
+
+// usageLocked returns the memory consumption of all the components,
+// and updates the consumption of the tables.
+func (a *Arbitrator) usageLocked() map[string]uint64 {
+	usage := map[string]uint64{ComponentPuller: 0, ComponentSharedPuller: 0, ComponentSorter: 0, ComponentSink: 0}
+	for component, counter := range a.counters {
+		if n := atomic.LoadInt64(counter); n > 0 {
+			usage[component] = uint64(n)
+		}
+	}
+	for component, usageFunc := range a.usageFuncs {
+		if n := usageFunc(); n > 0 {
+			usage[component] += uint64(n)
+		}
+	}
+	for _, cf := range a.changefeeds {
+		for _, table := range cf.tables {
+			table.consumed = table.consumer.GetConsumption()
+			table.blocked = table.consumer.IsBlocked()
+			usage[ComponentSink] += table.consumed
+		}
+	}
+	return usage
+}
+
+func (a *Arbitrator) rebalance() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	limit := a.Limit()
+	usage := a.usageLocked()
+	var total uint64
+	for component, n := range usage {
+		total += n
+		memoryUsageGauge.WithLabelValues(component).Set(float64(n))
+	}
+	memoryLimitGauge.Set(float64(limit))
+	defer func() {
+		for changefeedID, cf := range a.changefeeds {
+			var quota, consumed uint64
+			for _, table := range cf.tables {
+				quota += table.quota
+				consumed += table.consumed
+			}
+			changefeedMemoryQuotaGauge.WithLabelValues(changefeedID).Set(float64(quota))
+			changefeedMemoryUsageGauge.WithLabelValues(changefeedID).Set(float64(consumed))
+		}
+	}()
+
+	exhausted := limit != 0 && total >= limit
+	if exhausted != a.IsExhausted() {
+		log.Info("memory exhaustion state changed", zap.Bool("exhausted", exhausted),
+			zap.Uint64("limit", limit), zap.Any("usage", usage))
+	}
+	if exhausted {
+		atomic.StoreInt32(&a.exhausted, 1)
+	} else {
+		atomic.StoreInt32(&a.exhausted, 0)
+	}
+	maxPriority := 0
+	for _, cf := range a.changefeeds {
+		if cf.priority > maxPriority {
+			maxPriority = cf.priority
+		}
+	}
+	atomic.StoreInt32(&a.maxPriority, int32(maxPriority))
+	pending := usage[ComponentPuller] + usage[ComponentSharedPuller] + usage[ComponentSink]
+	a.pressured.setClosedLocked(limit != 0 && pending >= limit)
+	a.yielding.setClosedLocked(limit != 0 && pending >= limit/100*yieldPressureRatio)
+	if limit == 0 {
+		// only the changefeeds with memory budgets are arbitrated
+		for _, cf := range a.changefeeds {
+			if cf.budget != 0 {
+				cf.allocateLocked(cf.budget)
+			}
+		}
+		return
+	}
+
+	pool := uint64(0)
+	if used := usage[ComponentPuller] + usage[ComponentSharedPuller] + usage[ComponentSorter]; used < limit {
+		pool = limit - used
+	}
+	if pool < limit/minSinkPoolRatio {
+		pool = limit / minSinkPoolRatio
+	}
+	a.allocateLocked(pool)
+}
+
+// allocateLocked divides the pool to the changefeeds by their priorities, and no changefeed gets more than
+// its budget. The memory not demanded by a changefeed is allocated to the others.
+func (a *Arbitrator) allocateLocked(pool uint64) {
+	changefeeds := make([]*changefeedConsumers, 0, len(a.changefeeds))
+	cfDemands := make([]uint64, 0, len(a.changefeeds))
+	cfWeights := make([]float64, 0, len(a.changefeeds))
+	for _, cf := range a.changefeeds {
+		var cfDemand uint64
+		for _, table := range cf.tables {
+			cfDemand += table.demand()
+		}
+		if cf.budget != 0 && cfDemand > cf.budget {
+			cfDemand = cf.budget
+		}
+		changefeeds = append(changefeeds, cf)
+		cfDemands = append(cfDemands, cfDemand)
+		cfWeights = append(cfWeights, float64(cf.priority))
+	}
+	cfQuotas := allocate(pool, cfDemands, cfWeights)
+	for i, cf := range changefeeds {
+		cf.allocateLocked(cfQuotas[i])
+	}
+}
+
+// demand returns the memory demanded by the table, a blocked table demands doubling its quota
+func (t *tableConsumer) demand() uint64 {
+	demand := t.consumed * 2
+	if t.blocked && demand < t.quota*2 {
+		demand = t.quota * 2
+	}
+	if demand < minTableDemand {
+		demand = minTableDemand
+	}
+	return demand
+}
+
+// allocateLocked divides the quota of the changefeed to its tables evenly. The memory not demanded by
+// a table is allocated to the others, so a busy table can get more memory than its share.
+func (cf *changefeedConsumers) allocateLocked(quota uint64) {
+	tables := make([]*tableConsumer, 0, len(cf.tables))
+	demands := make([]uint64, 0, len(cf.tables))
+	weights := make([]float64, 0, len(cf.tables))
+	for _, table := range cf.tables {
+		tables = append(tables, table)
+		demands = append(demands, table.demand())
+		weights = append(weights, 1)
+	}
+	quotas := allocate(quota, demands, weights)
+	for i, table := range tables {
+		if table.quota != quotas[i] {
+			table.quota = quotas[i]
+			table.consumer.SetQuota(quotas[i])
+		}
+	}
+}
+
+// allocate divides the capacity by weighted max-min fairness: no one gets more than its demand,
+// and the capacity not demanded is divided by the weights of the others.
+func allocate(capacity uint64, demands []uint64, weights []float64) []uint64 {
+	quotas := make([]uint64, len(demands))
+	order := make([]int, len(demands))
+	totalWeight := float64(0)
+	for i := range order {
+		order[i] = i
+		totalWeight += weights[i]
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return float64(demands[order[i]])/weights[order[i]] < float64(demands[order[j]])/weights[order[j]]
+	})
+	left := capacity
+	for _, i := range order {
+		share := left
+		if weights[i] < totalWeight {
+			share = uint64(float64(left) * weights[i] / totalWeight)
+		}
+		if demands[i] < share {
+			share = demands[i]
+		}
+		quotas[i] = share
+		left -= share
+		totalWeight -= weights[i]
+	}
+	return quotas
+}
+
+// WriteDebugInfo writes the memory consumption and the quotas into the writer
+func (a *Arbitrator) WriteDebugInfo(w io.Writer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	usage := a.usageLocked()
+	fmt.Fprintf(w, "limit: %d, exhausted: %t, pressured: %t\n",
+		a.Limit(), a.IsExhausted(), a.pressured.isClosed())
+	components := make([]string, 0, len(usage))
+	for component := range usage {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		fmt.Fprintf(w, "component: %s, usage: %d\n", component, usage[component])
+	}
+	for changefeedID, cf := range a.changefeeds {
+		fmt.Fprintf(w, "changefeedID: %s, priority: %d, budget: %d\n", changefeedID, cf.priority, cf.budget)
+		for tableID, table := range cf.tables {
+			fmt.Fprintf(w, "tableID: %d, quota: %d, usage: %d, blocked: %t\n",
+				tableID, table.quota, table.consumed, table.blocked)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memquota
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type arbitratorSuite struct{}
+
+var _ = check.Suite(&arbitratorSuite{})
+
+type mockConsumer struct {
+	consumed uint64
+	blocked  bool
+	quota    uint64
+}
+
+func (m *mockConsumer) GetConsumption() uint64 {
+	return m.consumed
+}
+
+func (m *mockConsumer) IsBlocked() bool {
+	return m.blocked
+}
+
+func (m *mockConsumer) SetQuota(quota uint64) {
+	m.quota = quota
+}
+
+func (s *arbitratorSuite) TestAllocate(c *check.C) {
+	defer testleak.AfterTest(c)()
+	// nobody gets more than its demand
+	c.Assert(allocate(100, []uint64{10, 20, 30}, []float64{1, 1, 1}), check.DeepEquals, []uint64{10, 20, 30})
+	// the capacity not demanded by the first one is divided evenly
+	c.Assert(allocate(100, []uint64{10, 100, 100}, []float64{1, 1, 1}), check.DeepEquals, []uint64{10, 45, 45})
+	// the capacity is divided by the weights
+	c.Assert(allocate(100, []uint64{100, 100}, []float64{3, 1}), check.DeepEquals, []uint64{75, 25})
+	c.Assert(allocate(100, nil, nil), check.HasLen, 0)
+}
+
+func (s *arbitratorSuite) TestRebalance(c *check.C) {
+	defer testleak.AfterTest(c)()
+	a := NewArbitrator(0)
+	busy := &mockConsumer{consumed: 40 * minTableDemand, blocked: true}
+	idle := &mockConsumer{}
+	other := &mockConsumer{consumed: 40 * minTableDemand, blocked: true}
+	a.Register("changefeed-1", 1, DefaultPriority, 0, busy)
+	a.Register("changefeed-1", 2, DefaultPriority, 0, idle)
+	a.Register("changefeed-2", 3, DefaultPriority, 0, other)
+	sorterUsage := int64(0)
+	a.SetUsageFunc(ComponentSorter, func() int64 { return sorterUsage })
+
+	// the quotas are not allocated if the memory is not arbitrated
+	a.rebalance()
+	c.Assert(busy.quota, check.Equals, uint64(0))
+	c.Assert(a.IsExhausted(), check.IsFalse)
+
+	a.SetLimit(100 * minTableDemand)
+	a.rebalance()
+	// the changefeeds share the memory evenly, and the idle table only gets its minimum demand
+	c.Assert(idle.quota, check.Equals, uint64(minTableDemand))
+	c.Assert(busy.quota, check.Equals, uint64(49*minTableDemand))
+	c.Assert(other.quota, check.Equals, uint64(50*minTableDemand))
+	c.Assert(busy.quota+idle.quota+other.quota, check.Equals, a.Limit())
+	c.Assert(a.IsExhausted(), check.IsFalse)
+
+	// the memory consumed by the sorter isn't allocated to the tables
+	sorterUsage = 40 * minTableDemand
+	a.rebalance()
+	c.Assert(busy.quota+idle.quota+other.quota, check.Equals, uint64(60*minTableDemand))
+	c.Assert(a.IsExhausted(), check.IsTrue)
+
+	// the pullers are blocked if the sink buffers consume all the memory
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	c.Assert(a.WaitAvailable(ctx, DefaultPriority), check.IsNil)
+	a.AddUsage(ComponentPuller, 20*minTableDemand)
+	a.rebalance()
+	c.Assert(a.WaitAvailable(ctx, DefaultPriority), check.ErrorMatches, ".*context deadline exceeded.*")
+	a.AddUsage(ComponentPuller, -20*minTableDemand)
+	a.rebalance()
+	c.Assert(a.WaitAvailable(context.Background(), DefaultPriority), check.IsNil)
+
+	var buf bytes.Buffer
+	a.WriteDebugInfo(&buf)
+	c.Assert(buf.String(), check.Matches, "(?s)limit: 104857600, exhausted: true, pressured: false\n.*component: sorter, usage: 41943040\n.*changefeedID: changefeed-2, priority: 1, budget: 0\n.*")
+
+	a.Unregister("changefeed-2", 3)
+	c.Assert(a.changefeeds, check.HasLen, 1)
+}
+
+func (s *arbitratorSuite) TestPriority(c *check.C) {
+	defer testleak.AfterTest(c)()
+	a := NewArbitrator(40 * minTableDemand)
+	high := &mockConsumer{consumed: 40 * minTableDemand, blocked: true}
+	low := &mockConsumer{consumed: 40 * minTableDemand, blocked: true}
+	a.Register("high", 1, 3, 0, high)
+	a.Register("low", 1, DefaultPriority, 0, low)
+	a.rebalance()
+	c.Assert(high.quota, check.Equals, uint64(30*minTableDemand))
+	c.Assert(low.quota, check.Equals, uint64(10*minTableDemand))
+
+	// the pullers of the low priority changefeed yield to the high priority one under pressure
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	high.consumed, low.consumed = 20*minTableDemand, 15*minTableDemand
+	a.rebalance()
+	c.Assert(a.WaitAvailable(ctx, 3), check.IsNil)
+	c.Assert(a.WaitAvailable(ctx, DefaultPriority), check.ErrorMatches, ".*context deadline exceeded.*")
+	low.consumed = 5 * minTableDemand
+	a.rebalance()
+	c.Assert(a.WaitAvailable(context.Background(), DefaultPriority), check.IsNil)
+}
+
+func (s *arbitratorSuite) TestBudget(c *check.C) {
+	defer testleak.AfterTest(c)()
+	a := NewArbitrator(0)
+	limited := &mockConsumer{consumed: 40 * minTableDemand, blocked: true}
+	unlimited := &mockConsumer{consumed: 40 * minTableDemand, blocked: true}
+	a.Register("limited", 1, DefaultPriority, 10*minTableDemand, limited)
+	a.Register("unlimited", 1, DefaultPriority, 0, unlimited)
+
+	// the changefeeds with budgets are arbitrated even if the memory of the capture is not arbitrated
+	a.rebalance()
+	c.Assert(limited.quota, check.Equals, uint64(10*minTableDemand))
+	c.Assert(unlimited.quota, check.Equals, uint64(0))
+
+	// the memory not demanded by the changefeed with the budget is allocated to the others
+	a.SetLimit(100 * minTableDemand)
+	a.rebalance()
+	c.Assert(limited.quota, check.Equals, uint64(10*minTableDemand))
+	c.Assert(unlimited.quota, check.Equals, uint64(80*minTableDemand))
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memquota
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	memoryLimitGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "ticdc",
+		Subsystem: "memory",
+		Name:      "limit",
+		Help:      "the maximum memory consumption of the capture, 0 means unlimited",
+	})
+
+	memoryUsageGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ticdc",
+		Subsystem: "memory",
+		Name:      "usage",
+		Help:      "the memory consumption of the components",
+	}, []string{"component"})
+
+	changefeedMemoryQuotaGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ticdc",
+		Subsystem: "memory",
+		Name:      "changefeed_quota",
+		Help:      "the memory quota allocated to the tables of the changefeed",
+	}, []string{"changefeed"})
+
+	changefeedMemoryUsageGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ticdc",
+		Subsystem: "memory",
+		Name:      "changefeed_usage",
+		Help:      "the memory consumed by the tables of the changefeed",
+	}, []string{"changefeed"})
+)
+
+// InitMetrics registers all metrics in this file
+func InitMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(memoryLimitGauge)
+	registry.MustRegister(memoryUsageGauge)
+	registry.MustRegister(changefeedMemoryQuotaGauge)
+	registry.MustRegister(changefeedMemoryUsageGauge)
+}
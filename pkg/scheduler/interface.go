@@ -41,6 +41,8 @@ func NewScheduler(tp string) Scheduler {
 	switch tp {
 	case "table-number":
 		return newTableNumberScheduler()
+	case "workload":
+		return newWorkloadScheduler()
 	default:
 		log.Info("invalid scheduler type, using default scheduler")
 		return newTableNumberScheduler()
@@ -78,6 +78,9 @@ func (w workloads) Skewness() float64 {
 		totalWorkloads = append(totalWorkloads, total)
 		workloadSum += total
 	}
+	if workloadSum == 0 {
+		return 0
+	}
 	avgWorkload := float64(workloadSum) / float64(len(w))
 
 	var totalVariance float64
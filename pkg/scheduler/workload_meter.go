@@ -0,0 +1,81 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+const (
+	// workloadSampleInterval is the minimum interval between two samples of the throughput
+	workloadSampleInterval = 10 * time.Second
+	// workloadSmoothingFactor is the weight of the latest sample in the smoothed throughput
+	workloadSmoothingFactor = 0.5
+)
+
+// WorkloadMeter calculates the workload of a table by its throughput.
+// The throughput is smoothed, so that a short burst doesn't make the table look hot.
+// WorkloadMeter is not thread-safe.
+type WorkloadMeter struct {
+	lastSampleTime time.Time
+	lastRows       uint64
+	lastBytes      uint64
+
+	rowsPerSecond  float64
+	bytesPerSecond float64
+}
+
+// NewWorkloadMeter creates a new WorkloadMeter
+func NewWorkloadMeter() *WorkloadMeter {
+	return &WorkloadMeter{}
+}
+
+// Sample updates the throughput by the accumulated number of rows and bytes of the table,
+// and returns the workload of the table. The throughput is updated at most once per workloadSampleInterval.
+func (m *WorkloadMeter) Sample(now time.Time, rows, bytes uint64) model.WorkloadInfo {
+	if m.lastSampleTime.IsZero() {
+		m.lastSampleTime = now
+		m.lastRows = rows
+		m.lastBytes = bytes
+		return m.workload()
+	}
+	elapsed := now.Sub(m.lastSampleTime)
+	if elapsed < workloadSampleInterval {
+		return m.workload()
+	}
+	var rowsDelta, bytesDelta uint64
+	// the counters are reset if the table is re-created
+	if rows >= m.lastRows {
+		rowsDelta = rows - m.lastRows
+	}
+	if bytes >= m.lastBytes {
+		bytesDelta = bytes - m.lastBytes
+	}
+	m.rowsPerSecond = smooth(m.rowsPerSecond, float64(rowsDelta)/elapsed.Seconds())
+	m.bytesPerSecond = smooth(m.bytesPerSecond, float64(bytesDelta)/elapsed.Seconds())
+	m.lastSampleTime = now
+	m.lastRows = rows
+	m.lastBytes = bytes
+	return m.workload()
+}
+
+func (m *WorkloadMeter) workload() model.WorkloadInfo {
+	return model.NewWorkloadInfo(uint64(m.rowsPerSecond), uint64(m.bytesPerSecond))
+}
+
+func smooth(last, sample float64) float64 {
+	return last*(1-workloadSmoothingFactor) + sample*workloadSmoothingFactor
+}
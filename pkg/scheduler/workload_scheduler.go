@@ -0,0 +1,193 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+const (
+	// defaultSkewnessThreshold is the skewness under which the workloads are considered balanced,
+	// it's used if the target skewness is not specified.
+	defaultSkewnessThreshold = 0.1
+	// minImprovementRatio is the minimum ratio of the average capture workload by which a move must reduce
+	// the workload of the busier capture, so that tables are not moved for a negligible gain.
+	minImprovementRatio = 0.05
+	// moveTableCooldown is the duration in which a moved table is not moved again,
+	// so that a hot table is not bounced between captures.
+	moveTableCooldown = 10 * time.Minute
+)
+
+// WorkloadScheduler provides a feature that scheduling by the measured workload of tables
+type WorkloadScheduler struct {
+	workloads workloads
+	// lastMoveTime records the last time each table is moved by rebalance
+	lastMoveTime map[model.TableID]time.Time
+	now          func() time.Time
+}
+
+// newWorkloadScheduler creates a new workload scheduler
+func newWorkloadScheduler() *WorkloadScheduler {
+	return &WorkloadScheduler{
+		workloads:    make(workloads),
+		lastMoveTime: make(map[model.TableID]time.Time),
+		now:          time.Now,
+	}
+}
+
+// ResetWorkloads implements the Scheduler interface
+func (w *WorkloadScheduler) ResetWorkloads(captureID model.CaptureID, workloads model.TaskWorkload) {
+	w.workloads.SetCapture(captureID, workloads)
+}
+
+// AlignCapture implements the Scheduler interface
+func (w *WorkloadScheduler) AlignCapture(captureIDs map[model.CaptureID]struct{}) {
+	w.workloads.AlignCapture(captureIDs)
+}
+
+// Skewness implements the Scheduler interface
+func (w *WorkloadScheduler) Skewness() float64 {
+	return w.workloads.Skewness()
+}
+
+// CalRebalanceOperates implements the Scheduler interface.
+// It moves tables from the busiest capture to the idlest capture one by one until the skewness is
+// not higher than targetSkewness, or no move can reduce the workload of the busiest capture remarkably.
+func (w *WorkloadScheduler) CalRebalanceOperates(targetSkewness float64) (
+	skewness float64, moveTableJobs map[model.TableID]*model.MoveTableJob) {
+	if targetSkewness <= 0 {
+		targetSkewness = defaultSkewnessThreshold
+	}
+	moveTableJobs = make(map[model.TableID]*model.MoveTableJob)
+	if len(w.workloads) < 2 {
+		return w.Skewness(), moveTableJobs
+	}
+	now := w.now()
+	for tableID, lastMoveTime := range w.lastMoveTime {
+		if now.Sub(lastMoveTime) >= moveTableCooldown {
+			delete(w.lastMoveTime, tableID)
+		}
+	}
+
+	captureIDs := make([]model.CaptureID, 0, len(w.workloads))
+	totalWorkloads := make(map[model.CaptureID]uint64, len(w.workloads))
+	var workloadSum uint64
+	for captureID, captureWorkloads := range w.workloads {
+		captureIDs = append(captureIDs, captureID)
+		for _, workload := range captureWorkloads {
+			totalWorkloads[captureID] += workload.Workload
+		}
+		workloadSum += totalWorkloads[captureID]
+	}
+	// sort the captures to make the result stable
+	sort.Strings(captureIDs)
+	minImprovement := uint64(float64(workloadSum) / float64(len(w.workloads)) * minImprovementRatio)
+
+	for skewness = w.Skewness(); skewness > targetSkewness; skewness = w.Skewness() {
+		busiest, idlest := captureIDs[0], captureIDs[0]
+		for _, captureID := range captureIDs {
+			if totalWorkloads[captureID] > totalWorkloads[busiest] {
+				busiest = captureID
+			}
+			if totalWorkloads[captureID] < totalWorkloads[idlest] {
+				idlest = captureID
+			}
+		}
+		tableID, workload, ok := w.selectTableToMove(busiest, totalWorkloads[busiest], totalWorkloads[idlest], minImprovement)
+		if !ok {
+			break
+		}
+		w.workloads.RemoveTable(busiest, tableID)
+		w.workloads.SetTable(idlest, tableID, workload)
+		totalWorkloads[busiest] -= workload.Workload
+		totalWorkloads[idlest] += workload.Workload
+		w.lastMoveTime[tableID] = now
+
+		job, exist := moveTableJobs[tableID]
+		if !exist {
+			job = &model.MoveTableJob{From: busiest, TableID: tableID}
+			moveTableJobs[tableID] = job
+		}
+		job.To = idlest
+		if job.From == job.To {
+			delete(moveTableJobs, tableID)
+		}
+	}
+	return
+}
+
+// selectTableToMove selects the table whose moving from the busiest capture to the idlest capture
+// reduces the larger workload of the two captures most, tables moved recently are not selected.
+func (w *WorkloadScheduler) selectTableToMove(
+	busiest model.CaptureID, busiestWorkload, idlestWorkload, minImprovement uint64,
+) (tableID model.TableID, workload model.WorkloadInfo, ok bool) {
+	var maxImprovement uint64
+	for id, info := range w.workloads[busiest] {
+		if _, cooling := w.lastMoveTime[id]; cooling {
+			continue
+		}
+		// moving a table whose workload is not less than the gap doesn't make the two captures more balanced
+		if info.Workload >= busiestWorkload-idlestWorkload {
+			continue
+		}
+		newBusiestWorkload := busiestWorkload - info.Workload
+		if idlestWorkload+info.Workload > newBusiestWorkload {
+			newBusiestWorkload = idlestWorkload + info.Workload
+		}
+		improvement := busiestWorkload - newBusiestWorkload
+		if improvement < minImprovement || improvement == 0 {
+			continue
+		}
+		if improvement > maxImprovement || (improvement == maxImprovement && id < tableID) {
+			maxImprovement = improvement
+			tableID = id
+			workload = info
+			ok = true
+		}
+	}
+	return
+}
+
+// DistributeTables implements the Scheduler interface.
+// The workload of a new table is unknown, so it's estimated by the average workload of all tables.
+func (w *WorkloadScheduler) DistributeTables(tableIDs map[model.TableID]model.Ts) map[model.CaptureID]map[model.TableID]*model.TableOperation {
+	result := make(map[model.CaptureID]map[model.TableID]*model.TableOperation, len(w.workloads))
+	estimated := model.WorkloadInfo{Workload: 1}
+	var totalWorkload, totalTable uint64
+	for _, captureWorkloads := range w.workloads {
+		for _, workload := range captureWorkloads {
+			totalWorkload += workload.Workload
+		}
+		totalTable += uint64(len(captureWorkloads))
+	}
+	if totalTable > 0 && totalWorkload/totalTable > 1 {
+		estimated.Workload = totalWorkload / totalTable
+	}
+	for tableID, boundaryTs := range tableIDs {
+		captureID := w.workloads.SelectIdleCapture()
+		operations := result[captureID]
+		if operations == nil {
+			operations = make(map[model.TableID]*model.TableOperation)
+			result[captureID] = operations
+		}
+		operations[tableID] = &model.TableOperation{
+			BoundaryTs: boundaryTs,
+		}
+		w.workloads.SetTable(captureID, tableID, estimated)
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/util/testleak"
+)
+
+type workloadSchedulerSuite struct{}
+
+var _ = check.Suite(&workloadSchedulerSuite{})
+
+func (s *workloadSchedulerSuite) TestCalRebalanceOperates(c *check.C) {
+	defer testleak.AfterTest(c)()
+	now := time.Now()
+	scheduler := newWorkloadScheduler()
+	scheduler.now = func() time.Time { return now }
+	// the table numbers are balanced, but the workloads are not
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		1: model.WorkloadInfo{Workload: 100},
+		2: model.WorkloadInfo{Workload: 100},
+		3: model.WorkloadInfo{Workload: 100},
+	})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		4: model.WorkloadInfo{Workload: 1},
+		5: model.WorkloadInfo{Workload: 1},
+		6: model.WorkloadInfo{Workload: 1},
+	})
+	c.Assert(fmt.Sprintf("%.2f%%", scheduler.Skewness()*100), check.Equals, "98.02%")
+	skewness, moveJobs := scheduler.CalRebalanceOperates(0)
+	c.Assert(moveJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		1: {From: "capture1", To: "capture2", TableID: 1},
+	})
+	c.Assert(fmt.Sprintf("%.2f%%", skewness*100), check.Equals, "32.01%")
+
+	// the moved table is not moved again in the cooldown duration
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		1: model.WorkloadInfo{Workload: 100},
+		4: model.WorkloadInfo{Workload: 100},
+	})
+	skewness, moveJobs = scheduler.CalRebalanceOperates(0)
+	c.Assert(moveJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		4: {From: "capture2", To: "capture1", TableID: 4},
+	})
+	c.Assert(skewness, check.Equals, float64(0))
+	now = now.Add(moveTableCooldown)
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		1: model.WorkloadInfo{Workload: 100},
+		4: model.WorkloadInfo{Workload: 100},
+	})
+	_, moveJobs = scheduler.CalRebalanceOperates(0)
+	c.Assert(moveJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		1: {From: "capture2", To: "capture1", TableID: 1},
+	})
+
+	// a single hot table can't be balanced, and moving the idle tables is useless
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		1: model.WorkloadInfo{Workload: 1000},
+		2: model.WorkloadInfo{Workload: 1},
+	})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		3: model.WorkloadInfo{Workload: 1},
+	})
+	_, moveJobs = scheduler.CalRebalanceOperates(0)
+	c.Assert(moveJobs, check.HasLen, 0)
+
+	// nothing is moved if the skewness is lower than the target
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		2: model.WorkloadInfo{Workload: 110},
+		3: model.WorkloadInfo{Workload: 10},
+	})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		4: model.WorkloadInfo{Workload: 100},
+	})
+	_, moveJobs = scheduler.CalRebalanceOperates(0.1)
+	c.Assert(moveJobs, check.HasLen, 0)
+	_, moveJobs = scheduler.CalRebalanceOperates(0.01)
+	c.Assert(moveJobs, check.DeepEquals, map[model.TableID]*model.MoveTableJob{
+		3: {From: "capture1", To: "capture2", TableID: 3},
+	})
+}
+
+func (s *workloadSchedulerSuite) TestDistributeTables(c *check.C) {
+	defer testleak.AfterTest(c)()
+	scheduler := newWorkloadScheduler()
+	scheduler.ResetWorkloads("capture1", model.TaskWorkload{
+		1: model.WorkloadInfo{Workload: 300},
+	})
+	scheduler.ResetWorkloads("capture2", model.TaskWorkload{
+		2: model.WorkloadInfo{Workload: 100},
+		3: model.WorkloadInfo{Workload: 100},
+	})
+	result := scheduler.DistributeTables(map[model.TableID]model.Ts{4: 1, 5: 1})
+	// the new tables are estimated by the average workload of tables
+	c.Assert(result["capture1"], check.HasLen, 1)
+	c.Assert(result["capture2"], check.HasLen, 1)
+	c.Assert(fmt.Sprintf("%.2f%%", scheduler.Skewness()*100), check.Equals, "12.02%")
+}
+
+func (s *workloadSchedulerSuite) TestWorkloadMeter(c *check.C) {
+	defer testleak.AfterTest(c)()
+	now := time.Now()
+	meter := NewWorkloadMeter()
+	c.Assert(meter.Sample(now, 100, 1024), check.Equals, model.WorkloadInfo{Workload: 1})
+	// the throughput is not updated until the sample interval elapses
+	c.Assert(meter.Sample(now.Add(time.Second), 1000, 1024*1024), check.Equals, model.WorkloadInfo{Workload: 1})
+
+	now = now.Add(workloadSampleInterval)
+	workload := meter.Sample(now, 100+2000, 1024+2000*1024)
+	c.Assert(workload, check.Equals, model.WorkloadInfo{Workload: 201, RowsPerSecond: 100, BytesPerSecond: 100 * 1024})
+	// the throughput is smoothed
+	now = now.Add(workloadSampleInterval)
+	workload = meter.Sample(now, 100+2000, 1024+2000*1024)
+	c.Assert(workload, check.Equals, model.WorkloadInfo{Workload: 101, RowsPerSecond: 50, BytesPerSecond: 50 * 1024})
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"crypto/sha1" //nolint:gosec // htpasswd {SHA} scheme
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const htpasswdSHAPrefix = "{SHA}"
+
+var htpasswdBcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+// IsSupportedHtpasswdHash checks whether the given htpasswd-style hash is supported.
+func IsSupportedHtpasswdHash(hash string) bool {
+	if strings.HasPrefix(hash, htpasswdSHAPrefix) {
+		return true
+	}
+	for _, prefix := range htpasswdBcryptPrefixes {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyHtpasswd checks whether the password matches the htpasswd-style hash.
+func VerifyHtpasswd(hash, password string) bool {
+	if strings.HasPrefix(hash, htpasswdSHAPrefix) {
+		sum := sha1.Sum([]byte(password)) //nolint:gosec
+		expected := htpasswdSHAPrefix + base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(expected)) == 1
+	}
+	for _, prefix := range htpasswdBcryptPrefixes {
+		if strings.HasPrefix(hash, prefix) {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+		}
+	}
+	return false
+}
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/pkg/config"
 	"github.com/pingcap/tidb/kv"
 	"go.uber.org/zap"
 )
@@ -31,6 +32,9 @@ const (
 	ctxKeyIsOwner      = ctxKey("isOwner")
 	ctxKeyTimezone     = ctxKey("timezone")
 	ctxKeyKVStorage    = ctxKey("kvStorage")
+	ctxKeyScanLimit    = ctxKey("regionScanLimit")
+	ctxKeyScanPriority = ctxKey("scanPriority")
+	ctxKeyScanRate     = ctxKey("scanBytesPerSecond")
 )
 
 // CaptureAddrFromCtx returns a capture ID stored in the specified context.
@@ -121,6 +125,51 @@ func PutChangefeedIDInCtx(ctx context.Context, changefeedID string) context.Cont
 	return context.WithValue(ctx, ctxKeyChangefeedID, changefeedID)
 }
 
+// PutRegionScanLimitInCtx returns a new child context with the specified region scan limit stored.
+func PutRegionScanLimitInCtx(ctx context.Context, limit int) context.Context {
+	return context.WithValue(ctx, ctxKeyScanLimit, limit)
+}
+
+// RegionScanLimitFromCtx returns the region scan limit stored in the specified context.
+// It returns 0 if there's no region scan limit found.
+func RegionScanLimitFromCtx(ctx context.Context) int {
+	limit, ok := ctx.Value(ctxKeyScanLimit).(int)
+	if !ok {
+		return 0
+	}
+	return limit
+}
+
+// PutScanPriorityInCtx returns a new child context with the specified priority weight of the incremental scans stored.
+func PutScanPriorityInCtx(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, ctxKeyScanPriority, priority)
+}
+
+// ScanPriorityFromCtx returns the priority weight of the incremental scans stored in the specified context.
+// It returns the weight of the normal priority if there's no priority found.
+func ScanPriorityFromCtx(ctx context.Context) int {
+	priority, ok := ctx.Value(ctxKeyScanPriority).(int)
+	if !ok {
+		return (*config.ResourceConfig)(nil).PriorityWeight()
+	}
+	return priority
+}
+
+// PutScanBytesPerSecondInCtx returns a new child context with the specified incremental scan rate stored.
+func PutScanBytesPerSecondInCtx(ctx context.Context, bytesPerSecond uint64) context.Context {
+	return context.WithValue(ctx, ctxKeyScanRate, bytesPerSecond)
+}
+
+// ScanBytesPerSecondFromCtx returns the incremental scan rate stored in the specified context.
+// It returns 0 if there's no scan rate found.
+func ScanBytesPerSecondFromCtx(ctx context.Context) uint64 {
+	bytesPerSecond, ok := ctx.Value(ctxKeyScanRate).(uint64)
+	if !ok {
+		return 0
+	}
+	return bytesPerSecond
+}
+
 // ZapFieldCapture returns a zap field containing capture address
 // TODO: log redact for capture address
 func ZapFieldCapture(ctx context.Context) zap.Field {
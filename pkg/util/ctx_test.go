@@ -125,6 +125,13 @@ func (s *ctxValueSuite) TestKVStorageNotSet(c *check.C) {
 	c.Assert(err, check.NotNil)
 }
 
+func (s *ctxValueSuite) TestRegionScanLimit(c *check.C) {
+	defer testleak.AfterTest(c)()
+	c.Assert(RegionScanLimitFromCtx(context.Background()), check.Equals, 0)
+	ctx := PutRegionScanLimitInCtx(context.Background(), 4)
+	c.Assert(RegionScanLimitFromCtx(ctx), check.Equals, 4)
+}
+
 func (s *ctxValueSuite) TestZapFieldWithContext(c *check.C) {
 	defer testleak.AfterTest(c)()
 	var (
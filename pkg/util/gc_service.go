@@ -26,6 +26,8 @@ const (
 	cdcChangefeedCreatingServiceGCSafePointID = "ticdc-creating-"
 	// cdcChangefeedCreatingServiceGCSafePointTTL is service GC safe point TTL
 	cdcChangefeedCreatingServiceGCSafePointTTL = 10 * 60 // 10 mins
+	// cdcChangefeedValidatingServiceGCSafePointID is the service GC safe point ID used to query the GC safe point
+	cdcChangefeedValidatingServiceGCSafePointID = "ticdc-validating-"
 )
 
 // CheckSafetyOfStartTs checks if the startTs less than the minimum of Service-GC-Ts
@@ -41,3 +43,19 @@ func CheckSafetyOfStartTs(ctx context.Context, pdCli pd.Client, changefeedID str
 	}
 	return nil
 }
+
+// CheckStartTsBeforeGC checks if the startTs less than the minimum of Service-GC-Ts
+// like CheckSafetyOfStartTs, but no service GC safe point is kept in PD.
+func CheckStartTsBeforeGC(ctx context.Context, pdCli pd.Client, changefeedID string, startTs uint64) error {
+	// a service GC safe point whose TTL is not positive is removed immediately,
+	// PD only returns the minimum of the other service GC safe points.
+	minServiceGCTs, err := pdCli.UpdateServiceGCSafePoint(ctx, cdcChangefeedValidatingServiceGCSafePointID+changefeedID,
+		0, startTs)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if startTs < minServiceGCTs {
+		return cerrors.ErrStartTsBeforeGC.GenWithStackByArgs(startTs, minServiceGCTs)
+	}
+	return nil
+}
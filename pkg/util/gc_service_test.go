@@ -48,19 +48,34 @@ func (s *gcServiceSuite) TestCheckSafetyOfStartTs(c *check.C) {
 	})
 }
 
+func (s *gcServiceSuite) TestCheckStartTsBeforeGC(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	pdCli := mockPdClientForServiceGCSafePoint{serviceSafePoint: map[string]uint64{"service1": 60}}
+	err := CheckStartTsBeforeGC(ctx, pdCli, "changefeed1", 50)
+	c.Assert(err, check.ErrorMatches, ".*start-ts 50 is earlier than GC safepoint at 60.*")
+	err = CheckStartTsBeforeGC(ctx, pdCli, "changefeed1", 65)
+	c.Assert(err, check.IsNil)
+	// no service GC safe point is left
+	c.Assert(pdCli.serviceSafePoint, check.DeepEquals, map[string]uint64{"service1": 60})
+}
+
 type mockPdClientForServiceGCSafePoint struct {
 	pd.Client
 	serviceSafePoint map[string]uint64
 }
 
 func (m mockPdClientForServiceGCSafePoint) UpdateServiceGCSafePoint(ctx context.Context, serviceID string, ttl int64, safePoint uint64) (uint64, error) {
+	if ttl <= 0 {
+		delete(m.serviceSafePoint, serviceID)
+	}
 	minSafePoint := uint64(math.MaxUint64)
 	for _, safePoint := range m.serviceSafePoint {
 		if minSafePoint > safePoint {
 			minSafePoint = safePoint
 		}
 	}
-	if safePoint < minSafePoint && len(m.serviceSafePoint) != 0 {
+	if ttl <= 0 || (safePoint < minSafePoint && len(m.serviceSafePoint) != 0) {
 		return minSafePoint, nil
 	}
 	m.serviceSafePoint[serviceID] = safePoint